@@ -0,0 +1,39 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/Viet-CodingStars/kyupi-kyupi-backend/internal/config"
+)
+
+// runConfigCommand handles the `config` subcommand and returns the process exit code.
+func runConfigCommand(cfg *config.Config, args []string, stdout, stderr io.Writer) int {
+	if len(args) != 1 || args[0] != "check" {
+		fmt.Fprintln(stderr, "usage: kyupi-kyupi-backend config check")
+		return 2
+	}
+
+	settings := cfg.Redacted()
+	keys := make([]string, 0, len(settings))
+	for key := range settings {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		fmt.Fprintf(stdout, "%s=%s\n", key, settings[key])
+	}
+
+	problems := cfg.Validate()
+	if len(problems) == 0 {
+		fmt.Fprintln(stdout, "\nconfiguration OK")
+		return 0
+	}
+
+	fmt.Fprintf(stdout, "\n%d configuration problem(s):\n", len(problems))
+	for _, problem := range problems {
+		fmt.Fprintf(stdout, "  - %v\n", problem)
+	}
+	return 1
+}
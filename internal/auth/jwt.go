@@ -17,43 +17,20 @@ var (
 type Claims struct {
 	UserID uuid.UUID `json:"user_id"`
 	Email  string    `json:"email"`
+	// SessionID names the server-side session a session token belongs to.
+	SessionID uuid.UUID `json:"sid,omitempty"`
+	// Purpose is empty for session tokens and names the flow for single-purpose tokens.
+	Purpose string `json:"purpose,omitempty"`
 	jwt.RegisteredClaims
 }
 
-// GenerateToken creates a new JWT token for the given user
+// GenerateToken creates a new HS256 JWT token for the given user signed with a shared secret.
+// The token is not bound to a session.
 func GenerateToken(userID uuid.UUID, email string, secret string) (string, error) {
-	claims := Claims{
-		UserID: userID,
-		Email:  email,
-		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(24 * time.Hour)),
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
-		},
-	}
-
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(secret))
+	return NewHMACKeySet(secret).GenerateToken(userID, email, uuid.Nil, time.Now().Add(TokenTTL))
 }
 
-// ValidateToken validates and parses a JWT token
+// ValidateToken validates and parses an HS256 JWT token signed with a shared secret.
 func ValidateToken(tokenString string, secret string) (*Claims, error) {
-	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, ErrInvalidToken
-		}
-		return []byte(secret), nil
-	})
-
-	if err != nil {
-		return nil, err
-	}
-
-	if claims, ok := token.Claims.(*Claims); ok && token.Valid {
-		if claims.ExpiresAt != nil && claims.ExpiresAt.Before(time.Now()) {
-			return nil, ErrExpiredToken
-		}
-		return claims, nil
-	}
-
-	return nil, ErrInvalidToken
+	return NewHMACKeySet(secret).ValidateToken(tokenString)
 }
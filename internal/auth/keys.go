@@ -0,0 +1,436 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrUnknownKey       = errors.New("unknown signing key")
+	ErrUnsupportedKey   = errors.New("unsupported key type (expected RSA or Ed25519)")
+	ErrNoSigningKey     = errors.New("signing key is required")
+	ErrDuplicateKeyID   = errors.New("duplicate key id")
+	ErrVerificationOnly = errors.New("key cannot be used for signing")
+	errMissingPEMBlock  = errors.New("no PEM block found")
+)
+
+// TokenTTL is the lifetime of session tokens.
+const TokenTTL = 24 * time.Hour
+
+// Key is a single JWT key identified by its kid. Verification-only keys carry no private part.
+type Key struct {
+	ID      string
+	Method  jwt.SigningMethod
+	private crypto.PrivateKey
+	public  crypto.PublicKey
+}
+
+// KeySet signs tokens with one active key and verifies them against every key it holds,
+// which lets a retired key keep validating outstanding tokens during rotation.
+type KeySet struct {
+	signing *Key
+	keys    map[string]*Key
+}
+
+// KeySource describes where KeySet material is loaded from.
+type KeySource struct {
+	Secret           string            // HS256 shared secret, used when no signing key is configured
+	SigningKeyPEM    string            // PEM-encoded private key (RSA or Ed25519)
+	SigningKeyFile   string            // path to a PEM-encoded private key
+	SigningKeyID     string            // kid for the signing key; defaults to its RFC 7638 thumbprint
+	VerificationKeys map[string]string // kid -> path of, or inline, additional PEM keys accepted for verification
+	VerificationJWKS string            // JSON Web Key Set of additional public keys accepted for verification
+}
+
+// JWK is the public JSON Web Key representation of a Key.
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+// JWKS is a JSON Web Key Set document.
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// NewHMACKeySet returns a KeySet that signs and verifies HS256 tokens with a shared secret.
+func NewHMACKeySet(secret string) *KeySet {
+	key := &Key{Method: jwt.SigningMethodHS256, private: []byte(secret), public: []byte(secret)}
+	return &KeySet{signing: key, keys: map[string]*Key{"": key}}
+}
+
+// NewKeySet builds a KeySet that signs with signing and also accepts tokens from the verification keys.
+func NewKeySet(signing *Key, verification ...*Key) (*KeySet, error) {
+	if signing == nil {
+		return nil, ErrNoSigningKey
+	}
+	if signing.private == nil {
+		return nil, ErrVerificationOnly
+	}
+
+	ks := &KeySet{signing: signing, keys: map[string]*Key{}}
+	for _, key := range append([]*Key{signing}, verification...) {
+		if _, exists := ks.keys[key.ID]; exists {
+			return nil, fmt.Errorf("%w: %q", ErrDuplicateKeyID, key.ID)
+		}
+		ks.keys[key.ID] = key
+	}
+	return ks, nil
+}
+
+// LoadKeySet builds a KeySet from the configured source. Without an asymmetric signing key it
+// falls back to HS256 with the shared secret.
+func LoadKeySet(src KeySource) (*KeySet, error) {
+	pemData := []byte(src.SigningKeyPEM)
+	if src.SigningKeyFile != "" {
+		data, err := os.ReadFile(src.SigningKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("read signing key: %w", err)
+		}
+		pemData = data
+	}
+
+	if len(pemData) == 0 {
+		if src.Secret == "" {
+			return nil, ErrNoSigningKey
+		}
+		return NewHMACKeySet(src.Secret), nil
+	}
+
+	signing, err := ParsePrivateKeyPEM(src.SigningKeyID, pemData)
+	if err != nil {
+		return nil, fmt.Errorf("parse signing key: %w", err)
+	}
+
+	ids := make([]string, 0, len(src.VerificationKeys))
+	for id := range src.VerificationKeys {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	verification := make([]*Key, 0, len(ids))
+	for _, id := range ids {
+		data := []byte(src.VerificationKeys[id])
+		if !isInlinePEM(src.VerificationKeys[id]) {
+			data, err = os.ReadFile(src.VerificationKeys[id])
+			if err != nil {
+				return nil, fmt.Errorf("read verification key %q: %w", id, err)
+			}
+		}
+		key, err := ParsePublicKeyPEM(id, data)
+		if err != nil {
+			return nil, fmt.Errorf("parse verification key %q: %w", id, err)
+		}
+		verification = append(verification, key)
+	}
+
+	if src.VerificationJWKS != "" {
+		var set JWKS
+		if err := json.Unmarshal([]byte(src.VerificationJWKS), &set); err != nil {
+			return nil, fmt.Errorf("parse verification JWKS: %w", err)
+		}
+		for _, jwk := range set.Keys {
+			key, err := ParseJWK(jwk)
+			if err != nil {
+				return nil, fmt.Errorf("parse verification JWK %q: %w", jwk.Kid, err)
+			}
+			verification = append(verification, key)
+		}
+	}
+
+	return NewKeySet(signing, verification...)
+}
+
+// isInlinePEM reports whether a configured key is PEM text rather than a path to a PEM file.
+func isInlinePEM(value string) bool {
+	return strings.HasPrefix(strings.TrimSpace(value), "-----BEGIN ")
+}
+
+// ParsePrivateKeyPEM parses an RSA (PKCS#1 or PKCS#8) or Ed25519 (PKCS#8) private key.
+// An empty id is replaced by the key's RFC 7638 thumbprint.
+func ParsePrivateKeyPEM(id string, data []byte) (*Key, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errMissingPEMBlock
+	}
+
+	var parsed interface{}
+	var err error
+	if block.Type == "RSA PRIVATE KEY" {
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	} else {
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var key *Key
+	switch k := parsed.(type) {
+	case *rsa.PrivateKey:
+		key = &Key{Method: jwt.SigningMethodRS256, private: k, public: &k.PublicKey}
+	case ed25519.PrivateKey:
+		key = &Key{Method: jwt.SigningMethodEdDSA, private: k, public: k.Public()}
+	default:
+		return nil, ErrUnsupportedKey
+	}
+	return withKeyID(key, id)
+}
+
+// ParsePublicKeyPEM parses an RSA or Ed25519 public key. Private keys are accepted too,
+// in which case only their public half is kept.
+func ParsePublicKeyPEM(id string, data []byte) (*Key, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errMissingPEMBlock
+	}
+
+	switch block.Type {
+	case "PRIVATE KEY", "RSA PRIVATE KEY":
+		key, err := ParsePrivateKeyPEM(id, data)
+		if err != nil {
+			return nil, err
+		}
+		key.private = nil
+		return key, nil
+	case "RSA PUBLIC KEY":
+		pub, err := x509.ParsePKCS1PublicKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		return withKeyID(&Key{Method: jwt.SigningMethodRS256, public: pub}, id)
+	}
+
+	parsed, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	switch k := parsed.(type) {
+	case *rsa.PublicKey:
+		return withKeyID(&Key{Method: jwt.SigningMethodRS256, public: k}, id)
+	case ed25519.PublicKey:
+		return withKeyID(&Key{Method: jwt.SigningMethodEdDSA, public: k}, id)
+	default:
+		return nil, ErrUnsupportedKey
+	}
+}
+
+// ParseJWK parses an RSA or Ed25519 public JSON Web Key. A missing kid is replaced by the
+// key's RFC 7638 thumbprint.
+func ParseJWK(jwk JWK) (*Key, error) {
+	var key *Key
+	switch {
+	case jwk.Kty == "RSA":
+		n, err := base64.RawURLEncoding.DecodeString(jwk.N)
+		if err != nil {
+			return nil, fmt.Errorf("decode n: %w", err)
+		}
+		e, err := base64.RawURLEncoding.DecodeString(jwk.E)
+		if err != nil {
+			return nil, fmt.Errorf("decode e: %w", err)
+		}
+		if len(n) == 0 || len(e) == 0 || len(e) > 4 {
+			return nil, errors.New("invalid RSA key")
+		}
+		pub := &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+		key = &Key{Method: jwt.SigningMethodRS256, public: pub}
+	case jwk.Kty == "OKP" && jwk.Crv == "Ed25519":
+		x, err := base64.RawURLEncoding.DecodeString(jwk.X)
+		if err != nil {
+			return nil, fmt.Errorf("decode x: %w", err)
+		}
+		if len(x) != ed25519.PublicKeySize {
+			return nil, errors.New("invalid Ed25519 key")
+		}
+		key = &Key{Method: jwt.SigningMethodEdDSA, public: ed25519.PublicKey(x)}
+	default:
+		return nil, ErrUnsupportedKey
+	}
+	if jwk.Alg != "" && jwk.Alg != key.Method.Alg() {
+		return nil, fmt.Errorf("alg %q does not match %s key", jwk.Alg, jwk.Kty)
+	}
+	return withKeyID(key, jwk.Kid)
+}
+
+func withKeyID(key *Key, id string) (*Key, error) {
+	if id == "" {
+		thumbprint, err := key.thumbprint()
+		if err != nil {
+			return nil, err
+		}
+		id = thumbprint
+	}
+	key.ID = id
+	return key, nil
+}
+
+// JWK returns the public JSON Web Key for k. HMAC keys have no public form.
+func (k *Key) JWK() (JWK, error) {
+	jwk := JWK{Kid: k.ID, Use: "sig", Alg: k.Method.Alg()}
+	switch pub := k.public.(type) {
+	case *rsa.PublicKey:
+		jwk.Kty = "RSA"
+		jwk.N = base64.RawURLEncoding.EncodeToString(pub.N.Bytes())
+		jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes())
+	case ed25519.PublicKey:
+		jwk.Kty = "OKP"
+		jwk.Crv = "Ed25519"
+		jwk.X = base64.RawURLEncoding.EncodeToString(pub)
+	default:
+		return JWK{}, ErrUnsupportedKey
+	}
+	return jwk, nil
+}
+
+// thumbprint computes the RFC 7638 JWK thumbprint of the public key.
+func (k *Key) thumbprint() (string, error) {
+	jwk, err := k.JWK()
+	if err != nil {
+		return "", err
+	}
+
+	// Members must be serialized in lexicographic order; encoding/json sorts map keys.
+	members := map[string]string{"kty": jwk.Kty}
+	switch jwk.Kty {
+	case "RSA":
+		members["n"] = jwk.N
+		members["e"] = jwk.E
+	case "OKP":
+		members["crv"] = jwk.Crv
+		members["x"] = jwk.X
+	}
+	canonical, err := json.Marshal(members)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(canonical)
+	return base64.RawURLEncoding.EncodeToString(sum[:]), nil
+}
+
+// SigningKeyID returns the kid of the active signing key.
+func (ks *KeySet) SigningKeyID() string {
+	return ks.signing.ID
+}
+
+// JWKS returns the public keys of the set. HMAC secrets are never published.
+func (ks *KeySet) JWKS() JWKS {
+	ids := make([]string, 0, len(ks.keys))
+	for id := range ks.keys {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	set := JWKS{Keys: []JWK{}}
+	for _, id := range ids {
+		jwk, err := ks.keys[id].JWK()
+		if err != nil {
+			continue
+		}
+		set.Keys = append(set.Keys, jwk)
+	}
+	return set
+}
+
+// GenerateToken creates a session token for the given user signed with the active key.
+// The token is bound to the server-side session sessionID and expires with it.
+func (ks *KeySet) GenerateToken(userID uuid.UUID, email string, sessionID uuid.UUID, expiresAt time.Time) (string, error) {
+	claims := Claims{
+		UserID:    userID,
+		Email:     email,
+		SessionID: sessionID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+		},
+	}
+	return ks.sign(claims)
+}
+
+// SignClaims signs arbitrary claims with the active key. It is used for short-lived,
+// single-purpose tokens; such claims must carry a non-empty purpose so they are never
+// accepted as session tokens.
+func (ks *KeySet) SignClaims(claims jwt.Claims) (string, error) {
+	return ks.sign(claims)
+}
+
+func (ks *KeySet) sign(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(ks.signing.Method, claims)
+	if ks.signing.ID != "" {
+		token.Header["kid"] = ks.signing.ID
+	}
+	return token.SignedString(ks.signing.private)
+}
+
+// ParseClaims verifies a token signed by any key in the set and decodes it into claims.
+func (ks *KeySet) ParseClaims(tokenString string, claims jwt.Claims) error {
+	token, err := jwt.ParseWithClaims(tokenString, claims, ks.keyFunc, jwt.WithValidMethods(ks.methods()))
+	if err != nil {
+		if errors.Is(err, jwt.ErrTokenExpired) {
+			return ErrExpiredToken
+		}
+		return err
+	}
+	if !token.Valid {
+		return ErrInvalidToken
+	}
+	return nil
+}
+
+// ValidateToken verifies a session token against the key named by its kid header and parses its claims.
+func (ks *KeySet) ValidateToken(tokenString string) (*Claims, error) {
+	claims := &Claims{}
+	if err := ks.ParseClaims(tokenString, claims); err != nil {
+		return nil, err
+	}
+	if claims.Purpose != "" {
+		return nil, ErrInvalidToken
+	}
+	return claims, nil
+}
+
+func (ks *KeySet) keyFunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	key, ok := ks.keys[strings.TrimSpace(kid)]
+	if !ok {
+		return nil, ErrUnknownKey
+	}
+	if token.Method.Alg() != key.Method.Alg() {
+		return nil, ErrInvalidToken
+	}
+	return key.public, nil
+}
+
+func (ks *KeySet) methods() []string {
+	seen := map[string]bool{}
+	methods := []string{}
+	for _, key := range ks.keys {
+		alg := key.Method.Alg()
+		if !seen[alg] {
+			seen[alg] = true
+			methods = append(methods, alg)
+		}
+	}
+	return methods
+}
@@ -0,0 +1,249 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func rsaPrivatePEM(t *testing.T) []byte {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generate rsa key: %v", err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+}
+
+func ed25519PrivatePEM(t *testing.T) []byte {
+	t.Helper()
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("generate ed25519 key: %v", err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatalf("marshal ed25519 key: %v", err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+}
+
+func TestKeySetSignAndValidate(t *testing.T) {
+	userID := uuid.New()
+
+	for name, pemData := range map[string][]byte{
+		"RS256": rsaPrivatePEM(t),
+		"EdDSA": ed25519PrivatePEM(t),
+	} {
+		t.Run(name, func(t *testing.T) {
+			key, err := ParsePrivateKeyPEM("", pemData)
+			if err != nil {
+				t.Fatalf("expected no error parsing key, got %v", err)
+			}
+			if key.ID == "" {
+				t.Fatal("expected thumbprint kid")
+			}
+			if key.Method.Alg() != name {
+				t.Fatalf("expected alg %s, got %s", name, key.Method.Alg())
+			}
+
+			ks, err := NewKeySet(key)
+			if err != nil {
+				t.Fatalf("expected no error building key set, got %v", err)
+			}
+
+			sessionID := uuid.New()
+			token, err := ks.GenerateToken(userID, "test@example.com", sessionID, time.Now().Add(TokenTTL))
+			if err != nil {
+				t.Fatalf("expected no error generating token, got %v", err)
+			}
+
+			claims, err := ks.ValidateToken(token)
+			if err != nil {
+				t.Fatalf("expected no error validating token, got %v", err)
+			}
+			if claims.UserID != userID {
+				t.Fatalf("expected userID %v, got %v", userID, claims.UserID)
+			}
+			if claims.SessionID != sessionID {
+				t.Fatalf("expected sessionID %v, got %v", sessionID, claims.SessionID)
+			}
+
+			jwks := ks.JWKS()
+			if len(jwks.Keys) != 1 || jwks.Keys[0].Kid != key.ID || jwks.Keys[0].Alg != name {
+				t.Fatalf("unexpected JWKS: %+v", jwks)
+			}
+		})
+	}
+}
+
+func TestKeySetRotation(t *testing.T) {
+	oldKey, err := ParsePrivateKeyPEM("2024-01", rsaPrivatePEM(t))
+	if err != nil {
+		t.Fatalf("parse old key: %v", err)
+	}
+	newKey, err := ParsePrivateKeyPEM("2024-02", ed25519PrivatePEM(t))
+	if err != nil {
+		t.Fatalf("parse new key: %v", err)
+	}
+
+	oldSet, _ := NewKeySet(oldKey)
+	token, err := oldSet.GenerateToken(uuid.New(), "test@example.com", uuid.New(), time.Now().Add(TokenTTL))
+	if err != nil {
+		t.Fatalf("generate token: %v", err)
+	}
+
+	// After rotation the old key is kept for verification only.
+	oldKey.private = nil
+	rotated, err := NewKeySet(newKey, oldKey)
+	if err != nil {
+		t.Fatalf("build rotated set: %v", err)
+	}
+	if _, err := rotated.ValidateToken(token); err != nil {
+		t.Fatalf("expected token signed by retired key to validate, got %v", err)
+	}
+	if got := len(rotated.JWKS().Keys); got != 2 {
+		t.Fatalf("expected 2 published keys, got %d", got)
+	}
+
+	// Once the old key is dropped its tokens are rejected.
+	dropped, _ := NewKeySet(newKey)
+	if _, err := dropped.ValidateToken(token); err == nil {
+		t.Fatal("expected error for token signed by unknown key")
+	}
+
+	if _, err := NewKeySet(oldKey); err != ErrVerificationOnly {
+		t.Fatalf("expected ErrVerificationOnly, got %v", err)
+	}
+}
+
+func TestKeySetRejectsAlgorithmConfusion(t *testing.T) {
+	hmacSet := NewHMACKeySet("test-secret")
+	token, err := hmacSet.GenerateToken(uuid.New(), "test@example.com", uuid.New(), time.Now().Add(TokenTTL))
+	if err != nil {
+		t.Fatalf("generate token: %v", err)
+	}
+
+	key, err := ParsePrivateKeyPEM("", rsaPrivatePEM(t))
+	if err != nil {
+		t.Fatalf("parse key: %v", err)
+	}
+	rsaSet, _ := NewKeySet(key)
+	if _, err := rsaSet.ValidateToken(token); err == nil {
+		t.Fatal("expected HS256 token to be rejected by RS256 key set")
+	}
+	if got := len(hmacSet.JWKS().Keys); got != 0 {
+		t.Fatalf("expected HMAC secret not to be published, got %d keys", got)
+	}
+}
+
+func TestLoadKeySet(t *testing.T) {
+	t.Run("falls back to shared secret", func(t *testing.T) {
+		ks, err := LoadKeySet(KeySource{Secret: "test-secret"})
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		token, _ := ks.GenerateToken(uuid.New(), "test@example.com", uuid.New(), time.Now().Add(TokenTTL))
+		if _, err := ValidateToken(token, "test-secret"); err != nil {
+			t.Fatalf("expected HS256 token, got %v", err)
+		}
+	})
+
+	t.Run("loads signing and verification keys from files", func(t *testing.T) {
+		dir := t.TempDir()
+		signingPath := filepath.Join(dir, "signing.pem")
+		previousPath := filepath.Join(dir, "previous.pem")
+		if err := os.WriteFile(signingPath, ed25519PrivatePEM(t), 0o600); err != nil {
+			t.Fatalf("write signing key: %v", err)
+		}
+		if err := os.WriteFile(previousPath, rsaPrivatePEM(t), 0o600); err != nil {
+			t.Fatalf("write previous key: %v", err)
+		}
+
+		ks, err := LoadKeySet(KeySource{
+			Secret:           "ignored",
+			SigningKeyFile:   signingPath,
+			SigningKeyID:     "current",
+			VerificationKeys: map[string]string{"previous": previousPath},
+		})
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if ks.SigningKeyID() != "current" {
+			t.Fatalf("expected kid current, got %s", ks.SigningKeyID())
+		}
+		jwks := ks.JWKS()
+		if len(jwks.Keys) != 2 || jwks.Keys[0].Kid != "current" || jwks.Keys[1].Kid != "previous" {
+			t.Fatalf("unexpected JWKS: %+v", jwks)
+		}
+	})
+
+	t.Run("accepts inline PEM and JWK verification keys", func(t *testing.T) {
+		previous, err := ParsePublicKeyPEM("", rsaPrivatePEM(t))
+		if err != nil {
+			t.Fatalf("parse previous key: %v", err)
+		}
+		older, err := ParsePublicKeyPEM("older", ed25519PrivatePEM(t))
+		if err != nil {
+			t.Fatalf("parse older key: %v", err)
+		}
+		previousJWK, _ := previous.JWK()
+		olderJWK, _ := older.JWK()
+		jwks, _ := json.Marshal(JWKS{Keys: []JWK{olderJWK}})
+
+		ks, err := LoadKeySet(KeySource{
+			SigningKeyPEM:    string(ed25519PrivatePEM(t)),
+			SigningKeyID:     "current",
+			VerificationKeys: map[string]string{"previous": string(rsaPrivatePEM(t))},
+			VerificationJWKS: string(jwks),
+		})
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		set := ks.JWKS()
+		if len(set.Keys) != 3 || set.Keys[0].Kid != "current" || set.Keys[1].Kid != "older" || set.Keys[2].Kid != "previous" {
+			t.Fatalf("unexpected JWKS: %+v", set)
+		}
+		if set.Keys[1].X != olderJWK.X {
+			t.Fatal("expected the JWK to round-trip")
+		}
+		if _, err := ParseJWK(JWK{Kty: "RSA", N: previousJWK.N, E: previousJWK.E, Alg: "EdDSA"}); err == nil {
+			t.Fatal("expected a mismatched alg to be rejected")
+		}
+		if _, err := LoadKeySet(KeySource{SigningKeyPEM: string(ed25519PrivatePEM(t)), VerificationJWKS: "not json"}); err == nil {
+			t.Fatal("expected an invalid JWKS to be rejected")
+		}
+	})
+
+	t.Run("missing secret and key", func(t *testing.T) {
+		if _, err := LoadKeySet(KeySource{}); err != ErrNoSigningKey {
+			t.Fatalf("expected ErrNoSigningKey, got %v", err)
+		}
+	})
+}
+
+func TestValidateTokenRejectsPurposeTokens(t *testing.T) {
+	ks := NewHMACKeySet("test-secret")
+	token, err := ks.SignClaims(Claims{UserID: uuid.New(), Purpose: "mfa"})
+	if err != nil {
+		t.Fatalf("sign claims: %v", err)
+	}
+
+	if _, err := ks.ValidateToken(token); err != ErrInvalidToken {
+		t.Fatalf("expected ErrInvalidToken for purpose token, got %v", err)
+	}
+
+	var claims Claims
+	if err := ks.ParseClaims(token, &claims); err != nil || claims.Purpose != "mfa" {
+		t.Fatalf("expected purpose token to parse, got %v (%+v)", err, claims)
+	}
+}
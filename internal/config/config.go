@@ -17,17 +17,68 @@ const (
 
 // Config holds application configuration loaded from environment variables.
 type Config struct {
-	Env         string // APP_ENV: DEVELOPMENT, TESTING, PRODUCTION
-	Port        string // PORT, e.g. "8080"
-	LogLevel    string // LOG_LEVEL: debug/info/warn/error
-	LogDir      string // LOG_DIR: directory where application logs are written
-	JWTSecret   string // JWT_SECRET: secret key for signing JWT tokens
-	DatabaseURL string // DATABASE_URL, optional (generic)
+	Env       string // APP_ENV: DEVELOPMENT, TESTING, PRODUCTION
+	Port      string // PORT, e.g. "8080"
+	LogLevel  string // LOG_LEVEL: debug/info/warn/error
+	LogDir    string // LOG_DIR: directory where application logs are written
+	JWTSecret string // JWT_SECRET: secret key for signing JWT tokens (HS256 fallback)
+	// Asymmetric JWT signing (RS256/EdDSA); takes precedence over JWT_SECRET when set
+	JWTSigningKey       string            // JWT_SIGNING_KEY: PEM-encoded private key
+	JWTSigningKeyFile   string            // JWT_SIGNING_KEY_FILE: path to a PEM-encoded private key
+	JWTSigningKeyID     string            // JWT_SIGNING_KEY_ID: kid header value, defaults to the key thumbprint
+	JWTVerificationKeys map[string]string // JWT_VERIFICATION_KEYS: "kid=path,kid=path" of keys still accepted for verification; a path may be replaced by an inline PEM
+	JWTVerificationJWKS string            // JWT_VERIFICATION_JWKS: inline JSON Web Key Set of further keys still accepted for verification
+	DatabaseURL         string            // DATABASE_URL, optional (generic)
 	// Database connection URLs (preferred) or their components
 	PostgresURL      string // POSTGRES_URL, optional full DSN
 	MongoURL         string // MONGODB_URL, optional full connection string
 	AvatarStorageDir string // AVATAR_STORAGE_DIR: location on disk for avatars
 	AvatarURLPrefix  string // AVATAR_URL_PREFIX: public URL prefix for avatar files
+	// VerificationStorageDir holds photo verification selfies; unlike avatars it is never served publicly
+	VerificationStorageDir string // VERIFICATION_STORAGE_DIR
+	// CORS policy for browser clients
+	CORSAllowOrigins     []string      // CORS_ALLOW_ORIGINS: exact origins or https://*.example.com patterns, or "*"
+	CORSAllowMethods     []string      // CORS_ALLOW_METHODS
+	CORSAllowHeaders     []string      // CORS_ALLOW_HEADERS
+	CORSAllowCredentials bool          // CORS_ALLOW_CREDENTIALS
+	CORSMaxAge           time.Duration // CORS_MAX_AGE: how long browsers may cache preflight results
+	// TrustedProxies are the reverse proxies (IPs or CIDRs) whose X-Forwarded-For header is believed
+	// when deriving the client IP. Empty trusts none, so the connection's address is used.
+	TrustedProxies []string // TRUSTED_PROXIES
+	// OpenID Connect social login providers (OIDC_PROVIDERS plus OIDC_<NAME>_* variables)
+	OIDCProviders []OIDCProvider
+	// Account deletion
+	AccountDeletionGracePeriod time.Duration // ACCOUNT_DELETION_GRACE_PERIOD: how long a deleted account can still be restored
+	AccountPurgeInterval       time.Duration // ACCOUNT_PURGE_INTERVAL: how often accounts past their grace period are purged; 0 disables the in-process job
+	// Personal data exports
+	ExportStorageDir    string        // EXPORT_STORAGE_DIR: location on disk for export archives
+	DataExportRetention time.Duration // DATA_EXPORT_RETENTION: how long a finished export can be downloaded
+	DataExportInterval  time.Duration // DATA_EXPORT_INTERVAL: how often pending exports are built; 0 disables the in-process job
+	// Account pause
+	PauseResumeInterval time.Duration // PAUSE_RESUME_INTERVAL: how often paused accounts past their resume date are resumed; 0 disables the in-process job
+	// Super likes
+	SuperLikeDailyQuota int // SUPER_LIKE_DAILY_QUOTA: super likes each user may send per day, reset at their local midnight
+	// Rewinding passes
+	RewindWindow     time.Duration // REWIND_WINDOW: how long after a pass it can still be undone
+	RewindDailyLimit int           // REWIND_DAILY_LIMIT: passes each user may undo per day, reset at their local midnight
+	// Pass expiry
+	PassExpiry         time.Duration // PASS_EXPIRY: how long a pass hides a profile from the user; 0 keeps passes forever
+	PassExpiryInterval time.Duration // PASS_EXPIRY_INTERVAL: how often expired passes are removed; 0 disables the in-process job
+	// Discovery ranking
+	DiscoveryWeights         map[string]string // DISCOVERY_WEIGHTS: "signal=weight,..." overrides of the default ranking weights
+	DiscoveryCacheTTL        time.Duration     // DISCOVERY_CACHE_TTL: how long a user's precomputed ranking is served before it is recomputed
+	DiscoveryCacheSize       int               // DISCOVERY_CACHE_SIZE: how many ranked candidates are kept per user
+	DiscoveryRefreshInterval time.Duration     // DISCOVERY_REFRESH_INTERVAL: how often stale rankings of active users are recomputed; 0 disables the in-process job
+	DiscoveryMinCompleteness int               // DISCOVERY_MIN_COMPLETENESS: percentage of onboarding steps a profile needs before it is shown in discovery
+	DesirabilityInterval     time.Duration     // DESIRABILITY_INTERVAL: how often desirability scores are recomputed from swipe history; 0 disables the in-process job
+	// Daily picks
+	DailyPicksCount    int // DAILY_PICKS_COUNT: how many picks each user gets per day, reselected at their local midnight
+	PickLikeDailyQuota int // PICK_LIKE_DAILY_QUOTA: picks each user may like per day, on top of regular likes
+	// Profile
+	MaxInterests int // MAX_INTERESTS: how many interests from the catalog each user may pick
+	// Fake account detection
+	ModerationScanInterval time.Duration // MODERATION_SCAN_INTERVAL: how often new accounts are screened for duplicates and fakes; 0 disables the in-process job
+	ModerationAutoRestrict bool          // MODERATION_AUTO_RESTRICT: shadow-restrict flagged accounts until an admin reviews the flag
 	// Postgres individual parts (used when POSTGRES_URL not provided)
 	PostgresUser            string
 	PostgresPassword        string
@@ -48,6 +99,16 @@ type Config struct {
 	MongoReplicaSet string
 }
 
+// OIDCProvider is a relying-party registration with an OpenID Connect issuer.
+type OIDCProvider struct {
+	Name         string   // lower-case provider name used in URLs, e.g. "google"
+	Issuer       string   // OIDC_<NAME>_ISSUER
+	ClientID     string   // OIDC_<NAME>_CLIENT_ID
+	ClientSecret string   // OIDC_<NAME>_CLIENT_SECRET
+	RedirectURL  string   // OIDC_<NAME>_REDIRECT_URL: frontend page that receives the authorization code
+	Scopes       []string // OIDC_<NAME>_SCOPES, default "openid,email,profile"
+}
+
 // LoadFromEnv reads configuration from environment variables and returns a Config with defaults.
 func LoadFromEnv() *Config {
 	env := strings.ToUpper(strings.TrimSpace(os.Getenv("APP_ENV")))
@@ -72,9 +133,19 @@ func LoadFromEnv() *Config {
 
 	jwtSecret := strings.TrimSpace(os.Getenv("JWT_SECRET"))
 	if jwtSecret == "" {
-		jwtSecret = "default-secret-change-in-production"
+		jwtSecret = DefaultJWTSecret
 	}
 
+	// PEM blocks passed through a single-line env var may carry escaped newlines
+	jwtSigningKey := strings.ReplaceAll(strings.TrimSpace(os.Getenv("JWT_SIGNING_KEY")), `\n`, "\n")
+	jwtSigningKeyFile := strings.TrimSpace(os.Getenv("JWT_SIGNING_KEY_FILE"))
+	jwtSigningKeyID := strings.TrimSpace(os.Getenv("JWT_SIGNING_KEY_ID"))
+	jwtVerificationKeys := parseKeyValueListEnv("JWT_VERIFICATION_KEYS")
+	for kid, key := range jwtVerificationKeys {
+		jwtVerificationKeys[kid] = strings.ReplaceAll(key, `\n`, "\n")
+	}
+	jwtVerificationJWKS := strings.TrimSpace(os.Getenv("JWT_VERIFICATION_JWKS"))
+
 	db := strings.TrimSpace(os.Getenv("DATABASE_URL"))
 
 	pgURL := strings.TrimSpace(os.Getenv("POSTGRES_URL"))
@@ -90,6 +161,57 @@ func LoadFromEnv() *Config {
 	if !strings.HasPrefix(avatarPrefix, "/") {
 		avatarPrefix = "/" + avatarPrefix
 	}
+	verificationDir := strings.TrimSpace(os.Getenv("VERIFICATION_STORAGE_DIR"))
+	if verificationDir == "" {
+		verificationDir = "storage/verifications"
+	}
+
+	corsOrigins := parseListEnv("CORS_ALLOW_ORIGINS", []string{"http://localhost:5174"})
+	corsMethods := parseListEnv("CORS_ALLOW_METHODS", []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"})
+	corsHeaders := parseListEnv("CORS_ALLOW_HEADERS", []string{"Origin", "Content-Type", "Accept", "Authorization", "X-Device-Name"})
+	corsCredentials := parseBoolEnv("CORS_ALLOW_CREDENTIALS", true)
+	corsMaxAge := parseDurationEnv("CORS_MAX_AGE", 12*time.Hour)
+	trustedProxies := parseListEnv("TRUSTED_PROXIES", nil)
+
+	var oidcProviders []OIDCProvider
+	for _, name := range parseListEnv("OIDC_PROVIDERS", nil) {
+		prefix := "OIDC_" + strings.ToUpper(name) + "_"
+		oidcProviders = append(oidcProviders, OIDCProvider{
+			Name:         strings.ToLower(name),
+			Issuer:       strings.TrimSpace(os.Getenv(prefix + "ISSUER")),
+			ClientID:     strings.TrimSpace(os.Getenv(prefix + "CLIENT_ID")),
+			ClientSecret: strings.TrimSpace(os.Getenv(prefix + "CLIENT_SECRET")),
+			RedirectURL:  strings.TrimSpace(os.Getenv(prefix + "REDIRECT_URL")),
+			Scopes:       parseListEnv(prefix+"SCOPES", []string{"openid", "email", "profile"}),
+		})
+	}
+
+	deletionGracePeriod := parseDurationEnv("ACCOUNT_DELETION_GRACE_PERIOD", 30*24*time.Hour)
+	purgeInterval := parseDurationEnv("ACCOUNT_PURGE_INTERVAL", time.Hour)
+
+	exportDir := strings.TrimSpace(os.Getenv("EXPORT_STORAGE_DIR"))
+	if exportDir == "" {
+		exportDir = "storage/exports"
+	}
+	exportRetention := parseDurationEnv("DATA_EXPORT_RETENTION", 72*time.Hour)
+	exportInterval := parseDurationEnv("DATA_EXPORT_INTERVAL", time.Minute)
+	pauseResumeInterval := parseDurationEnv("PAUSE_RESUME_INTERVAL", 5*time.Minute)
+	superLikeDailyQuota := parseIntEnv("SUPER_LIKE_DAILY_QUOTA", 1)
+	rewindWindow := parseDurationEnv("REWIND_WINDOW", 5*time.Minute)
+	rewindDailyLimit := parseIntEnv("REWIND_DAILY_LIMIT", 3)
+	passExpiry := parseDurationEnv("PASS_EXPIRY", 90*24*time.Hour)
+	passExpiryInterval := parseDurationEnv("PASS_EXPIRY_INTERVAL", time.Hour)
+	discoveryWeights := parseKeyValueListEnv("DISCOVERY_WEIGHTS")
+	discoveryCacheTTL := parseDurationEnv("DISCOVERY_CACHE_TTL", time.Hour)
+	discoveryCacheSize := parseIntEnv("DISCOVERY_CACHE_SIZE", 500)
+	discoveryRefreshInterval := parseDurationEnv("DISCOVERY_REFRESH_INTERVAL", 15*time.Minute)
+	discoveryMinCompleteness := parseIntEnv("DISCOVERY_MIN_COMPLETENESS", 40)
+	desirabilityInterval := parseDurationEnv("DESIRABILITY_INTERVAL", 6*time.Hour)
+	dailyPicksCount := parseIntEnv("DAILY_PICKS_COUNT", 10)
+	pickLikeDailyQuota := parseIntEnv("PICK_LIKE_DAILY_QUOTA", 3)
+	maxInterests := parseIntEnv("MAX_INTERESTS", 10)
+	moderationScanInterval := parseDurationEnv("MODERATION_SCAN_INTERVAL", 15*time.Minute)
+	moderationAutoRestrict := parseBoolEnv("MODERATION_AUTO_RESTRICT", false)
 
 	// Postgres components (fallbacks)
 	pgUser := strings.TrimSpace(os.Getenv("POSTGRES_USER"))
@@ -142,32 +264,67 @@ func LoadFromEnv() *Config {
 	mongoReplicaSet := strings.TrimSpace(os.Getenv("MONGO_REPLICA_SET"))
 
 	return &Config{
-		Env:                     env,
-		Port:                    port,
-		LogLevel:                logLevel,
-		LogDir:                  logDir,
-		JWTSecret:               jwtSecret,
-		DatabaseURL:             db,
-		PostgresURL:             pgURL,
-		MongoURL:                mongoURL,
-		AvatarStorageDir:        avatarDir,
-		AvatarURLPrefix:         avatarPrefix,
-		PostgresUser:            pgUser,
-		PostgresPassword:        pgPass,
-		PostgresHost:            pgHost,
-		PostgresPort:            pgPort,
-		PostgresDB:              pgDB,
-		PostgresSSLMode:         pgSSLMode,
-		PostgresMaxOpenConns:    pgMaxOpen,
-		PostgresMaxIdleConns:    pgMaxIdle,
-		PostgresConnMaxLifetime: pgConnLife,
-		MongoHost:               mongoHost,
-		MongoPort:               mongoPort,
-		MongoUser:               mongoUser,
-		MongoPassword:           mongoPassword,
-		MongoDatabase:           mongoDB,
-		MongoAuthSource:         mongoAuthSource,
-		MongoReplicaSet:         mongoReplicaSet,
+		Env:                        env,
+		Port:                       port,
+		LogLevel:                   logLevel,
+		LogDir:                     logDir,
+		JWTSecret:                  jwtSecret,
+		JWTSigningKey:              jwtSigningKey,
+		JWTSigningKeyFile:          jwtSigningKeyFile,
+		JWTSigningKeyID:            jwtSigningKeyID,
+		JWTVerificationKeys:        jwtVerificationKeys,
+		JWTVerificationJWKS:        jwtVerificationJWKS,
+		DatabaseURL:                db,
+		PostgresURL:                pgURL,
+		MongoURL:                   mongoURL,
+		AvatarStorageDir:           avatarDir,
+		AvatarURLPrefix:            avatarPrefix,
+		VerificationStorageDir:     verificationDir,
+		CORSAllowOrigins:           corsOrigins,
+		CORSAllowMethods:           corsMethods,
+		CORSAllowHeaders:           corsHeaders,
+		CORSAllowCredentials:       corsCredentials,
+		CORSMaxAge:                 corsMaxAge,
+		TrustedProxies:             trustedProxies,
+		OIDCProviders:              oidcProviders,
+		AccountDeletionGracePeriod: deletionGracePeriod,
+		AccountPurgeInterval:       purgeInterval,
+		ExportStorageDir:           exportDir,
+		DataExportRetention:        exportRetention,
+		DataExportInterval:         exportInterval,
+		PauseResumeInterval:        pauseResumeInterval,
+		SuperLikeDailyQuota:        superLikeDailyQuota,
+		RewindWindow:               rewindWindow,
+		RewindDailyLimit:           rewindDailyLimit,
+		PassExpiry:                 passExpiry,
+		PassExpiryInterval:         passExpiryInterval,
+		DiscoveryWeights:           discoveryWeights,
+		DiscoveryCacheTTL:          discoveryCacheTTL,
+		DiscoveryCacheSize:         discoveryCacheSize,
+		DiscoveryRefreshInterval:   discoveryRefreshInterval,
+		DiscoveryMinCompleteness:   discoveryMinCompleteness,
+		DesirabilityInterval:       desirabilityInterval,
+		DailyPicksCount:            dailyPicksCount,
+		PickLikeDailyQuota:         pickLikeDailyQuota,
+		MaxInterests:               maxInterests,
+		ModerationScanInterval:     moderationScanInterval,
+		ModerationAutoRestrict:     moderationAutoRestrict,
+		PostgresUser:               pgUser,
+		PostgresPassword:           pgPass,
+		PostgresHost:               pgHost,
+		PostgresPort:               pgPort,
+		PostgresDB:                 pgDB,
+		PostgresSSLMode:            pgSSLMode,
+		PostgresMaxOpenConns:       pgMaxOpen,
+		PostgresMaxIdleConns:       pgMaxIdle,
+		PostgresConnMaxLifetime:    pgConnLife,
+		MongoHost:                  mongoHost,
+		MongoPort:                  mongoPort,
+		MongoUser:                  mongoUser,
+		MongoPassword:              mongoPassword,
+		MongoDatabase:              mongoDB,
+		MongoAuthSource:            mongoAuthSource,
+		MongoReplicaSet:            mongoReplicaSet,
 	}
 }
 
@@ -272,3 +429,45 @@ func parseDurationEnv(key string, def time.Duration) time.Duration {
 	}
 	return d
 }
+
+func parseBoolEnv(key string, def bool) bool {
+	v := strings.TrimSpace(os.Getenv(key))
+	if v == "" {
+		return def
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		return def
+	}
+	return b
+}
+
+// parseListEnv parses a comma-separated list, dropping empty entries.
+func parseListEnv(key string, def []string) []string {
+	v := strings.TrimSpace(os.Getenv(key))
+	if v == "" {
+		return def
+	}
+	var list []string
+	for _, item := range strings.Split(v, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+// parseKeyValueListEnv parses a comma-separated list of key=value pairs. Malformed entries are skipped.
+func parseKeyValueListEnv(key string) map[string]string {
+	result := map[string]string{}
+	for _, entry := range strings.Split(os.Getenv(key), ",") {
+		name, value, ok := strings.Cut(strings.TrimSpace(entry), "=")
+		name = strings.TrimSpace(name)
+		value = strings.TrimSpace(value)
+		if !ok || name == "" || value == "" {
+			continue
+		}
+		result[name] = value
+	}
+	return result
+}
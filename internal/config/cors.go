@@ -0,0 +1,119 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+)
+
+// corsAnyOrigin allows every origin; it cannot be combined with credentials.
+const corsAnyOrigin = "*"
+
+// originPattern is a parsed CORS_ALLOW_ORIGINS entry: either an exact origin or a wildcard
+// subdomain such as https://*.kyupi.vn, which matches exactly one extra label.
+type originPattern struct {
+	scheme   string
+	host     string // without the "*." prefix for wildcard patterns
+	port     string
+	wildcard bool
+}
+
+// parseOriginPattern parses scheme://host[:port] with an optional leading "*." label.
+func parseOriginPattern(raw string) (originPattern, error) {
+	u, err := url.Parse(raw)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return originPattern{}, fmt.Errorf("CORS origin %q must look like https://example.com", raw)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return originPattern{}, fmt.Errorf("CORS origin %q must use http or https", raw)
+	}
+	if (u.Path != "" && u.Path != "/") || u.RawQuery != "" || u.Fragment != "" || u.User != nil {
+		return originPattern{}, fmt.Errorf("CORS origin %q must not contain a path, query or credentials", raw)
+	}
+
+	p := originPattern{scheme: u.Scheme, host: strings.ToLower(u.Hostname()), port: u.Port()}
+	if strings.HasPrefix(p.host, "*.") {
+		p.wildcard = true
+		p.host = strings.TrimPrefix(p.host, "*.")
+		// Refuse wildcards directly under a top-level domain such as https://*.com.
+		if !strings.Contains(p.host, ".") {
+			return originPattern{}, fmt.Errorf("CORS origin %q wildcard is too broad", raw)
+		}
+	}
+	if strings.Contains(p.host, "*") {
+		return originPattern{}, fmt.Errorf("CORS origin %q may only use a wildcard as the leftmost label", raw)
+	}
+	return p, nil
+}
+
+func (p originPattern) matches(origin string) bool {
+	u, err := url.Parse(origin)
+	if err != nil || u.Scheme != p.scheme || u.Port() != p.port {
+		return false
+	}
+	host := strings.ToLower(u.Hostname())
+	if !p.wildcard {
+		return host == p.host
+	}
+	label, ok := strings.CutSuffix(host, "."+p.host)
+	return ok && label != "" && !strings.Contains(label, ".")
+}
+
+// CORSAllowsAnyOrigin reports whether CORS_ALLOW_ORIGINS is the "*" wildcard.
+func (c *Config) CORSAllowsAnyOrigin() bool {
+	return len(c.CORSAllowOrigins) == 1 && c.CORSAllowOrigins[0] == corsAnyOrigin
+}
+
+// AllowsOrigin reports whether a browser Origin header matches CORS_ALLOW_ORIGINS.
+func (c *Config) AllowsOrigin(origin string) bool {
+	if c.CORSAllowsAnyOrigin() {
+		return true
+	}
+	for _, raw := range c.CORSAllowOrigins {
+		p, err := parseOriginPattern(raw)
+		if err == nil && p.matches(origin) {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *Config) validateCORS() []error {
+	var problems []error
+
+	for _, raw := range c.CORSAllowOrigins {
+		if raw == corsAnyOrigin {
+			if len(c.CORSAllowOrigins) > 1 {
+				problems = append(problems, errors.New("CORS_ALLOW_ORIGINS \"*\" cannot be combined with other origins"))
+			}
+			if c.CORSAllowCredentials {
+				problems = append(problems, errors.New("CORS_ALLOW_ORIGINS \"*\" cannot be used with CORS_ALLOW_CREDENTIALS=true"))
+			}
+			continue
+		}
+
+		p, err := parseOriginPattern(raw)
+		if err != nil {
+			problems = append(problems, err)
+			continue
+		}
+		if c.IsProduction() && p.scheme == "http" && !isLoopback(p.host) {
+			problems = append(problems, fmt.Errorf("CORS origin %q must use https in production", raw))
+		}
+	}
+
+	if len(c.CORSAllowMethods) == 0 {
+		problems = append(problems, errors.New("CORS_ALLOW_METHODS must not be empty"))
+	}
+	return problems
+}
+
+func isLoopback(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
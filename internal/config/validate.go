@@ -0,0 +1,464 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/netip"
+	"net/url"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/lib/pq"
+)
+
+// DefaultJWTSecret is the development fallback used when JWT_SECRET is unset.
+const DefaultJWTSecret = "default-secret-change-in-production"
+
+// minJWTSecretLength is the shortest HS256 secret accepted in production (256 bits).
+const minJWTSecretLength = 32
+
+// weakSecrets lists placeholder values shipped in docs and samples.
+var weakSecrets = map[string]bool{
+	DefaultJWTSecret: true,
+	"your-secret-key-change-this-in-production": true,
+	"secret":   true,
+	"changeme": true,
+	"password": true,
+	"example":  true,
+	"postgres": true,
+}
+
+// securePostgresSSLModes are the libpq sslmode values that enforce TLS.
+var securePostgresSSLModes = map[string]bool{
+	"require":     true,
+	"verify-ca":   true,
+	"verify-full": true,
+}
+
+// redacted replaces secret values in Redacted output.
+const redacted = "<redacted>"
+
+// Validate returns every configuration problem found. Checks that only matter for a deployed
+// service (secrets, database TLS) are applied when APP_ENV=PRODUCTION.
+func (c *Config) Validate() []error {
+	var problems []error
+
+	switch c.Env {
+	case EnvDevelopment, EnvTesting, EnvProduction:
+	default:
+		problems = append(problems, fmt.Errorf("APP_ENV %q is not one of %s, %s, %s", c.Env, EnvDevelopment, EnvTesting, EnvProduction))
+	}
+
+	if strings.TrimSpace(c.AvatarStorageDir) == "" {
+		problems = append(problems, errors.New("AVATAR_STORAGE_DIR is required"))
+	}
+	if prefix := strings.TrimSpace(c.AvatarURLPrefix); prefix == "" || prefix == "/" {
+		problems = append(problems, errors.New("AVATAR_URL_PREFIX must be a non-root path such as /avatars"))
+	}
+	if dir := strings.TrimSpace(c.VerificationStorageDir); dir == "" {
+		problems = append(problems, errors.New("VERIFICATION_STORAGE_DIR is required"))
+	} else if withinDir(dir, c.AvatarStorageDir) {
+		problems = append(problems, errors.New("VERIFICATION_STORAGE_DIR must not be inside AVATAR_STORAGE_DIR, which is served publicly"))
+	}
+	problems = append(problems, c.validateCORS()...)
+	for _, proxy := range c.TrustedProxies {
+		if !validProxy(proxy) {
+			problems = append(problems, fmt.Errorf("TRUSTED_PROXIES entry %q is not an IP address or CIDR", proxy))
+		}
+	}
+	problems = append(problems, c.validateOIDC()...)
+	if c.AccountDeletionGracePeriod < 0 {
+		problems = append(problems, errors.New("ACCOUNT_DELETION_GRACE_PERIOD must not be negative"))
+	}
+	if c.AccountPurgeInterval < 0 {
+		problems = append(problems, errors.New("ACCOUNT_PURGE_INTERVAL must not be negative"))
+	}
+	if c.DataExportRetention < 0 {
+		problems = append(problems, errors.New("DATA_EXPORT_RETENTION must not be negative"))
+	}
+	if c.DataExportInterval < 0 {
+		problems = append(problems, errors.New("DATA_EXPORT_INTERVAL must not be negative"))
+	}
+	if c.PauseResumeInterval < 0 {
+		problems = append(problems, errors.New("PAUSE_RESUME_INTERVAL must not be negative"))
+	}
+	if c.SuperLikeDailyQuota < 0 {
+		problems = append(problems, errors.New("SUPER_LIKE_DAILY_QUOTA must not be negative"))
+	}
+	if c.RewindWindow < 0 {
+		problems = append(problems, errors.New("REWIND_WINDOW must not be negative"))
+	}
+	if c.RewindDailyLimit < 0 {
+		problems = append(problems, errors.New("REWIND_DAILY_LIMIT must not be negative"))
+	}
+	if c.PassExpiry < 0 {
+		problems = append(problems, errors.New("PASS_EXPIRY must not be negative"))
+	}
+	if c.PassExpiryInterval < 0 {
+		problems = append(problems, errors.New("PASS_EXPIRY_INTERVAL must not be negative"))
+	}
+	if c.DiscoveryCacheTTL < 0 {
+		problems = append(problems, errors.New("DISCOVERY_CACHE_TTL must not be negative"))
+	}
+	if c.DiscoveryCacheSize < 1 {
+		problems = append(problems, errors.New("DISCOVERY_CACHE_SIZE must be at least 1"))
+	}
+	if c.DiscoveryRefreshInterval < 0 {
+		problems = append(problems, errors.New("DISCOVERY_REFRESH_INTERVAL must not be negative"))
+	}
+	if c.DiscoveryMinCompleteness < 0 || c.DiscoveryMinCompleteness > 100 {
+		problems = append(problems, errors.New("DISCOVERY_MIN_COMPLETENESS must be between 0 and 100"))
+	}
+	if c.DesirabilityInterval < 0 {
+		problems = append(problems, errors.New("DESIRABILITY_INTERVAL must not be negative"))
+	}
+	if c.DailyPicksCount < 0 {
+		problems = append(problems, errors.New("DAILY_PICKS_COUNT must not be negative"))
+	}
+	if c.PickLikeDailyQuota < 0 {
+		problems = append(problems, errors.New("PICK_LIKE_DAILY_QUOTA must not be negative"))
+	}
+	if c.MaxInterests < 1 {
+		problems = append(problems, errors.New("MAX_INTERESTS must be at least 1"))
+	}
+	if c.ModerationScanInterval < 0 {
+		problems = append(problems, errors.New("MODERATION_SCAN_INTERVAL must not be negative"))
+	}
+	if c.JWTSigningKey != "" && c.JWTSigningKeyFile != "" {
+		problems = append(problems, errors.New("set only one of JWT_SIGNING_KEY and JWT_SIGNING_KEY_FILE"))
+	}
+	if c.JWTVerificationJWKS != "" {
+		var jwks struct {
+			Keys []json.RawMessage `json:"keys"`
+		}
+		if err := json.Unmarshal([]byte(c.JWTVerificationJWKS), &jwks); err != nil || len(jwks.Keys) == 0 {
+			problems = append(problems, errors.New("JWT_VERIFICATION_JWKS must be a JSON Web Key Set with at least one key"))
+		}
+	}
+
+	if !c.IsProduction() {
+		return problems
+	}
+
+	if !c.HasJWTSigningKey() {
+		if weakSecrets[c.JWTSecret] {
+			problems = append(problems, errors.New("JWT_SECRET is a default or placeholder value"))
+		} else if len(c.JWTSecret) < minJWTSecretLength {
+			problems = append(problems, fmt.Errorf("JWT_SECRET must be at least %d characters", minJWTSecretLength))
+		}
+	}
+
+	problems = append(problems, c.validatePostgres()...)
+	problems = append(problems, c.validateMongo()...)
+
+	return problems
+}
+
+// HasJWTSigningKey reports whether an asymmetric signing key is configured.
+func (c *Config) HasJWTSigningKey() bool {
+	return c.JWTSigningKey != "" || c.JWTSigningKeyFile != ""
+}
+
+func (c *Config) validatePostgres() []error {
+	var problems []error
+
+	password, sslMode := c.PostgresPassword, c.PostgresSSLMode
+	if c.PostgresURL != "" {
+		settings, err := postgresSettings(c.PostgresURL)
+		if err != nil {
+			return []error{errors.New("POSTGRES_URL is not a valid connection string")}
+		}
+		password, sslMode = settings["password"], settings["sslmode"]
+	}
+
+	if password == "" || weakSecrets[password] {
+		problems = append(problems, errors.New("Postgres password is empty or a default value"))
+	}
+	if !securePostgresSSLModes[sslMode] {
+		problems = append(problems, fmt.Errorf("Postgres sslmode %q does not enforce TLS (use require, verify-ca or verify-full)", sslMode))
+	}
+	return problems
+}
+
+func (c *Config) validateMongo() []error {
+	var problems []error
+
+	u, err := url.Parse(c.MongoConn())
+	if err != nil {
+		return []error{errors.New("MongoDB connection string is not a valid URL")}
+	}
+
+	query := u.Query()
+	if u.User == nil || u.User.Username() == "" {
+		problems = append(problems, errors.New("MongoDB credentials are not set"))
+	} else if query.Get("authMechanism") != "MONGODB-X509" {
+		// X.509 authentication identifies the user by certificate and carries no password.
+		if password, _ := u.User.Password(); password == "" || weakSecrets[password] {
+			problems = append(problems, errors.New("MongoDB password is empty or a default value"))
+		}
+	}
+
+	tls := u.Scheme == "mongodb+srv" || query.Get("tls") == "true" || query.Get("ssl") == "true"
+	if !tls {
+		problems = append(problems, errors.New("MongoDB connection does not enable TLS (add tls=true or use mongodb+srv)"))
+	}
+	return problems
+}
+
+func (c *Config) validateOIDC() []error {
+	var problems []error
+	seen := map[string]bool{}
+	for _, p := range c.OIDCProviders {
+		if seen[p.Name] {
+			problems = append(problems, fmt.Errorf("OIDC provider %q is listed twice", p.Name))
+		}
+		seen[p.Name] = true
+
+		prefix := "OIDC_" + strings.ToUpper(p.Name) + "_"
+		if p.Issuer == "" || p.ClientID == "" || p.RedirectURL == "" {
+			problems = append(problems, fmt.Errorf("%sISSUER, %sCLIENT_ID and %sREDIRECT_URL are required", prefix, prefix, prefix))
+			continue
+		}
+		if c.IsProduction() && !strings.HasPrefix(p.Issuer, "https://") {
+			problems = append(problems, fmt.Errorf("%sISSUER must use https in production", prefix))
+		}
+	}
+	return problems
+}
+
+// Redacted returns the effective configuration keyed by environment variable, with secrets
+// and connection-string passwords masked.
+func (c *Config) Redacted() map[string]string {
+	secret := func(v string) string {
+		if v == "" {
+			return ""
+		}
+		return redacted
+	}
+
+	verificationKeys := make([]string, 0, len(c.JWTVerificationKeys))
+	for kid, key := range c.JWTVerificationKeys {
+		if strings.HasPrefix(key, "-----BEGIN ") {
+			key = "(inline PEM)"
+		}
+		verificationKeys = append(verificationKeys, kid+"="+key)
+	}
+	sort.Strings(verificationKeys)
+
+	discoveryWeights := make([]string, 0, len(c.DiscoveryWeights))
+	for signal, weight := range c.DiscoveryWeights {
+		discoveryWeights = append(discoveryWeights, signal+"="+weight)
+	}
+	sort.Strings(discoveryWeights)
+
+	settings := map[string]string{
+		"APP_ENV":                       c.Env,
+		"PORT":                          c.Port,
+		"LOG_LEVEL":                     c.LogLevel,
+		"LOG_DIR":                       c.LogDir,
+		"JWT_SECRET":                    secret(c.JWTSecret),
+		"JWT_SIGNING_KEY":               secret(c.JWTSigningKey),
+		"JWT_SIGNING_KEY_FILE":          c.JWTSigningKeyFile,
+		"JWT_SIGNING_KEY_ID":            c.JWTSigningKeyID,
+		"JWT_VERIFICATION_KEYS":         strings.Join(verificationKeys, ","),
+		"JWT_VERIFICATION_JWKS":         c.JWTVerificationJWKS,
+		"DATABASE_URL":                  redactURL(c.DatabaseURL),
+		"POSTGRES_DSN":                  redactURL(c.PostgresDSN()),
+		"POSTGRES_MAX_OPEN_CONNS":       fmt.Sprint(c.PostgresMaxOpenConns),
+		"POSTGRES_MAX_IDLE_CONNS":       fmt.Sprint(c.PostgresMaxIdleConns),
+		"POSTGRES_CONN_MAX_LIFETIME":    c.PostgresConnMaxLifetime.String(),
+		"MONGODB_CONN":                  redactURL(c.MongoConn()),
+		"AVATAR_STORAGE_DIR":            c.AvatarStorageDir,
+		"AVATAR_URL_PREFIX":             c.AvatarURLPrefix,
+		"VERIFICATION_STORAGE_DIR":      c.VerificationStorageDir,
+		"CORS_ALLOW_ORIGINS":            strings.Join(c.CORSAllowOrigins, ","),
+		"CORS_ALLOW_METHODS":            strings.Join(c.CORSAllowMethods, ","),
+		"CORS_ALLOW_HEADERS":            strings.Join(c.CORSAllowHeaders, ","),
+		"CORS_ALLOW_CREDENTIALS":        fmt.Sprint(c.CORSAllowCredentials),
+		"CORS_MAX_AGE":                  c.CORSMaxAge.String(),
+		"TRUSTED_PROXIES":               strings.Join(c.TrustedProxies, ","),
+		"ACCOUNT_DELETION_GRACE_PERIOD": c.AccountDeletionGracePeriod.String(),
+		"ACCOUNT_PURGE_INTERVAL":        c.AccountPurgeInterval.String(),
+		"EXPORT_STORAGE_DIR":            c.ExportStorageDir,
+		"DATA_EXPORT_RETENTION":         c.DataExportRetention.String(),
+		"DATA_EXPORT_INTERVAL":          c.DataExportInterval.String(),
+		"PAUSE_RESUME_INTERVAL":         c.PauseResumeInterval.String(),
+		"SUPER_LIKE_DAILY_QUOTA":        fmt.Sprint(c.SuperLikeDailyQuota),
+		"REWIND_WINDOW":                 c.RewindWindow.String(),
+		"REWIND_DAILY_LIMIT":            fmt.Sprint(c.RewindDailyLimit),
+		"PASS_EXPIRY":                   c.PassExpiry.String(),
+		"PASS_EXPIRY_INTERVAL":          c.PassExpiryInterval.String(),
+		"DISCOVERY_WEIGHTS":             strings.Join(discoveryWeights, ","),
+		"DISCOVERY_CACHE_TTL":           c.DiscoveryCacheTTL.String(),
+		"DISCOVERY_CACHE_SIZE":          fmt.Sprint(c.DiscoveryCacheSize),
+		"DISCOVERY_REFRESH_INTERVAL":    c.DiscoveryRefreshInterval.String(),
+		"DISCOVERY_MIN_COMPLETENESS":    fmt.Sprint(c.DiscoveryMinCompleteness),
+		"DESIRABILITY_INTERVAL":         c.DesirabilityInterval.String(),
+		"DAILY_PICKS_COUNT":             fmt.Sprint(c.DailyPicksCount),
+		"PICK_LIKE_DAILY_QUOTA":         fmt.Sprint(c.PickLikeDailyQuota),
+		"MAX_INTERESTS":                 fmt.Sprint(c.MaxInterests),
+		"MODERATION_SCAN_INTERVAL":      c.ModerationScanInterval.String(),
+		"MODERATION_AUTO_RESTRICT":      fmt.Sprint(c.ModerationAutoRestrict),
+	}
+
+	names := make([]string, 0, len(c.OIDCProviders))
+	for _, p := range c.OIDCProviders {
+		names = append(names, p.Name)
+		prefix := "OIDC_" + strings.ToUpper(p.Name) + "_"
+		settings[prefix+"ISSUER"] = p.Issuer
+		settings[prefix+"CLIENT_ID"] = p.ClientID
+		settings[prefix+"CLIENT_SECRET"] = secret(p.ClientSecret)
+		settings[prefix+"REDIRECT_URL"] = p.RedirectURL
+		settings[prefix+"SCOPES"] = strings.Join(p.Scopes, ",")
+	}
+	settings["OIDC_PROVIDERS"] = strings.Join(names, ",")
+
+	return settings
+}
+
+// validProxy reports whether a TRUSTED_PROXIES entry is an IP address or a CIDR range.
+func validProxy(proxy string) bool {
+	if _, err := netip.ParsePrefix(proxy); err == nil {
+		return true
+	}
+	_, err := netip.ParseAddr(proxy)
+	return err == nil
+}
+
+// withinDir reports whether dir is parent or one of its subdirectories.
+func withinDir(dir, parent string) bool {
+	if strings.TrimSpace(parent) == "" {
+		return false
+	}
+	rel, err := filepath.Rel(filepath.Clean(parent), filepath.Clean(dir))
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// redactURL masks the password of a connection string, or the whole value when it cannot be parsed.
+// Besides URLs it accepts the libpq keyword/value form ("host=db user=app password=...").
+func redactURL(raw string) string {
+	if raw == "" {
+		return ""
+	}
+	if u, err := url.Parse(raw); err == nil && u.Scheme != "" {
+		if u.Opaque != "" {
+			return redacted
+		}
+		// lib/pq also reads the password from the query string.
+		if query := u.Query(); query.Has("password") {
+			query.Set("password", "xxxxx")
+			u.RawQuery = query.Encode()
+		}
+		return u.Redacted()
+	}
+
+	settings, err := parseKeywordDSN(raw)
+	if err != nil {
+		return redacted
+	}
+	parts := make([]string, 0, len(settings))
+	for _, s := range settings {
+		value := s.value
+		if s.key == "password" {
+			value = "xxxxx"
+		}
+		parts = append(parts, s.key+"="+quoteDSNValue(value))
+	}
+	return strings.Join(parts, " ")
+}
+
+// postgresSettings returns the settings of a Postgres connection string in either URL or
+// keyword/value form, the way lib/pq reads them.
+func postgresSettings(dsn string) (map[string]string, error) {
+	if strings.HasPrefix(dsn, "postgres://") || strings.HasPrefix(dsn, "postgresql://") {
+		converted, err := pq.ParseURL(dsn)
+		if err != nil {
+			return nil, err
+		}
+		dsn = converted
+	}
+	pairs, err := parseKeywordDSN(dsn)
+	if err != nil {
+		return nil, err
+	}
+	settings := make(map[string]string, len(pairs))
+	for _, p := range pairs {
+		settings[p.key] = p.value
+	}
+	return settings, nil
+}
+
+// dsnSetting is one key=value pair of a keyword/value connection string.
+type dsnSetting struct {
+	key, value string
+}
+
+// parseKeywordDSN splits a libpq keyword/value connection string, following lib/pq's rules:
+// values may be single-quoted and a backslash escapes the next character.
+func parseKeywordDSN(dsn string) ([]dsnSetting, error) {
+	var settings []dsnSetting
+	runes := []rune(dsn)
+	i := 0
+	skipSpaces := func() {
+		for i < len(runes) && unicode.IsSpace(runes[i]) {
+			i++
+		}
+	}
+
+	for {
+		skipSpaces()
+		if i == len(runes) {
+			return settings, nil
+		}
+
+		start := i
+		for i < len(runes) && !unicode.IsSpace(runes[i]) && runes[i] != '=' {
+			i++
+		}
+		key := string(runes[start:i])
+		skipSpaces()
+		if i == len(runes) || runes[i] != '=' {
+			return nil, fmt.Errorf("missing \"=\" after %q in connection string", key)
+		}
+		i++
+		skipSpaces()
+
+		var value []rune
+		if i < len(runes) && runes[i] == '\'' {
+			for i++; ; i++ {
+				if i == len(runes) {
+					return nil, errors.New("unterminated quoted value in connection string")
+				}
+				if runes[i] == '\'' {
+					i++
+					break
+				}
+				if runes[i] == '\\' && i+1 < len(runes) {
+					i++
+				}
+				value = append(value, runes[i])
+			}
+		} else {
+			for ; i < len(runes) && !unicode.IsSpace(runes[i]); i++ {
+				if runes[i] == '\\' {
+					if i++; i == len(runes) {
+						return nil, errors.New("missing character after backslash in connection string")
+					}
+				}
+				value = append(value, runes[i])
+			}
+		}
+		settings = append(settings, dsnSetting{key: key, value: string(value)})
+	}
+}
+
+// quoteDSNValue quotes a keyword/value setting when it is empty or holds spaces, quotes or backslashes.
+func quoteDSNValue(value string) string {
+	if value != "" && !strings.ContainsAny(value, " \t\n'\\") {
+		return value
+	}
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(value) + "'"
+}
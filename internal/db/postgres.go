@@ -62,6 +62,30 @@ func initSchema(db *sql.DB) error {
   ALTER TABLE users ADD COLUMN IF NOT EXISTS intention VARCHAR(50) NOT NULL DEFAULT 'still_figuring_out';
   ALTER TABLE users ADD COLUMN IF NOT EXISTS bio TEXT;
   ALTER TABLE users ADD COLUMN IF NOT EXISTS avatar_url TEXT;
+  -- The purge deadline is computed by the API and compared with NOW(), so it keeps its time zone
+  ALTER TABLE users ADD COLUMN IF NOT EXISTS deletion_requested_at TIMESTAMPTZ;
+  ALTER TABLE users ADD COLUMN IF NOT EXISTS deletion_scheduled_at TIMESTAMPTZ;
+  ALTER TABLE users ADD COLUMN IF NOT EXISTS paused_at TIMESTAMPTZ;
+  ALTER TABLE users ADD COLUMN IF NOT EXISTS resume_at TIMESTAMPTZ;
+  ALTER TABLE users ADD COLUMN IF NOT EXISTS visibility VARCHAR(20) NOT NULL DEFAULT 'members';
+  ALTER TABLE users ADD COLUMN IF NOT EXISTS timezone VARCHAR(64) NOT NULL DEFAULT 'UTC';
+  ALTER TABLE users ADD COLUMN IF NOT EXISTS latitude DOUBLE PRECISION;
+  ALTER TABLE users ADD COLUMN IF NOT EXISTS longitude DOUBLE PRECISION;
+  ALTER TABLE users ADD COLUMN IF NOT EXISTS height_cm SMALLINT;
+  ALTER TABLE users ADD COLUMN IF NOT EXISTS education VARCHAR(20);
+  ALTER TABLE users ADD COLUMN IF NOT EXISTS job_title VARCHAR(100);
+  ALTER TABLE users ADD COLUMN IF NOT EXISTS languages TEXT[] NOT NULL DEFAULT '{}';
+  ALTER TABLE users ADD COLUMN IF NOT EXISTS religion VARCHAR(20);
+  ALTER TABLE users ADD COLUMN IF NOT EXISTS drinking VARCHAR(20);
+  ALTER TABLE users ADD COLUMN IF NOT EXISTS smoking VARCHAR(20);
+  ALTER TABLE users ADD COLUMN IF NOT EXISTS children VARCHAR(30);
+  ALTER TABLE users ADD COLUMN IF NOT EXISTS role VARCHAR(20) NOT NULL DEFAULT 'member';
+  ALTER TABLE users ADD COLUMN IF NOT EXISTS verified_at TIMESTAMP;
+  ALTER TABLE users ADD COLUMN IF NOT EXISTS signup_ip VARCHAR(45);
+  ALTER TABLE users ADD COLUMN IF NOT EXISTS avatar_hash BIGINT;
+  ALTER TABLE users ADD COLUMN IF NOT EXISTS avatar_hashed_url TEXT;
+  ALTER TABLE users ADD COLUMN IF NOT EXISTS avatar_hashed_at TIMESTAMP;
+  ALTER TABLE users ADD COLUMN IF NOT EXISTS restricted_at TIMESTAMP;
 
   -- Add constraints if they don't exist
   DO $$
@@ -86,16 +110,48 @@ func initSchema(db *sql.DB) error {
           'still_figuring_out'
         ));
     END IF;
+
+    IF NOT EXISTS (
+      SELECT 1 FROM pg_constraint WHERE conname = 'chk_users_visibility'
+    ) THEN
+      ALTER TABLE users ADD CONSTRAINT chk_users_visibility
+        CHECK (visibility IN ('members', 'incognito', 'hidden'));
+    END IF;
+
+    IF NOT EXISTS (
+      SELECT 1 FROM pg_constraint WHERE conname = 'chk_users_attributes'
+    ) THEN
+      ALTER TABLE users ADD CONSTRAINT chk_users_attributes
+        CHECK (
+          height_cm BETWEEN 100 AND 250
+          AND education IN ('high_school', 'vocational', 'undergraduate', 'postgraduate', 'doctorate')
+          AND religion IN ('agnostic', 'atheist', 'buddhist', 'catholic', 'christian', 'hindu', 'jewish', 'muslim', 'spiritual', 'other')
+          AND drinking IN ('never', 'sometimes', 'often')
+          AND smoking IN ('never', 'sometimes', 'often')
+          AND children IN ('want', 'dont_want', 'have_and_want_more', 'have_and_dont_want_more', 'not_sure')
+        );
+    END IF;
+
+    IF NOT EXISTS (
+      SELECT 1 FROM pg_constraint WHERE conname = 'chk_users_role'
+    ) THEN
+      ALTER TABLE users ADD CONSTRAINT chk_users_role
+        CHECK (role IN ('member', 'admin'));
+    END IF;
   END $$;
 
   -- Create indexes
   CREATE INDEX IF NOT EXISTS idx_users_email ON users(email);
+  CREATE INDEX IF NOT EXISTS idx_users_deletion_scheduled_at ON users(deletion_scheduled_at)
+    WHERE deletion_scheduled_at IS NOT NULL;
+  CREATE INDEX IF NOT EXISTS idx_users_resume_at ON users(resume_at)
+    WHERE paused_at IS NOT NULL AND resume_at IS NOT NULL;
   
   CREATE TABLE IF NOT EXISTS likes (
     id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
     user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
     target_user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
-    status VARCHAR(10) NOT NULL CHECK (status IN ('like', 'pass')),
+    status VARCHAR(10) NOT NULL CHECK (status IN ('like', 'super_like', 'pass')),
     created_at TIMESTAMP NOT NULL DEFAULT NOW(),
     updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
     UNIQUE(user_id, target_user_id)
@@ -103,6 +159,50 @@ func initSchema(db *sql.DB) error {
   CREATE INDEX IF NOT EXISTS idx_likes_user_id ON likes(user_id);
   CREATE INDEX IF NOT EXISTS idx_likes_target_user_id ON likes(target_user_id);
   CREATE INDEX IF NOT EXISTS idx_likes_status ON likes(status);
+  CREATE INDEX IF NOT EXISTS idx_likes_pass_updated_at ON likes(updated_at) WHERE status = 'pass';
+  ALTER TABLE likes ADD COLUMN IF NOT EXISTS queued_at TIMESTAMP;
+  CREATE INDEX IF NOT EXISTS idx_likes_queued ON likes(target_user_id) WHERE queued_at IS NOT NULL;
+  -- Optional comment sent with a like, optionally anchored to a photo or prompt
+  ALTER TABLE likes ADD COLUMN IF NOT EXISTS comment TEXT;
+  ALTER TABLE likes ADD COLUMN IF NOT EXISTS target_type VARCHAR(20) CHECK (target_type IN ('photo', 'prompt'));
+  ALTER TABLE likes ADD COLUMN IF NOT EXISTS target_ref VARCHAR(255);
+
+  -- Allow super likes on tables created before they existed
+  DO $$
+  BEGIN
+    IF EXISTS (
+      SELECT 1 FROM pg_constraint
+      WHERE conname = 'likes_status_check' AND pg_get_constraintdef(oid) NOT LIKE '%super_like%'
+    ) THEN
+      ALTER TABLE likes DROP CONSTRAINT likes_status_check;
+      ALTER TABLE likes ADD CONSTRAINT likes_status_check
+        CHECK (status IN ('like', 'super_like', 'pass'));
+    END IF;
+  END $$;
+
+  -- Super likes sent per user per local calendar day
+  CREATE TABLE IF NOT EXISTS super_like_usage (
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    day DATE NOT NULL,
+    used INT NOT NULL,
+    PRIMARY KEY (user_id, day)
+  );
+
+  -- Likes sent to daily picks per user per local calendar day
+  CREATE TABLE IF NOT EXISTS pick_like_usage (
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    day DATE NOT NULL,
+    used INT NOT NULL,
+    PRIMARY KEY (user_id, day)
+  );
+
+  -- Passes undone per user per local calendar day
+  CREATE TABLE IF NOT EXISTS rewind_usage (
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    day DATE NOT NULL,
+    used INT NOT NULL,
+    PRIMARY KEY (user_id, day)
+  );
   
   CREATE TABLE IF NOT EXISTS matches (
     id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
@@ -115,6 +215,323 @@ func initSchema(db *sql.DB) error {
   );
   CREATE INDEX IF NOT EXISTS idx_matches_user1_id ON matches(user1_id);
   CREATE INDEX IF NOT EXISTS idx_matches_user2_id ON matches(user2_id);
+
+  CREATE TABLE IF NOT EXISTS identities (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    provider VARCHAR(50) NOT NULL,
+    subject VARCHAR(255) NOT NULL,
+    email VARCHAR(255),
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    UNIQUE(provider, subject)
+  );
+  CREATE INDEX IF NOT EXISTS idx_identities_user_id ON identities(user_id);
+
+  CREATE TABLE IF NOT EXISTS oidc_login_states (
+    state VARCHAR(128) PRIMARY KEY,
+    provider VARCHAR(50) NOT NULL,
+    nonce VARCHAR(128) NOT NULL,
+    code_verifier VARCHAR(128) NOT NULL,
+    expires_at TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+  );
+  -- Set when a signed-in user re-authenticates (e.g. before deleting the account) instead of signing in
+  ALTER TABLE oidc_login_states ADD COLUMN IF NOT EXISTS user_id UUID REFERENCES users(id) ON DELETE CASCADE;
+
+  CREATE TABLE IF NOT EXISTS user_mfa (
+    user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    totp_secret VARCHAR(64) NOT NULL,
+    totp_enabled BOOLEAN NOT NULL DEFAULT FALSE,
+    totp_last_step BIGINT,
+    enabled_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+  );
+  -- Failed second-factor attempts since the last success; reaching the limit locks sign-in
+  ALTER TABLE user_mfa ADD COLUMN IF NOT EXISTS failed_attempts INT NOT NULL DEFAULT 0;
+  ALTER TABLE user_mfa ADD COLUMN IF NOT EXISTS locked_until TIMESTAMPTZ;
+
+  -- Single-use challenges handed out after the password step of a sign-in
+  CREATE TABLE IF NOT EXISTS mfa_challenges (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    expires_at TIMESTAMPTZ NOT NULL,
+    consumed_at TIMESTAMPTZ
+  );
+  CREATE INDEX IF NOT EXISTS idx_mfa_challenges_user_id ON mfa_challenges(user_id);
+
+  CREATE TABLE IF NOT EXISTS mfa_recovery_codes (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    code_hash CHAR(64) NOT NULL,
+    used_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    UNIQUE(user_id, code_hash)
+  );
+
+  CREATE TABLE IF NOT EXISTS sessions (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    device_name VARCHAR(100),
+    user_agent VARCHAR(512),
+    ip_address VARCHAR(45),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    last_seen_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    expires_at TIMESTAMPTZ NOT NULL,
+    revoked_at TIMESTAMPTZ
+  );
+  CREATE INDEX IF NOT EXISTS idx_sessions_user_id ON sessions(user_id);
+
+  -- Proof of erasure for purged accounts; holds no personal data beyond a hashed email
+  CREATE TABLE IF NOT EXISTS account_tombstones (
+    user_id UUID PRIMARY KEY,
+    email_sha256 CHAR(64) NOT NULL,
+    deletion_requested_at TIMESTAMPTZ,
+    purged_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+  );
+
+  CREATE TABLE IF NOT EXISTS data_exports (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    status VARCHAR(20) NOT NULL CHECK (status IN ('pending', 'processing', 'ready', 'failed', 'expired')),
+    file_path TEXT,
+    error TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    started_at TIMESTAMPTZ,
+    completed_at TIMESTAMPTZ,
+    expires_at TIMESTAMPTZ
+  );
+  CREATE INDEX IF NOT EXISTS idx_data_exports_user_id ON data_exports(user_id);
+  CREATE INDEX IF NOT EXISTS idx_data_exports_status ON data_exports(status);
+  CREATE UNIQUE INDEX IF NOT EXISTS idx_data_exports_active ON data_exports(user_id)
+    WHERE status IN ('pending', 'processing');
+
+  -- One-time download links for export archives, looked up by the SHA-256 of their token
+  CREATE TABLE IF NOT EXISTS export_download_links (
+    token_hash CHAR(64) PRIMARY KEY,
+    export_id UUID NOT NULL REFERENCES data_exports(id) ON DELETE CASCADE,
+    expires_at TIMESTAMPTZ NOT NULL
+  );
+  CREATE INDEX IF NOT EXISTS idx_export_download_links_export_id ON export_download_links(export_id);
+
+  -- Precomputed discovery ranking per viewer, refreshed when older than the cache TTL
+  CREATE TABLE IF NOT EXISTS discovery_caches (
+    user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    computed_at TIMESTAMPTZ NOT NULL
+  );
+  -- Bumped on every recompute so feed cursors from an older ranking can be rejected
+  ALTER TABLE discovery_caches ADD COLUMN IF NOT EXISTS generation BIGINT NOT NULL DEFAULT 0;
+  CREATE TABLE IF NOT EXISTS discovery_candidates (
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    candidate_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    score DOUBLE PRECISION NOT NULL,
+    PRIMARY KEY (user_id, candidate_id)
+  );
+  CREATE INDEX IF NOT EXISTS idx_discovery_candidates_rank ON discovery_candidates(user_id, score DESC, candidate_id DESC);
+  CREATE INDEX IF NOT EXISTS idx_discovery_candidates_candidate_id ON discovery_candidates(candidate_id);
+
+  -- Discovery filters; those named in dealbreakers exclude, the rest only rank
+  CREATE TABLE IF NOT EXISTS user_preferences (
+    user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    age_min SMALLINT,
+    age_max SMALLINT,
+    max_distance_km INT,
+    height_min_cm SMALLINT,
+    height_max_cm SMALLINT,
+    educations TEXT[] NOT NULL DEFAULT '{}',
+    languages TEXT[] NOT NULL DEFAULT '{}',
+    religions TEXT[] NOT NULL DEFAULT '{}',
+    drinking TEXT[] NOT NULL DEFAULT '{}',
+    smoking TEXT[] NOT NULL DEFAULT '{}',
+    children TEXT[] NOT NULL DEFAULT '{}',
+    dealbreakers TEXT[] NOT NULL DEFAULT '{}',
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+  );
+  ALTER TABLE user_preferences ADD COLUMN IF NOT EXISTS verified_only BOOLEAN NOT NULL DEFAULT FALSE;
+
+  -- Daily picks, selected once per user per local calendar day
+  CREATE TABLE IF NOT EXISTS daily_pick_sets (
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    day DATE NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (user_id, day)
+  );
+  CREATE TABLE IF NOT EXISTS daily_picks (
+    user_id UUID NOT NULL,
+    day DATE NOT NULL,
+    candidate_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    position INT NOT NULL,
+    PRIMARY KEY (user_id, day, candidate_id),
+    FOREIGN KEY (user_id, day) REFERENCES daily_pick_sets(user_id, day) ON DELETE CASCADE
+  );
+
+  -- Desirability ratings and swiping behaviour computed from likes and passes; internal to ranking
+  CREATE TABLE IF NOT EXISTS user_scores (
+    user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    rating DOUBLE PRECISION NOT NULL,
+    likes_given INT NOT NULL,
+    passes_given INT NOT NULL,
+    swipes_per_day DOUBLE PRECISION NOT NULL,
+    indiscriminate BOOLEAN NOT NULL,
+    computed_at TIMESTAMP NOT NULL
+  );
+
+  -- Curated interests catalog with a label per language; retired interests are kept inactive
+  CREATE TABLE IF NOT EXISTS interests (
+    slug VARCHAR(50) PRIMARY KEY,
+    category VARCHAR(50) NOT NULL,
+    labels JSONB NOT NULL,
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+  );
+  CREATE TABLE IF NOT EXISTS user_interests (
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    interest VARCHAR(50) NOT NULL REFERENCES interests(slug) ON DELETE CASCADE,
+    PRIMARY KEY (user_id, interest)
+  );
+  CREATE INDEX IF NOT EXISTS idx_user_interests_interest ON user_interests(interest);
+
+  INSERT INTO interests (slug, category, labels) VALUES
+    ('hiking', 'outdoors', '{"en": "Hiking", "vi": "Leo núi"}'),
+    ('camping', 'outdoors', '{"en": "Camping", "vi": "Cắm trại"}'),
+    ('travel', 'outdoors', '{"en": "Travel", "vi": "Du lịch"}'),
+    ('running', 'sports', '{"en": "Running", "vi": "Chạy bộ"}'),
+    ('cycling', 'sports', '{"en": "Cycling", "vi": "Đạp xe"}'),
+    ('football', 'sports', '{"en": "Football", "vi": "Bóng đá"}'),
+    ('badminton', 'sports', '{"en": "Badminton", "vi": "Cầu lông"}'),
+    ('yoga', 'sports', '{"en": "Yoga", "vi": "Yoga"}'),
+    ('gym', 'sports', '{"en": "Gym", "vi": "Tập gym"}'),
+    ('kpop', 'music', '{"en": "K-pop", "vi": "K-pop"}'),
+    ('karaoke', 'music', '{"en": "Karaoke", "vi": "Karaoke"}'),
+    ('live_music', 'music', '{"en": "Live music", "vi": "Nhạc sống"}'),
+    ('coffee', 'food_drink', '{"en": "Coffee", "vi": "Cà phê"}'),
+    ('cooking', 'food_drink', '{"en": "Cooking", "vi": "Nấu ăn"}'),
+    ('street_food', 'food_drink', '{"en": "Street food", "vi": "Ẩm thực đường phố"}'),
+    ('photography', 'arts', '{"en": "Photography", "vi": "Nhiếp ảnh"}'),
+    ('movies', 'arts', '{"en": "Movies", "vi": "Xem phim"}'),
+    ('reading', 'arts', '{"en": "Reading", "vi": "Đọc sách"}'),
+    ('gaming', 'games', '{"en": "Gaming", "vi": "Chơi game"}'),
+    ('board_games', 'games', '{"en": "Board games", "vi": "Board game"}'),
+    ('pets', 'lifestyle', '{"en": "Pets", "vi": "Thú cưng"}'),
+    ('volunteering', 'lifestyle', '{"en": "Volunteering", "vi": "Tình nguyện"}')
+  ON CONFLICT (slug) DO NOTHING;
+
+  -- Prompt catalog with the prompt text per language, and users' ordered answers
+  CREATE TABLE IF NOT EXISTS prompts (
+    id VARCHAR(50) PRIMARY KEY,
+    texts JSONB NOT NULL,
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+  );
+  CREATE TABLE IF NOT EXISTS user_prompts (
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    prompt_id VARCHAR(50) NOT NULL REFERENCES prompts(id) ON DELETE CASCADE,
+    answer VARCHAR(150) NOT NULL,
+    position SMALLINT NOT NULL,
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (user_id, prompt_id)
+  );
+
+  INSERT INTO prompts (id, texts) VALUES
+    ('ideal_sunday', '{"en": "My ideal Sunday is...", "vi": "Chủ nhật lý tưởng của mình là..."}'),
+    ('green_flag', '{"en": "A green flag I look for is...", "vi": "Điểm cộng mình tìm kiếm ở một người là..."}'),
+    ('simple_pleasures', '{"en": "My simple pleasures are...", "vi": "Niềm vui giản dị của mình là..."}'),
+    ('best_travel_story', '{"en": "My best travel story is...", "vi": "Chuyến đi đáng nhớ nhất của mình là..."}'),
+    ('go_to_karaoke_song', '{"en": "My go-to karaoke song is...", "vi": "Bài hát karaoke tủ của mình là..."}'),
+    ('perfect_first_date', '{"en": "A perfect first date would be...", "vi": "Buổi hẹn đầu tiên hoàn hảo là..."}'),
+    ('unusual_skill', '{"en": "An unusual skill I have is...", "vi": "Một kỹ năng đặc biệt của mình là..."}'),
+    ('looking_for', '{"en": "I am looking for someone who...", "vi": "Mình đang tìm một người..."}'),
+    ('favourite_street_food', '{"en": "The street food I could eat every day is...", "vi": "Món ăn vặt mình có thể ăn mỗi ngày là..."}'),
+    ('never_shut_up_about', '{"en": "I will never stop talking about...", "vi": "Chủ đề mình có thể nói mãi không chán là..."}')
+  ON CONFLICT (id) DO NOTHING;
+
+  -- Compatibility question bank; options is a JSON array of each option's text per language
+  CREATE TABLE IF NOT EXISTS questions (
+    id VARCHAR(50) PRIMARY KEY,
+    texts JSONB NOT NULL,
+    options JSONB NOT NULL,
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+  );
+  -- answer is an option index, accepted a bitmask of option indexes, importance a level 0..4
+  CREATE TABLE IF NOT EXISTS question_answers (
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    question_id VARCHAR(50) NOT NULL REFERENCES questions(id) ON DELETE CASCADE,
+    answer SMALLINT NOT NULL CHECK (answer BETWEEN 0 AND 7),
+    accepted SMALLINT NOT NULL CHECK (accepted BETWEEN 0 AND 255),
+    importance SMALLINT NOT NULL CHECK (importance BETWEEN 0 AND 4),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (user_id, question_id)
+  );
+
+  INSERT INTO questions (id, texts, options) VALUES
+    ('want_children', '{"en": "Do you want children someday?", "vi": "Bạn có muốn có con trong tương lai không?"}',
+      '[{"en": "Yes", "vi": "Có"}, {"en": "No", "vi": "Không"}, {"en": "Not sure", "vi": "Chưa chắc"}]'),
+    ('smoking_partner', '{"en": "Would you date a smoker?", "vi": "Bạn có hẹn hò với người hút thuốc không?"}',
+      '[{"en": "Yes", "vi": "Có"}, {"en": "Only occasional smokers", "vi": "Chỉ khi thỉnh thoảng hút"}, {"en": "No", "vi": "Không"}]'),
+    ('weekend_style', '{"en": "How do you like to spend weekends?", "vi": "Bạn thích dành cuối tuần thế nào?"}',
+      '[{"en": "Out with friends", "vi": "Đi chơi với bạn bè"}, {"en": "Somewhere new", "vi": "Khám phá nơi mới"}, {"en": "Relaxing at home", "vi": "Nghỉ ngơi ở nhà"}]'),
+    ('tidiness', '{"en": "How tidy are you?", "vi": "Bạn gọn gàng đến mức nào?"}',
+      '[{"en": "Very tidy", "vi": "Rất gọn gàng"}, {"en": "Somewhat tidy", "vi": "Tương đối"}, {"en": "Messy", "vi": "Bừa bộn"}]'),
+    ('live_with_parents', '{"en": "Would you live with your partner''s parents?", "vi": "Bạn có sẵn sàng sống chung với bố mẹ người yêu không?"}',
+      '[{"en": "Yes", "vi": "Có"}, {"en": "For a while", "vi": "Một thời gian"}, {"en": "No", "vi": "Không"}]'),
+    ('pets_at_home', '{"en": "Do you want pets at home?", "vi": "Bạn có muốn nuôi thú cưng không?"}',
+      '[{"en": "Yes", "vi": "Có"}, {"en": "No", "vi": "Không"}, {"en": "I am allergic", "vi": "Mình bị dị ứng"}]'),
+    ('money_split', '{"en": "Who should pay on a first date?", "vi": "Ai nên trả tiền trong buổi hẹn đầu?"}',
+      '[{"en": "Whoever asked", "vi": "Người mời"}, {"en": "Split the bill", "vi": "Chia đôi"}, {"en": "Whoever offers", "vi": "Ai muốn trả thì trả"}]'),
+    ('faith_importance', '{"en": "How important is religion in your life?", "vi": "Tôn giáo quan trọng thế nào trong cuộc sống của bạn?"}',
+      '[{"en": "Very important", "vi": "Rất quan trọng"}, {"en": "Somewhat important", "vi": "Khá quan trọng"}, {"en": "Not important", "vi": "Không quan trọng"}]')
+  ON CONFLICT (id) DO NOTHING;
+
+  -- Selfie verification; a submitted selfie waits in the review queue until it is approved or rejected
+  CREATE TABLE IF NOT EXISTS photo_verifications (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    pose VARCHAR(30) NOT NULL,
+    selfie_url TEXT,
+    status VARCHAR(20) NOT NULL CHECK (status IN ('awaiting_selfie', 'pending', 'approved', 'rejected')),
+    reason TEXT,
+    reviewed_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    submitted_at TIMESTAMP,
+    reviewed_at TIMESTAMP
+  );
+  CREATE INDEX IF NOT EXISTS idx_photo_verifications_user_id ON photo_verifications(user_id, created_at DESC);
+  CREATE INDEX IF NOT EXISTS idx_photo_verifications_queue ON photo_verifications(submitted_at) WHERE status = 'pending';
+  CREATE UNIQUE INDEX IF NOT EXISTS idx_photo_verifications_open ON photo_verifications(user_id)
+    WHERE status IN ('awaiting_selfie', 'pending');
+  -- The avatar the selfie is compared with, so an approval cannot verify a photo swapped in later
+  ALTER TABLE photo_verifications ADD COLUMN IF NOT EXISTS avatar_url TEXT;
+
+  -- normalized_email maps aliases of one mailbox to the same address: lower case, without a
+  -- +tag and, for Gmail, without dots.
+  CREATE OR REPLACE FUNCTION normalized_email(email TEXT) RETURNS TEXT AS $$
+    SELECT CASE WHEN domain IN ('gmail.com', 'googlemail.com')
+      THEN replace(local, '.', '') || '@gmail.com'
+      ELSE local || '@' || domain END
+    FROM (SELECT split_part(split_part(lower(email), '@', 1), '+', 1) AS local,
+      split_part(lower(email), '@', 2) AS domain) e
+  $$ LANGUAGE SQL IMMUTABLE;
+  CREATE INDEX IF NOT EXISTS idx_users_normalized_email ON users(normalized_email(email));
+  CREATE INDEX IF NOT EXISTS idx_users_signup_ip ON users(signup_ip, created_at) WHERE signup_ip IS NOT NULL;
+  CREATE INDEX IF NOT EXISTS idx_users_avatar_hash ON users(avatar_hash) WHERE avatar_hash IS NOT NULL;
+
+  CREATE TABLE IF NOT EXISTS moderation_flags (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    reason VARCHAR(30) NOT NULL CHECK (reason IN ('duplicate_avatar', 'email_alias', 'signup_burst', 'duplicate_bio', 'rapid_liking')),
+    related_user_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    details TEXT NOT NULL DEFAULT '',
+    status VARCHAR(20) NOT NULL DEFAULT 'open' CHECK (status IN ('open', 'dismissed', 'confirmed')),
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    resolved_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    resolved_at TIMESTAMP
+  );
+  CREATE INDEX IF NOT EXISTS idx_moderation_flags_user_id ON moderation_flags(user_id, reason);
+  CREATE INDEX IF NOT EXISTS idx_moderation_flags_queue ON moderation_flags(created_at) WHERE status = 'open';
   `
   _, err := db.Exec(schema)
   return err
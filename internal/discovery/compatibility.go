@@ -0,0 +1,72 @@
+package discovery
+
+import (
+	"math"
+
+	"github.com/Viet-CodingStars/kyupi-kyupi-backend/internal/models"
+)
+
+// importanceWeights is how many points a partner's answer is worth at each importance level,
+// from irrelevant to mandatory.
+var importanceWeights = [...]float64{0, 1, 10, 50, 250}
+
+// Compatibility is the match percentage between two users from the questions both answered,
+// in [0, 1]: the geometric mean of how satisfied each is with the other's answers, weighted by
+// importance, less a margin of one over the number of questions in common. It reports false
+// when the two have no questions in common or neither cares about any of them.
+//
+// Both answer lists must be sorted by question ID in byte order; the lists are merged in a
+// single pass without allocating, so ranking can afford it for every candidate.
+func Compatibility(a, b []models.QuestionAnswer) (float64, bool) {
+	var earnedA, possibleA, earnedB, possibleB float64
+	common := 0
+	for i, j := 0, 0; i < len(a) && j < len(b); {
+		switch {
+		case a[i].QuestionID < b[j].QuestionID:
+			i++
+		case a[i].QuestionID > b[j].QuestionID:
+			j++
+		default:
+			wA, wB := importanceWeight(a[i].Importance), importanceWeight(b[j].Importance)
+			possibleA += wA
+			possibleB += wB
+			if a[i].Accepts(b[j].Answer) {
+				earnedA += wA
+			}
+			if b[j].Accepts(a[i].Answer) {
+				earnedB += wB
+			}
+			common++
+			i++
+			j++
+		}
+	}
+	if common == 0 || (possibleA == 0 && possibleB == 0) {
+		return 0, false
+	}
+	// A side that marked every common question irrelevant is satisfied either way
+	satisfaction := func(earned, possible float64) float64 {
+		if possible == 0 {
+			return 1
+		}
+		return earned / possible
+	}
+	match := math.Sqrt(satisfaction(earnedA, possibleA)*satisfaction(earnedB, possibleB)) - 1/float64(common)
+	return math.Max(0, match), true
+}
+
+func importanceWeight(level int) float64 {
+	if level < 0 || level >= len(importanceWeights) {
+		return 0
+	}
+	return importanceWeights[level]
+}
+
+// QuestionFit is the compatibility signal: the match percentage, or unknownScore when it
+// cannot be computed.
+func QuestionFit(viewer, candidate *Profile) float64 {
+	if match, ok := Compatibility(viewer.Answers, candidate.Answers); ok {
+		return match
+	}
+	return unknownScore
+}
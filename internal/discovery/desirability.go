@@ -0,0 +1,156 @@
+package discovery
+
+import (
+	"math"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// InitialRating is the desirability rating of a user nobody has swiped on yet.
+	InitialRating = 1000.0
+	// ratingScale is the rating gap at which a like becomes ten times less expected.
+	ratingScale = 400.0
+	// ratingStep is the most a single swipe can move a rating.
+	ratingStep = 32.0
+
+	// indiscriminateMinSwipes is how many swipes a user needs before they can be judged.
+	indiscriminateMinSwipes = 50
+	// indiscriminateLikeRate is the like rate at or above which a user likes almost everyone.
+	indiscriminateLikeRate = 0.9
+	// indiscriminateSwipesPerDay is the swipe rate at or above which a user cannot be looking
+	// at the profiles they swipe on.
+	indiscriminateSwipesPerDay = 300.0
+	// indiscriminateWeight is how much an indiscriminate swiper's swipes count.
+	indiscriminateWeight = 0.2
+)
+
+// Swipe is one like or pass, in the order they were made.
+type Swipe struct {
+	SwiperID uuid.UUID
+	TargetID uuid.UUID
+	Liked    bool
+}
+
+// SwiperStats summarises how a user swipes.
+type SwiperStats struct {
+	Likes   int
+	Passes  int
+	FirstAt time.Time
+	LastAt  time.Time
+}
+
+// Swipes is the number of likes and passes given.
+func (s SwiperStats) Swipes() int {
+	return s.Likes + s.Passes
+}
+
+// LikeRate is the share of swipes that were likes.
+func (s SwiperStats) LikeRate() float64 {
+	if s.Swipes() == 0 {
+		return 0
+	}
+	return float64(s.Likes) / float64(s.Swipes())
+}
+
+// SwipesPerDay is the average number of swipes per day between the first and last swipe,
+// counting anything shorter than a day as one day.
+func (s SwiperStats) SwipesPerDay() float64 {
+	days := s.LastAt.Sub(s.FirstAt).Hours() / 24
+	return float64(s.Swipes()) / math.Max(1, days)
+}
+
+// Indiscriminate reports whether the user likes nearly everyone or swipes too fast to be
+// choosing, so their swipes say little about the people they swipe on.
+func (s SwiperStats) Indiscriminate() bool {
+	if s.Swipes() < indiscriminateMinSwipes {
+		return false
+	}
+	return s.LikeRate() >= indiscriminateLikeRate || s.SwipesPerDay() >= indiscriminateSwipesPerDay
+}
+
+// UserScore is a user's computed desirability and swiping behaviour. It is internal to ranking
+// and never shown to users.
+type UserScore struct {
+	UserID         uuid.UUID
+	Rating         float64
+	Likes          int
+	Passes         int
+	SwipesPerDay   float64
+	Indiscriminate bool
+}
+
+// Rater computes Elo-style desirability ratings from swipe history. Each swipe is a contest
+// the target wins with a like and loses with a pass; the swiper's own rating sets how expected
+// the outcome was, so a like from a sought-after user gains more than one from anybody else,
+// and a pass from them costs less. Swipes from indiscriminate swipers count for little.
+type Rater struct {
+	stats   map[uuid.UUID]SwiperStats
+	ratings map[uuid.UUID]float64
+}
+
+func NewRater(stats map[uuid.UUID]SwiperStats) *Rater {
+	return &Rater{stats: stats, ratings: make(map[uuid.UUID]float64)}
+}
+
+func (r *Rater) rating(id uuid.UUID) float64 {
+	if rating, ok := r.ratings[id]; ok {
+		return rating
+	}
+	return InitialRating
+}
+
+// Observe applies one swipe. Swipes must be observed in the order they were made.
+func (r *Rater) Observe(s Swipe) error {
+	swiper, target := r.rating(s.SwiperID), r.rating(s.TargetID)
+	expected := 1 / (1 + math.Pow(10, (swiper-target)/ratingScale))
+	outcome := 0.0
+	if s.Liked {
+		outcome = 1
+	}
+	weight := 1.0
+	if r.stats[s.SwiperID].Indiscriminate() {
+		weight = indiscriminateWeight
+	}
+	r.ratings[s.TargetID] = target + ratingStep*weight*(outcome-expected)
+	if _, ok := r.ratings[s.SwiperID]; !ok {
+		r.ratings[s.SwiperID] = InitialRating
+	}
+	return nil
+}
+
+// Scores returns the rating and swiping behaviour of every user seen so far.
+func (r *Rater) Scores() []UserScore {
+	seen := make(map[uuid.UUID]bool, len(r.ratings)+len(r.stats))
+	scores := make([]UserScore, 0, len(r.ratings)+len(r.stats))
+	add := func(id uuid.UUID) {
+		if seen[id] {
+			return
+		}
+		seen[id] = true
+		stats := r.stats[id]
+		score := UserScore{UserID: id, Rating: r.rating(id), Likes: stats.Likes, Passes: stats.Passes}
+		if stats.Swipes() > 0 {
+			score.SwipesPerDay = stats.SwipesPerDay()
+			score.Indiscriminate = stats.Indiscriminate()
+		}
+		scores = append(scores, score)
+	}
+	for id := range r.ratings {
+		add(id)
+	}
+	for id := range r.stats {
+		add(id)
+	}
+	return scores
+}
+
+// Desirability maps a rating to [0, 1], one half for a user at the initial rating. Users
+// without a rating yet are treated as average.
+func Desirability(rating *float64) float64 {
+	if rating == nil {
+		return unknownScore
+	}
+	return 1 / (1 + math.Pow(10, (InitialRating-*rating)/ratingScale))
+}
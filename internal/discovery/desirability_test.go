@@ -0,0 +1,202 @@
+package discovery
+
+import (
+	"math/rand"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestSwiperStats(t *testing.T) {
+	start := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	selective := SwiperStats{Likes: 20, Passes: 80, FirstAt: start, LastAt: start.Add(10 * 24 * time.Hour)}
+	if selective.Indiscriminate() {
+		t.Fatal("expected a selective swiper not to be indiscriminate")
+	}
+	if got := selective.SwipesPerDay(); got != 10 {
+		t.Fatalf("expected 10 swipes per day, got %v", got)
+	}
+
+	likesEveryone := SwiperStats{Likes: 95, Passes: 5, FirstAt: start, LastAt: start.Add(10 * 24 * time.Hour)}
+	if !likesEveryone.Indiscriminate() {
+		t.Fatal("expected a swiper who likes 95% of profiles to be indiscriminate")
+	}
+
+	tooFast := SwiperStats{Likes: 200, Passes: 300, FirstAt: start, LastAt: start.Add(time.Hour)}
+	if !tooFast.Indiscriminate() {
+		t.Fatal("expected 500 swipes within a day to be indiscriminate")
+	}
+
+	newcomer := SwiperStats{Likes: 10, FirstAt: start, LastAt: start}
+	if newcomer.Indiscriminate() {
+		t.Fatal("expected too few swipes not to be judged")
+	}
+}
+
+func TestRater(t *testing.T) {
+	t.Run("likes raise and passes lower a rating", func(t *testing.T) {
+		liked, passed, swiper := uuid.New(), uuid.New(), uuid.New()
+		rater := NewRater(nil)
+		rater.Observe(Swipe{SwiperID: swiper, TargetID: liked, Liked: true})
+		rater.Observe(Swipe{SwiperID: swiper, TargetID: passed})
+		if rater.rating(liked) <= InitialRating || rater.rating(passed) >= InitialRating {
+			t.Fatalf("unexpected ratings: liked %v, passed %v", rater.rating(liked), rater.rating(passed))
+		}
+		if rater.rating(swiper) != InitialRating {
+			t.Fatalf("expected swiping not to change the swiper's rating, got %v", rater.rating(swiper))
+		}
+	})
+
+	t.Run("a like from a sought-after user counts more", func(t *testing.T) {
+		star, average, fromStar, fromAverage := uuid.New(), uuid.New(), uuid.New(), uuid.New()
+		rater := NewRater(nil)
+		rater.ratings[star] = InitialRating + 300
+		rater.ratings[average] = InitialRating
+		rater.Observe(Swipe{SwiperID: star, TargetID: fromStar, Liked: true})
+		rater.Observe(Swipe{SwiperID: average, TargetID: fromAverage, Liked: true})
+		if rater.rating(fromStar) <= rater.rating(fromAverage) {
+			t.Fatalf("expected a like from a sought-after user to gain more (%v vs %v)", rater.rating(fromStar), rater.rating(fromAverage))
+		}
+	})
+
+	t.Run("likes from indiscriminate swipers count less", func(t *testing.T) {
+		start := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+		spammer, regular, a, b := uuid.New(), uuid.New(), uuid.New(), uuid.New()
+		rater := NewRater(map[uuid.UUID]SwiperStats{
+			spammer: {Likes: 100, FirstAt: start, LastAt: start.Add(time.Hour)},
+			regular: {Likes: 10, Passes: 40, FirstAt: start, LastAt: start.Add(10 * 24 * time.Hour)},
+		})
+		rater.Observe(Swipe{SwiperID: spammer, TargetID: a, Liked: true})
+		rater.Observe(Swipe{SwiperID: regular, TargetID: b, Liked: true})
+		if rater.rating(a)-InitialRating >= rater.rating(b)-InitialRating {
+			t.Fatalf("expected the indiscriminate like to gain less (%v vs %v)", rater.rating(a), rater.rating(b))
+		}
+
+		scores := make(map[uuid.UUID]UserScore)
+		for _, s := range rater.Scores() {
+			scores[s.UserID] = s
+		}
+		if len(scores) != 4 {
+			t.Fatalf("expected scores for all 4 users, got %d", len(scores))
+		}
+		if !scores[spammer].Indiscriminate || scores[regular].Indiscriminate {
+			t.Fatalf("unexpected indiscriminate flags: %+v", scores)
+		}
+	})
+}
+
+func TestDesirability(t *testing.T) {
+	if got := Desirability(nil); got != unknownScore {
+		t.Fatalf("expected an unscored user to be average, got %v", got)
+	}
+	initial := InitialRating
+	if got := Desirability(&initial); !approx(got, 0.5) {
+		t.Fatalf("expected 0.5 at the initial rating, got %v", got)
+	}
+	high := InitialRating + ratingScale
+	if got := Desirability(&high); !approx(got, 10.0/11) {
+		t.Fatalf("expected 10/11 one scale above, got %v", got)
+	}
+}
+
+// TestRaterRecoversAttractiveness simulates swiping in a population with a hidden
+// attractiveness per user, plus bots that mass-like the least attractive users, and checks
+// that the ratings put the genuinely attractive users ahead of the rest.
+func TestRaterRecoversAttractiveness(t *testing.T) {
+	rng := rand.New(rand.NewSource(7))
+	start := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	const users, bots, swipesPerUser = 300, 30, 60
+	ids := make([]uuid.UUID, users)
+	attractiveness := make(map[uuid.UUID]float64, users)
+	for i := range ids {
+		rng.Read(ids[i][:])
+		attractiveness[ids[i]] = rng.Float64()
+	}
+	var unattractive []uuid.UUID
+	for _, id := range ids {
+		if attractiveness[id] < 0.3 {
+			unattractive = append(unattractive, id)
+		}
+	}
+	botIDs := make([]uuid.UUID, bots)
+	for i := range botIDs {
+		rng.Read(botIDs[i][:])
+	}
+
+	var swipes []Swipe
+	stats := make(map[uuid.UUID]SwiperStats)
+	record := func(s Swipe) {
+		swipes = append(swipes, s)
+		st := stats[s.SwiperID]
+		if s.Liked {
+			st.Likes++
+		} else {
+			st.Passes++
+		}
+		st.FirstAt, st.LastAt = start, start.Add(30*24*time.Hour)
+		stats[s.SwiperID] = st
+	}
+	for round := 0; round < swipesPerUser; round++ {
+		for _, swiper := range ids {
+			target := ids[rng.Intn(users)]
+			if target == swiper {
+				continue
+			}
+			record(Swipe{SwiperID: swiper, TargetID: target, Liked: rng.Float64() < attractiveness[target]})
+		}
+		// Bots like the least attractive users to try to push them up
+		for _, bot := range botIDs {
+			for n := 0; n < 3; n++ {
+				record(Swipe{SwiperID: bot, TargetID: unattractive[rng.Intn(len(unattractive))], Liked: true})
+			}
+		}
+	}
+
+	for _, bot := range botIDs {
+		if !stats[bot].Indiscriminate() {
+			t.Fatalf("expected bot %s to be flagged as indiscriminate: %+v", bot, stats[bot])
+		}
+	}
+
+	rater := NewRater(stats)
+	for _, s := range swipes {
+		rater.Observe(s)
+	}
+
+	// Compare the top and bottom fifth by hidden attractiveness
+	sorted := append([]uuid.UUID(nil), ids...)
+	sort.Slice(sorted, func(i, j int) bool { return attractiveness[sorted[i]] > attractiveness[sorted[j]] })
+	mean := func(group []uuid.UUID) float64 {
+		total := 0.0
+		for _, id := range group {
+			total += rater.rating(id)
+		}
+		return total / float64(len(group))
+	}
+	top, bottom := mean(sorted[:users/5]), mean(sorted[len(sorted)-users/5:])
+	t.Logf("mean rating: top fifth %.0f, bottom fifth %.0f", top, bottom)
+	if top-bottom < 200 {
+		t.Fatalf("expected the most attractive users to rate well above the least (top %.0f, bottom %.0f)", top, bottom)
+	}
+
+	// Concordance: how often a more attractive user also has the higher rating
+	agree, pairs := 0, 0
+	for i := 0; i < users; i++ {
+		for j := i + 1; j < users; j++ {
+			a, b := ids[i], ids[j]
+			if (attractiveness[a] > attractiveness[b]) == (rater.rating(a) > rater.rating(b)) {
+				agree++
+			}
+			pairs++
+		}
+	}
+	concordance := float64(agree) / float64(pairs)
+	t.Logf("pairwise concordance %.2f", concordance)
+	if concordance < 0.75 {
+		t.Fatalf("expected ratings to order most pairs like attractiveness, got %.2f", concordance)
+	}
+}
@@ -0,0 +1,204 @@
+// Package discovery ranks the profiles shown to a user while swiping.
+//
+// Each candidate is scored from a handful of signals in [0, 1] (preference compatibility,
+// intention similarity, recent activity, profile completeness, how likely the candidate is to
+// like someone like the viewer, distance, how sought-after the candidate is, how well each
+// side meets the other's soft filters, shared interests, and the match percentage from the
+// compatibility questions), combined as a weighted mean. Filters a user marked as
+// dealbreakers exclude candidates instead. Rankings are precomputed per viewer and cached by a
+// Store so that paging through the feed is cheap.
+package discovery
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/Viet-CodingStars/kyupi-kyupi-backend/internal/models"
+	"github.com/google/uuid"
+)
+
+// Signal names, as used in DISCOVERY_WEIGHTS.
+const (
+	SignalPreference   = "preference"
+	SignalIntention    = "intention"
+	SignalRecency      = "recency"
+	SignalCompleteness = "completeness"
+	SignalReciprocal   = "reciprocal"
+	SignalDistance     = "distance"
+	SignalDesirability = "desirability"
+	SignalFilters      = "filters"
+	SignalInterests    = "interests"
+	SignalQuestions    = "questions"
+)
+
+// Profile holds the attributes of a user that ranking looks at.
+type Profile struct {
+	ID           uuid.UUID
+	Gender       int
+	TargetGender *int
+	Intention    string
+	BirthDate    time.Time
+	HasBio       bool
+	HasAvatar    bool
+	Latitude     *float64
+	Longitude    *float64
+	LastActiveAt *time.Time
+	HeightCm     *int
+	Education    string
+	Languages    []string
+	Religion     string
+	Drinking     string
+	Smoking      string
+	Children     string
+	Interests    []string // slugs of the user's active interests
+	HasPrompts   bool
+	Verified     bool // the user's photos passed selfie verification
+	// Answers are the user's compatibility answers, sorted by question ID in byte order
+	Answers []models.QuestionAnswer
+	// Preferences is nil when the user has not set any filters
+	Preferences *models.Preferences
+}
+
+// Candidate is a profile that may be shown to a viewer, together with the candidate's swipe
+// history towards people like the viewer (same gender and intention).
+type Candidate struct {
+	Profile
+	SimilarLikes  int // likes the candidate gave to people like the viewer
+	SimilarSwipes int // likes and passes the candidate gave to people like the viewer
+	Likes         int // likes the candidate gave to anyone
+	Swipes        int // likes and passes the candidate gave to anyone
+	// Rating is the candidate's desirability rating, nil until they have been scored
+	Rating *float64
+}
+
+// Scored is a candidate's position in a viewer's ranking.
+type Scored struct {
+	CandidateID uuid.UUID
+	Score       float64
+}
+
+// Weights sets how much each signal counts towards a candidate's score.
+type Weights struct {
+	Preference   float64
+	Intention    float64
+	Recency      float64
+	Completeness float64
+	Reciprocal   float64
+	Distance     float64
+	Desirability float64
+	Filters      float64
+	Interests    float64
+	Questions    float64
+}
+
+// DefaultWeights favours mutual fit over activity and presentation.
+func DefaultWeights() Weights {
+	return Weights{
+		Preference:   1,
+		Intention:    1,
+		Recency:      0.75,
+		Completeness: 0.5,
+		Reciprocal:   1,
+		Distance:     0.75,
+		Desirability: 0.5,
+		Filters:      1,
+		Interests:    0.75,
+		Questions:    1,
+	}
+}
+
+// ParseWeights applies "signal=weight" overrides to the default weights. Unknown signals and
+// negative or malformed weights are rejected.
+func ParseWeights(overrides map[string]string) (Weights, error) {
+	w := DefaultWeights()
+	fields := map[string]*float64{
+		SignalPreference:   &w.Preference,
+		SignalIntention:    &w.Intention,
+		SignalRecency:      &w.Recency,
+		SignalCompleteness: &w.Completeness,
+		SignalReciprocal:   &w.Reciprocal,
+		SignalDistance:     &w.Distance,
+		SignalDesirability: &w.Desirability,
+		SignalFilters:      &w.Filters,
+		SignalInterests:    &w.Interests,
+		SignalQuestions:    &w.Questions,
+	}
+	for name, raw := range overrides {
+		field, ok := fields[name]
+		if !ok {
+			return Weights{}, fmt.Errorf("unknown discovery signal %q", name)
+		}
+		value, err := strconv.ParseFloat(raw, 64)
+		if err != nil || value < 0 {
+			return Weights{}, fmt.Errorf("discovery weight for %q must be a non-negative number", name)
+		}
+		*field = value
+	}
+	if w.total() == 0 {
+		return Weights{}, fmt.Errorf("at least one discovery weight must be positive")
+	}
+	return w, nil
+}
+
+func (w Weights) total() float64 {
+	return w.Preference + w.Intention + w.Recency + w.Completeness + w.Reciprocal + w.Distance + w.Desirability + w.Filters + w.Interests + w.Questions
+}
+
+// Compatible reports whether the candidate may be shown to the viewer at all. Gender
+// preferences and both sides' dealbreakers are also enforced by the Store when it loads
+// candidates.
+func Compatible(viewer *Profile, candidate *Candidate, now time.Time) bool {
+	if candidate.ID == viewer.ID {
+		return false
+	}
+	if !wantsGender(viewer.TargetGender, candidate.Gender) || !wantsGender(candidate.TargetGender, viewer.Gender) {
+		return false
+	}
+	if !MeetsDealbreakers(viewer, &candidate.Profile, now) || !MeetsDealbreakers(&candidate.Profile, viewer, now) {
+		return false
+	}
+	return IntentionSimilarity(viewer.Intention, candidate.Intention) > 0
+}
+
+// Score combines the candidate's signals into a weighted mean in [0, 1].
+func Score(viewer *Profile, candidate *Candidate, w Weights, now time.Time) float64 {
+	total := w.total()
+	if total == 0 {
+		return 0
+	}
+	sum := w.Preference*PreferenceFit(viewer, &candidate.Profile, now) +
+		w.Intention*IntentionSimilarity(viewer.Intention, candidate.Intention) +
+		w.Recency*Recency(candidate.LastActiveAt, now) +
+		w.Completeness*Completeness(&candidate.Profile) +
+		w.Reciprocal*ReciprocalInterest(candidate) +
+		w.Distance*Proximity(viewer, &candidate.Profile) +
+		w.Desirability*Desirability(candidate.Rating) +
+		w.Filters*FilterFit(viewer, &candidate.Profile, now) +
+		w.Interests*SharedInterests(viewer, &candidate.Profile) +
+		w.Questions*QuestionFit(viewer, &candidate.Profile)
+	return sum / total
+}
+
+// Rank scores the compatible candidates and returns them best first, keeping at most limit.
+// Ties are broken by ID so the order is stable.
+func Rank(viewer *Profile, candidates []*Candidate, w Weights, now time.Time, limit int) []Scored {
+	ranked := make([]Scored, 0, len(candidates))
+	for _, c := range candidates {
+		if !Compatible(viewer, c, now) {
+			continue
+		}
+		ranked = append(ranked, Scored{CandidateID: c.ID, Score: Score(viewer, c, w, now)})
+	}
+	sort.Slice(ranked, func(i, j int) bool {
+		if ranked[i].Score != ranked[j].Score {
+			return ranked[i].Score > ranked[j].Score
+		}
+		return ranked[i].CandidateID.String() > ranked[j].CandidateID.String()
+	})
+	if limit >= 0 && len(ranked) > limit {
+		ranked = ranked[:limit]
+	}
+	return ranked
+}
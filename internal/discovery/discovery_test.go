@@ -0,0 +1,317 @@
+package discovery
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/Viet-CodingStars/kyupi-kyupi-backend/internal/models"
+	"github.com/google/uuid"
+)
+
+func intPtr(v int) *int { return &v }
+
+func floatPtr(v float64) *float64 { return &v }
+
+func approx(a, b float64) bool { return math.Abs(a-b) < 1e-9 }
+
+func TestParseWeights(t *testing.T) {
+	t.Run("defaults without overrides", func(t *testing.T) {
+		w, err := ParseWeights(nil)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if w != DefaultWeights() {
+			t.Fatalf("expected default weights, got %+v", w)
+		}
+	})
+
+	t.Run("applies overrides", func(t *testing.T) {
+		w, err := ParseWeights(map[string]string{"distance": "2", "recency": "0"})
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if w.Distance != 2 || w.Recency != 0 || w.Preference != DefaultWeights().Preference {
+			t.Fatalf("unexpected weights %+v", w)
+		}
+	})
+
+	for name, overrides := range map[string]map[string]string{
+		"unknown signal":  {"looks": "1"},
+		"negative weight": {"distance": "-1"},
+		"malformed":       {"distance": "far"},
+		"all zero": {
+			"preference": "0", "intention": "0", "recency": "0",
+			"completeness": "0", "reciprocal": "0", "distance": "0", "desirability": "0",
+			"filters": "0", "interests": "0", "questions": "0",
+		},
+	} {
+		t.Run("rejects "+name, func(t *testing.T) {
+			if _, err := ParseWeights(overrides); err == nil {
+				t.Fatal("expected error")
+			}
+		})
+	}
+}
+
+func TestIntentionSimilarity(t *testing.T) {
+	cases := []struct {
+		a, b string
+		want float64
+	}{
+		{models.IntentionLongTermPartner, models.IntentionLongTermPartner, 1},
+		{models.IntentionLongTermPartner, models.IntentionLongTermOpenToShort, 2.0 / 3},
+		{models.IntentionLongTermPartner, models.IntentionShortTermFun, 0},
+		{models.IntentionNewFriends, models.IntentionNewFriends, 1},
+		{models.IntentionNewFriends, models.IntentionShortTermFun, 0},
+		{models.IntentionStillFiguringOut, models.IntentionNewFriends, 0.5},
+	}
+	for _, tc := range cases {
+		if got := IntentionSimilarity(tc.a, tc.b); !approx(got, tc.want) {
+			t.Errorf("IntentionSimilarity(%s, %s) = %v, want %v", tc.a, tc.b, got, tc.want)
+		}
+		if got := IntentionSimilarity(tc.b, tc.a); !approx(got, tc.want) {
+			t.Errorf("IntentionSimilarity(%s, %s) = %v, want %v", tc.b, tc.a, got, tc.want)
+		}
+	}
+}
+
+func TestRecency(t *testing.T) {
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	if got := Recency(nil, now); got != 0 {
+		t.Fatalf("expected 0 without activity, got %v", got)
+	}
+	justNow := now
+	if got := Recency(&justNow, now); got != 1 {
+		t.Fatalf("expected 1 for current activity, got %v", got)
+	}
+	threeDaysAgo := now.Add(-recencyHalfLife)
+	if got := Recency(&threeDaysAgo, now); !approx(got, 0.5) {
+		t.Fatalf("expected 0.5 after one half-life, got %v", got)
+	}
+}
+
+func TestReciprocalInterest(t *testing.T) {
+	newcomer := &Candidate{}
+	if got := ReciprocalInterest(newcomer); !approx(got, 0.5) {
+		t.Fatalf("expected 0.5 without history, got %v", got)
+	}
+
+	picky := &Candidate{SimilarLikes: 1, SimilarSwipes: 50, Likes: 10, Swipes: 100}
+	keen := &Candidate{SimilarLikes: 40, SimilarSwipes: 50, Likes: 60, Swipes: 100}
+	if ReciprocalInterest(picky) >= ReciprocalInterest(keen) {
+		t.Fatalf("expected a candidate who likes people like the viewer to score higher")
+	}
+
+	// A few swipes should not outweigh the overall like rate
+	lucky := &Candidate{SimilarLikes: 1, SimilarSwipes: 1, Likes: 5, Swipes: 100}
+	if got := ReciprocalInterest(lucky); got > 0.25 {
+		t.Fatalf("expected a sparse history to stay near the overall rate, got %v", got)
+	}
+}
+
+func TestProximity(t *testing.T) {
+	hanoi := &Profile{Latitude: floatPtr(21.0285), Longitude: floatPtr(105.8542)}
+	saigon := &Profile{Latitude: floatPtr(10.8231), Longitude: floatPtr(106.6297)}
+	unknown := &Profile{}
+
+	km := DistanceKm(*hanoi.Latitude, *hanoi.Longitude, *saigon.Latitude, *saigon.Longitude)
+	if km < 1100 || km > 1200 {
+		t.Fatalf("expected about 1140km between Hanoi and Saigon, got %v", km)
+	}
+	if got := Proximity(hanoi, hanoi); got != 1 {
+		t.Fatalf("expected 1 for the same place, got %v", got)
+	}
+	if got := Proximity(hanoi, saigon); got > 0.05 {
+		t.Fatalf("expected a low score across the country, got %v", got)
+	}
+	if got := Proximity(hanoi, unknown); got != unknownScore {
+		t.Fatalf("expected neutral score without a location, got %v", got)
+	}
+}
+
+func TestSharedInterests(t *testing.T) {
+	viewer := &Profile{Interests: []string{"hiking", "coffee", "kpop"}}
+	cases := []struct {
+		interests []string
+		want      float64
+	}{
+		{nil, unknownScore},
+		{[]string{"gaming"}, unknownScore},
+		{[]string{"coffee", "gaming"}, 0.75},
+		{[]string{"kpop", "coffee", "hiking"}, 0.9375},
+	}
+	for _, tc := range cases {
+		if got := SharedInterests(viewer, &Profile{Interests: tc.interests}); got != tc.want {
+			t.Fatalf("SharedInterests(%v) = %v, want %v", tc.interests, got, tc.want)
+		}
+	}
+}
+
+func TestCompatibility(t *testing.T) {
+	answer := func(id string, option int, accepted uint8, importance string) models.QuestionAnswer {
+		level, _ := models.ImportanceLevel(importance)
+		return models.QuestionAnswer{QuestionID: id, Answer: option, Accepted: accepted, Importance: level}
+	}
+	a := []models.QuestionAnswer{
+		answer("pets_at_home", 0, 0b001, models.ImportanceVery),
+		answer("tidiness", 1, 0b010, models.ImportanceALittle),
+		answer("want_children", 0, 0b001, models.ImportanceMandatory),
+	}
+	b := []models.QuestionAnswer{
+		answer("faith_importance", 2, 0b111, models.ImportanceVery),
+		answer("pets_at_home", 0, 0b011, models.ImportanceSomewhat),
+		answer("tidiness", 2, 0b110, models.ImportanceMandatory),
+	}
+
+	// a is satisfied with 50 of 51 points and b with all 260, over two questions in common
+	want := math.Sqrt(50.0/51.0) - 0.5
+	if got, ok := Compatibility(a, b); !ok || !approx(got, want) {
+		t.Fatalf("expected %v, got %v (%v)", want, got, ok)
+	}
+	if got, _ := Compatibility(b, a); !approx(got, want) {
+		t.Fatalf("expected the match to be symmetric, got %v", got)
+	}
+	if _, ok := Compatibility(a, b[:1]); ok {
+		t.Fatal("expected no match percentage without questions in common")
+	}
+	indifferent := []models.QuestionAnswer{answer("tidiness", 0, 0, models.ImportanceIrrelevant)}
+	if _, ok := Compatibility(indifferent, indifferent); ok {
+		t.Fatal("expected no match percentage when nobody cares")
+	}
+	if got := QuestionFit(&Profile{Answers: a}, &Profile{}); got != unknownScore {
+		t.Fatalf("expected neutral score without answers, got %v", got)
+	}
+}
+
+func TestCompleteness(t *testing.T) {
+	if got := Completeness(&Profile{Intention: models.IntentionStillFiguringOut}); got != 0 {
+		t.Fatalf("expected 0 for an empty profile, got %v", got)
+	}
+	// An open gender preference and still figuring out are choices, not missing steps
+	full := &Profile{
+		HasBio: true, HasAvatar: true, Intention: models.IntentionStillFiguringOut,
+		Latitude: floatPtr(1), Longitude: floatPtr(1), Interests: []string{"hiking"}, HasPrompts: true,
+	}
+	if got := Completeness(full); got != 1 {
+		t.Fatalf("expected 1 for a full profile, got %v", got)
+	}
+}
+
+func TestRank(t *testing.T) {
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	recent := now.Add(-time.Hour)
+	viewer := &Profile{
+		ID: uuid.New(), Gender: models.GenderMale, TargetGender: intPtr(models.GenderFemale),
+		Intention: models.IntentionLongTermPartner, BirthDate: now.AddDate(-30, 0, 0),
+	}
+	candidate := func(gender int, intention string, lastActive *time.Time) *Candidate {
+		return &Candidate{Profile: Profile{
+			ID: uuid.New(), Gender: gender, Intention: intention,
+			BirthDate: now.AddDate(-29, 0, 0), LastActiveAt: lastActive,
+		}}
+	}
+	active := candidate(models.GenderFemale, models.IntentionLongTermPartner, &recent)
+	dormant := candidate(models.GenderFemale, models.IntentionLongTermPartner, nil)
+	wrongGender := candidate(models.GenderMale, models.IntentionLongTermPartner, &recent)
+	casual := candidate(models.GenderFemale, models.IntentionShortTermFun, &recent)
+	notInterested := candidate(models.GenderFemale, models.IntentionLongTermPartner, &recent)
+	notInterested.TargetGender = intPtr(models.GenderFemale)
+
+	ranked := Rank(viewer, []*Candidate{dormant, wrongGender, casual, notInterested, active}, DefaultWeights(), now, 10)
+	if len(ranked) != 2 {
+		t.Fatalf("expected 2 compatible candidates, got %d", len(ranked))
+	}
+	if ranked[0].CandidateID != active.ID || ranked[1].CandidateID != dormant.ID {
+		t.Fatalf("expected the active candidate first, got %+v", ranked)
+	}
+	if ranked[0].Score <= 0 || ranked[0].Score > 1 {
+		t.Fatalf("expected a score in (0, 1], got %v", ranked[0].Score)
+	}
+
+	if limited := Rank(viewer, []*Candidate{dormant, active}, DefaultWeights(), now, 1); len(limited) != 1 {
+		t.Fatalf("expected the limit to apply, got %d", len(limited))
+	}
+}
+
+func TestFilters(t *testing.T) {
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	viewer := &Profile{
+		ID: uuid.New(), Gender: models.GenderMale, Intention: models.IntentionLongTermPartner,
+		BirthDate: now.AddDate(-30, 0, 0),
+		Preferences: &models.Preferences{
+			AgeMin: intPtr(25), AgeMax: intPtr(32),
+			Religions:    []string{models.ReligionBuddhist},
+			Smoking:      []string{models.HabitNever},
+			Dealbreakers: []string{models.FilterAge, models.FilterSmoking},
+		},
+	}
+	candidate := func(age int, religion, smoking string) *Candidate {
+		return &Candidate{Profile: Profile{
+			ID: uuid.New(), Gender: models.GenderFemale, Intention: models.IntentionLongTermPartner,
+			BirthDate: now.AddDate(-age, 0, 0), Religion: religion, Smoking: smoking,
+		}}
+	}
+
+	t.Run("dealbreakers exclude", func(t *testing.T) {
+		if !Compatible(viewer, candidate(28, models.ReligionBuddhist, models.HabitNever), now) {
+			t.Fatal("expected a candidate meeting every filter to be compatible")
+		}
+		if Compatible(viewer, candidate(40, models.ReligionBuddhist, models.HabitNever), now) {
+			t.Fatal("expected a candidate outside the age range to be excluded")
+		}
+		if Compatible(viewer, candidate(28, models.ReligionBuddhist, models.HabitOften), now) {
+			t.Fatal("expected a smoker to be excluded")
+		}
+		if !Compatible(viewer, candidate(28, models.ReligionBuddhist, ""), now) {
+			t.Fatal("expected a candidate who has not answered to be kept")
+		}
+	})
+
+	t.Run("soft filters only rank", func(t *testing.T) {
+		other := candidate(28, models.ReligionCatholic, models.HabitNever)
+		if !Compatible(viewer, other, now) {
+			t.Fatal("expected a soft filter not to exclude")
+		}
+		same := candidate(28, models.ReligionBuddhist, models.HabitNever)
+		if FilterFit(viewer, &same.Profile, now) != 1 || FilterFit(viewer, &other.Profile, now) != 0 {
+			t.Fatalf("unexpected filter fit: %v and %v", FilterFit(viewer, &same.Profile, now), FilterFit(viewer, &other.Profile, now))
+		}
+		unanswered := candidate(28, "", models.HabitNever)
+		if got := FilterFit(viewer, &unanswered.Profile, now); got != unknownScore {
+			t.Fatalf("expected an unanswered filter to be neutral, got %v", got)
+		}
+	})
+
+	t.Run("the candidate's dealbreakers apply too", func(t *testing.T) {
+		picky := candidate(28, models.ReligionBuddhist, models.HabitNever)
+		picky.Preferences = &models.Preferences{AgeMax: intPtr(29), Dealbreakers: []string{models.FilterAge}}
+		if Compatible(viewer, picky, now) {
+			t.Fatal("expected the candidate's age dealbreaker to exclude the viewer")
+		}
+	})
+
+	t.Run("verified only", func(t *testing.T) {
+		wary := *viewer
+		wary.Preferences = &models.Preferences{VerifiedOnly: true}
+		unverified := candidate(28, models.ReligionBuddhist, models.HabitNever)
+		if Compatible(&wary, unverified, now) {
+			t.Fatal("expected an unverified candidate to be excluded")
+		}
+		verified := candidate(28, models.ReligionBuddhist, models.HabitNever)
+		verified.Verified = true
+		if !Compatible(&wary, verified, now) {
+			t.Fatal("expected a verified candidate to be kept")
+		}
+		verified.Preferences = wary.Preferences
+		if Compatible(&wary, verified, now) {
+			t.Fatal("expected a verified-only candidate not to be shown to an unverified viewer")
+		}
+	})
+
+	t.Run("neutral without filters", func(t *testing.T) {
+		if got := FilterFit(&Profile{}, &Profile{}, now); got != unknownScore {
+			t.Fatalf("expected %v, got %v", unknownScore, got)
+		}
+	})
+}
@@ -0,0 +1,123 @@
+package discovery
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Viet-CodingStars/kyupi-kyupi-backend/internal/models"
+	"github.com/google/uuid"
+)
+
+// candidatePoolFactor is how many candidates are scored for every ranking slot kept in the cache.
+const candidatePoolFactor = 4
+
+// ErrStaleCursor is returned when a feed cursor points into a ranking that has since been
+// recomputed, so the client has to start again from the first page.
+var ErrStaleCursor = errors.New("discovery ranking changed since the cursor was issued")
+
+// Cursor marks a position in one generation of a viewer's cached ranking. Every recomputed
+// ranking gets a new generation, so a cursor can tell when its ranking was replaced.
+type Cursor struct {
+	Generation  int64
+	Score       float64
+	CandidateID uuid.UUID
+}
+
+// Page is one page of a viewer's feed. Next is the position of its last user, nil when empty.
+type Page struct {
+	Users []*models.User
+	Next  *Cursor
+}
+
+// Store loads ranking inputs and keeps each viewer's precomputed ranking.
+type Store interface {
+	GetProfile(userID uuid.UUID) (*Profile, error)
+	// ListCandidates returns up to limit users the viewer may be shown, enforcing visibility,
+	// both sides' gender preferences and excluding anyone the viewer already liked or passed.
+	ListCandidates(viewerID uuid.UUID, limit int) ([]*Candidate, error)
+	ReplaceCache(viewerID uuid.UUID, ranked []Scored, computedAt time.Time) error
+	// CacheComputedAt returns nil when the viewer has no cached ranking.
+	CacheComputedAt(viewerID uuid.UUID) (*time.Time, error)
+	// ListCached pages through the cached ranking after the cursor, skipping candidates who
+	// can no longer be shown. It returns ErrStaleCursor when the cursor belongs to an older
+	// generation of the ranking.
+	ListCached(viewerID uuid.UUID, cursor *Cursor, limit int) (*Page, error)
+}
+
+// Engine serves ranked discovery feeds from a per-viewer cache, recomputing a viewer's
+// ranking when it is missing or older than the cache TTL. Candidates whose profile
+// completeness is below minCompleteness are not shown until they fill in more of it.
+type Engine struct {
+	store           Store
+	weights         Weights
+	minCompleteness float64
+	cacheTTL        time.Duration
+	cacheSize       int
+	now             func() time.Time
+}
+
+func NewEngine(store Store, weights Weights, minCompleteness float64, cacheTTL time.Duration, cacheSize int) *Engine {
+	return &Engine{store: store, weights: weights, minCompleteness: minCompleteness, cacheTTL: cacheTTL, cacheSize: cacheSize, now: time.Now}
+}
+
+// complete leaves out the candidates whose profiles are not complete enough to be shown.
+func (e *Engine) complete(candidates []*Candidate) []*Candidate {
+	kept := make([]*Candidate, 0, len(candidates))
+	for _, c := range candidates {
+		if Completeness(&c.Profile) >= e.minCompleteness {
+			kept = append(kept, c)
+		}
+	}
+	return kept
+}
+
+// Refresh recomputes and stores the viewer's ranking.
+func (e *Engine) Refresh(viewerID uuid.UUID) error {
+	viewer, err := e.store.GetProfile(viewerID)
+	if err != nil {
+		return fmt.Errorf("load viewer profile: %w", err)
+	}
+	candidates, err := e.store.ListCandidates(viewerID, e.cacheSize*candidatePoolFactor)
+	if err != nil {
+		return fmt.Errorf("list candidates: %w", err)
+	}
+	now := e.now()
+	ranked := Rank(viewer, e.complete(candidates), e.weights, now, e.cacheSize)
+	if err := e.store.ReplaceCache(viewerID, ranked, now); err != nil {
+		return fmt.Errorf("store ranking: %w", err)
+	}
+	return nil
+}
+
+// Recommend returns the next page of the viewer's ranked feed after the cursor (the Next of
+// the previous page). The ranking is only recomputed on the first page; if it was recomputed
+// elsewhere while the viewer was paging, ErrStaleCursor is returned instead of a page.
+func (e *Engine) Recommend(viewerID uuid.UUID, cursor *Cursor, limit int) (*Page, error) {
+	if cursor == nil {
+		computedAt, err := e.store.CacheComputedAt(viewerID)
+		if err != nil {
+			return nil, err
+		}
+		if computedAt == nil || e.now().Sub(*computedAt) >= e.cacheTTL {
+			if err := e.Refresh(viewerID); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return e.store.ListCached(viewerID, cursor, limit)
+}
+
+// SelectPicks returns the viewer's count best candidates right now, for a daily picks list.
+// It uses the same candidates and weights as the feed but leaves the feed's cache alone.
+func (e *Engine) SelectPicks(viewerID uuid.UUID, count int) ([]Scored, error) {
+	viewer, err := e.store.GetProfile(viewerID)
+	if err != nil {
+		return nil, fmt.Errorf("load viewer profile: %w", err)
+	}
+	candidates, err := e.store.ListCandidates(viewerID, count*candidatePoolFactor)
+	if err != nil {
+		return nil, fmt.Errorf("list candidates: %w", err)
+	}
+	return Rank(viewer, e.complete(candidates), e.weights, e.now(), count), nil
+}
@@ -0,0 +1,292 @@
+package discovery
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Viet-CodingStars/kyupi-kyupi-backend/internal/models"
+	"github.com/google/uuid"
+)
+
+type mockStore struct {
+	profile    *Profile
+	candidates []*Candidate
+	cache      []Scored
+	computedAt *time.Time
+	generation int64
+	poolLimit  int
+	refreshes  int
+	listErr    error
+	// users holds the visibility of candidates and likedViewer the ones who liked the viewer;
+	// candidates missing from users are visible to members
+	users       map[uuid.UUID]*models.User
+	likedViewer map[uuid.UUID]bool
+}
+
+// listable mirrors the store's visibility rule: paused and hidden users are never listed and
+// incognito users only to people they liked.
+func (m *mockStore) listable(id uuid.UUID) bool {
+	user, ok := m.users[id]
+	if !ok {
+		return true
+	}
+	switch {
+	case user.PausedAt != nil, user.Visibility == models.VisibilityHidden:
+		return false
+	case user.Visibility == models.VisibilityIncognito:
+		return m.likedViewer[id]
+	}
+	return true
+}
+
+func (m *mockStore) GetProfile(userID uuid.UUID) (*Profile, error) {
+	return m.profile, nil
+}
+
+func (m *mockStore) ListCandidates(viewerID uuid.UUID, limit int) ([]*Candidate, error) {
+	m.poolLimit = limit
+	candidates := make([]*Candidate, 0, len(m.candidates))
+	for _, c := range m.candidates {
+		if m.listable(c.ID) {
+			candidates = append(candidates, c)
+		}
+	}
+	return candidates, m.listErr
+}
+
+func (m *mockStore) ReplaceCache(viewerID uuid.UUID, ranked []Scored, computedAt time.Time) error {
+	m.cache = ranked
+	m.computedAt = &computedAt
+	m.generation++
+	m.refreshes++
+	return nil
+}
+
+func (m *mockStore) CacheComputedAt(viewerID uuid.UUID) (*time.Time, error) {
+	return m.computedAt, nil
+}
+
+func (m *mockStore) ListCached(viewerID uuid.UUID, cursor *Cursor, limit int) (*Page, error) {
+	start := 0
+	if cursor != nil {
+		if cursor.Generation != m.generation {
+			return nil, ErrStaleCursor
+		}
+		for i, s := range m.cache {
+			if s.CandidateID == cursor.CandidateID {
+				start = i + 1
+			}
+		}
+	}
+	page := &Page{Users: make([]*models.User, 0)}
+	for _, s := range m.cache[start:] {
+		if len(page.Users) == limit {
+			break
+		}
+		if !m.listable(s.CandidateID) {
+			continue
+		}
+		page.Users = append(page.Users, &models.User{ID: s.CandidateID})
+		page.Next = &Cursor{Generation: m.generation, Score: s.Score, CandidateID: s.CandidateID}
+	}
+	return page, nil
+}
+
+func TestEngineRecommend(t *testing.T) {
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	newStore := func() *mockStore {
+		viewer := &Profile{ID: uuid.New(), Gender: models.GenderMale, Intention: models.IntentionLongTermPartner}
+		store := &mockStore{profile: viewer}
+		for i := 0; i < 5; i++ {
+			lastActive := now.Add(-time.Duration(i) * 24 * time.Hour)
+			store.candidates = append(store.candidates, &Candidate{Profile: Profile{
+				ID: uuid.New(), Gender: models.GenderFemale, Intention: models.IntentionLongTermPartner,
+				LastActiveAt: &lastActive,
+			}})
+		}
+		return store
+	}
+	newEngine := func(store *mockStore) *Engine {
+		engine := NewEngine(store, DefaultWeights(), 0, time.Hour, 3)
+		engine.now = func() time.Time { return now }
+		return engine
+	}
+
+	t.Run("computes the ranking on first use and pages through it", func(t *testing.T) {
+		store := newStore()
+		engine := newEngine(store)
+
+		page, err := engine.Recommend(store.profile.ID, nil, 2)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if store.refreshes != 1 {
+			t.Fatalf("expected one refresh, got %d", store.refreshes)
+		}
+		if store.poolLimit != 3*candidatePoolFactor {
+			t.Fatalf("expected a pool of %d candidates, got %d", 3*candidatePoolFactor, store.poolLimit)
+		}
+		if len(store.cache) != 3 {
+			t.Fatalf("expected the cache to keep 3 candidates, got %d", len(store.cache))
+		}
+		if len(page.Users) != 2 || page.Users[0].ID != store.candidates[0].ID {
+			t.Fatalf("expected the most recently active candidate first, got %+v", page.Users)
+		}
+
+		next, err := engine.Recommend(store.profile.ID, page.Next, 2)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if len(next.Users) != 1 || store.refreshes != 1 {
+			t.Fatalf("expected the rest of the cached ranking without a refresh, got %d users and %d refreshes", len(next.Users), store.refreshes)
+		}
+	})
+
+	t.Run("rejects a cursor once the ranking is recomputed", func(t *testing.T) {
+		store := newStore()
+		engine := newEngine(store)
+
+		page, err := engine.Recommend(store.profile.ID, nil, 2)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		// The background refresher replaces the ranking while the viewer is paging
+		if err := engine.Refresh(store.profile.ID); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+
+		if _, err := engine.Recommend(store.profile.ID, page.Next, 2); err != ErrStaleCursor {
+			t.Fatalf("expected ErrStaleCursor, got %v", err)
+		}
+	})
+
+	t.Run("reuses a fresh cache and recomputes a stale one", func(t *testing.T) {
+		store := newStore()
+		engine := newEngine(store)
+		fresh := now.Add(-30 * time.Minute)
+		store.computedAt = &fresh
+
+		if _, err := engine.Recommend(store.profile.ID, nil, 2); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if store.refreshes != 0 {
+			t.Fatalf("expected the fresh cache to be reused, got %d refreshes", store.refreshes)
+		}
+
+		stale := now.Add(-2 * time.Hour)
+		store.computedAt = &stale
+		if _, err := engine.Recommend(store.profile.ID, nil, 2); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if store.refreshes != 1 {
+			t.Fatalf("expected the stale cache to be recomputed, got %d refreshes", store.refreshes)
+		}
+	})
+
+	t.Run("hides candidates with incomplete profiles", func(t *testing.T) {
+		store := newStore()
+		complete := store.candidates[3]
+		complete.HasAvatar, complete.HasBio = true, true
+		complete.Interests = []string{"hiking"}
+		engine := newEngine(store)
+		engine.minCompleteness = 0.5
+
+		if _, err := engine.Recommend(store.profile.ID, nil, 2); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if len(store.cache) != 1 || store.cache[0].CandidateID != complete.ID {
+			t.Fatalf("expected only the candidate past the threshold, got %+v", store.cache)
+		}
+	})
+
+	t.Run("hidden, incognito and paused users never reach the feed", func(t *testing.T) {
+		store := newStore()
+		paused := now.Add(-time.Hour)
+		hidden, incognito, liker, pausing := store.candidates[0], store.candidates[1], store.candidates[2], store.candidates[3]
+		store.users = map[uuid.UUID]*models.User{
+			hidden.ID:    {ID: hidden.ID, Visibility: models.VisibilityHidden},
+			incognito.ID: {ID: incognito.ID, Visibility: models.VisibilityIncognito},
+			liker.ID:     {ID: liker.ID, Visibility: models.VisibilityIncognito},
+			pausing.ID:   {ID: pausing.ID, Visibility: models.VisibilityMembers},
+		}
+		store.likedViewer = map[uuid.UUID]bool{liker.ID: true}
+		engine := NewEngine(store, DefaultWeights(), 0, time.Hour, 10)
+		engine.now = func() time.Time { return now }
+
+		page, err := engine.Recommend(store.profile.ID, nil, 10)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if len(page.Users) != 3 || page.Users[0].ID != liker.ID || page.Users[1].ID != pausing.ID {
+			t.Fatalf("expected the incognito liker and both members, got %+v", page.Users)
+		}
+
+		// Going on a break after the ranking was cached still removes the user from the feed
+		store.users[pausing.ID].PausedAt = &paused
+		page, err = engine.Recommend(store.profile.ID, nil, 10)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if store.refreshes != 1 {
+			t.Fatalf("expected the cached ranking to be reused, got %d refreshes", store.refreshes)
+		}
+		for _, user := range page.Users {
+			if user.ID == hidden.ID || user.ID == incognito.ID || user.ID == pausing.ID {
+				t.Fatalf("expected hidden, incognito and paused users to be left out, got %+v", page.Users)
+			}
+		}
+		if len(page.Users) != 2 {
+			t.Fatalf("expected 2 users, got %d", len(page.Users))
+		}
+	})
+
+	t.Run("reports store failures", func(t *testing.T) {
+		store := newStore()
+		store.listErr = errors.New("db unavailable")
+		if _, err := newEngine(store).Recommend(store.profile.ID, nil, 2); err == nil {
+			t.Fatal("expected error")
+		}
+	})
+}
+
+func TestEngineSelectPicks(t *testing.T) {
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	viewer := &Profile{ID: uuid.New(), Gender: models.GenderMale, Intention: models.IntentionLongTermPartner}
+	store := &mockStore{profile: viewer}
+	for i := 0; i < 5; i++ {
+		lastActive := now.Add(-time.Duration(i) * 24 * time.Hour)
+		store.candidates = append(store.candidates, &Candidate{Profile: Profile{
+			ID: uuid.New(), Gender: models.GenderFemale, Intention: models.IntentionLongTermPartner,
+			LastActiveAt: &lastActive,
+		}})
+	}
+	casual := &Candidate{Profile: Profile{ID: uuid.New(), Gender: models.GenderFemale, Intention: models.IntentionShortTermFun}}
+	store.candidates = append(store.candidates, casual)
+	engine := NewEngine(store, DefaultWeights(), 0, time.Hour, 100)
+	engine.now = func() time.Time { return now }
+
+	picks, err := engine.SelectPicks(viewer.ID, 2)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(picks) != 2 || picks[0].CandidateID != store.candidates[0].ID || picks[1].CandidateID != store.candidates[1].ID {
+		t.Fatalf("expected the two best candidates, got %+v", picks)
+	}
+	if store.poolLimit != 2*candidatePoolFactor {
+		t.Fatalf("expected a pool of %d candidates, got %d", 2*candidatePoolFactor, store.poolLimit)
+	}
+	if store.refreshes != 0 {
+		t.Fatal("expected picks to leave the feed cache alone")
+	}
+
+	all, err := engine.SelectPicks(viewer.ID, 10)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	for _, p := range all {
+		if p.CandidateID == casual.ID {
+			t.Fatal("expected an incompatible intention never to be picked")
+		}
+	}
+}
@@ -0,0 +1,165 @@
+package discovery
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/Viet-CodingStars/kyupi-kyupi-backend/internal/models"
+	"github.com/google/uuid"
+)
+
+// Offline evaluation on a synthetic population. Each synthetic candidate has a hidden chance of
+// matching the viewer, driven by the same kinds of factors the ranker looks at but only partly
+// observable to it (for example, the candidate's true taste is seen only through a handful of
+// past swipes). A good ranking puts candidates with a high hidden chance near the top.
+
+var evalIntentions = []string{
+	models.IntentionLongTermPartner,
+	models.IntentionLongTermOpenToShort,
+	models.IntentionShortTermOpenToLong,
+	models.IntentionShortTermFun,
+	models.IntentionNewFriends,
+	models.IntentionStillFiguringOut,
+}
+
+type syntheticCandidate struct {
+	*Candidate
+	matchChance float64
+}
+
+func syntheticPopulation(seed int64, size int, viewer *Profile, now time.Time) []syntheticCandidate {
+	rng := rand.New(rand.NewSource(seed))
+	population := make([]syntheticCandidate, 0, size)
+	for i := 0; i < size; i++ {
+		var id uuid.UUID
+		rng.Read(id[:])
+
+		p := Profile{
+			ID:        id,
+			Gender:    models.GenderFemale,
+			Intention: evalIntentions[rng.Intn(len(evalIntentions))],
+			BirthDate: now.AddDate(-(20 + rng.Intn(25)), -rng.Intn(12), 0),
+			HasBio:    rng.Float64() < 0.6,
+			HasAvatar: rng.Float64() < 0.8,
+		}
+		if rng.Float64() < 0.7 {
+			p.TargetGender = intPtr(models.GenderMale)
+		}
+		if rng.Float64() < 0.8 {
+			p.Latitude = floatPtr(*viewer.Latitude + rng.NormFloat64()*0.5)
+			p.Longitude = floatPtr(*viewer.Longitude + rng.NormFloat64()*0.5)
+		}
+		if rng.Float64() < 0.9 {
+			lastActive := now.Add(-time.Duration(rng.ExpFloat64() * float64(4*24*time.Hour)))
+			p.LastActiveAt = &lastActive
+		}
+
+		// The candidate's true taste for people like the viewer, seen only through past swipes
+		taste := rng.Float64()
+		c := &Candidate{Profile: p}
+		c.SimilarSwipes = rng.Intn(20)
+		for s := 0; s < c.SimilarSwipes; s++ {
+			if rng.Float64() < taste {
+				c.SimilarLikes++
+			}
+		}
+		c.Swipes = c.SimilarSwipes + rng.Intn(60)
+		c.Likes = c.SimilarLikes + int(float64(c.Swipes-c.SimilarSwipes)*rng.Float64())
+
+		active := 0.0
+		if p.LastActiveAt != nil {
+			active = math.Exp(-now.Sub(*p.LastActiveAt).Hours() / (24 * 5))
+		}
+		near := 0.6
+		if p.Latitude != nil {
+			near = 1 / (1 + DistanceKm(*viewer.Latitude, *viewer.Longitude, *p.Latitude, *p.Longitude)/40)
+		}
+		ageGap := math.Abs(ageYears(viewer.BirthDate, now) - ageYears(p.BirthDate, now))
+		chance := taste * active * IntentionSimilarity(viewer.Intention, p.Intention) *
+			(0.4 + 0.6*near) * math.Max(0.1, 1-ageGap/20)
+		population = append(population, syntheticCandidate{Candidate: c, matchChance: chance})
+	}
+	return population
+}
+
+// expectedMatches sums the hidden match chances of the first k candidates in order.
+func expectedMatches(order []uuid.UUID, chances map[uuid.UUID]float64, k int) float64 {
+	total := 0.0
+	for i := 0; i < k && i < len(order); i++ {
+		total += chances[order[i]]
+	}
+	return total
+}
+
+func evaluate(t *testing.T, seed int64, w Weights, k int) (ranked, baseline, ideal float64) {
+	t.Helper()
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	viewer := &Profile{
+		ID: uuid.New(), Gender: models.GenderMale, TargetGender: intPtr(models.GenderFemale),
+		Intention: models.IntentionLongTermPartner, BirthDate: now.AddDate(-31, 0, 0),
+		Latitude: floatPtr(10.7769), Longitude: floatPtr(106.7009),
+	}
+	population := syntheticPopulation(seed, 2000, viewer, now)
+
+	candidates := make([]*Candidate, len(population))
+	chances := make(map[uuid.UUID]float64, len(population))
+	var compatible []uuid.UUID
+	for i, sc := range population {
+		candidates[i] = sc.Candidate
+		chances[sc.ID] = sc.matchChance
+		if Compatible(viewer, sc.Candidate, now) {
+			compatible = append(compatible, sc.ID)
+		}
+	}
+
+	order := make([]uuid.UUID, 0, k)
+	for _, s := range Rank(viewer, candidates, w, now, k) {
+		order = append(order, s.CandidateID)
+	}
+
+	// The previous feed ordered by UUID, which is effectively random
+	byID := append([]uuid.UUID(nil), compatible...)
+	sort.Slice(byID, func(i, j int) bool { return byID[i].String() < byID[j].String() })
+
+	best := append([]uuid.UUID(nil), compatible...)
+	sort.Slice(best, func(i, j int) bool { return chances[best[i]] > chances[best[j]] })
+
+	return expectedMatches(order, chances, k), expectedMatches(byID, chances, k), expectedMatches(best, chances, k)
+}
+
+func TestOfflineEvaluation(t *testing.T) {
+	const k = 50
+
+	t.Run("ranking beats the UUID order", func(t *testing.T) {
+		for _, seed := range []int64{1, 2, 3} {
+			ranked, baseline, ideal := evaluate(t, seed, DefaultWeights(), k)
+			t.Logf("seed %d: expected matches in top %d: ranked %.2f, by id %.2f, ideal %.2f", seed, k, ranked, baseline, ideal)
+			if ranked < 3*baseline {
+				t.Errorf("seed %d: expected the ranking to at least triple expected matches (ranked %.2f, by id %.2f)", seed, ranked, baseline)
+			}
+			if ranked > ideal {
+				t.Errorf("seed %d: ranked %.2f exceeds the ideal %.2f", seed, ranked, ideal)
+			}
+		}
+	})
+
+	t.Run("each behavioural signal helps", func(t *testing.T) {
+		full, _, _ := evaluate(t, 1, DefaultWeights(), k)
+		for name, ablate := range map[string]func(*Weights){
+			SignalRecency:    func(w *Weights) { w.Recency = 0 },
+			SignalReciprocal: func(w *Weights) { w.Reciprocal = 0 },
+			SignalIntention:  func(w *Weights) { w.Intention = 0 },
+		} {
+			w := DefaultWeights()
+			ablate(&w)
+			without, _, _ := evaluate(t, 1, w, k)
+			t.Logf("without %s: %.2f (full %.2f)", name, without, full)
+			if without >= full {
+				t.Errorf("expected dropping %s to lower expected matches (without %.2f, full %.2f)", name, without, full)
+			}
+		}
+	})
+}
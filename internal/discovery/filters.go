@@ -0,0 +1,133 @@
+package discovery
+
+import (
+	"time"
+
+	"github.com/Viet-CodingStars/kyupi-kyupi-backend/internal/models"
+)
+
+var filters = []string{
+	models.FilterAge, models.FilterDistance, models.FilterHeight, models.FilterEducation, models.FilterLanguages,
+	models.FilterReligion, models.FilterDrinking, models.FilterSmoking, models.FilterChildren,
+}
+
+// filterMet rates how well other meets one of chooser's filters: 1 or 0, or unknownScore when
+// other has not filled in the attribute. ok is false when the filter is not set.
+func filterMet(filter string, chooser, other *Profile, now time.Time) (fit float64, ok bool) {
+	prefs := chooser.Preferences
+	if prefs == nil || !prefs.HasFilter(filter) {
+		return 0, false
+	}
+	boolScore := func(met bool) float64 {
+		if met {
+			return 1
+		}
+		return 0
+	}
+	oneOf := func(value string, allowed []string) float64 {
+		if value == "" {
+			return unknownScore
+		}
+		return boolScore(contains(allowed, value))
+	}
+
+	switch filter {
+	case models.FilterAge:
+		if other.BirthDate.IsZero() {
+			return unknownScore, true
+		}
+		age := wholeYears(other.BirthDate, now)
+		return boolScore((prefs.AgeMin == nil || age >= *prefs.AgeMin) && (prefs.AgeMax == nil || age <= *prefs.AgeMax)), true
+	case models.FilterDistance:
+		if chooser.Latitude == nil || chooser.Longitude == nil || other.Latitude == nil || other.Longitude == nil {
+			return unknownScore, true
+		}
+		km := DistanceKm(*chooser.Latitude, *chooser.Longitude, *other.Latitude, *other.Longitude)
+		return boolScore(km <= float64(*prefs.MaxDistanceKm)), true
+	case models.FilterHeight:
+		if other.HeightCm == nil {
+			return unknownScore, true
+		}
+		h := *other.HeightCm
+		return boolScore((prefs.HeightMinCm == nil || h >= *prefs.HeightMinCm) && (prefs.HeightMaxCm == nil || h <= *prefs.HeightMaxCm)), true
+	case models.FilterEducation:
+		return oneOf(other.Education, prefs.Educations), true
+	case models.FilterLanguages:
+		if len(other.Languages) == 0 {
+			return unknownScore, true
+		}
+		for _, l := range other.Languages {
+			if contains(prefs.Languages, l) {
+				return 1, true
+			}
+		}
+		return 0, true
+	case models.FilterReligion:
+		return oneOf(other.Religion, prefs.Religions), true
+	case models.FilterDrinking:
+		return oneOf(other.Drinking, prefs.Drinking), true
+	case models.FilterSmoking:
+		return oneOf(other.Smoking, prefs.Smoking), true
+	case models.FilterChildren:
+		return oneOf(other.Children, prefs.Children), true
+	}
+	return 0, false
+}
+
+// MeetsDealbreakers reports whether other meets every filter chooser marked as a dealbreaker,
+// and is verified if chooser only wants verified users. A filter on an attribute other has not
+// filled in is not applied.
+func MeetsDealbreakers(chooser, other *Profile, now time.Time) bool {
+	if chooser.Preferences == nil {
+		return true
+	}
+	if chooser.Preferences.VerifiedOnly && !other.Verified {
+		return false
+	}
+	for _, filter := range chooser.Preferences.Dealbreakers {
+		if fit, ok := filterMet(filter, chooser, other, now); ok && fit == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// FilterFit is the share of both sides' soft filters, those that are not dealbreakers, that the
+// other side meets. It is neutral when neither side has any.
+func FilterFit(viewer, candidate *Profile, now time.Time) float64 {
+	total, count := 0.0, 0
+	for _, side := range [][2]*Profile{{viewer, candidate}, {candidate, viewer}} {
+		chooser, other := side[0], side[1]
+		for _, filter := range filters {
+			if chooser.Preferences != nil && chooser.Preferences.IsDealbreaker(filter) {
+				continue
+			}
+			if fit, ok := filterMet(filter, chooser, other, now); ok {
+				total += fit
+				count++
+			}
+		}
+	}
+	if count == 0 {
+		return unknownScore
+	}
+	return total / float64(count)
+}
+
+// wholeYears is a person's age in completed years.
+func wholeYears(birthDate, now time.Time) int {
+	years := now.Year() - birthDate.Year()
+	if now.Month() < birthDate.Month() || (now.Month() == birthDate.Month() && now.Day() < birthDate.Day()) {
+		years--
+	}
+	return years
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
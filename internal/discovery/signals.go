@@ -0,0 +1,146 @@
+package discovery
+
+import (
+	"math"
+	"time"
+
+	"github.com/Viet-CodingStars/kyupi-kyupi-backend/internal/models"
+)
+
+const (
+	// ageSpanYears is the age gap at which the age part of PreferenceFit reaches zero.
+	ageSpanYears = 15.0
+	// recencyHalfLife is how long after a user's last activity their recency score halves.
+	recencyHalfLife = 3 * 24 * time.Hour
+	// distanceScaleKm is the distance at which Proximity drops to one half.
+	distanceScaleKm = 25.0
+	// reciprocalPriorWeight is how many swipes the candidate's overall like rate counts for
+	// when estimating how they treat people like the viewer.
+	reciprocalPriorWeight = 5.0
+	// unknownScore is used when a signal cannot be computed, so it neither helps nor hurts.
+	unknownScore = 0.5
+)
+
+// intentionScale orders dating intentions from long-term to short-term.
+var intentionScale = map[string]float64{
+	models.IntentionLongTermPartner:     0,
+	models.IntentionLongTermOpenToShort: 1,
+	models.IntentionShortTermOpenToLong: 2,
+	models.IntentionShortTermFun:        3,
+}
+
+func wantsGender(target *int, gender int) bool {
+	return target == nil || *target == gender
+}
+
+// PreferenceFit rates how well two people fit each other's stated preferences: an explicit
+// gender preference on both sides fits better than an open one, and closer ages fit better.
+func PreferenceFit(viewer, candidate *Profile, now time.Time) float64 {
+	genderFit := func(target *int, gender int) float64 {
+		switch {
+		case target == nil:
+			return 0.75
+		case *target == gender:
+			return 1
+		default:
+			return 0
+		}
+	}
+	gender := (genderFit(viewer.TargetGender, candidate.Gender) + genderFit(candidate.TargetGender, viewer.Gender)) / 2
+
+	age := unknownScore
+	if !viewer.BirthDate.IsZero() && !candidate.BirthDate.IsZero() {
+		gap := math.Abs(ageYears(viewer.BirthDate, now) - ageYears(candidate.BirthDate, now))
+		age = math.Max(0, 1-gap/ageSpanYears)
+	}
+	return (gender + age) / 2
+}
+
+func ageYears(birthDate, now time.Time) float64 {
+	return now.Sub(birthDate).Hours() / (24 * 365.25)
+}
+
+// IntentionSimilarity rates how close two dating intentions are. Dating intentions at opposite
+// ends (a long-term partner and short-term fun) and friendship versus dating score zero.
+// "Still figuring it out" is neutral towards everything.
+func IntentionSimilarity(a, b string) float64 {
+	if a == b {
+		return 1
+	}
+	if a == models.IntentionStillFiguringOut || b == models.IntentionStillFiguringOut || a == "" || b == "" {
+		return unknownScore
+	}
+	x, okA := intentionScale[a]
+	y, okB := intentionScale[b]
+	if !okA || !okB {
+		// new_friends only matches itself
+		return 0
+	}
+	return 1 - math.Abs(x-y)/3
+}
+
+// Recency decays with the time since the user was last active, halving every three days.
+// Users with no recorded activity score zero.
+func Recency(lastActiveAt *time.Time, now time.Time) float64 {
+	if lastActiveAt == nil {
+		return 0
+	}
+	idle := now.Sub(*lastActiveAt)
+	if idle <= 0 {
+		return 1
+	}
+	return math.Pow(0.5, float64(idle)/float64(recencyHalfLife))
+}
+
+// Completeness is the share of onboarding steps the user has completed.
+func Completeness(p *Profile) float64 {
+	return models.ProfileChecklist{
+		Avatar:    p.HasAvatar,
+		Bio:       p.HasBio,
+		Location:  p.Latitude != nil && p.Longitude != nil,
+		Interests: len(p.Interests) > 0,
+		Prompts:   p.HasPrompts,
+	}.Completeness()
+}
+
+// ReciprocalInterest estimates how likely the candidate is to like the viewer, from how often
+// they liked people like the viewer. Sparse histories lean on the candidate's overall like
+// rate, which itself starts at one half.
+func ReciprocalInterest(c *Candidate) float64 {
+	prior := (float64(c.Likes) + 1) / (float64(c.Swipes) + 2)
+	return (float64(c.SimilarLikes) + reciprocalPriorWeight*prior) / (float64(c.SimilarSwipes) + reciprocalPriorWeight)
+}
+
+// Proximity falls off with the distance between the two users. Users without a location
+// are treated as neither near nor far.
+func Proximity(viewer, candidate *Profile) float64 {
+	if viewer.Latitude == nil || viewer.Longitude == nil || candidate.Latitude == nil || candidate.Longitude == nil {
+		return unknownScore
+	}
+	km := DistanceKm(*viewer.Latitude, *viewer.Longitude, *candidate.Latitude, *candidate.Longitude)
+	return 1 / (1 + km/distanceScaleKm)
+}
+
+// SharedInterests rises with every interest the two users have in common: one half with none,
+// three quarters with one, and so on towards 1. Having no interests in common is treated like
+// having none filled in, so picking interests never hurts a user's ranking.
+func SharedInterests(viewer, candidate *Profile) float64 {
+	shared := 0
+	for _, interest := range candidate.Interests {
+		if contains(viewer.Interests, interest) {
+			shared++
+		}
+	}
+	return 1 - unknownScore*math.Pow(0.5, float64(shared))
+}
+
+// DistanceKm returns the great-circle distance between two coordinates.
+func DistanceKm(lat1, lon1, lat2, lon2 float64) float64 {
+	const earthRadiusKm = 6371.0
+	toRad := func(deg float64) float64 { return deg * math.Pi / 180 }
+	dLat := toRad(lat2 - lat1)
+	dLon := toRad(lon2 - lon1)
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(toRad(lat1))*math.Cos(toRad(lat2))*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * earthRadiusKm * math.Asin(math.Min(1, math.Sqrt(h)))
+}
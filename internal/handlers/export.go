@@ -0,0 +1,201 @@
+package handlers
+
+import (
+	"database/sql"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/Viet-CodingStars/kyupi-kyupi-backend/internal/middleware"
+	"github.com/Viet-CodingStars/kyupi-kyupi-backend/internal/models"
+	"github.com/Viet-CodingStars/kyupi-kyupi-backend/internal/oidc"
+	"github.com/Viet-CodingStars/kyupi-kyupi-backend/internal/repo"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// exportDownloadTTL bounds how long a download link works once handed out.
+const exportDownloadTTL = 15 * time.Minute
+
+// ExportRepository declares the minimal persistence operations required by ExportHandler.
+type ExportRepository interface {
+	Create(export *models.DataExport) error
+	GetByID(userID, id uuid.UUID) (*models.DataExport, error)
+	ListByUser(userID uuid.UUID) ([]*models.DataExport, error)
+	CreateDownloadLink(exportID uuid.UUID, token string, expiresAt time.Time) error
+	ConsumeDownloadLink(token string) (*models.DataExport, error)
+}
+
+type ExportHandler struct {
+	exportRepo ExportRepository
+}
+
+func NewExportHandler(db *sql.DB) *ExportHandler {
+	return &ExportHandler{exportRepo: repo.NewExportRepo(db)}
+}
+
+// DataExportResponse is an export's status plus, once it is ready, a short-lived download link.
+type DataExportResponse struct {
+	*models.DataExport
+	DownloadURL          string     `json:"download_url,omitempty"`
+	DownloadURLExpiresAt *time.Time `json:"download_url_expires_at,omitempty"`
+}
+
+// GetDataExportsResponse lists the caller's recent exports.
+type GetDataExportsResponse struct {
+	Exports []*models.DataExport `json:"exports"`
+}
+
+// RequestExport queues a copy of the user's personal data (POST /api/users/exports).
+// @Summary Request a personal data export
+// @Description Queues a ZIP archive with the profile, likes and passes given, matches, messages and photos. Poll the export for its status; a notification is sent when it is ready.
+// @Tags Users
+// @Produce json
+// @Security BearerAuth
+// @Success 202 {object} DataExportResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/users/exports [post]
+func (h *ExportHandler) RequestExport(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c.Request.Context())
+	if !ok {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "user not authenticated"})
+		return
+	}
+
+	export := &models.DataExport{UserID: userID}
+	if err := h.exportRepo.Create(export); err != nil {
+		if err == repo.ErrExportInProgress {
+			c.JSON(http.StatusConflict, ErrorResponse{Error: "an export is already in progress"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to request export"})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, DataExportResponse{DataExport: export})
+}
+
+// GetExports lists the user's recent exports (GET /api/users/exports).
+// @Summary List personal data exports
+// @Tags Users
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} GetDataExportsResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/users/exports [get]
+func (h *ExportHandler) GetExports(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c.Request.Context())
+	if !ok {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "user not authenticated"})
+		return
+	}
+
+	exports, err := h.exportRepo.ListByUser(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to fetch exports"})
+		return
+	}
+
+	c.JSON(http.StatusOK, GetDataExportsResponse{Exports: exports})
+}
+
+// GetExport returns an export's status (GET /api/users/exports/:export_id).
+// @Summary Get a personal data export
+// @Description Returns the export status. Ready exports include a one-time download link valid for 15 minutes; poll again for a fresh one.
+// @Tags Users
+// @Produce json
+// @Security BearerAuth
+// @Param export_id path string true "Export ID"
+// @Success 200 {object} DataExportResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/users/exports/{export_id} [get]
+func (h *ExportHandler) GetExport(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c.Request.Context())
+	if !ok {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "user not authenticated"})
+		return
+	}
+
+	exportID, err := uuid.Parse(c.Param("export_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "invalid export_id"})
+		return
+	}
+
+	export, err := h.exportRepo.GetByID(userID, exportID)
+	if err != nil {
+		if err == repo.ErrExportNotFound {
+			c.JSON(http.StatusNotFound, ErrorResponse{Error: "export not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to fetch export"})
+		return
+	}
+
+	resp := DataExportResponse{DataExport: export}
+	now := time.Now()
+	if downloadable(export, now) {
+		expiresAt := now.Add(exportDownloadTTL)
+		if export.ExpiresAt.Before(expiresAt) {
+			expiresAt = *export.ExpiresAt
+		}
+		token, err := oidc.RandomToken()
+		if err == nil {
+			err = h.exportRepo.CreateDownloadLink(export.ID, token, expiresAt)
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to generate download link"})
+			return
+		}
+		resp.DownloadURL = "/api/exports/download/" + token
+		resp.DownloadURLExpiresAt = &expiresAt
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// DownloadExport streams an export archive (GET /api/exports/download/:token).
+// @Summary Download a personal data export
+// @Description Authorized by the one-time link returned from GET /api/users/exports/{export_id}. The link stops working once it has been opened, so the token is useless to anyone who later reads it from a log.
+// @Tags Users
+// @Produce application/zip
+// @Param token path string true "Download token"
+// @Success 200 {file} file
+// @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/exports/download/{token} [get]
+func (h *ExportHandler) DownloadExport(c *gin.Context) {
+	export, err := h.exportRepo.ConsumeDownloadLink(c.Param("token"))
+	if err != nil {
+		if err == repo.ErrDownloadLinkUsed {
+			c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "invalid or expired download link"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to fetch export"})
+		return
+	}
+	if !downloadable(export, time.Now()) {
+		c.JSON(http.StatusNotFound, ErrorResponse{Error: "export is not available"})
+		return
+	}
+	if _, err := os.Stat(export.FilePath); err != nil {
+		c.JSON(http.StatusNotFound, ErrorResponse{Error: "export is not available"})
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.FileAttachment(export.FilePath, "kyupi-kyupi-data-"+export.CreatedAt.Format("2006-01-02")+".zip")
+}
+
+func downloadable(export *models.DataExport, now time.Time) bool {
+	return export.Status == models.ExportStatusReady &&
+		export.FilePath != "" &&
+		export.ExpiresAt != nil && export.ExpiresAt.After(now)
+}
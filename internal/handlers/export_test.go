@@ -0,0 +1,184 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Viet-CodingStars/kyupi-kyupi-backend/internal/middleware"
+	"github.com/Viet-CodingStars/kyupi-kyupi-backend/internal/models"
+	"github.com/Viet-CodingStars/kyupi-kyupi-backend/internal/repo"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// mockExportRepo implements a mock export repository for testing
+type mockExportRepo struct {
+	exports map[uuid.UUID]*models.DataExport
+	links   map[string]uuid.UUID
+}
+
+func newMockExportRepo() *mockExportRepo {
+	return &mockExportRepo{exports: make(map[uuid.UUID]*models.DataExport), links: make(map[string]uuid.UUID)}
+}
+
+func (m *mockExportRepo) Create(export *models.DataExport) error {
+	for _, existing := range m.exports {
+		if existing.UserID == export.UserID &&
+			(existing.Status == models.ExportStatusPending || existing.Status == models.ExportStatusProcessing) {
+			return repo.ErrExportInProgress
+		}
+	}
+	export.ID = uuid.New()
+	export.Status = models.ExportStatusPending
+	export.CreatedAt = time.Now()
+	m.exports[export.ID] = export
+	return nil
+}
+
+func (m *mockExportRepo) GetByID(userID, id uuid.UUID) (*models.DataExport, error) {
+	export, exists := m.exports[id]
+	if !exists || export.UserID != userID {
+		return nil, repo.ErrExportNotFound
+	}
+	return export, nil
+}
+
+func (m *mockExportRepo) ListByUser(userID uuid.UUID) ([]*models.DataExport, error) {
+	exports := make([]*models.DataExport, 0)
+	for _, export := range m.exports {
+		if export.UserID == userID {
+			exports = append(exports, export)
+		}
+	}
+	return exports, nil
+}
+
+func (m *mockExportRepo) CreateDownloadLink(exportID uuid.UUID, token string, expiresAt time.Time) error {
+	m.links[token] = exportID
+	return nil
+}
+
+func (m *mockExportRepo) ConsumeDownloadLink(token string) (*models.DataExport, error) {
+	exportID, exists := m.links[token]
+	if !exists {
+		return nil, repo.ErrDownloadLinkUsed
+	}
+	delete(m.links, token)
+	return m.exports[exportID], nil
+}
+
+// exportTestRouter wires the export endpoints, authenticating as the user in X-Test-User.
+func exportTestRouter(handler *ExportHandler) *gin.Engine {
+	router := gin.New()
+	users := router.Group("/api/users")
+	users.Use(func(c *gin.Context) {
+		if userID, err := uuid.Parse(c.GetHeader("X-Test-User")); err == nil {
+			c.Request = c.Request.WithContext(context.WithValue(c.Request.Context(), middleware.UserIDKey, userID))
+		}
+	})
+	users.POST("/exports", handler.RequestExport)
+	users.GET("/exports", handler.GetExports)
+	users.GET("/exports/:export_id", handler.GetExport)
+	router.GET("/api/exports/download/:token", handler.DownloadExport)
+	return router
+}
+
+func doAsUser(router *gin.Engine, method, path string, userID uuid.UUID) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, nil)
+	if userID != uuid.Nil {
+		req.Header.Set("X-Test-User", userID.String())
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestDataExports(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	t.Run("request queues one export at a time", func(t *testing.T) {
+		router := exportTestRouter(&ExportHandler{exportRepo: newMockExportRepo()})
+		userID := uuid.New()
+
+		w := doAsUser(router, http.MethodPost, "/api/users/exports", userID)
+		if w.Code != http.StatusAccepted {
+			t.Fatalf("expected status 202, got %d: %s", w.Code, w.Body.String())
+		}
+		var resp DataExportResponse
+		json.Unmarshal(w.Body.Bytes(), &resp)
+		if resp.Status != models.ExportStatusPending || resp.DownloadURL != "" {
+			t.Fatalf("expected pending export without link, got %+v", resp)
+		}
+
+		if w := doAsUser(router, http.MethodPost, "/api/users/exports", userID); w.Code != http.StatusConflict {
+			t.Fatalf("expected status 409, got %d", w.Code)
+		}
+		if w := doAsUser(router, http.MethodPost, "/api/users/exports", uuid.Nil); w.Code != http.StatusUnauthorized {
+			t.Fatalf("expected status 401, got %d", w.Code)
+		}
+	})
+
+	t.Run("ready export can be downloaded with its link", func(t *testing.T) {
+		exportRepo := newMockExportRepo()
+		router := exportTestRouter(&ExportHandler{exportRepo: exportRepo})
+		userID := uuid.New()
+
+		filePath := filepath.Join(t.TempDir(), "export.zip")
+		if err := os.WriteFile(filePath, []byte("zip-content"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		expiresAt := time.Now().Add(time.Hour)
+		export := &models.DataExport{UserID: userID}
+		exportRepo.Create(export)
+		export.Status = models.ExportStatusReady
+		export.FilePath = filePath
+		export.ExpiresAt = &expiresAt
+
+		if w := doAsUser(router, http.MethodGet, "/api/users/exports/"+export.ID.String(), uuid.New()); w.Code != http.StatusNotFound {
+			t.Fatalf("expected status 404 for another user, got %d", w.Code)
+		}
+
+		w := doAsUser(router, http.MethodGet, "/api/users/exports/"+export.ID.String(), userID)
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+		}
+		var resp DataExportResponse
+		json.Unmarshal(w.Body.Bytes(), &resp)
+		if resp.DownloadURL == "" || resp.DownloadURLExpiresAt == nil {
+			t.Fatalf("expected download link, got %+v", resp)
+		}
+
+		w = doAsUser(router, http.MethodGet, resp.DownloadURL, uuid.Nil)
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+		}
+		if w.Body.String() != "zip-content" {
+			t.Fatalf("unexpected archive body %q", w.Body.String())
+		}
+
+		if w := doAsUser(router, http.MethodGet, resp.DownloadURL, uuid.Nil); w.Code != http.StatusUnauthorized {
+			t.Fatalf("expected status 401 when the link is reused, got %d", w.Code)
+		}
+		if w := doAsUser(router, http.MethodGet, "/api/exports/download/not-a-token", uuid.Nil); w.Code != http.StatusUnauthorized {
+			t.Fatalf("expected status 401 for a bad token, got %d", w.Code)
+		}
+		if strings.Contains(resp.DownloadURL, "?") {
+			t.Fatalf("expected the token outside the query string, got %s", resp.DownloadURL)
+		}
+
+		// A fresh link stops working once the export expires
+		w = doAsUser(router, http.MethodGet, "/api/users/exports/"+export.ID.String(), userID)
+		json.Unmarshal(w.Body.Bytes(), &resp)
+		export.Status = models.ExportStatusExpired
+		if w := doAsUser(router, http.MethodGet, resp.DownloadURL, uuid.Nil); w.Code != http.StatusNotFound {
+			t.Fatalf("expected status 404 once expired, got %d", w.Code)
+		}
+	})
+}
@@ -0,0 +1,323 @@
+package handlers
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/Viet-CodingStars/kyupi-kyupi-backend/internal/middleware"
+	"github.com/Viet-CodingStars/kyupi-kyupi-backend/internal/models"
+	"github.com/Viet-CodingStars/kyupi-kyupi-backend/internal/repo"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// ProfileInterestRepository declares the interest lookup required by UserHandler.
+type ProfileInterestRepository interface {
+	ListForProfile(viewerID, userID uuid.UUID) ([]*models.ProfileInterest, error)
+}
+
+// InterestRepository declares the minimal persistence operations required by InterestHandler.
+type InterestRepository interface {
+	List(includeInactive bool) ([]*models.Interest, error)
+	Create(interest *models.Interest) error
+	Update(interest *models.Interest) error
+	Delete(slug string) error
+	ListForUser(userID uuid.UUID) ([]*models.Interest, error)
+	SetForUser(userID uuid.UUID, slugs []string) error
+}
+
+type InterestHandler struct {
+	interestRepo InterestRepository
+	// maxInterests is how many interests each user may pick
+	maxInterests int
+}
+
+func NewInterestHandler(db *sql.DB, maxInterests int) *InterestHandler {
+	return &InterestHandler{interestRepo: repo.NewInterestRepo(db), maxInterests: maxInterests}
+}
+
+// InterestItem is an interest labelled in the requested language.
+type InterestItem struct {
+	Slug     string `json:"slug"`
+	Category string `json:"category"`
+	Label    string `json:"label"`
+}
+
+// InterestsResponse lists interests labelled in the requested language.
+type InterestsResponse struct {
+	Interests []InterestItem `json:"interests"`
+}
+
+// UpdateInterestsRequest replaces the caller's interests.
+type UpdateInterestsRequest struct {
+	Interests []string `json:"interests"` // catalog slugs
+}
+
+// InterestRequest adds or changes a catalog interest. Slug is only read when creating.
+type InterestRequest struct {
+	Slug     string            `json:"slug,omitempty"`
+	Category string            `json:"category"`
+	Labels   map[string]string `json:"labels"`
+	Active   *bool             `json:"active,omitempty"` // defaults to true
+}
+
+// requestLanguage picks the language to label interests in: the lang query parameter, else the
+// first supported language in Accept-Language, else models.DefaultLanguage.
+func requestLanguage(c *gin.Context) string {
+	candidates := []string{c.Query("lang")}
+	for _, tag := range strings.Split(c.GetHeader("Accept-Language"), ",") {
+		tag = strings.TrimSpace(strings.SplitN(tag, ";", 2)[0])
+		candidates = append(candidates, strings.SplitN(tag, "-", 2)[0])
+	}
+	for _, lang := range candidates {
+		if lang = strings.ToLower(lang); models.IsValidLanguage(lang) {
+			return lang
+		}
+	}
+	return models.DefaultLanguage
+}
+
+func interestItems(interests []*models.Interest, lang string) []InterestItem {
+	items := make([]InterestItem, 0, len(interests))
+	for _, i := range interests {
+		items = append(items, InterestItem{Slug: i.Slug, Category: i.Category, Label: i.Label(lang)})
+	}
+	return items
+}
+
+// GetCatalog lists the interests users may pick (GET /api/interests).
+// @Summary List interests
+// @Description Lists the active interests catalog ordered by category, labelled in the language from the lang parameter or Accept-Language header, falling back to English.
+// @Tags Interests
+// @Produce json
+// @Param lang query string false "ISO 639-1 language code, e.g. vi"
+// @Success 200 {object} InterestsResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/interests [get]
+func (h *InterestHandler) GetCatalog(c *gin.Context) {
+	interests, err := h.interestRepo.List(false)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to retrieve interests"})
+		return
+	}
+	c.JSON(http.StatusOK, InterestsResponse{Interests: interestItems(interests, requestLanguage(c))})
+}
+
+// GetInterests lists the caller's interests (GET /api/users/interests).
+// @Summary Get my interests
+// @Description Lists the interests the caller picked, labelled in the language from the lang parameter or Accept-Language header. Retired interests are left out.
+// @Tags Users
+// @Produce json
+// @Security BearerAuth
+// @Param lang query string false "ISO 639-1 language code, e.g. vi"
+// @Success 200 {object} InterestsResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/users/interests [get]
+func (h *InterestHandler) GetInterests(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c.Request.Context())
+	if !ok {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "user not authenticated"})
+		return
+	}
+
+	interests, err := h.interestRepo.ListForUser(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to retrieve interests"})
+		return
+	}
+	c.JSON(http.StatusOK, InterestsResponse{Interests: interestItems(interests, requestLanguage(c))})
+}
+
+// UpdateInterests replaces the caller's interests (PUT /api/users/interests).
+// @Summary Update my interests
+// @Description Replaces the caller's interests with up to the configured maximum of active catalog slugs. An empty list clears them. Interests in common are shown on profiles and rank people higher in discovery.
+// @Tags Users
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param payload body UpdateInterestsRequest true "Interest slugs"
+// @Param lang query string false "ISO 639-1 language code, e.g. vi"
+// @Success 200 {object} InterestsResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/users/interests [put]
+func (h *InterestHandler) UpdateInterests(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c.Request.Context())
+	if !ok {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "user not authenticated"})
+		return
+	}
+
+	var req UpdateInterestsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "invalid request body"})
+		return
+	}
+	slugs := make([]string, 0, len(req.Interests))
+	seen := make(map[string]bool, len(req.Interests))
+	for _, slug := range req.Interests {
+		if !seen[slug] {
+			seen[slug] = true
+			slugs = append(slugs, slug)
+		}
+	}
+	if len(slugs) > h.maxInterests {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: fmt.Sprintf("at most %d interests may be picked", h.maxInterests)})
+		return
+	}
+
+	if err := h.interestRepo.SetForUser(userID, slugs); err != nil {
+		if err == repo.ErrUnknownInterest {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: "unknown interest"})
+			return
+		}
+		if err == repo.ErrUserNotFound {
+			c.JSON(http.StatusNotFound, ErrorResponse{Error: "user not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to update interests"})
+		return
+	}
+
+	interests, err := h.interestRepo.ListForUser(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to retrieve interests"})
+		return
+	}
+	c.JSON(http.StatusOK, InterestsResponse{Interests: interestItems(interests, requestLanguage(c))})
+}
+
+// ListCatalog lists every catalog interest with all of its labels (GET /api/admin/interests).
+// @Summary List the interests catalog
+// @Description Lists every interest, including retired ones, with its labels in every language. Admins only.
+// @Tags Admin
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} models.Interest
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/admin/interests [get]
+func (h *InterestHandler) ListCatalog(c *gin.Context) {
+	interests, err := h.interestRepo.List(true)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to retrieve interests"})
+		return
+	}
+	c.JSON(http.StatusOK, interests)
+}
+
+func (req *InterestRequest) interest(slug string) (*models.Interest, error) {
+	interest := &models.Interest{Slug: slug, Category: req.Category, Labels: req.Labels, Active: true}
+	if req.Active != nil {
+		interest.Active = *req.Active
+	}
+	return interest, interest.Validate()
+}
+
+// CreateInterest adds an interest to the catalog (POST /api/admin/interests).
+// @Summary Create an interest
+// @Description Adds an interest to the catalog. The slug is permanent; labels are keyed by ISO 639-1 language code and must include English. Admins only.
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param payload body InterestRequest true "Interest"
+// @Success 201 {object} models.Interest
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/admin/interests [post]
+func (h *InterestHandler) CreateInterest(c *gin.Context) {
+	var req InterestRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "invalid request body"})
+		return
+	}
+	interest, err := req.interest(req.Slug)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	if err := h.interestRepo.Create(interest); err != nil {
+		if err == repo.ErrInterestAlreadyExists {
+			c.JSON(http.StatusConflict, ErrorResponse{Error: "interest already exists"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to create interest"})
+		return
+	}
+	c.JSON(http.StatusCreated, interest)
+}
+
+// UpdateInterest changes a catalog interest (PUT /api/admin/interests/:slug).
+// @Summary Update an interest
+// @Description Replaces an interest's category and labels and sets whether it is active. Retiring an interest hides it from the catalog and from profiles without removing it from users, so reactivating it restores their picks. Admins only.
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param slug path string true "Interest slug"
+// @Param payload body InterestRequest true "Interest"
+// @Success 200 {object} models.Interest
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/admin/interests/{slug} [put]
+func (h *InterestHandler) UpdateInterest(c *gin.Context) {
+	var req InterestRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "invalid request body"})
+		return
+	}
+	interest, err := req.interest(c.Param("slug"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	if err := h.interestRepo.Update(interest); err != nil {
+		if err == repo.ErrInterestNotFound {
+			c.JSON(http.StatusNotFound, ErrorResponse{Error: "interest not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to update interest"})
+		return
+	}
+	c.JSON(http.StatusOK, interest)
+}
+
+// DeleteInterest removes an interest from the catalog (DELETE /api/admin/interests/:slug).
+// @Summary Delete an interest
+// @Description Removes an interest from the catalog and from every user who picked it. Prefer retiring it with active=false to keep users' picks. Admins only.
+// @Tags Admin
+// @Produce json
+// @Security BearerAuth
+// @Param slug path string true "Interest slug"
+// @Success 200 {object} MessageResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/admin/interests/{slug} [delete]
+func (h *InterestHandler) DeleteInterest(c *gin.Context) {
+	if err := h.interestRepo.Delete(c.Param("slug")); err != nil {
+		if err == repo.ErrInterestNotFound {
+			c.JSON(http.StatusNotFound, ErrorResponse{Error: "interest not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to delete interest"})
+		return
+	}
+	c.JSON(http.StatusOK, MessageResponse{Message: "interest deleted"})
+}
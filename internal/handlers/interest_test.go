@@ -0,0 +1,240 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Viet-CodingStars/kyupi-kyupi-backend/internal/middleware"
+	"github.com/Viet-CodingStars/kyupi-kyupi-backend/internal/models"
+	"github.com/Viet-CodingStars/kyupi-kyupi-backend/internal/repo"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type mockInterestRepo struct {
+	catalog map[string]*models.Interest
+	picked  map[uuid.UUID][]string
+}
+
+func newMockInterestRepo() *mockInterestRepo {
+	m := &mockInterestRepo{catalog: make(map[string]*models.Interest), picked: make(map[uuid.UUID][]string)}
+	for _, i := range []*models.Interest{
+		{Slug: "coffee", Category: "food_drink", Labels: map[string]string{"en": "Coffee", "vi": "Cà phê"}, Active: true},
+		{Slug: "hiking", Category: "outdoors", Labels: map[string]string{"en": "Hiking", "vi": "Leo núi"}, Active: true},
+		{Slug: "kpop", Category: "music", Labels: map[string]string{"en": "K-pop"}, Active: true},
+		{Slug: "fax_machines", Category: "lifestyle", Labels: map[string]string{"en": "Fax machines"}},
+	} {
+		m.catalog[i.Slug] = i
+	}
+	return m
+}
+
+func (m *mockInterestRepo) List(includeInactive bool) ([]*models.Interest, error) {
+	interests := make([]*models.Interest, 0)
+	for _, slug := range []string{"coffee", "kpop", "hiking", "fax_machines"} {
+		if i, ok := m.catalog[slug]; ok && (i.Active || includeInactive) {
+			interests = append(interests, i)
+		}
+	}
+	return interests, nil
+}
+
+func (m *mockInterestRepo) Create(interest *models.Interest) error {
+	if _, ok := m.catalog[interest.Slug]; ok {
+		return repo.ErrInterestAlreadyExists
+	}
+	m.catalog[interest.Slug] = interest
+	return nil
+}
+
+func (m *mockInterestRepo) Update(interest *models.Interest) error {
+	if _, ok := m.catalog[interest.Slug]; !ok {
+		return repo.ErrInterestNotFound
+	}
+	m.catalog[interest.Slug] = interest
+	return nil
+}
+
+func (m *mockInterestRepo) Delete(slug string) error {
+	if _, ok := m.catalog[slug]; !ok {
+		return repo.ErrInterestNotFound
+	}
+	delete(m.catalog, slug)
+	return nil
+}
+
+func (m *mockInterestRepo) ListForUser(userID uuid.UUID) ([]*models.Interest, error) {
+	interests := make([]*models.Interest, 0)
+	for _, slug := range m.picked[userID] {
+		if i, ok := m.catalog[slug]; ok && i.Active {
+			interests = append(interests, i)
+		}
+	}
+	return interests, nil
+}
+
+func (m *mockInterestRepo) SetForUser(userID uuid.UUID, slugs []string) error {
+	for _, slug := range slugs {
+		if i, ok := m.catalog[slug]; !ok || !i.Active {
+			return repo.ErrUnknownInterest
+		}
+	}
+	m.picked[userID] = slugs
+	return nil
+}
+
+func (m *mockInterestRepo) ListForProfile(viewerID, userID uuid.UUID) ([]*models.ProfileInterest, error) {
+	interests, _ := m.ListForUser(userID)
+	profile := make([]*models.ProfileInterest, 0, len(interests))
+	for _, i := range interests {
+		shared := false
+		for _, slug := range m.picked[viewerID] {
+			shared = shared || slug == i.Slug
+		}
+		profile = append(profile, &models.ProfileInterest{Interest: *i, Shared: shared})
+	}
+	return profile, nil
+}
+
+func TestRequestLanguage(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cases := []struct {
+		query, acceptLanguage, want string
+	}{
+		{"", "", "en"},
+		{"vi", "fr-FR,fr;q=0.9", "vi"},
+		{"", "vi-VN,vi;q=0.9,en;q=0.8", "vi"},
+		{"xx", "tlh,ja;q=0.5", "ja"},
+	}
+	for _, tc := range cases {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = httptest.NewRequest(http.MethodGet, "/api/interests?lang="+tc.query, nil)
+		c.Request.Header.Set("Accept-Language", tc.acceptLanguage)
+		if got := requestLanguage(c); got != tc.want {
+			t.Fatalf("requestLanguage(%q, %q) = %q, want %q", tc.query, tc.acceptLanguage, got, tc.want)
+		}
+	}
+}
+
+func TestGetCatalog(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	handler := &InterestHandler{interestRepo: newMockInterestRepo(), maxInterests: 3}
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/api/interests?lang=vi", nil)
+	handler.GetCatalog(c)
+
+	var response InterestsResponse
+	json.Unmarshal(w.Body.Bytes(), &response)
+	if w.Code != http.StatusOK || len(response.Interests) != 3 {
+		t.Fatalf("expected the 3 active interests, got %d: %s", w.Code, w.Body.String())
+	}
+	if response.Interests[0].Label != "Cà phê" || response.Interests[1].Label != "K-pop" {
+		t.Fatalf("expected Vietnamese labels falling back to English, got %+v", response.Interests)
+	}
+}
+
+func TestUpdateInterests(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	interestRepo := newMockInterestRepo()
+	handler := &InterestHandler{interestRepo: interestRepo, maxInterests: 2}
+	userID := uuid.New()
+
+	put := func(body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		req := httptest.NewRequest(http.MethodPut, "/api/users/interests", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		c.Request = req.WithContext(context.WithValue(req.Context(), middleware.UserIDKey, userID))
+		handler.UpdateInterests(c)
+		return w
+	}
+
+	w := put(`{"interests":["hiking","coffee","hiking"]}`)
+	var response InterestsResponse
+	json.Unmarshal(w.Body.Bytes(), &response)
+	if w.Code != http.StatusOK || len(response.Interests) != 2 || len(interestRepo.picked[userID]) != 2 {
+		t.Fatalf("expected duplicates to be dropped, got %d: %s", w.Code, w.Body.String())
+	}
+
+	for name, body := range map[string]string{
+		"too many":  `{"interests":["hiking","coffee","kpop"]}`,
+		"unknown":   `{"interests":["skydiving"]}`,
+		"retired":   `{"interests":["fax_machines"]}`,
+		"malformed": `{"interests":"hiking"}`,
+	} {
+		t.Run("rejects "+name, func(t *testing.T) {
+			if w := put(body); w.Code != http.StatusBadRequest {
+				t.Fatalf("expected status 400, got %d: %s", w.Code, w.Body.String())
+			}
+		})
+	}
+
+	if w := put(`{"interests":[]}`); w.Code != http.StatusOK || len(interestRepo.picked[userID]) != 0 {
+		t.Fatalf("expected interests to be cleared, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestManageInterests(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	interestRepo := newMockInterestRepo()
+	handler := &InterestHandler{interestRepo: interestRepo, maxInterests: 10}
+
+	request := func(fn gin.HandlerFunc, method, slug, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Params = gin.Params{{Key: "slug", Value: slug}}
+		req := httptest.NewRequest(method, "/api/admin/interests/"+slug, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		c.Request = req
+		fn(c)
+		return w
+	}
+
+	t.Run("create", func(t *testing.T) {
+		w := request(handler.CreateInterest, http.MethodPost, "", `{"slug":"board_games","category":"games","labels":{"en":"Board games","vi":"Board game"}}`)
+		if w.Code != http.StatusCreated || !interestRepo.catalog["board_games"].Active {
+			t.Fatalf("expected an active interest to be created, got %d: %s", w.Code, w.Body.String())
+		}
+		if w := request(handler.CreateInterest, http.MethodPost, "", `{"slug":"coffee","category":"food_drink","labels":{"en":"Coffee"}}`); w.Code != http.StatusConflict {
+			t.Fatalf("expected status 409 for an existing slug, got %d", w.Code)
+		}
+	})
+
+	for name, body := range map[string]string{
+		"bad slug":         `{"slug":"Board Games","category":"games","labels":{"en":"Board games"}}`,
+		"no english label": `{"slug":"go","category":"games","labels":{"vi":"Cờ vây"}}`,
+		"unknown language": `{"slug":"go","category":"games","labels":{"en":"Go","xx":"Go"}}`,
+		"missing category": `{"slug":"go","labels":{"en":"Go"}}`,
+	} {
+		t.Run("create rejects "+name, func(t *testing.T) {
+			if w := request(handler.CreateInterest, http.MethodPost, "", body); w.Code != http.StatusBadRequest {
+				t.Fatalf("expected status 400, got %d: %s", w.Code, w.Body.String())
+			}
+		})
+	}
+
+	t.Run("retire", func(t *testing.T) {
+		w := request(handler.UpdateInterest, http.MethodPut, "kpop", `{"category":"music","labels":{"en":"K-pop"},"active":false}`)
+		if w.Code != http.StatusOK || interestRepo.catalog["kpop"].Active {
+			t.Fatalf("expected the interest to be retired, got %d: %s", w.Code, w.Body.String())
+		}
+		if w := request(handler.UpdateInterest, http.MethodPut, "skydiving", `{"category":"sports","labels":{"en":"Skydiving"}}`); w.Code != http.StatusNotFound {
+			t.Fatalf("expected status 404 for an unknown slug, got %d", w.Code)
+		}
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		if w := request(handler.DeleteInterest, http.MethodDelete, "coffee", ""); w.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d", w.Code)
+		}
+		if w := request(handler.DeleteInterest, http.MethodDelete, "coffee", ""); w.Code != http.StatusNotFound {
+			t.Fatalf("expected status 404 once deleted, got %d", w.Code)
+		}
+	})
+}
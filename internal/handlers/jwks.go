@@ -0,0 +1,28 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/Viet-CodingStars/kyupi-kyupi-backend/internal/auth"
+	"github.com/gin-gonic/gin"
+)
+
+type JWKSHandler struct {
+	keys *auth.KeySet
+}
+
+func NewJWKSHandler(keys *auth.KeySet) *JWKSHandler {
+	return &JWKSHandler{keys: keys}
+}
+
+// GetJWKS publishes the public token verification keys (GET /.well-known/jwks.json).
+// @Summary JSON Web Key Set
+// @Description Returns the public keys other services use to verify tokens issued by this API. Empty when tokens are signed with a shared HS256 secret.
+// @Tags Auth
+// @Produce json
+// @Success 200 {object} auth.JWKS
+// @Router /.well-known/jwks.json [get]
+func (h *JWKSHandler) GetJWKS(c *gin.Context) {
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, h.keys.JWKS())
+}
@@ -0,0 +1,34 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Viet-CodingStars/kyupi-kyupi-backend/internal/auth"
+	"github.com/gin-gonic/gin"
+)
+
+func TestGetJWKS(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	handler := NewJWKSHandler(auth.NewHMACKeySet("test-secret"))
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/.well-known/jwks.json", nil)
+
+	handler.GetJWKS(c)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", w.Code)
+	}
+
+	var response auth.JWKS
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if response.Keys == nil || len(response.Keys) != 0 {
+		t.Fatalf("expected empty key list for shared-secret signing, got %+v", response.Keys)
+	}
+}
@@ -1,8 +1,15 @@
 package handlers
 
 import (
+	"context"
 	"database/sql"
+	"encoding/base64"
+	"errors"
+	"fmt"
 	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
 
 	"github.com/Viet-CodingStars/kyupi-kyupi-backend/internal/middleware"
 	"github.com/Viet-CodingStars/kyupi-kyupi-backend/internal/models"
@@ -16,6 +23,26 @@ type LikeRepository interface {
 	Create(like *models.Like) error
 	GetByUserAndTarget(userID, targetUserID uuid.UUID) (*models.Like, error)
 	CheckMutualLike(userID, targetUserID uuid.UUID) (bool, error)
+	ListReceived(userID uuid.UUID, cursor *repo.LikeCursor, limit int) ([]*models.ReceivedLike, error)
+	CreateSuperLike(like *models.Like, dailyQuota int) (int, error)
+	SuperLikeUsage(userID uuid.UUID) (int, time.Time, error)
+	ListComments(userID, otherUserID uuid.UUID) ([]*models.Like, error)
+	RewindLastPass(userID uuid.UUID, window time.Duration, dailyLimit int) (*models.Like, int, error)
+}
+
+// MessageSeeder opens a new match's chat with the comments sent along with the pair's likes.
+type MessageSeeder interface {
+	SeedFromLikes(ctx context.Context, matchID uuid.UUID, likes []*models.Like) error
+}
+
+// PromptAnswerChecker declares the prompt lookup LikeHandler uses to validate likes on a prompt answer.
+type PromptAnswerChecker interface {
+	HasAnswered(userID uuid.UUID, promptID string) (bool, error)
+}
+
+// PhotoChecker declares the photo lookup LikeHandler uses to validate likes on a photo.
+type PhotoChecker interface {
+	HasPhoto(userID uuid.UUID, photoURL string) (bool, error)
 }
 
 // MatchRepository declares the minimal persistence operations required by LikeHandler.
@@ -26,21 +53,35 @@ type MatchRepository interface {
 }
 
 type LikeHandler struct {
-	likeRepo  LikeRepository
-	matchRepo MatchRepository
+	likeRepo      LikeRepository
+	matchRepo     MatchRepository
+	messageSeeder MessageSeeder
+	promptRepo    PromptAnswerChecker
+	photoRepo     PhotoChecker
+	// superLikeDailyQuota is how many super likes a user may send per local calendar day
+	superLikeDailyQuota int
 }
 
-func NewLikeHandler(db *sql.DB) *LikeHandler {
+func NewLikeHandler(db *sql.DB, messageSeeder MessageSeeder, superLikeDailyQuota int) *LikeHandler {
 	return &LikeHandler{
-		likeRepo:  repo.NewLikeRepo(db),
-		matchRepo: repo.NewMatchRepo(db),
+		likeRepo:            repo.NewLikeRepo(db),
+		matchRepo:           repo.NewMatchRepo(db),
+		messageSeeder:       messageSeeder,
+		promptRepo:          repo.NewPromptRepo(db),
+		photoRepo:           repo.NewUserRepo(db),
+		superLikeDailyQuota: superLikeDailyQuota,
 	}
 }
 
-// CreateLikeRequest represents the like/pass request.
+// CreateLikeRequest represents the like/pass request. A like may carry a comment and may be
+// anchored to one photo (target_ref is the photo URL) or prompt answer (target_ref is the ID of
+// a prompt the target answered).
 type CreateLikeRequest struct {
 	TargetUserID string `json:"target_user_id" binding:"required"`
-	Status       string `json:"status" binding:"required,oneof=like pass"`
+	Status       string `json:"status" binding:"required,oneof=like super_like pass"`
+	Comment      string `json:"comment,omitempty"`
+	TargetType   string `json:"target_type,omitempty" binding:"omitempty,oneof=photo prompt"`
+	TargetRef    string `json:"target_ref,omitempty" binding:"omitempty,max=255"`
 }
 
 // LikeResponse represents the response after creating a like
@@ -48,11 +89,20 @@ type LikeResponse struct {
 	Like    *models.Like  `json:"like"`
 	Match   *models.Match `json:"match,omitempty"`
 	Matched bool          `json:"matched"`
+	// SuperLikesRemaining is set after a super like
+	SuperLikesRemaining *int `json:"super_likes_remaining,omitempty"`
+}
+
+// SuperLikeQuotaResponse reports the caller's super likes for the current local day.
+type SuperLikeQuotaResponse struct {
+	DailyQuota int       `json:"daily_quota"`
+	Remaining  int       `json:"remaining"`
+	ResetsAt   time.Time `json:"resets_at"`
 }
 
 // CreateLike handles creating a like (POST /api/likes).
 // @Summary Create a like
-// @Description Creates a like for a target user. If both users like each other, a match is automatically created. For passing on a user, use the /api/passes endpoint instead.
+// @Description Creates a like or super like for a target user, replacing an earlier pass if there is one, optionally with a comment about the whole profile or a single photo or prompt answer (target_type prompt with the prompt ID as target_ref, which the target must have answered). The comment is shown in the target's inbox and opens the chat once the users match. Super likes count against a daily quota that resets at midnight in the user's timezone, and are shown first in the target's inbox. If both users like each other, a match is automatically created. For passing on a user, use the /api/passes endpoint instead.
 // @Tags Likes
 // @Accept json
 // @Produce json
@@ -61,7 +111,9 @@ type LikeResponse struct {
 // @Success 201 {object} LikeResponse
 // @Failure 400 {object} ErrorResponse
 // @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
 // @Failure 409 {object} ErrorResponse
+// @Failure 429 {object} ErrorResponse
 // @Failure 500 {object} ErrorResponse
 // @Router /api/likes [post]
 func (h *LikeHandler) CreateLike(c *gin.Context) {
@@ -89,62 +141,289 @@ func (h *LikeHandler) CreateLike(c *gin.Context) {
 		return
 	}
 
+	comment := strings.TrimSpace(req.Comment)
+	if !models.IsPositiveLikeStatus(req.Status) && (comment != "" || req.TargetType != "") {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "only likes can carry a comment or target"})
+		return
+	}
+	if utf8.RuneCountInString(comment) > models.MaxLikeCommentLength {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: fmt.Sprintf("comment must be at most %d characters", models.MaxLikeCommentLength)})
+		return
+	}
+	if (req.TargetType == "") != (strings.TrimSpace(req.TargetRef) == "") {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "target_type and target_ref must be set together"})
+		return
+	}
+	targetRef := strings.TrimSpace(req.TargetRef)
+	if req.TargetType == models.LikeTargetPhoto {
+		hasPhoto, err := h.photoRepo.HasPhoto(targetUserID, targetRef)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to create like"})
+			return
+		}
+		if !hasPhoto {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: "target_ref is not one of the target user's photos"})
+			return
+		}
+	}
+	if req.TargetType == models.LikeTargetPrompt {
+		answered, err := h.promptRepo.HasAnswered(targetUserID, targetRef)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to create like"})
+			return
+		}
+		if !answered {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: "target user has not answered that prompt"})
+			return
+		}
+	}
+
 	// Create the like
 	like := &models.Like{
 		UserID:       userID,
 		TargetUserID: targetUserID,
 		Status:       req.Status,
+		Comment:      comment,
+		TargetType:   req.TargetType,
+		TargetRef:    targetRef,
 	}
 
-	if err := h.likeRepo.Create(like); err != nil {
+	var superLikesRemaining *int
+	if req.Status == models.LikeStatusSuperLike {
+		var remaining int
+		remaining, err = h.likeRepo.CreateSuperLike(like, h.superLikeDailyQuota)
+		superLikesRemaining = &remaining
+	} else {
+		err = h.likeRepo.Create(like)
+	}
+	if err != nil {
+		if err == repo.ErrSuperLikeQuotaExceeded {
+			c.JSON(http.StatusTooManyRequests, ErrorResponse{Error: "daily super like limit reached"})
+			return
+		}
 		if err == repo.ErrLikeAlreadyExists {
 			c.JSON(http.StatusConflict, ErrorResponse{Error: "you have already liked or passed this user"})
 			return
 		}
+		if err == repo.ErrUserNotFound {
+			c.JSON(http.StatusNotFound, ErrorResponse{Error: "target user not found"})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to create like"})
 		return
 	}
 
 	response := LikeResponse{
-		Like:    like,
-		Matched: false,
+		Like:                like,
+		Matched:             false,
+		SuperLikesRemaining: superLikesRemaining,
+	}
+
+	// The like stands even if the match cannot be created
+	if match, err := matchIfMutual(c.Request.Context(), h.likeRepo, h.matchRepo, h.messageSeeder, like); err == nil && match != nil {
+		response.Match = match
+		response.Matched = true
+	}
+
+	c.JSON(http.StatusCreated, response)
+}
+
+// matchIfMutual creates a match when a like or super like is returned by its target and the
+// pair has not matched yet, and seeds the match's chat. It returns nil when no match was
+// created. Likes queued for a paused user are matched when that user resumes.
+func matchIfMutual(ctx context.Context, likeRepo LikeRepository, matchRepo MatchRepository, seeder MessageSeeder, like *models.Like) (*models.Match, error) {
+	if !models.IsPositiveLikeStatus(like.Status) || like.QueuedAt != nil {
+		return nil, nil
+	}
+	mutualLike, err := likeRepo.CheckMutualLike(like.UserID, like.TargetUserID)
+	if err != nil || !mutualLike {
+		return nil, err
+	}
+	matchExists, err := matchRepo.Exists(like.UserID, like.TargetUserID)
+	if err != nil || matchExists {
+		return nil, err
+	}
+
+	match := &models.Match{
+		User1ID: like.UserID,
+		User2ID: like.TargetUserID,
+	}
+	if err := matchRepo.Create(match); err != nil {
+		return nil, err
+	}
+
+	// The match stands even if its chat cannot be seeded
+	_ = seedMatchMessages(ctx, likeRepo, seeder, match)
+	return match, nil
+}
+
+// seedMatchMessages copies the comments sent with the pair's likes into a new match's chat.
+func seedMatchMessages(ctx context.Context, likeRepo LikeRepository, seeder MessageSeeder, match *models.Match) error {
+	if seeder == nil {
+		return nil
+	}
+	comments, err := likeRepo.ListComments(match.User1ID, match.User2ID)
+	if err != nil {
+		return err
+	}
+	return seeder.SeedFromLikes(ctx, match.ID, comments)
+}
+
+// ReceivedLikeItem is a pending like shown in the caller's inbox.
+type ReceivedLikeItem struct {
+	User       UserListItem `json:"user"`
+	SuperLike  bool         `json:"super_like"`
+	Comment    string       `json:"comment,omitempty"`
+	TargetType string       `json:"target_type,omitempty"`
+	TargetRef  string       `json:"target_ref,omitempty"`
+	LikedAt    time.Time    `json:"liked_at"`
+}
+
+// ReceivedLikesResponse represents a page of the caller's like inbox.
+type ReceivedLikesResponse struct {
+	Likes      []ReceivedLikeItem `json:"likes"`
+	NextCursor *string            `json:"next_cursor"`
+}
+
+// GetReceivedLikes lists users who liked the caller (GET /api/likes/received).
+// @Summary List received likes
+// @Description Returns users who liked the caller and whom the caller has not liked or passed yet, super likes first and then newest first. Paused, deleted and hidden users are left out; blocking does not exist yet, so blocked users are not filtered. Pass next_cursor back as cursor for the next page.
+// @Tags Likes
+// @Produce json
+// @Security BearerAuth
+// @Param cursor query string false "Cursor from the previous page"
+// @Param limit query int false "Number of likes to return (default: 20, max: 100)"
+// @Success 200 {object} ReceivedLikesResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/likes/received [get]
+func (h *LikeHandler) GetReceivedLikes(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c.Request.Context())
+	if !ok {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "user not authenticated"})
+		return
 	}
 
-	// If status is "like", check for mutual like and create match
-	if req.Status == "like" {
-		mutualLike, err := h.likeRepo.CheckMutualLike(userID, targetUserID)
+	var cursor *repo.LikeCursor
+	if raw := c.Query("cursor"); raw != "" {
+		decoded, err := decodeLikeCursor(raw)
 		if err != nil {
-			// Log error but don't fail the request
-			c.JSON(http.StatusCreated, response)
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: "invalid cursor format"})
+			return
+		}
+		cursor = decoded
+	}
+
+	limit := 20
+	if limitStr := c.Query("limit"); limitStr != "" {
+		fmt.Sscanf(limitStr, "%d", &limit)
+		if limit < 1 || limit > 100 {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: "limit must be between 1 and 100"})
 			return
 		}
+	}
+
+	received, err := h.likeRepo.ListReceived(userID, cursor, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to retrieve likes"})
+		return
+	}
 
-		if mutualLike {
-			// Check if match already exists
-			matchExists, err := h.matchRepo.Exists(userID, targetUserID)
-			if err != nil {
-				c.JSON(http.StatusCreated, response)
-				return
-			}
-
-			if !matchExists {
-				// Create match
-				match := &models.Match{
-					User1ID: userID,
-					User2ID: targetUserID,
-				}
-
-				if err := h.matchRepo.Create(match); err != nil {
-					// Log error but don't fail the request
-					c.JSON(http.StatusCreated, response)
-					return
-				}
-
-				response.Match = match
-				response.Matched = true
-			}
+	items := make([]ReceivedLikeItem, len(received))
+	for i, like := range received {
+		items[i] = ReceivedLikeItem{
+			User: UserListItem{
+				ID:        like.User.ID,
+				Name:      like.User.Name,
+				Gender:    like.User.Gender,
+				AvatarURL: like.User.AvatarURL,
+				Intention: like.User.Intention,
+				Verified:  like.User.VerifiedAt != nil,
+			},
+			SuperLike:  like.SuperLike,
+			Comment:    like.Comment,
+			TargetType: like.TargetType,
+			TargetRef:  like.TargetRef,
+			LikedAt:    like.LikedAt,
 		}
 	}
 
-	c.JSON(http.StatusCreated, response)
+	var nextCursor *string
+	if len(received) == limit {
+		last := received[len(received)-1]
+		encoded := encodeLikeCursor(repo.LikeCursor{SuperLike: last.SuperLike, CreatedAt: last.LikedAt, ID: last.LikeID})
+		nextCursor = &encoded
+	}
+
+	c.JSON(http.StatusOK, ReceivedLikesResponse{Likes: items, NextCursor: nextCursor})
+}
+
+// encodeLikeCursor packs a like position into an opaque URL-safe token.
+func encodeLikeCursor(cursor repo.LikeCursor) string {
+	kind := "l"
+	if cursor.SuperLike {
+		kind = "s"
+	}
+	raw := kind + "_" + cursor.CreatedAt.UTC().Format(time.RFC3339Nano) + "_" + cursor.ID.String()
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodeLikeCursor(token string) (*repo.LikeCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, err
+	}
+	parts := strings.Split(string(raw), "_")
+	if len(parts) != 3 || (parts[0] != "l" && parts[0] != "s") {
+		return nil, errors.New("malformed cursor")
+	}
+	createdAt, err := time.Parse(time.RFC3339Nano, parts[1])
+	if err != nil {
+		return nil, err
+	}
+	likeID, err := uuid.Parse(parts[2])
+	if err != nil {
+		return nil, err
+	}
+	return &repo.LikeCursor{SuperLike: parts[0] == "s", CreatedAt: createdAt, ID: likeID}, nil
+}
+
+// GetSuperLikeQuota reports the caller's remaining super likes (GET /api/likes/super_likes).
+// @Summary Get super like quota
+// @Description Returns the daily super like quota, how many are left today and when the count resets (midnight in the user's timezone).
+// @Tags Likes
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} SuperLikeQuotaResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/likes/super_likes [get]
+func (h *LikeHandler) GetSuperLikeQuota(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c.Request.Context())
+	if !ok {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "user not authenticated"})
+		return
+	}
+
+	used, resetsAt, err := h.likeRepo.SuperLikeUsage(userID)
+	if err != nil {
+		if err == repo.ErrUserNotFound {
+			c.JSON(http.StatusNotFound, ErrorResponse{Error: "user not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to retrieve super like quota"})
+		return
+	}
+
+	remaining := h.superLikeDailyQuota - used
+	if remaining < 0 {
+		remaining = 0
+	}
+	c.JSON(http.StatusOK, SuperLikeQuotaResponse{
+		DailyQuota: h.superLikeDailyQuota,
+		Remaining:  remaining,
+		ResetsAt:   resetsAt,
+	})
 }
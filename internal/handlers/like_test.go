@@ -6,6 +6,8 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"sort"
+	"strings"
 	"testing"
 	"time"
 
@@ -20,6 +22,9 @@ import (
 type mockLikeRepo struct {
 	likes       map[string]*models.Like
 	mutualLikes map[string]bool
+	paused      map[uuid.UUID]bool
+	superLikes  map[uuid.UUID]int
+	rewinds     map[uuid.UUID]int
 }
 
 // mockMatchRepo implements a mock match repository for testing
@@ -32,6 +37,9 @@ func newMockLikeRepo() *mockLikeRepo {
 	return &mockLikeRepo{
 		likes:       make(map[string]*models.Like),
 		mutualLikes: make(map[string]bool),
+		paused:      make(map[uuid.UUID]bool),
+		superLikes:  make(map[uuid.UUID]int),
+		rewinds:     make(map[uuid.UUID]int),
 	}
 }
 
@@ -44,12 +52,21 @@ func newMockMatchRepo() *mockMatchRepo {
 
 func (m *mockLikeRepo) Create(like *models.Like) error {
 	key := like.UserID.String() + "-" + like.TargetUserID.String()
-	if _, exists := m.likes[key]; exists {
+	if existing, exists := m.likes[key]; exists &&
+		(existing.Status != models.LikeStatusPass || !models.IsPositiveLikeStatus(like.Status)) {
 		return repo.ErrLikeAlreadyExists
 	}
-	like.ID = uuid.New()
+	if existing, exists := m.likes[key]; exists {
+		like.ID = existing.ID
+	} else {
+		like.ID = uuid.New()
+	}
 	like.CreatedAt = time.Now()
 	like.UpdatedAt = time.Now()
+	if models.IsPositiveLikeStatus(like.Status) && m.paused[like.TargetUserID] {
+		queuedAt := time.Now()
+		like.QueuedAt = &queuedAt
+	}
 	m.likes[key] = like
 	return nil
 }
@@ -68,13 +85,124 @@ func (m *mockLikeRepo) CheckMutualLike(userID, targetUserID uuid.UUID) (bool, er
 	if mutual, exists := m.mutualLikes[key]; exists {
 		return mutual, nil
 	}
-	// Check if a like exists from target to user with "like" status
-	if like, exists := m.likes[key]; exists && like.Status == "like" {
+	// Check if a like or super like exists from target to user
+	if like, exists := m.likes[key]; exists && models.IsPositiveLikeStatus(like.Status) {
 		return true, nil
 	}
 	return false, nil
 }
 
+func (m *mockLikeRepo) ListReceived(userID uuid.UUID, cursor *repo.LikeCursor, limit int) ([]*models.ReceivedLike, error) {
+	received := make([]*models.ReceivedLike, 0)
+	for _, like := range m.likes {
+		if like.TargetUserID != userID || !models.IsPositiveLikeStatus(like.Status) || like.QueuedAt != nil {
+			continue
+		}
+		if _, answered := m.likes[userID.String()+"-"+like.UserID.String()]; answered {
+			continue
+		}
+		received = append(received, &models.ReceivedLike{
+			LikeID:     like.ID,
+			User:       &models.User{ID: like.UserID, Name: "Liker"},
+			SuperLike:  like.Status == models.LikeStatusSuperLike,
+			Comment:    like.Comment,
+			TargetType: like.TargetType,
+			TargetRef:  like.TargetRef,
+			LikedAt:    like.CreatedAt,
+		})
+	}
+	// before reports whether a sorts ahead of b: super likes first, then newest first
+	before := func(a, b repo.LikeCursor) bool {
+		if a.SuperLike != b.SuperLike {
+			return a.SuperLike
+		}
+		if !a.CreatedAt.Equal(b.CreatedAt) {
+			return a.CreatedAt.After(b.CreatedAt)
+		}
+		return a.ID.String() > b.ID.String()
+	}
+	position := func(like *models.ReceivedLike) repo.LikeCursor {
+		return repo.LikeCursor{SuperLike: like.SuperLike, CreatedAt: like.LikedAt, ID: like.LikeID}
+	}
+	sort.Slice(received, func(i, j int) bool {
+		return before(position(received[i]), position(received[j]))
+	})
+
+	page := make([]*models.ReceivedLike, 0)
+	for _, like := range received {
+		if cursor != nil && !before(*cursor, position(like)) {
+			continue
+		}
+		if len(page) == limit {
+			break
+		}
+		page = append(page, like)
+	}
+	return page, nil
+}
+
+func (m *mockLikeRepo) CreateSuperLike(like *models.Like, dailyQuota int) (int, error) {
+	if m.superLikes[like.UserID] >= dailyQuota {
+		return 0, repo.ErrSuperLikeQuotaExceeded
+	}
+	if err := m.Create(like); err != nil {
+		return 0, err
+	}
+	m.superLikes[like.UserID]++
+	return dailyQuota - m.superLikes[like.UserID], nil
+}
+
+func (m *mockLikeRepo) SuperLikeUsage(userID uuid.UUID) (int, time.Time, error) {
+	tomorrow := time.Now().UTC().Truncate(24 * time.Hour).Add(24 * time.Hour)
+	return m.superLikes[userID], tomorrow, nil
+}
+
+func (m *mockLikeRepo) ListComments(userID, otherUserID uuid.UUID) ([]*models.Like, error) {
+	comments := make([]*models.Like, 0)
+	for _, like := range m.likes {
+		if like.Comment == "" {
+			continue
+		}
+		if (like.UserID == userID && like.TargetUserID == otherUserID) ||
+			(like.UserID == otherUserID && like.TargetUserID == userID) {
+			comments = append(comments, like)
+		}
+	}
+	sort.Slice(comments, func(i, j int) bool { return comments[i].CreatedAt.Before(comments[j].CreatedAt) })
+	return comments, nil
+}
+
+func (m *mockLikeRepo) RewindLastPass(userID uuid.UUID, window time.Duration, dailyLimit int) (*models.Like, int, error) {
+	var last *models.Like
+	for _, like := range m.likes {
+		if like.UserID != userID || like.Status != models.LikeStatusPass || time.Since(like.CreatedAt) > window {
+			continue
+		}
+		if last == nil || like.CreatedAt.After(last.CreatedAt) {
+			last = like
+		}
+	}
+	if last == nil {
+		return nil, 0, repo.ErrNoPassToRewind
+	}
+	if m.rewinds[userID] >= dailyLimit {
+		return nil, 0, repo.ErrRewindLimitExceeded
+	}
+	m.rewinds[userID]++
+	delete(m.likes, last.UserID.String()+"-"+last.TargetUserID.String())
+	return last, dailyLimit - m.rewinds[userID], nil
+}
+
+// mockMessageSeeder records the likes each match's chat was seeded with
+type mockMessageSeeder struct {
+	seeded map[uuid.UUID][]*models.Like
+}
+
+func (m *mockMessageSeeder) SeedFromLikes(ctx context.Context, matchID uuid.UUID, likes []*models.Like) error {
+	m.seeded[matchID] = likes
+	return nil
+}
+
 func (m *mockMatchRepo) Create(match *models.Match) error {
 	user1ID, user2ID := match.User1ID, match.User2ID
 	if user1ID.String() > user2ID.String() {
@@ -339,4 +467,333 @@ func TestCreateLike(t *testing.T) {
 			t.Fatalf("expected status 409, got %d", w.Code)
 		}
 	})
+	t.Run("like replaces an earlier pass and matches", func(t *testing.T) {
+		mockLikeRepo := newMockLikeRepo()
+		mockMatchRepo := newMockMatchRepo()
+		handler := &LikeHandler{
+			likeRepo:  mockLikeRepo,
+			matchRepo: mockMatchRepo,
+		}
+
+		userID := uuid.New()
+		targetUserID := uuid.New()
+		pass := &models.Like{UserID: userID, TargetUserID: targetUserID, Status: "pass"}
+		mockLikeRepo.Create(pass)
+		mockLikeRepo.Create(&models.Like{UserID: targetUserID, TargetUserID: userID, Status: "like"})
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		body := bytes.NewBufferString(`{"target_user_id":"` + targetUserID.String() + `","status":"like"}`)
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/likes", body)
+		req.Header.Set("Content-Type", "application/json")
+		c.Request = req.WithContext(context.WithValue(req.Context(), middleware.UserIDKey, userID))
+
+		handler.CreateLike(c)
+
+		if w.Code != http.StatusCreated {
+			t.Fatalf("expected status 201, got %d: %s", w.Code, w.Body.String())
+		}
+		var response LikeResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		if response.Like.ID != pass.ID || response.Like.Status != "like" {
+			t.Fatalf("expected the pass to become a like, got %+v", response.Like)
+		}
+		if !response.Matched || len(mockMatchRepo.matches) != 1 {
+			t.Fatal("expected the converted like to match")
+		}
+	})
+
+	t.Run("like to paused user is queued without matching", func(t *testing.T) {
+		mockLikeRepo := newMockLikeRepo()
+		mockMatchRepo := newMockMatchRepo()
+		handler := &LikeHandler{
+			likeRepo:  mockLikeRepo,
+			matchRepo: mockMatchRepo,
+		}
+
+		userID := uuid.New()
+		targetUserID := uuid.New()
+
+		// The paused target had already liked the user
+		mockLikeRepo.Create(&models.Like{
+			UserID:       targetUserID,
+			TargetUserID: userID,
+			Status:       "like",
+		})
+		mockLikeRepo.paused[targetUserID] = true
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+
+		body := bytes.NewBufferString(`{"target_user_id":"` + targetUserID.String() + `","status":"like"}`)
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/likes", body)
+		req.Header.Set("Content-Type", "application/json")
+		ctx := context.WithValue(req.Context(), middleware.UserIDKey, userID)
+		req = req.WithContext(ctx)
+		c.Request = req
+
+		handler.CreateLike(c)
+
+		if w.Code != http.StatusCreated {
+			t.Fatalf("expected status 201, got %d", w.Code)
+		}
+
+		var response LikeResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		if response.Matched || len(mockMatchRepo.matches) != 0 {
+			t.Fatal("expected no match while the target is paused")
+		}
+	})
+}
+
+func TestCreateLikeWithComment(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	send := func(handler *LikeHandler, userID uuid.UUID, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		req := httptest.NewRequest(http.MethodPost, "/api/likes", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		c.Request = req.WithContext(context.WithValue(req.Context(), middleware.UserIDKey, userID))
+		handler.CreateLike(c)
+		return w
+	}
+
+	t.Run("comment opens the chat when the like matches", func(t *testing.T) {
+		mockLikeRepo := newMockLikeRepo()
+		mockMatchRepo := newMockMatchRepo()
+		seeder := &mockMessageSeeder{seeded: make(map[uuid.UUID][]*models.Like)}
+		userRepo := newMockUserRepo()
+		handler := &LikeHandler{likeRepo: mockLikeRepo, matchRepo: mockMatchRepo, messageSeeder: seeder, photoRepo: userRepo}
+
+		userID := uuid.New()
+		targetUserID := uuid.New()
+		userRepo.users["target@example.com"] = &models.User{ID: targetUserID, Email: "target@example.com", AvatarURL: "/avatars/x/photo.png"}
+
+		w := send(handler, userID, `{"target_user_id":"`+targetUserID.String()+`","status":"like","comment":"  Where was this taken? ","target_type":"photo","target_ref":"/avatars/x/photo.png"}`)
+		if w.Code != http.StatusCreated {
+			t.Fatalf("expected status 201, got %d: %s", w.Code, w.Body.String())
+		}
+		var first LikeResponse
+		json.Unmarshal(w.Body.Bytes(), &first)
+		if first.Like.Comment != "Where was this taken?" || first.Like.TargetType != models.LikeTargetPhoto || first.Like.TargetRef != "/avatars/x/photo.png" {
+			t.Fatalf("unexpected like %+v", first.Like)
+		}
+		first.Like.CreatedAt = time.Now().Add(-time.Minute)
+
+		w = send(handler, targetUserID, `{"target_user_id":"`+userID.String()+`","status":"like"}`)
+		var second LikeResponse
+		json.Unmarshal(w.Body.Bytes(), &second)
+		if !second.Matched || second.Match == nil {
+			t.Fatal("expected a match")
+		}
+		seeded := seeder.seeded[second.Match.ID]
+		if len(seeded) != 1 || seeded[0].Comment != "Where was this taken?" || seeded[0].UserID != userID {
+			t.Fatalf("expected the comment to seed the chat, got %v", seeded)
+		}
+	})
+
+	t.Run("photo target must be one of the target's photos", func(t *testing.T) {
+		userRepo := newMockUserRepo()
+		handler := &LikeHandler{likeRepo: newMockLikeRepo(), matchRepo: newMockMatchRepo(), photoRepo: userRepo}
+		targetUserID := uuid.New()
+		userRepo.users["target@example.com"] = &models.User{ID: targetUserID, Email: "target@example.com", AvatarURL: "/avatars/x/photo.png"}
+
+		w := send(handler, uuid.New(), `{"target_user_id":"`+targetUserID.String()+`","status":"like","comment":"Click here","target_type":"photo","target_ref":"https://phishing.example/free-gift"}`)
+		if w.Code != http.StatusBadRequest {
+			t.Fatalf("expected status 400 for a photo the target does not have, got %d", w.Code)
+		}
+	})
+
+	t.Run("prompt answer target", func(t *testing.T) {
+		promptRepo := newMockPromptRepo()
+		handler := &LikeHandler{likeRepo: newMockLikeRepo(), matchRepo: newMockMatchRepo(), promptRepo: promptRepo}
+		targetUserID := uuid.New()
+		promptRepo.answers[targetUserID] = []*models.PromptAnswer{{PromptID: "ideal_sunday", Answer: "Phở and a long walk"}}
+
+		w := send(handler, uuid.New(), `{"target_user_id":"`+targetUserID.String()+`","status":"like","target_type":"prompt","target_ref":"green_flag"}`)
+		if w.Code != http.StatusBadRequest {
+			t.Fatalf("expected status 400 for a prompt the target did not answer, got %d", w.Code)
+		}
+		w = send(handler, uuid.New(), `{"target_user_id":"`+targetUserID.String()+`","status":"like","comment":"Which phở place?","target_type":"prompt","target_ref":"ideal_sunday"}`)
+		var response LikeResponse
+		json.Unmarshal(w.Body.Bytes(), &response)
+		if w.Code != http.StatusCreated || response.Like.TargetType != models.LikeTargetPrompt || response.Like.TargetRef != "ideal_sunday" {
+			t.Fatalf("expected the like to target the answer, got %d: %s", w.Code, w.Body.String())
+		}
+	})
+
+	t.Run("invalid comments and targets", func(t *testing.T) {
+		handler := &LikeHandler{likeRepo: newMockLikeRepo(), matchRepo: newMockMatchRepo()}
+		target := uuid.New().String()
+		long := strings.Repeat("é", models.MaxLikeCommentLength+1)
+
+		for name, body := range map[string]string{
+			"comment on a pass":  `{"target_user_id":"` + target + `","status":"pass","comment":"no thanks"}`,
+			"comment too long":   `{"target_user_id":"` + target + `","status":"like","comment":"` + long + `"}`,
+			"target without ref": `{"target_user_id":"` + target + `","status":"like","target_type":"prompt"}`,
+			"ref without target": `{"target_user_id":"` + target + `","status":"like","target_ref":"abc"}`,
+			"unsupported target": `{"target_user_id":"` + target + `","status":"like","target_type":"video","target_ref":"abc"}`,
+		} {
+			if w := send(handler, uuid.New(), body); w.Code != http.StatusBadRequest {
+				t.Errorf("%s: expected status 400, got %d", name, w.Code)
+			}
+		}
+	})
+}
+
+func TestCreateSuperLike(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	send := func(handler *LikeHandler, userID, targetUserID uuid.UUID) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		body := bytes.NewBufferString(`{"target_user_id":"` + targetUserID.String() + `","status":"super_like"}`)
+		req := httptest.NewRequest(http.MethodPost, "/api/likes", body)
+		req.Header.Set("Content-Type", "application/json")
+		c.Request = req.WithContext(context.WithValue(req.Context(), middleware.UserIDKey, userID))
+		handler.CreateLike(c)
+		return w
+	}
+
+	t.Run("super like matches a mutual like", func(t *testing.T) {
+		mockLikeRepo := newMockLikeRepo()
+		mockMatchRepo := newMockMatchRepo()
+		handler := &LikeHandler{likeRepo: mockLikeRepo, matchRepo: mockMatchRepo, superLikeDailyQuota: 2}
+
+		userID := uuid.New()
+		targetUserID := uuid.New()
+		mockLikeRepo.Create(&models.Like{UserID: targetUserID, TargetUserID: userID, Status: "like"})
+
+		w := send(handler, userID, targetUserID)
+		if w.Code != http.StatusCreated {
+			t.Fatalf("expected status 201, got %d: %s", w.Code, w.Body.String())
+		}
+		var response LikeResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		if !response.Matched || response.Match == nil {
+			t.Fatal("expected the super like to create a match")
+		}
+		if response.SuperLikesRemaining == nil || *response.SuperLikesRemaining != 1 {
+			t.Fatalf("expected 1 super like remaining, got %v", response.SuperLikesRemaining)
+		}
+	})
+
+	t.Run("daily quota exhausted", func(t *testing.T) {
+		mockLikeRepo := newMockLikeRepo()
+		handler := &LikeHandler{likeRepo: mockLikeRepo, matchRepo: newMockMatchRepo(), superLikeDailyQuota: 1}
+
+		userID := uuid.New()
+		if w := send(handler, userID, uuid.New()); w.Code != http.StatusCreated {
+			t.Fatalf("expected status 201, got %d", w.Code)
+		}
+		if w := send(handler, userID, uuid.New()); w.Code != http.StatusTooManyRequests {
+			t.Fatalf("expected status 429, got %d", w.Code)
+		}
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		req := httptest.NewRequest(http.MethodGet, "/api/likes/super_likes", nil)
+		c.Request = req.WithContext(context.WithValue(req.Context(), middleware.UserIDKey, userID))
+		handler.GetSuperLikeQuota(c)
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d", w.Code)
+		}
+		var quota SuperLikeQuotaResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &quota); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		if quota.DailyQuota != 1 || quota.Remaining != 0 || !quota.ResetsAt.After(time.Now()) {
+			t.Fatalf("unexpected quota %+v", quota)
+		}
+	})
+}
+
+func TestGetReceivedLikes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	mockLikeRepo := newMockLikeRepo()
+	handler := &LikeHandler{likeRepo: mockLikeRepo, matchRepo: newMockMatchRepo()}
+	userID := uuid.New()
+
+	base := time.Now().Add(-time.Hour)
+	likers := make([]uuid.UUID, 3)
+	for i := range likers {
+		likers[i] = uuid.New()
+		like := &models.Like{UserID: likers[i], TargetUserID: userID, Status: "like"}
+		mockLikeRepo.Create(like)
+		like.CreatedAt = base.Add(time.Duration(i) * time.Minute)
+	}
+	// An older super like is still listed before every regular like
+	superLiker := uuid.New()
+	superLike := &models.Like{UserID: superLiker, TargetUserID: userID, Status: "super_like", Comment: "Love your dog"}
+	mockLikeRepo.Create(superLike)
+	superLike.CreatedAt = base.Add(-time.Hour)
+	// A like the user already answered does not appear
+	answered := uuid.New()
+	mockLikeRepo.Create(&models.Like{UserID: answered, TargetUserID: userID, Status: "like"})
+	mockLikeRepo.Create(&models.Like{UserID: userID, TargetUserID: answered, Status: "pass"})
+
+	request := func(query string, userID uuid.UUID) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		req := httptest.NewRequest(http.MethodGet, "/api/likes/received"+query, nil)
+		if userID != uuid.Nil {
+			req = req.WithContext(context.WithValue(req.Context(), middleware.UserIDKey, userID))
+		}
+		c.Request = req
+		handler.GetReceivedLikes(c)
+		return w
+	}
+
+	t.Run("pages super likes first then newest first", func(t *testing.T) {
+		w := request("?limit=2", userID)
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+		}
+		var first ReceivedLikesResponse
+		json.Unmarshal(w.Body.Bytes(), &first)
+		if len(first.Likes) != 2 || first.NextCursor == nil {
+			t.Fatalf("expected a full first page with a cursor, got %+v", first)
+		}
+		if first.Likes[0].User.ID != superLiker || !first.Likes[0].SuperLike || first.Likes[0].Comment != "Love your dog" {
+			t.Fatalf("expected the super like first, got %+v", first.Likes[0])
+		}
+		if first.Likes[1].User.ID != likers[2] || first.Likes[1].SuperLike {
+			t.Fatalf("expected the newest like second, got %+v", first.Likes[1])
+		}
+
+		w = request("?limit=2&cursor="+*first.NextCursor, userID)
+		var second ReceivedLikesResponse
+		json.Unmarshal(w.Body.Bytes(), &second)
+		if len(second.Likes) != 2 || second.Likes[0].User.ID != likers[1] || second.Likes[1].User.ID != likers[0] || second.NextCursor == nil {
+			t.Fatalf("expected the remaining likes newest first, got %+v", second)
+		}
+
+		w = request("?limit=2&cursor="+*second.NextCursor, userID)
+		var third ReceivedLikesResponse
+		json.Unmarshal(w.Body.Bytes(), &third)
+		if len(third.Likes) != 0 || third.NextCursor != nil {
+			t.Fatalf("expected an empty last page, got %+v", third)
+		}
+	})
+
+	t.Run("invalid cursor", func(t *testing.T) {
+		if w := request("?cursor=not-a-cursor", userID); w.Code != http.StatusBadRequest {
+			t.Fatalf("expected status 400, got %d", w.Code)
+		}
+	})
+
+	t.Run("requires authentication", func(t *testing.T) {
+		if w := request("", uuid.Nil); w.Code != http.StatusUnauthorized {
+			t.Fatalf("expected status 401, got %d", w.Code)
+		}
+	})
 }
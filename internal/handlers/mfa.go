@@ -0,0 +1,538 @@
+package handlers
+
+import (
+	"database/sql"
+	"net/http"
+	"time"
+
+	"github.com/Viet-CodingStars/kyupi-kyupi-backend/internal/auth"
+	"github.com/Viet-CodingStars/kyupi-kyupi-backend/internal/middleware"
+	"github.com/Viet-CodingStars/kyupi-kyupi-backend/internal/models"
+	"github.com/Viet-CodingStars/kyupi-kyupi-backend/internal/repo"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+const (
+	// totpIssuer is the account label shown in authenticator apps.
+	totpIssuer = "Kyupi Kyupi"
+	// mfaChallengeTTL bounds how long the second sign-in step may take.
+	mfaChallengeTTL   = 5 * time.Minute
+	purposeMFA        = "mfa"
+	recoveryCodeCount = 10
+	// mfaMaxFailedAttempts wrong codes in a row lock second-factor sign-in for mfaLockout, so
+	// someone holding the password cannot guess their way through.
+	mfaMaxFailedAttempts = 5
+	mfaLockout           = 15 * time.Minute
+)
+
+// MFARepository declares the minimal persistence operations required by MFAHandler.
+type MFARepository interface {
+	Get(userID uuid.UUID) (*models.UserMFA, error)
+	SaveTOTPSecret(userID uuid.UUID, secret string) error
+	Enable(userID uuid.UUID, step int64, recoveryCodeHashes []string) error
+	ConsumeTOTPStep(userID uuid.UUID, step int64) (bool, error)
+	ConsumeRecoveryCode(userID uuid.UUID, codeHash string) (bool, error)
+	CountRecoveryCodes(userID uuid.UUID) (int, error)
+	ReplaceRecoveryCodes(userID uuid.UUID, codeHashes []string) error
+	Disable(userID uuid.UUID) error
+	CreateChallenge(userID uuid.UUID, ttl time.Duration) (uuid.UUID, error)
+	ChallengeActive(id, userID uuid.UUID) (bool, error)
+	ConsumeChallenge(id, userID uuid.UUID) (bool, error)
+	RecordFailedAttempt(userID uuid.UUID, maxAttempts int, lockout time.Duration) (bool, error)
+	ResetFailedAttempts(userID uuid.UUID) error
+}
+
+type MFAHandler struct {
+	mfaRepo     MFARepository
+	userRepo    UserRepository
+	sessionRepo SessionRepository
+	keys        *auth.KeySet
+}
+
+func NewMFAHandler(db *sql.DB, keys *auth.KeySet) *MFAHandler {
+	return &MFAHandler{
+		mfaRepo:     repo.NewMFARepo(db),
+		userRepo:    repo.NewUserRepo(db),
+		sessionRepo: repo.NewSessionRepo(db),
+		keys:        keys,
+	}
+}
+
+// MFAChallengeResponse is returned by sign-in when a second factor is required.
+type MFAChallengeResponse struct {
+	MFARequired bool   `json:"mfa_required"`
+	MFAToken    string `json:"mfa_token"`
+}
+
+// MFASignInRequest completes a sign-in with a TOTP or recovery code.
+type MFASignInRequest struct {
+	MFAToken string `json:"mfa_token" binding:"required"`
+	Code     string `json:"code" binding:"required"`
+}
+
+// MFACodeRequest carries a TOTP code, or a recovery code where stated.
+type MFACodeRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+// MFAStatusResponse describes the current user's second factor.
+type MFAStatusResponse struct {
+	TOTPEnabled            bool `json:"totp_enabled"`
+	RecoveryCodesRemaining int  `json:"recovery_codes_remaining"`
+}
+
+// TOTPEnrollmentResponse carries the secret to load into an authenticator app.
+type TOTPEnrollmentResponse struct {
+	Secret          string `json:"secret"`
+	ProvisioningURI string `json:"provisioning_uri"` // otpauth:// URI to render as a QR code
+}
+
+// RecoveryCodesResponse carries freshly generated recovery codes. They are shown only once.
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+// mfaChallengeClaims proves the password step of a sign-in succeeded. Its ID names a
+// server-side challenge, so the token works for one sign-in only.
+type mfaChallengeClaims struct {
+	Purpose string `json:"purpose"`
+	jwt.RegisteredClaims
+}
+
+// respondWithSession signs a user in after their first factor, or returns an MFA challenge
+// token instead of the session JWT when the user has TOTP enabled.
+func respondWithSession(c *gin.Context, keys *auth.KeySet, mfaRepo MFARepository, sessionRepo SessionRepository, user *models.User) {
+	mfa, err := mfaRepo.Get(user.ID)
+	if err != nil && err != repo.ErrMFANotFound {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to load mfa settings"})
+		return
+	}
+
+	if mfa != nil && mfa.TOTPEnabled {
+		challengeID, err := mfaRepo.CreateChallenge(user.ID, mfaChallengeTTL)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to generate token"})
+			return
+		}
+		now := time.Now()
+		token, err := keys.SignClaims(mfaChallengeClaims{
+			Purpose: purposeMFA,
+			RegisteredClaims: jwt.RegisteredClaims{
+				ID:        challengeID.String(),
+				Subject:   user.ID.String(),
+				IssuedAt:  jwt.NewNumericDate(now),
+				ExpiresAt: jwt.NewNumericDate(now.Add(mfaChallengeTTL)),
+			},
+		})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to generate token"})
+			return
+		}
+		c.JSON(http.StatusOK, MFAChallengeResponse{MFARequired: true, MFAToken: token})
+		return
+	}
+
+	token, err := issueSession(c, keys, sessionRepo, user)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to create session"})
+		return
+	}
+	c.JSON(http.StatusOK, AuthResponse{Token: token, User: user})
+}
+
+// verifySecondFactor accepts an unused TOTP code or, if allowRecovery is set, an unused
+// recovery code. Accepted codes are consumed.
+func verifySecondFactor(mfaRepo MFARepository, mfa *models.UserMFA, code string, allowRecovery bool) (bool, error) {
+	if step, ok := auth.ValidateTOTP(mfa.TOTPSecret, code, time.Now()); ok {
+		return mfaRepo.ConsumeTOTPStep(mfa.UserID, step)
+	}
+	if !allowRecovery {
+		return false, nil
+	}
+	return mfaRepo.ConsumeRecoveryCode(mfa.UserID, auth.HashRecoveryCode(code))
+}
+
+// loadEnabledMFA returns the user's MFA settings, writing a 400 response if TOTP is not enabled.
+func (h *MFAHandler) loadEnabledMFA(c *gin.Context, userID uuid.UUID) (*models.UserMFA, bool) {
+	mfa, err := h.mfaRepo.Get(userID)
+	if err != nil && err != repo.ErrMFANotFound {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to load mfa settings"})
+		return nil, false
+	}
+	if mfa == nil || !mfa.TOTPEnabled {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "two-factor authentication is not enabled"})
+		return nil, false
+	}
+	return mfa, true
+}
+
+func newRecoveryCodes() ([]string, []string, error) {
+	codes, err := auth.GenerateRecoveryCodes(recoveryCodeCount)
+	if err != nil {
+		return nil, nil, err
+	}
+	hashes := make([]string, len(codes))
+	for i, code := range codes {
+		hashes[i] = auth.HashRecoveryCode(code)
+	}
+	return codes, hashes, nil
+}
+
+// SignIn completes a two-step login (POST /api/users/sign_in/mfa).
+// @Summary Complete sign in with a second factor
+// @Description Exchanges the MFA challenge token from sign in plus a TOTP or recovery code for a JWT. A challenge token completes one sign-in only. After 5 wrong codes in a row, second-factor sign-in is locked for 15 minutes.
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param payload body MFASignInRequest true "Second factor payload"
+// @Success 200 {object} AuthResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 429 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/users/sign_in/mfa [post]
+func (h *MFAHandler) SignIn(c *gin.Context) {
+	var req MFASignInRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "invalid request body"})
+		return
+	}
+
+	var claims mfaChallengeClaims
+	if err := h.keys.ParseClaims(req.MFAToken, &claims); err != nil || claims.Purpose != purposeMFA {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "invalid or expired mfa token"})
+		return
+	}
+	userID, err := uuid.Parse(claims.Subject)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "invalid or expired mfa token"})
+		return
+	}
+	challengeID, err := uuid.Parse(claims.ID)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "invalid or expired mfa token"})
+		return
+	}
+
+	mfa, err := h.mfaRepo.Get(userID)
+	if err != nil || !mfa.TOTPEnabled {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "invalid or expired mfa token"})
+		return
+	}
+	if mfa.Locked {
+		h.mfaRepo.ConsumeChallenge(challengeID, userID)
+		c.JSON(http.StatusTooManyRequests, ErrorResponse{Error: "too many failed attempts, try again later"})
+		return
+	}
+
+	active, err := h.mfaRepo.ChallengeActive(challengeID, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to verify code"})
+		return
+	}
+	if !active {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "invalid or expired mfa token"})
+		return
+	}
+
+	ok, err := verifySecondFactor(h.mfaRepo, mfa, req.Code, true)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to verify code"})
+		return
+	}
+	if !ok {
+		locked, err := h.mfaRepo.RecordFailedAttempt(userID, mfaMaxFailedAttempts, mfaLockout)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to verify code"})
+			return
+		}
+		if locked {
+			h.mfaRepo.ConsumeChallenge(challengeID, userID)
+			c.JSON(http.StatusTooManyRequests, ErrorResponse{Error: "too many failed attempts, try again later"})
+			return
+		}
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "invalid code"})
+		return
+	}
+
+	// Only one request may complete the sign-in with this challenge.
+	consumed, err := h.mfaRepo.ConsumeChallenge(challengeID, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to verify code"})
+		return
+	}
+	if !consumed {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "invalid or expired mfa token"})
+		return
+	}
+	if err := h.mfaRepo.ResetFailedAttempts(userID); err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to verify code"})
+		return
+	}
+
+	user, err := h.userRepo.GetByID(userID)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "invalid or expired mfa token"})
+		return
+	}
+
+	token, err := issueSession(c, h.keys, h.sessionRepo, user)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to create session"})
+		return
+	}
+
+	c.JSON(http.StatusOK, AuthResponse{Token: token, User: user})
+}
+
+// GetStatus returns the current user's MFA status (GET /api/users/mfa).
+// @Summary Get two-factor status
+// @Description Returns whether TOTP is enabled and how many recovery codes are left.
+// @Tags Auth
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} MFAStatusResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/users/mfa [get]
+func (h *MFAHandler) GetStatus(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c.Request.Context())
+	if !ok {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "user not authenticated"})
+		return
+	}
+
+	mfa, err := h.mfaRepo.Get(userID)
+	if err == repo.ErrMFANotFound || (err == nil && !mfa.TOTPEnabled) {
+		c.JSON(http.StatusOK, MFAStatusResponse{})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to load mfa settings"})
+		return
+	}
+
+	remaining, err := h.mfaRepo.CountRecoveryCodes(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to load mfa settings"})
+		return
+	}
+
+	c.JSON(http.StatusOK, MFAStatusResponse{TOTPEnabled: true, RecoveryCodesRemaining: remaining})
+}
+
+// EnrollTOTP starts TOTP enrolment (POST /api/users/mfa/totp).
+// @Summary Start TOTP enrolment
+// @Description Generates a new TOTP secret and provisioning URI. The secret is not active until confirmed with a code.
+// @Tags Auth
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} TOTPEnrollmentResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/users/mfa/totp [post]
+func (h *MFAHandler) EnrollTOTP(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c.Request.Context())
+	if !ok {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "user not authenticated"})
+		return
+	}
+
+	user, err := h.userRepo.GetByID(userID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, ErrorResponse{Error: "user not found"})
+		return
+	}
+
+	mfa, err := h.mfaRepo.Get(userID)
+	if err != nil && err != repo.ErrMFANotFound {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to load mfa settings"})
+		return
+	}
+	if mfa != nil && mfa.TOTPEnabled {
+		c.JSON(http.StatusConflict, ErrorResponse{Error: "two-factor authentication is already enabled"})
+		return
+	}
+
+	secret, err := auth.GenerateTOTPSecret()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to generate secret"})
+		return
+	}
+	if err := h.mfaRepo.SaveTOTPSecret(userID, secret); err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to save secret"})
+		return
+	}
+
+	c.JSON(http.StatusOK, TOTPEnrollmentResponse{
+		Secret:          secret,
+		ProvisioningURI: auth.TOTPProvisioningURI(secret, totpIssuer, user.Email),
+	})
+}
+
+// ConfirmTOTP enables TOTP after the user proves their app is set up (POST /api/users/mfa/totp/verify).
+// @Summary Confirm TOTP enrolment
+// @Description Verifies a code from the authenticator app, enables two-factor authentication and returns one-time recovery codes.
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param payload body MFACodeRequest true "TOTP code"
+// @Success 200 {object} RecoveryCodesResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/users/mfa/totp/verify [post]
+func (h *MFAHandler) ConfirmTOTP(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c.Request.Context())
+	if !ok {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "user not authenticated"})
+		return
+	}
+
+	var req MFACodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "invalid request body"})
+		return
+	}
+
+	mfa, err := h.mfaRepo.Get(userID)
+	if err == repo.ErrMFANotFound {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "start enrolment first"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to load mfa settings"})
+		return
+	}
+	if mfa.TOTPEnabled {
+		c.JSON(http.StatusConflict, ErrorResponse{Error: "two-factor authentication is already enabled"})
+		return
+	}
+
+	step, valid := auth.ValidateTOTP(mfa.TOTPSecret, req.Code, time.Now())
+	if !valid {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "invalid code"})
+		return
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to generate recovery codes"})
+		return
+	}
+	if err := h.mfaRepo.Enable(userID, step, hashes); err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to enable two-factor authentication"})
+		return
+	}
+
+	c.JSON(http.StatusOK, RecoveryCodesResponse{RecoveryCodes: codes})
+}
+
+// DisableTOTP turns two-factor authentication off (DELETE /api/users/mfa/totp).
+// @Summary Disable TOTP
+// @Description Removes the TOTP secret and recovery codes. Requires a current TOTP or recovery code.
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param payload body MFACodeRequest true "TOTP or recovery code"
+// @Success 200 {object} MessageResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/users/mfa/totp [delete]
+func (h *MFAHandler) DisableTOTP(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c.Request.Context())
+	if !ok {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "user not authenticated"})
+		return
+	}
+
+	var req MFACodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "invalid request body"})
+		return
+	}
+
+	mfa, ok := h.loadEnabledMFA(c, userID)
+	if !ok {
+		return
+	}
+
+	valid, err := verifySecondFactor(h.mfaRepo, mfa, req.Code, true)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to verify code"})
+		return
+	}
+	if !valid {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "invalid code"})
+		return
+	}
+
+	if err := h.mfaRepo.Disable(userID); err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to disable two-factor authentication"})
+		return
+	}
+
+	c.JSON(http.StatusOK, MessageResponse{Message: "two-factor authentication disabled"})
+}
+
+// RegenerateRecoveryCodes replaces all recovery codes (POST /api/users/mfa/recovery_codes).
+// @Summary Regenerate recovery codes
+// @Description Invalidates existing recovery codes and returns a new set. Requires a current TOTP code.
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param payload body MFACodeRequest true "TOTP code"
+// @Success 200 {object} RecoveryCodesResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/users/mfa/recovery_codes [post]
+func (h *MFAHandler) RegenerateRecoveryCodes(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c.Request.Context())
+	if !ok {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "user not authenticated"})
+		return
+	}
+
+	var req MFACodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "invalid request body"})
+		return
+	}
+
+	mfa, ok := h.loadEnabledMFA(c, userID)
+	if !ok {
+		return
+	}
+
+	valid, err := verifySecondFactor(h.mfaRepo, mfa, req.Code, false)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to verify code"})
+		return
+	}
+	if !valid {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "invalid code"})
+		return
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to generate recovery codes"})
+		return
+	}
+	if err := h.mfaRepo.ReplaceRecoveryCodes(userID, hashes); err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to save recovery codes"})
+		return
+	}
+
+	c.JSON(http.StatusOK, RecoveryCodesResponse{RecoveryCodes: codes})
+}
@@ -0,0 +1,358 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Viet-CodingStars/kyupi-kyupi-backend/internal/auth"
+	"github.com/Viet-CodingStars/kyupi-kyupi-backend/internal/middleware"
+	"github.com/Viet-CodingStars/kyupi-kyupi-backend/internal/models"
+	"github.com/Viet-CodingStars/kyupi-kyupi-backend/internal/repo"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// mockMFARepo implements a mock MFA repository for testing
+type mockMFARepo struct {
+	settings       map[uuid.UUID]*models.UserMFA
+	recoveryCodes  map[uuid.UUID]map[string]bool // hash -> used
+	challenges     map[uuid.UUID]uuid.UUID       // active challenge -> user
+	failedAttempts map[uuid.UUID]int
+}
+
+func newMockMFARepo() *mockMFARepo {
+	return &mockMFARepo{
+		settings:       make(map[uuid.UUID]*models.UserMFA),
+		recoveryCodes:  make(map[uuid.UUID]map[string]bool),
+		challenges:     make(map[uuid.UUID]uuid.UUID),
+		failedAttempts: make(map[uuid.UUID]int),
+	}
+}
+
+func (m *mockMFARepo) Get(userID uuid.UUID) (*models.UserMFA, error) {
+	mfa, exists := m.settings[userID]
+	if !exists {
+		return nil, repo.ErrMFANotFound
+	}
+	copied := *mfa
+	return &copied, nil
+}
+
+func (m *mockMFARepo) SaveTOTPSecret(userID uuid.UUID, secret string) error {
+	if mfa, exists := m.settings[userID]; exists && mfa.TOTPEnabled {
+		return nil
+	}
+	m.settings[userID] = &models.UserMFA{UserID: userID, TOTPSecret: secret, CreatedAt: time.Now(), UpdatedAt: time.Now()}
+	return nil
+}
+
+func (m *mockMFARepo) Enable(userID uuid.UUID, step int64, recoveryCodeHashes []string) error {
+	mfa, exists := m.settings[userID]
+	if !exists {
+		return repo.ErrMFANotFound
+	}
+	now := time.Now()
+	mfa.TOTPEnabled = true
+	mfa.TOTPLastStep = step
+	mfa.EnabledAt = &now
+	return m.ReplaceRecoveryCodes(userID, recoveryCodeHashes)
+}
+
+func (m *mockMFARepo) ConsumeTOTPStep(userID uuid.UUID, step int64) (bool, error) {
+	mfa, exists := m.settings[userID]
+	if !exists || mfa.TOTPLastStep >= step {
+		return false, nil
+	}
+	mfa.TOTPLastStep = step
+	return true, nil
+}
+
+func (m *mockMFARepo) ConsumeRecoveryCode(userID uuid.UUID, codeHash string) (bool, error) {
+	used, exists := m.recoveryCodes[userID][codeHash]
+	if !exists || used {
+		return false, nil
+	}
+	m.recoveryCodes[userID][codeHash] = true
+	return true, nil
+}
+
+func (m *mockMFARepo) CountRecoveryCodes(userID uuid.UUID) (int, error) {
+	count := 0
+	for _, used := range m.recoveryCodes[userID] {
+		if !used {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (m *mockMFARepo) ReplaceRecoveryCodes(userID uuid.UUID, codeHashes []string) error {
+	codes := make(map[string]bool, len(codeHashes))
+	for _, hash := range codeHashes {
+		codes[hash] = false
+	}
+	m.recoveryCodes[userID] = codes
+	return nil
+}
+
+func (m *mockMFARepo) Disable(userID uuid.UUID) error {
+	delete(m.settings, userID)
+	delete(m.recoveryCodes, userID)
+	return nil
+}
+
+func (m *mockMFARepo) CreateChallenge(userID uuid.UUID, ttl time.Duration) (uuid.UUID, error) {
+	id := uuid.New()
+	m.challenges[id] = userID
+	return id, nil
+}
+
+func (m *mockMFARepo) ChallengeActive(id, userID uuid.UUID) (bool, error) {
+	owner, exists := m.challenges[id]
+	return exists && owner == userID, nil
+}
+
+func (m *mockMFARepo) ConsumeChallenge(id, userID uuid.UUID) (bool, error) {
+	active, _ := m.ChallengeActive(id, userID)
+	delete(m.challenges, id)
+	return active, nil
+}
+
+func (m *mockMFARepo) RecordFailedAttempt(userID uuid.UUID, maxAttempts int, lockout time.Duration) (bool, error) {
+	m.failedAttempts[userID]++
+	if m.failedAttempts[userID] >= maxAttempts {
+		m.failedAttempts[userID] = 0
+		m.settings[userID].Locked = true
+	}
+	return m.settings[userID].Locked, nil
+}
+
+func (m *mockMFARepo) ResetFailedAttempts(userID uuid.UUID) error {
+	m.failedAttempts[userID] = 0
+	return nil
+}
+
+// performMFARequest invokes fn with a JSON body, authenticated as userID unless it is uuid.Nil.
+func performMFARequest(fn gin.HandlerFunc, method string, userID uuid.UUID, body interface{}) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+
+	var buf bytes.Buffer
+	if body != nil {
+		json.NewEncoder(&buf).Encode(body)
+	}
+	req := httptest.NewRequest(method, "/", &buf)
+	req.Header.Set("Content-Type", "application/json")
+	if userID != uuid.Nil {
+		req = req.WithContext(context.WithValue(req.Context(), middleware.UserIDKey, userID))
+	}
+	c.Request = req
+
+	fn(c)
+	return w
+}
+
+// enableTOTP enrols and confirms TOTP for the user, returning the secret and recovery codes.
+func enableTOTP(t *testing.T, handler *MFAHandler, userID uuid.UUID) (string, []string) {
+	t.Helper()
+	w := performMFARequest(handler.EnrollTOTP, http.MethodPost, userID, nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected enroll status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var enrollment TOTPEnrollmentResponse
+	json.Unmarshal(w.Body.Bytes(), &enrollment)
+
+	code, _ := auth.TOTPCode(enrollment.Secret, auth.TOTPStep(time.Now()))
+	w = performMFARequest(handler.ConfirmTOTP, http.MethodPost, userID, MFACodeRequest{Code: code})
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected confirm status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var recovery RecoveryCodesResponse
+	json.Unmarshal(w.Body.Bytes(), &recovery)
+	return enrollment.Secret, recovery.RecoveryCodes
+}
+
+func newMFATestHandler(t *testing.T) (*MFAHandler, *models.User) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	userRepo := newMockUserRepo()
+	hashedPassword, _ := auth.HashPassword("password123")
+	user := &models.User{Email: "test@example.com", PasswordHash: hashedPassword, Name: "Test User", Gender: models.GenderMale}
+	userRepo.Create(user)
+
+	return &MFAHandler{mfaRepo: newMockMFARepo(), userRepo: userRepo, sessionRepo: newMockSessionRepo(), keys: auth.NewHMACKeySet("test-secret")}, user
+}
+
+func TestMFAEnrollment(t *testing.T) {
+	handler, user := newMFATestHandler(t)
+
+	w := performMFARequest(handler.EnrollTOTP, http.MethodPost, user.ID, nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", w.Code)
+	}
+	var enrollment TOTPEnrollmentResponse
+	json.Unmarshal(w.Body.Bytes(), &enrollment)
+	if enrollment.Secret == "" || enrollment.ProvisioningURI == "" {
+		t.Fatalf("unexpected enrollment response: %+v", enrollment)
+	}
+
+	w = performMFARequest(handler.ConfirmTOTP, http.MethodPost, user.ID, MFACodeRequest{Code: "000000"})
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected status 400 for wrong code, got %d", w.Code)
+	}
+
+	code, _ := auth.TOTPCode(enrollment.Secret, auth.TOTPStep(time.Now()))
+	w = performMFARequest(handler.ConfirmTOTP, http.MethodPost, user.ID, MFACodeRequest{Code: code})
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var recovery RecoveryCodesResponse
+	json.Unmarshal(w.Body.Bytes(), &recovery)
+	if len(recovery.RecoveryCodes) != recoveryCodeCount {
+		t.Fatalf("expected %d recovery codes, got %d", recoveryCodeCount, len(recovery.RecoveryCodes))
+	}
+
+	w = performMFARequest(handler.EnrollTOTP, http.MethodPost, user.ID, nil)
+	if w.Code != http.StatusConflict {
+		t.Fatalf("expected status 409 when already enabled, got %d", w.Code)
+	}
+
+	w = performMFARequest(handler.GetStatus, http.MethodGet, user.ID, nil)
+	var status MFAStatusResponse
+	json.Unmarshal(w.Body.Bytes(), &status)
+	if !status.TOTPEnabled || status.RecoveryCodesRemaining != recoveryCodeCount {
+		t.Fatalf("unexpected status: %+v", status)
+	}
+}
+
+func TestMFASignIn(t *testing.T) {
+	handler, user := newMFATestHandler(t)
+	secret, recoveryCodes := enableTOTP(t, handler, user.ID)
+	userHandler := &UserHandler{userRepo: handler.userRepo, mfaRepo: handler.mfaRepo, sessionRepo: handler.sessionRepo, keys: handler.keys}
+
+	challenge := func(t *testing.T) string {
+		t.Helper()
+		w := performMFARequest(userHandler.SignIn, http.MethodPost, uuid.Nil, SignInRequest{Email: user.Email, Password: "password123"})
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d", w.Code)
+		}
+		var response MFAChallengeResponse
+		json.Unmarshal(w.Body.Bytes(), &response)
+		if !response.MFARequired || response.MFAToken == "" {
+			t.Fatalf("expected MFA challenge, got %s", w.Body.String())
+		}
+		if _, err := handler.keys.ValidateToken(response.MFAToken); err == nil {
+			t.Fatal("expected challenge token to be rejected as session token")
+		}
+		return response.MFAToken
+	}
+
+	t.Run("totp code completes sign in once", func(t *testing.T) {
+		token := challenge(t)
+		// The confirmation step consumed the current step, so use the next one.
+		code, _ := auth.TOTPCode(secret, auth.TOTPStep(time.Now())+1)
+
+		w := performMFARequest(handler.SignIn, http.MethodPost, uuid.Nil, MFASignInRequest{MFAToken: token, Code: code})
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+		}
+		var response AuthResponse
+		json.Unmarshal(w.Body.Bytes(), &response)
+		if _, err := handler.keys.ValidateToken(response.Token); err != nil {
+			t.Fatalf("expected valid session token, got %v", err)
+		}
+
+		w = performMFARequest(handler.SignIn, http.MethodPost, uuid.Nil, MFASignInRequest{MFAToken: challenge(t), Code: code})
+		if w.Code != http.StatusUnauthorized {
+			t.Fatalf("expected replayed code to be rejected with a fresh challenge, got %d", w.Code)
+		}
+	})
+
+	t.Run("recovery code is single use", func(t *testing.T) {
+		token := challenge(t)
+		w := performMFARequest(handler.SignIn, http.MethodPost, uuid.Nil, MFASignInRequest{MFAToken: token, Code: recoveryCodes[0]})
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+		}
+		w = performMFARequest(handler.SignIn, http.MethodPost, uuid.Nil, MFASignInRequest{MFAToken: token, Code: recoveryCodes[0]})
+		if w.Code != http.StatusUnauthorized {
+			t.Fatalf("expected used recovery code to be rejected, got %d", w.Code)
+		}
+	})
+
+	t.Run("challenge token completes one sign in only", func(t *testing.T) {
+		token := challenge(t)
+		w := performMFARequest(handler.SignIn, http.MethodPost, uuid.Nil, MFASignInRequest{MFAToken: token, Code: recoveryCodes[1]})
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+		}
+		w = performMFARequest(handler.SignIn, http.MethodPost, uuid.Nil, MFASignInRequest{MFAToken: token, Code: recoveryCodes[2]})
+		if w.Code != http.StatusUnauthorized {
+			t.Fatalf("expected a used challenge to be rejected, got %d", w.Code)
+		}
+	})
+
+	t.Run("locks out after repeated wrong codes", func(t *testing.T) {
+		token := challenge(t)
+		for i := 1; i < mfaMaxFailedAttempts; i++ {
+			w := performMFARequest(handler.SignIn, http.MethodPost, uuid.Nil, MFASignInRequest{MFAToken: token, Code: "000000"})
+			if w.Code != http.StatusUnauthorized {
+				t.Fatalf("attempt %d: expected status 401, got %d", i, w.Code)
+			}
+		}
+		w := performMFARequest(handler.SignIn, http.MethodPost, uuid.Nil, MFASignInRequest{MFAToken: token, Code: "000000"})
+		if w.Code != http.StatusTooManyRequests {
+			t.Fatalf("expected lockout, got %d", w.Code)
+		}
+
+		// Even the right code on a fresh challenge is refused while locked.
+		w = performMFARequest(handler.SignIn, http.MethodPost, uuid.Nil, MFASignInRequest{MFAToken: challenge(t), Code: recoveryCodes[3]})
+		if w.Code != http.StatusTooManyRequests {
+			t.Fatalf("expected status 429 while locked, got %d", w.Code)
+		}
+
+		// The challenge that hit the limit stays spent once the lock is lifted.
+		handler.mfaRepo.(*mockMFARepo).settings[user.ID].Locked = false
+		w = performMFARequest(handler.SignIn, http.MethodPost, uuid.Nil, MFASignInRequest{MFAToken: token, Code: recoveryCodes[3]})
+		if w.Code != http.StatusUnauthorized {
+			t.Fatalf("expected the locked out challenge to be consumed, got %d", w.Code)
+		}
+	})
+
+	t.Run("session token is not a challenge token", func(t *testing.T) {
+		sessionToken, _ := handler.keys.GenerateToken(user.ID, user.Email, uuid.New(), time.Now().Add(auth.TokenTTL))
+		code, _ := auth.TOTPCode(secret, auth.TOTPStep(time.Now())+1)
+		w := performMFARequest(handler.SignIn, http.MethodPost, uuid.Nil, MFASignInRequest{MFAToken: sessionToken, Code: code})
+		if w.Code != http.StatusUnauthorized {
+			t.Fatalf("expected status 401, got %d", w.Code)
+		}
+	})
+}
+
+func TestDisableTOTP(t *testing.T) {
+	handler, user := newMFATestHandler(t)
+	_, recoveryCodes := enableTOTP(t, handler, user.ID)
+
+	w := performMFARequest(handler.DisableTOTP, http.MethodDelete, user.ID, MFACodeRequest{Code: "bad-code"})
+	if w.Code != http.StatusUnauthorized {
+		t.Fatalf("expected status 401, got %d", w.Code)
+	}
+
+	w = performMFARequest(handler.DisableTOTP, http.MethodDelete, user.ID, MFACodeRequest{Code: recoveryCodes[1]})
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	userHandler := &UserHandler{userRepo: handler.userRepo, mfaRepo: handler.mfaRepo, sessionRepo: handler.sessionRepo, keys: handler.keys}
+	w = performMFARequest(userHandler.SignIn, http.MethodPost, uuid.Nil, SignInRequest{Email: user.Email, Password: "password123"})
+	var response AuthResponse
+	json.Unmarshal(w.Body.Bytes(), &response)
+	if response.Token == "" {
+		t.Fatalf("expected session token after disabling MFA, got %s", w.Body.String())
+	}
+}
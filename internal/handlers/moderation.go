@@ -0,0 +1,127 @@
+package handlers
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+
+	"github.com/Viet-CodingStars/kyupi-kyupi-backend/internal/middleware"
+	"github.com/Viet-CodingStars/kyupi-kyupi-backend/internal/models"
+	"github.com/Viet-CodingStars/kyupi-kyupi-backend/internal/repo"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// ModerationRepository declares the minimal persistence operations required by ModerationHandler.
+type ModerationRepository interface {
+	ListOpen(limit int) ([]*models.ModerationFlag, error)
+	Dismiss(id, adminID uuid.UUID) (*models.ModerationFlag, error)
+	Confirm(id, adminID uuid.UUID) (*models.ModerationFlag, error)
+}
+
+type ModerationHandler struct {
+	moderationRepo ModerationRepository
+}
+
+func NewModerationHandler(db *sql.DB) *ModerationHandler {
+	return &ModerationHandler{moderationRepo: repo.NewModerationRepo(db)}
+}
+
+// FlagsResponse lists the moderation queue.
+type FlagsResponse struct {
+	Flags []*models.ModerationFlag `json:"flags"`
+}
+
+// ListFlags returns suspected fake accounts waiting for review (GET /api/admin/flags).
+// @Summary List the moderation queue
+// @Description Returns open flags raised against suspected duplicate or fake accounts, oldest first, with whether each account is shadow-restricted. Admins only.
+// @Tags Admin
+// @Produce json
+// @Security BearerAuth
+// @Param limit query int false "Number of flags to return (default: 20, max: 100)"
+// @Success 200 {object} FlagsResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/admin/flags [get]
+func (h *ModerationHandler) ListFlags(c *gin.Context) {
+	limit := 20
+	if limitStr := c.Query("limit"); limitStr != "" {
+		fmt.Sscanf(limitStr, "%d", &limit)
+		if limit < 1 || limit > 100 {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: "limit must be between 1 and 100"})
+			return
+		}
+	}
+
+	queue, err := h.moderationRepo.ListOpen(limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to retrieve flags"})
+		return
+	}
+
+	c.JSON(http.StatusOK, FlagsResponse{Flags: queue})
+}
+
+// DismissFlag closes a flag as a false alarm (POST /api/admin/flags/:flag_id/dismiss).
+// @Summary Dismiss a moderation flag
+// @Description Closes an open flag as a false alarm. The account's shadow restriction is lifted unless another flag against it is open or confirmed. The same suspicion is not raised again. Admins only.
+// @Tags Admin
+// @Produce json
+// @Security BearerAuth
+// @Param flag_id path string true "Flag ID"
+// @Success 200 {object} models.ModerationFlag
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/admin/flags/{flag_id}/dismiss [post]
+func (h *ModerationHandler) DismissFlag(c *gin.Context) {
+	h.resolve(c, h.moderationRepo.Dismiss)
+}
+
+// ConfirmFlag closes a flag as a fake account (POST /api/admin/flags/:flag_id/confirm).
+// @Summary Confirm a moderation flag
+// @Description Closes an open flag as a fake account and shadow-restricts it, so it no longer appears to anybody else. Admins only.
+// @Tags Admin
+// @Produce json
+// @Security BearerAuth
+// @Param flag_id path string true "Flag ID"
+// @Success 200 {object} models.ModerationFlag
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/admin/flags/{flag_id}/confirm [post]
+func (h *ModerationHandler) ConfirmFlag(c *gin.Context) {
+	h.resolve(c, h.moderationRepo.Confirm)
+}
+
+func (h *ModerationHandler) resolve(c *gin.Context, resolve func(id, adminID uuid.UUID) (*models.ModerationFlag, error)) {
+	adminID, ok := middleware.GetUserID(c.Request.Context())
+	if !ok {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "user not authenticated"})
+		return
+	}
+
+	id, err := uuid.Parse(c.Param("flag_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "invalid flag ID"})
+		return
+	}
+
+	flag, err := resolve(id, adminID)
+	if err == repo.ErrFlagNotFound {
+		c.JSON(http.StatusNotFound, ErrorResponse{Error: "flag not found or already resolved"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to resolve flag"})
+		return
+	}
+
+	c.JSON(http.StatusOK, flag)
+}
@@ -0,0 +1,119 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Viet-CodingStars/kyupi-kyupi-backend/internal/middleware"
+	"github.com/Viet-CodingStars/kyupi-kyupi-backend/internal/models"
+	"github.com/Viet-CodingStars/kyupi-kyupi-backend/internal/repo"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type mockModerationRepo struct {
+	flags []*models.ModerationFlag
+}
+
+func (m *mockModerationRepo) ListOpen(limit int) ([]*models.ModerationFlag, error) {
+	queue := make([]*models.ModerationFlag, 0)
+	for _, f := range m.flags {
+		if f.Status == models.FlagOpen && len(queue) < limit {
+			queue = append(queue, f)
+		}
+	}
+	return queue, nil
+}
+
+func (m *mockModerationRepo) Dismiss(id, adminID uuid.UUID) (*models.ModerationFlag, error) {
+	f, err := m.resolve(id, adminID, models.FlagDismissed)
+	if err != nil {
+		return nil, err
+	}
+	f.Restricted = false
+	for _, other := range m.flags {
+		if other.UserID == f.UserID && other.Status != models.FlagDismissed {
+			f.Restricted = true
+		}
+	}
+	return f, nil
+}
+
+func (m *mockModerationRepo) Confirm(id, adminID uuid.UUID) (*models.ModerationFlag, error) {
+	f, err := m.resolve(id, adminID, models.FlagConfirmed)
+	if err != nil {
+		return nil, err
+	}
+	f.Restricted = true
+	return f, nil
+}
+
+func (m *mockModerationRepo) resolve(id, adminID uuid.UUID, status string) (*models.ModerationFlag, error) {
+	for _, f := range m.flags {
+		if f.ID == id && f.Status == models.FlagOpen {
+			now := time.Now()
+			f.Status, f.ResolvedBy, f.ResolvedAt = status, &adminID, &now
+			return f, nil
+		}
+	}
+	return nil, repo.ErrFlagNotFound
+}
+
+func TestModerationQueue(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	adminID, suspectID, originalID := uuid.New(), uuid.New(), uuid.New()
+	alias := &models.ModerationFlag{ID: uuid.New(), UserID: suspectID, Reason: models.FlagEmailAlias, RelatedUserID: &originalID, Status: models.FlagOpen, Restricted: true}
+	burst := &models.ModerationFlag{ID: uuid.New(), UserID: suspectID, Reason: models.FlagSignupBurst, Status: models.FlagOpen, Restricted: true}
+	moderationRepo := &mockModerationRepo{flags: []*models.ModerationFlag{alias, burst}}
+	handler := &ModerationHandler{moderationRepo: moderationRepo}
+
+	request := func(fn gin.HandlerFunc, path string, params gin.Params) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Params = params
+		req := httptest.NewRequest(http.MethodPost, path, nil)
+		c.Request = req.WithContext(context.WithValue(req.Context(), middleware.UserIDKey, adminID))
+		fn(c)
+		return w
+	}
+	decode := func(w *httptest.ResponseRecorder) *models.ModerationFlag {
+		var f models.ModerationFlag
+		json.Unmarshal(w.Body.Bytes(), &f)
+		return &f
+	}
+
+	w := request(handler.ListFlags, "/api/admin/flags?limit=1", nil)
+	var queue FlagsResponse
+	json.Unmarshal(w.Body.Bytes(), &queue)
+	if w.Code != http.StatusOK || len(queue.Flags) != 1 || queue.Flags[0].ID != alias.ID {
+		t.Fatalf("expected the oldest flag, got %d: %s", w.Code, w.Body.String())
+	}
+	if w := request(handler.ListFlags, "/api/admin/flags?limit=0", nil); w.Code != http.StatusBadRequest {
+		t.Fatalf("expected status 400, got %d", w.Code)
+	}
+
+	if w := request(handler.DismissFlag, "/dismiss", gin.Params{{Key: "flag_id", Value: "not-a-uuid"}}); w.Code != http.StatusBadRequest {
+		t.Fatalf("expected status 400, got %d", w.Code)
+	}
+
+	params := gin.Params{{Key: "flag_id", Value: alias.ID.String()}}
+	w = request(handler.DismissFlag, "/dismiss", params)
+	if dismissed := decode(w); w.Code != http.StatusOK || dismissed.Status != models.FlagDismissed || !dismissed.Restricted {
+		t.Fatalf("expected the flag dismissed with the account still restricted by the other flag, got %d: %s", w.Code, w.Body.String())
+	}
+	if alias.ResolvedBy == nil || *alias.ResolvedBy != adminID {
+		t.Fatal("expected the admin to be recorded")
+	}
+	if w := request(handler.ConfirmFlag, "/confirm", params); w.Code != http.StatusNotFound {
+		t.Fatalf("expected a resolved flag not to be resolved again, got %d", w.Code)
+	}
+
+	w = request(handler.ConfirmFlag, "/confirm", gin.Params{{Key: "flag_id", Value: burst.ID.String()}})
+	if confirmed := decode(w); w.Code != http.StatusOK || confirmed.Status != models.FlagConfirmed || !confirmed.Restricted {
+		t.Fatalf("expected the flag confirmed and the account restricted, got %d: %s", w.Code, w.Body.String())
+	}
+}
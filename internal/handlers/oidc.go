@@ -0,0 +1,455 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"time"
+
+	"github.com/Viet-CodingStars/kyupi-kyupi-backend/internal/auth"
+	"github.com/Viet-CodingStars/kyupi-kyupi-backend/internal/middleware"
+	"github.com/Viet-CodingStars/kyupi-kyupi-backend/internal/models"
+	"github.com/Viet-CodingStars/kyupi-kyupi-backend/internal/oidc"
+	"github.com/Viet-CodingStars/kyupi-kyupi-backend/internal/repo"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+const (
+	// oidcLoginStateTTL bounds how long a user may take at the provider's consent screen.
+	oidcLoginStateTTL = 10 * time.Minute
+	// oidcRegistrationTTL bounds how long a new social user has to complete their profile.
+	oidcRegistrationTTL     = 15 * time.Minute
+	purposeOIDCRegistration = "oidc_registration"
+	// oidcReauthenticationTTL bounds how long a fresh provider sign-in counts as re-authentication.
+	oidcReauthenticationTTL     = 5 * time.Minute
+	purposeOIDCReauthentication = "oidc_reauthentication"
+)
+
+// OIDCProvider declares the relying-party operations required by OIDCHandler.
+type OIDCProvider interface {
+	AuthCodeURL(ctx context.Context, state, nonce, codeChallenge string) (string, error)
+	Authenticate(ctx context.Context, code, codeVerifier, nonce string) (*oidc.Identity, error)
+}
+
+// IdentityRepository declares the minimal persistence operations required by OIDCHandler.
+type IdentityRepository interface {
+	Create(identity *models.Identity) error
+	GetByProviderSubject(provider, subject string) (*models.Identity, error)
+	SaveLoginState(state *models.OIDCLoginState) error
+	ConsumeLoginState(state string) (*models.OIDCLoginState, error)
+}
+
+type OIDCHandler struct {
+	providers    map[string]OIDCProvider
+	identityRepo IdentityRepository
+	userRepo     UserRepository
+	mfaRepo      MFARepository
+	sessionRepo  SessionRepository
+	keys         *auth.KeySet
+}
+
+func NewOIDCHandler(db *sql.DB, keys *auth.KeySet, providers map[string]OIDCProvider) *OIDCHandler {
+	return &OIDCHandler{
+		providers:    providers,
+		identityRepo: repo.NewIdentityRepo(db),
+		userRepo:     repo.NewUserRepo(db),
+		mfaRepo:      repo.NewMFARepo(db),
+		sessionRepo:  repo.NewSessionRepo(db),
+		keys:         keys,
+	}
+}
+
+// OIDCAuthorizeResponse carries the provider URL the client should navigate to.
+type OIDCAuthorizeResponse struct {
+	AuthorizationURL string `json:"authorization_url"`
+	State            string `json:"state"`
+}
+
+// OIDCCallbackRequest carries the parameters the provider redirected back with.
+type OIDCCallbackRequest struct {
+	Code  string `json:"code" binding:"required"`
+	State string `json:"state" binding:"required"`
+}
+
+// OIDCRegistrationResponse is returned when the external identity has no local account yet.
+type OIDCRegistrationResponse struct {
+	RegistrationRequired bool   `json:"registration_required"`
+	RegistrationToken    string `json:"registration_token"`
+	Email                string `json:"email"`
+	Name                 string `json:"name,omitempty"`
+}
+
+// OIDCRegisterRequest completes a social sign-up with the profile fields providers do not supply.
+type OIDCRegisterRequest struct {
+	RegistrationToken string  `json:"registration_token" binding:"required"`
+	Name              *string `json:"name,omitempty"`
+	Gender            int     `json:"gender"`
+	BirthDate         string  `json:"birth_date"` // "YYYY-MM-DD"
+	TargetGender      *int    `json:"target_gender,omitempty"`
+	Intention         *string `json:"intention,omitempty"`
+}
+
+// OIDCReauthenticationResponse is returned by the callback of a flow started to re-authenticate.
+type OIDCReauthenticationResponse struct {
+	ReauthenticationToken string `json:"reauthentication_token"`
+}
+
+// oidcReauthenticationClaims is the signed proof that a signed-in user just signed in at their
+// provider again. The subject is the local user ID.
+type oidcReauthenticationClaims struct {
+	Purpose string `json:"purpose"`
+	jwt.RegisteredClaims
+}
+
+// oidcRegistrationClaims is the signed proof of a verified external identity awaiting registration.
+type oidcRegistrationClaims struct {
+	Purpose  string `json:"purpose"`
+	Provider string `json:"provider"`
+	Email    string `json:"email"`
+	Name     string `json:"name,omitempty"`
+	jwt.RegisteredClaims
+}
+
+// Authorize starts an authorization code flow with PKCE (GET /api/auth/oidc/:provider/authorize).
+// @Summary Start social login
+// @Description Creates a one-time state, nonce and PKCE verifier and returns the provider authorization URL.
+// @Tags Auth
+// @Produce json
+// @Param provider path string true "Provider name, e.g. google"
+// @Success 200 {object} OIDCAuthorizeResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Failure 502 {object} ErrorResponse
+// @Router /api/auth/oidc/{provider}/authorize [get]
+func (h *OIDCHandler) Authorize(c *gin.Context) {
+	h.startAuthorization(c, nil)
+}
+
+// AuthorizeReauthentication starts a provider sign-in that proves the current user is present
+// (GET /api/users/reauthenticate/:provider/authorize).
+// @Summary Start social re-authentication
+// @Description For accounts without a password (created through social login). Returns the provider authorization URL; completing it through the usual callback endpoint yields a short-lived reauthentication_token instead of a session, accepted by sensitive operations such as account deletion.
+// @Tags Auth
+// @Produce json
+// @Security BearerAuth
+// @Param provider path string true "Provider name, e.g. google"
+// @Success 200 {object} OIDCAuthorizeResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Failure 502 {object} ErrorResponse
+// @Router /api/users/reauthenticate/{provider}/authorize [get]
+func (h *OIDCHandler) AuthorizeReauthentication(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c.Request.Context())
+	if !ok {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "user not authenticated"})
+		return
+	}
+	h.startAuthorization(c, &userID)
+}
+
+// startAuthorization stores a one-time login state and returns the provider URL. userID is set
+// when the flow re-authenticates a signed-in user.
+func (h *OIDCHandler) startAuthorization(c *gin.Context, userID *uuid.UUID) {
+	providerName := c.Param("provider")
+	provider, ok := h.providers[providerName]
+	if !ok {
+		c.JSON(http.StatusNotFound, ErrorResponse{Error: "unknown provider"})
+		return
+	}
+
+	state, err := oidc.RandomToken()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to start login"})
+		return
+	}
+	nonce, err := oidc.RandomToken()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to start login"})
+		return
+	}
+	verifier, err := oidc.RandomToken()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to start login"})
+		return
+	}
+
+	authURL, err := provider.AuthCodeURL(c.Request.Context(), state, nonce, oidc.CodeChallengeS256(verifier))
+	if err != nil {
+		c.JSON(http.StatusBadGateway, ErrorResponse{Error: "identity provider unavailable"})
+		return
+	}
+
+	err = h.identityRepo.SaveLoginState(&models.OIDCLoginState{
+		State:        state,
+		Provider:     providerName,
+		Nonce:        nonce,
+		CodeVerifier: verifier,
+		UserID:       userID,
+		ExpiresAt:    time.Now().Add(oidcLoginStateTTL),
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to start login"})
+		return
+	}
+
+	c.JSON(http.StatusOK, OIDCAuthorizeResponse{AuthorizationURL: authURL, State: state})
+}
+
+// Callback completes the authorization code flow (POST /api/auth/oidc/:provider/callback).
+// @Summary Complete social login
+// @Description Exchanges the authorization code, verifies the ID token and signs the user in. Identities are linked to an existing account when the provider verified the same email. Unknown users receive a registration token instead (202), and users with two-factor authentication enabled receive an MFA challenge token. Flows started through the re-authentication endpoint receive a reauthentication token instead of a session.
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param provider path string true "Provider name, e.g. google"
+// @Param payload body OIDCCallbackRequest true "Authorization response"
+// @Success 200 {object} AuthResponse
+// @Success 200 {object} MFAChallengeResponse
+// @Success 200 {object} OIDCReauthenticationResponse
+// @Success 202 {object} OIDCRegistrationResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/auth/oidc/{provider}/callback [post]
+func (h *OIDCHandler) Callback(c *gin.Context) {
+	providerName := c.Param("provider")
+	provider, ok := h.providers[providerName]
+	if !ok {
+		c.JSON(http.StatusNotFound, ErrorResponse{Error: "unknown provider"})
+		return
+	}
+
+	var req OIDCCallbackRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "invalid request body"})
+		return
+	}
+
+	loginState, err := h.identityRepo.ConsumeLoginState(req.State)
+	if err != nil {
+		if err == repo.ErrLoginStateNotFound {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: "invalid or expired state"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to verify state"})
+		return
+	}
+	if loginState.Provider != providerName {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "invalid or expired state"})
+		return
+	}
+
+	external, err := provider.Authenticate(c.Request.Context(), req.Code, loginState.CodeVerifier, loginState.Nonce)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "failed to authenticate with provider"})
+		return
+	}
+
+	if loginState.UserID != nil {
+		h.respondWithReauthentication(c, providerName, external, *loginState.UserID)
+		return
+	}
+
+	identity, err := h.identityRepo.GetByProviderSubject(providerName, external.Subject)
+	if err == nil {
+		user, err := h.userRepo.GetByID(identity.UserID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to load user"})
+			return
+		}
+		respondWithSession(c, h.keys, h.mfaRepo, h.sessionRepo, user)
+		return
+	}
+	if err != repo.ErrIdentityNotFound {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to look up identity"})
+		return
+	}
+
+	if external.Email == "" {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "provider did not share an email address"})
+		return
+	}
+
+	user, err := h.userRepo.GetByEmail(external.Email)
+	switch {
+	case err == nil:
+		// Only link to an existing account when the provider vouches for the email;
+		// otherwise anyone could claim an account by registering the address elsewhere.
+		if !external.EmailVerified {
+			c.JSON(http.StatusConflict, ErrorResponse{Error: "an account with this email already exists; sign in with your password"})
+			return
+		}
+		err = h.identityRepo.Create(&models.Identity{
+			UserID:   user.ID,
+			Provider: providerName,
+			Subject:  external.Subject,
+			Email:    external.Email,
+		})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to link identity"})
+			return
+		}
+		respondWithSession(c, h.keys, h.mfaRepo, h.sessionRepo, user)
+	case err == repo.ErrUserNotFound:
+		now := time.Now()
+		token, err := h.keys.SignClaims(oidcRegistrationClaims{
+			Purpose:  purposeOIDCRegistration,
+			Provider: providerName,
+			Email:    external.Email,
+			Name:     external.Name,
+			RegisteredClaims: jwt.RegisteredClaims{
+				Subject:   external.Subject,
+				IssuedAt:  jwt.NewNumericDate(now),
+				ExpiresAt: jwt.NewNumericDate(now.Add(oidcRegistrationTTL)),
+			},
+		})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to generate token"})
+			return
+		}
+		c.JSON(http.StatusAccepted, OIDCRegistrationResponse{
+			RegistrationRequired: true,
+			RegistrationToken:    token,
+			Email:                external.Email,
+			Name:                 external.Name,
+		})
+	default:
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to look up user"})
+	}
+}
+
+// Register creates an account for a verified external identity (POST /api/auth/oidc/register).
+// @Summary Complete social sign up
+// @Description Creates a user from a registration token returned by the callback endpoint plus the required profile fields, links the identity and returns a JWT.
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param payload body OIDCRegisterRequest true "Registration payload"
+// @Success 201 {object} AuthResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/auth/oidc/register [post]
+func (h *OIDCHandler) Register(c *gin.Context) {
+	var req OIDCRegisterRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "invalid request body"})
+		return
+	}
+
+	var claims oidcRegistrationClaims
+	if err := h.keys.ParseClaims(req.RegistrationToken, &claims); err != nil || claims.Purpose != purposeOIDCRegistration {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "invalid or expired registration token"})
+		return
+	}
+
+	name := claims.Name
+	if req.Name != nil {
+		name = *req.Name
+	}
+	if name == "" || req.BirthDate == "" || req.Gender == 0 {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "name, gender, and birth_date are required"})
+		return
+	}
+
+	user, err := newUserProfile(name, req.Gender, req.BirthDate, req.TargetGender, req.Intention)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	// Social accounts have no password; store a hash nobody knows the preimage of.
+	unusablePassword, err := oidc.RandomToken()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to create user"})
+		return
+	}
+	hashedPassword, err := auth.HashPassword(unusablePassword)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to hash password"})
+		return
+	}
+	user.Email = claims.Email
+	user.PasswordHash = hashedPassword
+	user.SignupIP = c.ClientIP()
+
+	if err := h.userRepo.Create(user); err != nil {
+		if err == repo.ErrEmailAlreadyExists {
+			c.JSON(http.StatusConflict, ErrorResponse{Error: "email already exists"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to create user"})
+		return
+	}
+
+	err = h.identityRepo.Create(&models.Identity{
+		UserID:   user.ID,
+		Provider: claims.Provider,
+		Subject:  claims.Subject,
+		Email:    claims.Email,
+	})
+	if err != nil {
+		if err == repo.ErrIdentityAlreadyExists {
+			c.JSON(http.StatusConflict, ErrorResponse{Error: "identity already linked to another account"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to link identity"})
+		return
+	}
+
+	h.respondWithToken(c, http.StatusCreated, user)
+}
+
+// respondWithReauthentication answers a flow started by a signed-in user. The provider identity
+// must already be linked to that user; no session is issued.
+func (h *OIDCHandler) respondWithReauthentication(c *gin.Context, providerName string, external *oidc.Identity, userID uuid.UUID) {
+	identity, err := h.identityRepo.GetByProviderSubject(providerName, external.Subject)
+	if err != nil && err != repo.ErrIdentityNotFound {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to look up identity"})
+		return
+	}
+	if err == repo.ErrIdentityNotFound || identity.UserID != userID {
+		c.JSON(http.StatusForbidden, ErrorResponse{Error: "this identity is not linked to your account"})
+		return
+	}
+
+	now := time.Now()
+	token, err := h.keys.SignClaims(oidcReauthenticationClaims{
+		Purpose: purposeOIDCReauthentication,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   userID.String(),
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(oidcReauthenticationTTL)),
+		},
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to generate token"})
+		return
+	}
+	c.JSON(http.StatusOK, OIDCReauthenticationResponse{ReauthenticationToken: token})
+}
+
+// validReauthentication reports whether token is an unexpired reauthentication token for userID.
+func validReauthentication(keys *auth.KeySet, token string, userID uuid.UUID) bool {
+	var claims oidcReauthenticationClaims
+	if err := keys.ParseClaims(token, &claims); err != nil || claims.Purpose != purposeOIDCReauthentication {
+		return false
+	}
+	return claims.Subject == userID.String()
+}
+
+func (h *OIDCHandler) respondWithToken(c *gin.Context, status int, user *models.User) {
+	token, err := issueSession(c, h.keys, h.sessionRepo, user)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to create session"})
+		return
+	}
+	c.JSON(status, AuthResponse{Token: token, User: user})
+}
@@ -0,0 +1,345 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Viet-CodingStars/kyupi-kyupi-backend/internal/auth"
+	"github.com/Viet-CodingStars/kyupi-kyupi-backend/internal/middleware"
+	"github.com/Viet-CodingStars/kyupi-kyupi-backend/internal/models"
+	"github.com/Viet-CodingStars/kyupi-kyupi-backend/internal/oidc"
+	"github.com/Viet-CodingStars/kyupi-kyupi-backend/internal/oidc/oidctest"
+	"github.com/Viet-CodingStars/kyupi-kyupi-backend/internal/repo"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// mockIdentityRepo implements a mock identity repository for testing
+type mockIdentityRepo struct {
+	identities map[string]*models.Identity
+	states     map[string]*models.OIDCLoginState
+}
+
+func newMockIdentityRepo() *mockIdentityRepo {
+	return &mockIdentityRepo{
+		identities: make(map[string]*models.Identity),
+		states:     make(map[string]*models.OIDCLoginState),
+	}
+}
+
+func (m *mockIdentityRepo) Create(identity *models.Identity) error {
+	key := identity.Provider + "|" + identity.Subject
+	if _, exists := m.identities[key]; exists {
+		return repo.ErrIdentityAlreadyExists
+	}
+	identity.ID = uuid.New()
+	identity.CreatedAt = time.Now()
+	identity.UpdatedAt = time.Now()
+	m.identities[key] = identity
+	return nil
+}
+
+func (m *mockIdentityRepo) GetByProviderSubject(provider, subject string) (*models.Identity, error) {
+	identity, exists := m.identities[provider+"|"+subject]
+	if !exists {
+		return nil, repo.ErrIdentityNotFound
+	}
+	return identity, nil
+}
+
+func (m *mockIdentityRepo) SaveLoginState(state *models.OIDCLoginState) error {
+	m.states[state.State] = state
+	return nil
+}
+
+func (m *mockIdentityRepo) ConsumeLoginState(state string) (*models.OIDCLoginState, error) {
+	s, exists := m.states[state]
+	delete(m.states, state)
+	if !exists || s.ExpiresAt.Before(time.Now()) {
+		return nil, repo.ErrLoginStateNotFound
+	}
+	return s, nil
+}
+
+type oidcTestEnv struct {
+	issuer       *oidctest.Issuer
+	handler      *OIDCHandler
+	router       *gin.Engine
+	userRepo     *mockUserRepo
+	identityRepo *mockIdentityRepo
+}
+
+func newOIDCTestEnv(t *testing.T) *oidcTestEnv {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	issuer := oidctest.NewIssuer("client-id", "client-secret")
+	t.Cleanup(issuer.Close)
+
+	provider := oidc.NewProvider(oidc.ProviderConfig{
+		Name:         "mock",
+		Issuer:       issuer.URL(),
+		ClientID:     issuer.ClientID,
+		ClientSecret: issuer.ClientSecret,
+		RedirectURL:  "http://localhost:5174/auth/callback",
+	}, nil)
+
+	env := &oidcTestEnv{issuer: issuer, userRepo: newMockUserRepo(), identityRepo: newMockIdentityRepo()}
+	env.handler = &OIDCHandler{
+		providers:    map[string]OIDCProvider{"mock": provider},
+		identityRepo: env.identityRepo,
+		userRepo:     env.userRepo,
+		mfaRepo:      newMockMFARepo(),
+		sessionRepo:  newMockSessionRepo(),
+		keys:         auth.NewHMACKeySet("test-secret"),
+	}
+
+	env.router = gin.New()
+	env.router.GET("/api/auth/oidc/:provider/authorize", env.handler.Authorize)
+	env.router.POST("/api/auth/oidc/:provider/callback", env.handler.Callback)
+	env.router.POST("/api/auth/oidc/register", env.handler.Register)
+	env.router.GET("/api/users/reauthenticate/:provider/authorize", env.handler.AuthorizeReauthentication)
+	return env
+}
+
+func (env *oidcTestEnv) do(method, path string, body interface{}) *httptest.ResponseRecorder {
+	var buf bytes.Buffer
+	if body != nil {
+		json.NewEncoder(&buf).Encode(body)
+	}
+	req := httptest.NewRequest(method, path, &buf)
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	env.router.ServeHTTP(w, req)
+	return w
+}
+
+// login runs authorize + provider consent + callback for the given external user.
+func (env *oidcTestEnv) login(t *testing.T, user oidctest.User) *httptest.ResponseRecorder {
+	t.Helper()
+	w := env.do(http.MethodGet, "/api/auth/oidc/mock/authorize", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected authorize status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var authorize OIDCAuthorizeResponse
+	json.Unmarshal(w.Body.Bytes(), &authorize)
+
+	code, err := env.issuer.Authorize(authorize.AuthorizationURL, user)
+	if err != nil {
+		t.Fatalf("issuer authorize: %v", err)
+	}
+	return env.do(http.MethodPost, "/api/auth/oidc/mock/callback", OIDCCallbackRequest{Code: code, State: authorize.State})
+}
+
+// reauthenticate runs the re-authentication flow for a signed-in user with the given external user.
+func (env *oidcTestEnv) reauthenticate(t *testing.T, userID uuid.UUID, user oidctest.User) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodGet, "/api/users/reauthenticate/mock/authorize", nil)
+	req = req.WithContext(context.WithValue(req.Context(), middleware.UserIDKey, userID))
+	w := httptest.NewRecorder()
+	env.router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected authorize status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var authorize OIDCAuthorizeResponse
+	json.Unmarshal(w.Body.Bytes(), &authorize)
+
+	code, err := env.issuer.Authorize(authorize.AuthorizationURL, user)
+	if err != nil {
+		t.Fatalf("issuer authorize: %v", err)
+	}
+	return env.do(http.MethodPost, "/api/auth/oidc/mock/callback", OIDCCallbackRequest{Code: code, State: authorize.State})
+}
+
+// registerSocialUser signs up a new account through the provider.
+func (env *oidcTestEnv) registerSocialUser(t *testing.T, user oidctest.User) *models.User {
+	t.Helper()
+	var registration OIDCRegistrationResponse
+	json.Unmarshal(env.login(t, user).Body.Bytes(), &registration)
+	w := env.do(http.MethodPost, "/api/auth/oidc/register", OIDCRegisterRequest{
+		RegistrationToken: registration.RegistrationToken,
+		Name:              stringPtr("Social User"),
+		Gender:            models.GenderFemale,
+		BirthDate:         "1995-05-05",
+	})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected status 201, got %d: %s", w.Code, w.Body.String())
+	}
+	var response AuthResponse
+	json.Unmarshal(w.Body.Bytes(), &response)
+	return response.User
+}
+
+func TestOIDCLogin(t *testing.T) {
+	t.Run("new user registers after callback", func(t *testing.T) {
+		env := newOIDCTestEnv(t)
+		w := env.login(t, oidctest.User{Subject: "sub-1", Email: "new@example.com", EmailVerified: true, Name: "New User"})
+		if w.Code != http.StatusAccepted {
+			t.Fatalf("expected status 202, got %d: %s", w.Code, w.Body.String())
+		}
+		var registration OIDCRegistrationResponse
+		json.Unmarshal(w.Body.Bytes(), &registration)
+		if !registration.RegistrationRequired || registration.RegistrationToken == "" {
+			t.Fatalf("unexpected registration response: %+v", registration)
+		}
+
+		// The registration token must not work as a session token.
+		if _, err := env.handler.keys.ValidateToken(registration.RegistrationToken); err == nil {
+			t.Fatal("expected registration token to be rejected as session token")
+		}
+
+		w = env.do(http.MethodPost, "/api/auth/oidc/register", OIDCRegisterRequest{
+			RegistrationToken: registration.RegistrationToken,
+			Gender:            models.GenderFemale,
+			BirthDate:         "1995-05-05",
+		})
+		if w.Code != http.StatusCreated {
+			t.Fatalf("expected status 201, got %d: %s", w.Code, w.Body.String())
+		}
+		var response AuthResponse
+		json.Unmarshal(w.Body.Bytes(), &response)
+		if response.Token == "" || response.User.Email != "new@example.com" || response.User.Name != "New User" {
+			t.Fatalf("unexpected auth response: %+v", response)
+		}
+
+		// Signing in again with the same identity goes straight to a session.
+		w = env.login(t, oidctest.User{Subject: "sub-1", Email: "new@example.com", EmailVerified: true})
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected status 200 for returning user, got %d: %s", w.Code, w.Body.String())
+		}
+	})
+
+	t.Run("verified email links existing account", func(t *testing.T) {
+		env := newOIDCTestEnv(t)
+		existing := &models.User{Email: "existing@example.com", Name: "Existing"}
+		env.userRepo.Create(existing)
+
+		w := env.login(t, oidctest.User{Subject: "sub-2", Email: "existing@example.com", EmailVerified: true})
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+		}
+		identity, err := env.identityRepo.GetByProviderSubject("mock", "sub-2")
+		if err != nil || identity.UserID != existing.ID {
+			t.Fatalf("expected identity linked to existing user, got %+v (%v)", identity, err)
+		}
+	})
+
+	t.Run("unverified email does not link existing account", func(t *testing.T) {
+		env := newOIDCTestEnv(t)
+		env.userRepo.Create(&models.User{Email: "existing@example.com", Name: "Existing"})
+
+		w := env.login(t, oidctest.User{Subject: "sub-3", Email: "existing@example.com", EmailVerified: false})
+		if w.Code != http.StatusConflict {
+			t.Fatalf("expected status 409, got %d: %s", w.Code, w.Body.String())
+		}
+	})
+
+	t.Run("state cannot be replayed", func(t *testing.T) {
+		env := newOIDCTestEnv(t)
+		w := env.do(http.MethodGet, "/api/auth/oidc/mock/authorize", nil)
+		var authorize OIDCAuthorizeResponse
+		json.Unmarshal(w.Body.Bytes(), &authorize)
+		code, _ := env.issuer.Authorize(authorize.AuthorizationURL, oidctest.User{Subject: "sub-4", Email: "a@example.com", EmailVerified: true})
+
+		env.do(http.MethodPost, "/api/auth/oidc/mock/callback", OIDCCallbackRequest{Code: code, State: authorize.State})
+		w = env.do(http.MethodPost, "/api/auth/oidc/mock/callback", OIDCCallbackRequest{Code: code, State: authorize.State})
+		if w.Code != http.StatusBadRequest {
+			t.Fatalf("expected status 400, got %d", w.Code)
+		}
+	})
+
+	t.Run("unknown provider", func(t *testing.T) {
+		env := newOIDCTestEnv(t)
+		w := env.do(http.MethodGet, "/api/auth/oidc/unknown/authorize", nil)
+		if w.Code != http.StatusNotFound {
+			t.Fatalf("expected status 404, got %d", w.Code)
+		}
+	})
+
+	t.Run("forged registration token", func(t *testing.T) {
+		env := newOIDCTestEnv(t)
+		forged, _ := auth.NewHMACKeySet("other-secret").SignClaims(oidcRegistrationClaims{Purpose: purposeOIDCRegistration, Email: "x@example.com"})
+		w := env.do(http.MethodPost, "/api/auth/oidc/register", OIDCRegisterRequest{
+			RegistrationToken: forged,
+			Name:              stringPtr("X"),
+			Gender:            models.GenderMale,
+			BirthDate:         "1990-01-01",
+		})
+		if w.Code != http.StatusUnauthorized {
+			t.Fatalf("expected status 401, got %d", w.Code)
+		}
+	})
+}
+
+func TestOIDCReauthentication(t *testing.T) {
+	t.Run("social account without a password deletes itself after re-authenticating", func(t *testing.T) {
+		env := newOIDCTestEnv(t)
+		external := oidctest.User{Subject: "sub-1", Email: "social@example.com", EmailVerified: true}
+		user := env.registerSocialUser(t, external)
+		users := &UserHandler{
+			userRepo:            env.userRepo,
+			mfaRepo:             env.handler.mfaRepo,
+			sessionRepo:         env.handler.sessionRepo,
+			keys:                env.handler.keys,
+			deletionGracePeriod: 30 * 24 * time.Hour,
+		}
+		deleteAccount := func(body string) *httptest.ResponseRecorder {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			req := httptest.NewRequest(http.MethodDelete, "/api/users/profile", bytes.NewBufferString(body))
+			req.Header.Set("Content-Type", "application/json")
+			c.Request = req.WithContext(context.WithValue(req.Context(), middleware.UserIDKey, user.ID))
+			users.DeleteAccount(c)
+			return w
+		}
+
+		// The account has no password anyone knows.
+		if w := deleteAccount(`{"password":"guess"}`); w.Code != http.StatusForbidden {
+			t.Fatalf("expected status 403 for a password, got %d", w.Code)
+		}
+
+		w := env.reauthenticate(t, user.ID, external)
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+		}
+		var reauthentication OIDCReauthenticationResponse
+		json.Unmarshal(w.Body.Bytes(), &reauthentication)
+		if reauthentication.ReauthenticationToken == "" {
+			t.Fatalf("expected a reauthentication token, got %s", w.Body.String())
+		}
+		if _, err := env.handler.keys.ValidateToken(reauthentication.ReauthenticationToken); err == nil {
+			t.Fatal("expected reauthentication token to be rejected as session token")
+		}
+
+		w = deleteAccount(`{"reauthentication_token":"` + reauthentication.ReauthenticationToken + `"}`)
+		if w.Code != http.StatusAccepted {
+			t.Fatalf("expected status 202, got %d: %s", w.Code, w.Body.String())
+		}
+		if stored, _ := env.userRepo.GetByID(user.ID); stored.DeletionScheduledAt == nil {
+			t.Fatal("expected deletion to be scheduled")
+		}
+	})
+
+	t.Run("identity linked to another account is rejected", func(t *testing.T) {
+		env := newOIDCTestEnv(t)
+		user := env.registerSocialUser(t, oidctest.User{Subject: "sub-1", Email: "one@example.com", EmailVerified: true})
+		other := oidctest.User{Subject: "sub-2", Email: "two@example.com", EmailVerified: true}
+		env.registerSocialUser(t, other)
+
+		if w := env.reauthenticate(t, user.ID, other); w.Code != http.StatusForbidden {
+			t.Fatalf("expected status 403, got %d: %s", w.Code, w.Body.String())
+		}
+		if w := env.reauthenticate(t, user.ID, oidctest.User{Subject: "sub-3", Email: "three@example.com", EmailVerified: true}); w.Code != http.StatusForbidden {
+			t.Fatalf("expected status 403 for an unlinked identity, got %d: %s", w.Code, w.Body.String())
+		}
+	})
+}
+
+func stringPtr(s string) *string {
+	return &s
+}
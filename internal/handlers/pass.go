@@ -3,6 +3,7 @@ package handlers
 import (
 	"database/sql"
 	"net/http"
+	"time"
 
 	"github.com/Viet-CodingStars/kyupi-kyupi-backend/internal/middleware"
 	"github.com/Viet-CodingStars/kyupi-kyupi-backend/internal/models"
@@ -13,11 +14,17 @@ import (
 
 type PassHandler struct {
 	likeRepo LikeRepository
+	// rewindWindow is how long after a pass it can still be undone
+	rewindWindow time.Duration
+	// rewindDailyLimit is how many passes a user may undo per local calendar day
+	rewindDailyLimit int
 }
 
-func NewPassHandler(db *sql.DB) *PassHandler {
+func NewPassHandler(db *sql.DB, rewindWindow time.Duration, rewindDailyLimit int) *PassHandler {
 	return &PassHandler{
-		likeRepo: repo.NewLikeRepo(db),
+		likeRepo:         repo.NewLikeRepo(db),
+		rewindWindow:     rewindWindow,
+		rewindDailyLimit: rewindDailyLimit,
 	}
 }
 
@@ -31,6 +38,12 @@ type PassResponse struct {
 	Pass *models.Like `json:"pass"`
 }
 
+// RewindPassResponse returns the pass that was undone.
+type RewindPassResponse struct {
+	Pass             *models.Like `json:"pass"`
+	RewindsRemaining int          `json:"rewinds_remaining"`
+}
+
 // CreatePass handles creating a pass (POST /api/passes).
 // @Summary Create a pass
 // @Description Creates a pass for a target user (indicating no interest).
@@ -42,6 +55,7 @@ type PassResponse struct {
 // @Success 201 {object} PassResponse
 // @Failure 400 {object} ErrorResponse
 // @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
 // @Failure 409 {object} ErrorResponse
 // @Failure 500 {object} ErrorResponse
 // @Router /api/passes [post]
@@ -82,6 +96,10 @@ func (h *PassHandler) CreatePass(c *gin.Context) {
 			c.JSON(http.StatusConflict, ErrorResponse{Error: "you have already liked or passed this user"})
 			return
 		}
+		if err == repo.ErrUserNotFound {
+			c.JSON(http.StatusNotFound, ErrorResponse{Error: "target user not found"})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to create pass"})
 		return
 	}
@@ -92,3 +110,39 @@ func (h *PassHandler) CreatePass(c *gin.Context) {
 
 	c.JSON(http.StatusCreated, response)
 }
+
+// RewindPass undoes the caller's most recent pass (POST /api/passes/rewind).
+// @Summary Rewind the last pass
+// @Description Deletes the caller's most recent pass if it was made within the rewind window, so the profile shows up again. Rewinds are limited per day, resetting at midnight in the user's timezone.
+// @Tags Passes
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} RewindPassResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 429 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/passes/rewind [post]
+func (h *PassHandler) RewindPass(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c.Request.Context())
+	if !ok {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "user not authenticated"})
+		return
+	}
+
+	pass, remaining, err := h.likeRepo.RewindLastPass(userID, h.rewindWindow, h.rewindDailyLimit)
+	if err != nil {
+		if err == repo.ErrNoPassToRewind {
+			c.JSON(http.StatusNotFound, ErrorResponse{Error: "no recent pass to rewind"})
+			return
+		}
+		if err == repo.ErrRewindLimitExceeded {
+			c.JSON(http.StatusTooManyRequests, ErrorResponse{Error: "daily rewind limit reached"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to rewind pass"})
+		return
+	}
+
+	c.JSON(http.StatusOK, RewindPassResponse{Pass: pass, RewindsRemaining: remaining})
+}
@@ -7,6 +7,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/Viet-CodingStars/kyupi-kyupi-backend/internal/middleware"
 	"github.com/Viet-CodingStars/kyupi-kyupi-backend/internal/models"
@@ -160,3 +161,60 @@ func TestCreatePassUnauthenticated(t *testing.T) {
 		t.Errorf("expected status %d, got %d", http.StatusUnauthorized, w.Code)
 	}
 }
+
+func TestRewindPass(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	mockRepo := newMockLikeRepo()
+	handler := &PassHandler{likeRepo: mockRepo, rewindWindow: 5 * time.Minute, rewindDailyLimit: 1}
+	userID := uuid.New()
+
+	rewind := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/passes/rewind", nil)
+		req = req.WithContext(context.WithValue(req.Context(), middleware.UserIDKey, userID))
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = req
+		handler.RewindPass(c)
+		return w
+	}
+
+	stale := &models.Like{UserID: userID, TargetUserID: uuid.New(), Status: "pass"}
+	mockRepo.Create(stale)
+	stale.CreatedAt = time.Now().Add(-time.Hour)
+
+	t.Run("nothing recent to rewind", func(t *testing.T) {
+		if w := rewind(); w.Code != http.StatusNotFound {
+			t.Fatalf("expected status 404, got %d", w.Code)
+		}
+	})
+
+	older := &models.Like{UserID: userID, TargetUserID: uuid.New(), Status: "pass"}
+	mockRepo.Create(older)
+	older.CreatedAt = time.Now().Add(-time.Minute)
+	latest := &models.Like{UserID: userID, TargetUserID: uuid.New(), Status: "pass"}
+	mockRepo.Create(latest)
+
+	t.Run("undoes the most recent pass", func(t *testing.T) {
+		w := rewind()
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+		}
+		var resp RewindPassResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("failed to unmarshal response: %v", err)
+		}
+		if resp.Pass == nil || resp.Pass.TargetUserID != latest.TargetUserID || resp.RewindsRemaining != 0 {
+			t.Fatalf("expected the latest pass to be rewound, got %+v", resp)
+		}
+		if _, err := mockRepo.GetByUserAndTarget(userID, latest.TargetUserID); err != repo.ErrLikeNotFound {
+			t.Fatalf("expected the pass to be deleted, got %v", err)
+		}
+	})
+
+	t.Run("daily limit", func(t *testing.T) {
+		if w := rewind(); w.Code != http.StatusTooManyRequests {
+			t.Fatalf("expected status 429, got %d", w.Code)
+		}
+	})
+}
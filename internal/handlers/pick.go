@@ -0,0 +1,222 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"time"
+
+	"github.com/Viet-CodingStars/kyupi-kyupi-backend/internal/discovery"
+	"github.com/Viet-CodingStars/kyupi-kyupi-backend/internal/middleware"
+	"github.com/Viet-CodingStars/kyupi-kyupi-backend/internal/models"
+	"github.com/Viet-CodingStars/kyupi-kyupi-backend/internal/repo"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// PickRepository declares the minimal persistence operations required by PickHandler.
+type PickRepository interface {
+	HasPicks(userID uuid.UUID) (bool, error)
+	SavePicks(userID uuid.UUID, picks []discovery.Scored) error
+	ListPicks(userID uuid.UUID) ([]*models.DailyPick, error)
+	CreatePickLike(like *models.Like, dailyQuota int) (int, error)
+	PickLikeUsage(userID uuid.UUID) (int, time.Time, error)
+}
+
+// PickSelector chooses a user's best candidates for the day.
+type PickSelector interface {
+	SelectPicks(viewerID uuid.UUID, count int) ([]discovery.Scored, error)
+}
+
+type PickHandler struct {
+	pickRepo      PickRepository
+	likeRepo      LikeRepository
+	matchRepo     MatchRepository
+	messageSeeder MessageSeeder
+	selector      PickSelector
+	// count is how many picks a user gets per local calendar day
+	count int
+	// likeDailyQuota is how many picks a user may like per local calendar day
+	likeDailyQuota int
+}
+
+func NewPickHandler(db *sql.DB, selector PickSelector, messageSeeder MessageSeeder, count, likeDailyQuota int) *PickHandler {
+	return &PickHandler{
+		pickRepo:       repo.NewPickRepo(db),
+		likeRepo:       repo.NewLikeRepo(db),
+		matchRepo:      repo.NewMatchRepo(db),
+		messageSeeder:  messageSeeder,
+		selector:       selector,
+		count:          count,
+		likeDailyQuota: likeDailyQuota,
+	}
+}
+
+// PickItem is one of the caller's picks for the day.
+type PickItem struct {
+	User     UserListItem `json:"user"`
+	Position int          `json:"position"`
+	// Status is the caller's like or pass on the pick, if any
+	Status string `json:"status,omitempty"`
+}
+
+// PicksResponse lists the caller's picks with the pick like quota for the current local day.
+type PicksResponse struct {
+	Picks          []PickItem `json:"picks"`
+	DailyLikeQuota int        `json:"daily_like_quota"`
+	LikesRemaining int        `json:"likes_remaining"`
+	ResetsAt       time.Time  `json:"resets_at"`
+}
+
+// PickLikeResponse represents the response after liking a pick
+type PickLikeResponse struct {
+	Like           *models.Like  `json:"like"`
+	Match          *models.Match `json:"match,omitempty"`
+	Matched        bool          `json:"matched"`
+	LikesRemaining int           `json:"likes_remaining"`
+}
+
+// GetPicks returns the caller's picks for today (GET /api/picks).
+// @Summary Get today's picks
+// @Description Returns a small set of the most compatible candidates for the caller, chosen on the first request of the day and kept until midnight in the user's timezone. Candidates are filtered like the discovery feed (gender preferences, dating intention, users already liked or passed); picks who have since become hidden, paused or deleted are left out. Also returns the pick like quota, which is separate from regular likes.
+// @Tags Picks
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} PicksResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/picks [get]
+func (h *PickHandler) GetPicks(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c.Request.Context())
+	if !ok {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "user not authenticated"})
+		return
+	}
+
+	if err := h.ensurePicks(c.Request.Context(), userID); err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to select picks"})
+		return
+	}
+
+	picks, err := h.pickRepo.ListPicks(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to retrieve picks"})
+		return
+	}
+
+	used, resetsAt, err := h.pickRepo.PickLikeUsage(userID)
+	if err != nil {
+		if err == repo.ErrUserNotFound {
+			c.JSON(http.StatusNotFound, ErrorResponse{Error: "user not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to retrieve pick like quota"})
+		return
+	}
+	remaining := h.likeDailyQuota - used
+	if remaining < 0 {
+		remaining = 0
+	}
+
+	items := make([]PickItem, len(picks))
+	for i, pick := range picks {
+		items[i] = PickItem{
+			User: UserListItem{
+				ID:        pick.User.ID,
+				Name:      pick.User.Name,
+				Gender:    pick.User.Gender,
+				AvatarURL: pick.User.AvatarURL,
+				Intention: pick.User.Intention,
+				Verified:  pick.User.VerifiedAt != nil,
+			},
+			Position: pick.Position,
+			Status:   pick.Status,
+		}
+	}
+
+	c.JSON(http.StatusOK, PicksResponse{
+		Picks:          items,
+		DailyLikeQuota: h.likeDailyQuota,
+		LikesRemaining: remaining,
+		ResetsAt:       resetsAt,
+	})
+}
+
+// ensurePicks selects and saves the user's picks unless today's are already saved.
+func (h *PickHandler) ensurePicks(ctx context.Context, userID uuid.UUID) error {
+	exists, err := h.pickRepo.HasPicks(userID)
+	if err != nil || exists {
+		return err
+	}
+	picks, err := h.selector.SelectPicks(userID, h.count)
+	if err != nil {
+		return err
+	}
+	return h.pickRepo.SavePicks(userID, picks)
+}
+
+// LikePick likes one of the caller's picks (POST /api/picks/:user_id/like).
+// @Summary Like a pick
+// @Description Likes one of today's picks, replacing an earlier pass if there is one. Pick likes count against their own daily quota, which resets at midnight in the user's timezone, and not against regular or super likes. If both users like each other, a match is automatically created.
+// @Tags Picks
+// @Produce json
+// @Security BearerAuth
+// @Param user_id path string true "ID of the picked user"
+// @Success 201 {object} PickLikeResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 429 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/picks/{user_id}/like [post]
+func (h *PickHandler) LikePick(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c.Request.Context())
+	if !ok {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "user not authenticated"})
+		return
+	}
+
+	targetUserID, err := uuid.Parse(c.Param("user_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "invalid user_id"})
+		return
+	}
+
+	like := &models.Like{
+		UserID:       userID,
+		TargetUserID: targetUserID,
+		Status:       models.LikeStatusLike,
+	}
+	remaining, err := h.pickRepo.CreatePickLike(like, h.likeDailyQuota)
+	if err != nil {
+		if err == repo.ErrNotPicked {
+			c.JSON(http.StatusNotFound, ErrorResponse{Error: "user is not in today's picks"})
+			return
+		}
+		if err == repo.ErrPickLikeQuotaExceeded {
+			c.JSON(http.StatusTooManyRequests, ErrorResponse{Error: "daily pick like limit reached"})
+			return
+		}
+		if err == repo.ErrLikeAlreadyExists {
+			c.JSON(http.StatusConflict, ErrorResponse{Error: "you have already liked or passed this user"})
+			return
+		}
+		if err == repo.ErrUserNotFound {
+			c.JSON(http.StatusNotFound, ErrorResponse{Error: "target user not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to like pick"})
+		return
+	}
+
+	response := PickLikeResponse{Like: like, LikesRemaining: remaining}
+	// The like stands even if the match cannot be created
+	if match, err := matchIfMutual(c.Request.Context(), h.likeRepo, h.matchRepo, h.messageSeeder, like); err == nil && match != nil {
+		response.Match = match
+		response.Matched = true
+	}
+
+	c.JSON(http.StatusCreated, response)
+}
@@ -0,0 +1,224 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Viet-CodingStars/kyupi-kyupi-backend/internal/discovery"
+	"github.com/Viet-CodingStars/kyupi-kyupi-backend/internal/middleware"
+	"github.com/Viet-CodingStars/kyupi-kyupi-backend/internal/models"
+	"github.com/Viet-CodingStars/kyupi-kyupi-backend/internal/repo"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// mockPickRepo keeps one day of picks per user and stores pick likes in a mockLikeRepo
+type mockPickRepo struct {
+	likeRepo *mockLikeRepo
+	picks    map[uuid.UUID][]discovery.Scored
+	used     map[uuid.UUID]int
+	saves    int
+}
+
+func newMockPickRepo(likeRepo *mockLikeRepo) *mockPickRepo {
+	return &mockPickRepo{
+		likeRepo: likeRepo,
+		picks:    make(map[uuid.UUID][]discovery.Scored),
+		used:     make(map[uuid.UUID]int),
+	}
+}
+
+func (m *mockPickRepo) HasPicks(userID uuid.UUID) (bool, error) {
+	_, exists := m.picks[userID]
+	return exists, nil
+}
+
+func (m *mockPickRepo) SavePicks(userID uuid.UUID, picks []discovery.Scored) error {
+	m.picks[userID] = picks
+	m.saves++
+	return nil
+}
+
+func (m *mockPickRepo) ListPicks(userID uuid.UUID) ([]*models.DailyPick, error) {
+	picks := make([]*models.DailyPick, 0)
+	for i, p := range m.picks[userID] {
+		pick := &models.DailyPick{User: &models.User{ID: p.CandidateID, Name: "Pick"}, Position: i + 1}
+		if like, err := m.likeRepo.GetByUserAndTarget(userID, p.CandidateID); err == nil {
+			pick.Status = like.Status
+		}
+		picks = append(picks, pick)
+	}
+	return picks, nil
+}
+
+func (m *mockPickRepo) CreatePickLike(like *models.Like, dailyQuota int) (int, error) {
+	picked := false
+	for _, p := range m.picks[like.UserID] {
+		picked = picked || p.CandidateID == like.TargetUserID
+	}
+	if !picked {
+		return 0, repo.ErrNotPicked
+	}
+	if m.used[like.UserID] >= dailyQuota {
+		return 0, repo.ErrPickLikeQuotaExceeded
+	}
+	if err := m.likeRepo.Create(like); err != nil {
+		return 0, err
+	}
+	m.used[like.UserID]++
+	return dailyQuota - m.used[like.UserID], nil
+}
+
+func (m *mockPickRepo) PickLikeUsage(userID uuid.UUID) (int, time.Time, error) {
+	return m.used[userID], time.Date(2025, 6, 2, 0, 0, 0, 0, time.UTC), nil
+}
+
+type mockPickSelector struct {
+	picks []discovery.Scored
+	err   error
+	calls int
+}
+
+func (m *mockPickSelector) SelectPicks(viewerID uuid.UUID, count int) ([]discovery.Scored, error) {
+	m.calls++
+	if len(m.picks) > count {
+		return m.picks[:count], m.err
+	}
+	return m.picks, m.err
+}
+
+func TestGetPicks(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	get := func(handler *PickHandler, userID uuid.UUID) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		req := httptest.NewRequest(http.MethodGet, "/api/picks", nil)
+		c.Request = req.WithContext(context.WithValue(req.Context(), middleware.UserIDKey, userID))
+		handler.GetPicks(c)
+		return w
+	}
+
+	t.Run("selects picks once and keeps them for the day", func(t *testing.T) {
+		likeRepo := newMockLikeRepo()
+		pickRepo := newMockPickRepo(likeRepo)
+		selector := &mockPickSelector{picks: []discovery.Scored{{CandidateID: uuid.New()}, {CandidateID: uuid.New()}, {CandidateID: uuid.New()}}}
+		handler := &PickHandler{pickRepo: pickRepo, likeRepo: likeRepo, matchRepo: newMockMatchRepo(), selector: selector, count: 2, likeDailyQuota: 3}
+		userID := uuid.New()
+
+		w := get(handler, userID)
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+		}
+		var response PicksResponse
+		json.Unmarshal(w.Body.Bytes(), &response)
+		if len(response.Picks) != 2 || response.Picks[0].User.ID != selector.picks[0].CandidateID || response.Picks[0].Position != 1 {
+			t.Fatalf("expected the two best candidates in order, got %+v", response.Picks)
+		}
+		if response.DailyLikeQuota != 3 || response.LikesRemaining != 3 || response.ResetsAt.IsZero() {
+			t.Fatalf("unexpected quota in %+v", response)
+		}
+
+		get(handler, userID)
+		if selector.calls != 1 || pickRepo.saves != 1 {
+			t.Fatalf("expected picks to be selected once, got %d selections and %d saves", selector.calls, pickRepo.saves)
+		}
+	})
+
+	t.Run("selection failure", func(t *testing.T) {
+		likeRepo := newMockLikeRepo()
+		selector := &mockPickSelector{err: errors.New("db unavailable")}
+		handler := &PickHandler{pickRepo: newMockPickRepo(likeRepo), likeRepo: likeRepo, selector: selector, count: 10}
+
+		w := get(handler, uuid.New())
+		if w.Code != http.StatusInternalServerError {
+			t.Fatalf("expected status 500, got %d", w.Code)
+		}
+	})
+
+	t.Run("unauthenticated", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodGet, "/api/picks", nil)
+		(&PickHandler{}).GetPicks(c)
+		if w.Code != http.StatusUnauthorized {
+			t.Fatalf("expected status 401, got %d", w.Code)
+		}
+	})
+}
+
+func TestLikePick(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	like := func(handler *PickHandler, userID uuid.UUID, target string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		req := httptest.NewRequest(http.MethodPost, "/api/picks/"+target+"/like", nil)
+		c.Request = req.WithContext(context.WithValue(req.Context(), middleware.UserIDKey, userID))
+		c.Params = gin.Params{{Key: "user_id", Value: target}}
+		handler.LikePick(c)
+		return w
+	}
+	setup := func(quota int) (*PickHandler, *mockLikeRepo, uuid.UUID, []uuid.UUID) {
+		likeRepo := newMockLikeRepo()
+		pickRepo := newMockPickRepo(likeRepo)
+		userID := uuid.New()
+		picked := []uuid.UUID{uuid.New(), uuid.New()}
+		pickRepo.picks[userID] = []discovery.Scored{{CandidateID: picked[0]}, {CandidateID: picked[1]}}
+		handler := &PickHandler{pickRepo: pickRepo, likeRepo: likeRepo, matchRepo: newMockMatchRepo(), likeDailyQuota: quota}
+		return handler, likeRepo, userID, picked
+	}
+
+	t.Run("likes a pick and matches when the like is returned", func(t *testing.T) {
+		handler, likeRepo, userID, picked := setup(3)
+		likeRepo.Create(&models.Like{UserID: picked[0], TargetUserID: userID, Status: models.LikeStatusLike})
+
+		w := like(handler, userID, picked[0].String())
+		if w.Code != http.StatusCreated {
+			t.Fatalf("expected status 201, got %d: %s", w.Code, w.Body.String())
+		}
+		var response PickLikeResponse
+		json.Unmarshal(w.Body.Bytes(), &response)
+		if !response.Matched || response.Match == nil || response.LikesRemaining != 2 {
+			t.Fatalf("expected a match with 2 pick likes left, got %+v", response)
+		}
+	})
+
+	t.Run("rejects users outside today's picks", func(t *testing.T) {
+		handler, _, userID, _ := setup(3)
+		if w := like(handler, userID, uuid.New().String()); w.Code != http.StatusNotFound {
+			t.Fatalf("expected status 404, got %d", w.Code)
+		}
+	})
+
+	t.Run("daily quota", func(t *testing.T) {
+		handler, _, userID, picked := setup(1)
+		if w := like(handler, userID, picked[0].String()); w.Code != http.StatusCreated {
+			t.Fatalf("expected status 201, got %d", w.Code)
+		}
+		w := like(handler, userID, picked[1].String())
+		if w.Code != http.StatusTooManyRequests {
+			t.Fatalf("expected status 429, got %d", w.Code)
+		}
+	})
+
+	t.Run("already liked", func(t *testing.T) {
+		handler, likeRepo, userID, picked := setup(3)
+		likeRepo.Create(&models.Like{UserID: userID, TargetUserID: picked[0], Status: models.LikeStatusLike})
+		if w := like(handler, userID, picked[0].String()); w.Code != http.StatusConflict {
+			t.Fatalf("expected status 409, got %d", w.Code)
+		}
+	})
+
+	t.Run("invalid user_id", func(t *testing.T) {
+		handler, _, userID, _ := setup(3)
+		if w := like(handler, userID, "not-a-uuid"); w.Code != http.StatusBadRequest {
+			t.Fatalf("expected status 400, got %d", w.Code)
+		}
+	})
+}
@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"database/sql"
+	"net/http"
+
+	"github.com/Viet-CodingStars/kyupi-kyupi-backend/internal/middleware"
+	"github.com/Viet-CodingStars/kyupi-kyupi-backend/internal/models"
+	"github.com/Viet-CodingStars/kyupi-kyupi-backend/internal/repo"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// PreferenceRepository declares the minimal persistence operations required by PreferenceHandler.
+type PreferenceRepository interface {
+	Get(userID uuid.UUID) (*models.Preferences, error)
+	Save(userID uuid.UUID, prefs *models.Preferences) error
+}
+
+type PreferenceHandler struct {
+	prefRepo PreferenceRepository
+}
+
+func NewPreferenceHandler(db *sql.DB) *PreferenceHandler {
+	return &PreferenceHandler{prefRepo: repo.NewPreferenceRepo(db)}
+}
+
+// GetPreferences returns the caller's discovery filters (GET /api/users/preferences).
+// @Summary Get discovery preferences
+// @Description Returns the caller's discovery filters and which of them are dealbreakers. Filters that were never set are left out or empty.
+// @Tags Users
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} models.Preferences
+// @Failure 401 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/users/preferences [get]
+func (h *PreferenceHandler) GetPreferences(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c.Request.Context())
+	if !ok {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "user not authenticated"})
+		return
+	}
+
+	prefs, err := h.prefRepo.Get(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to retrieve preferences"})
+		return
+	}
+	c.JSON(http.StatusOK, prefs)
+}
+
+// UpdatePreferences replaces the caller's discovery filters (PUT /api/users/preferences).
+// @Summary Update discovery preferences
+// @Description Replaces every discovery filter: age range, maximum distance, height range, and accepted education levels, languages (at least one in common), religions, drinking and smoking habits and family plans. Filters named in dealbreakers hide everyone who does not meet them from discovery; the others rank people who meet them higher. Nobody is hidden for an attribute they have not filled in. The discovery feed is recomputed on the next request.
+// @Tags Users
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param payload body models.Preferences true "Discovery filters"
+// @Success 200 {object} models.Preferences
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/users/preferences [put]
+func (h *PreferenceHandler) UpdatePreferences(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c.Request.Context())
+	if !ok {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "user not authenticated"})
+		return
+	}
+
+	var prefs models.Preferences
+	if err := c.ShouldBindJSON(&prefs); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "invalid request body"})
+		return
+	}
+	if err := prefs.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	if err := h.prefRepo.Save(userID, &prefs); err != nil {
+		if err == repo.ErrUserNotFound {
+			c.JSON(http.StatusNotFound, ErrorResponse{Error: "user not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to update preferences"})
+		return
+	}
+	c.JSON(http.StatusOK, prefs)
+}
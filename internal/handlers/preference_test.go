@@ -0,0 +1,85 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Viet-CodingStars/kyupi-kyupi-backend/internal/middleware"
+	"github.com/Viet-CodingStars/kyupi-kyupi-backend/internal/models"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type mockPreferenceRepo struct {
+	prefs map[uuid.UUID]*models.Preferences
+}
+
+func (m *mockPreferenceRepo) Get(userID uuid.UUID) (*models.Preferences, error) {
+	if prefs, ok := m.prefs[userID]; ok {
+		return prefs, nil
+	}
+	return &models.Preferences{}, nil
+}
+
+func (m *mockPreferenceRepo) Save(userID uuid.UUID, prefs *models.Preferences) error {
+	now := time.Now()
+	prefs.UpdatedAt = &now
+	m.prefs[userID] = prefs
+	return nil
+}
+
+func TestUpdatePreferences(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	prefRepo := &mockPreferenceRepo{prefs: make(map[uuid.UUID]*models.Preferences)}
+	handler := &PreferenceHandler{prefRepo: prefRepo}
+	userID := uuid.New()
+
+	put := func(body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		req := httptest.NewRequest(http.MethodPut, "/api/users/preferences", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		c.Request = req.WithContext(context.WithValue(req.Context(), middleware.UserIDKey, userID))
+		handler.UpdatePreferences(c)
+		return w
+	}
+
+	w := put(`{"age_min":25,"age_max":35,"max_distance_km":30,"religions":["buddhist","catholic"],"smoking":["never"],"dealbreakers":["age","smoking"],"verified_only":true}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	saved := prefRepo.prefs[userID]
+	if saved == nil || *saved.AgeMin != 25 || *saved.MaxDistanceKm != 30 || !saved.IsDealbreaker(models.FilterSmoking) || saved.IsDealbreaker(models.FilterReligion) || !saved.VerifiedOnly {
+		t.Fatalf("unexpected saved preferences %+v", saved)
+	}
+
+	w = httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	req := httptest.NewRequest(http.MethodGet, "/api/users/preferences", nil)
+	c.Request = req.WithContext(context.WithValue(req.Context(), middleware.UserIDKey, userID))
+	handler.GetPreferences(c)
+	var got models.Preferences
+	json.Unmarshal(w.Body.Bytes(), &got)
+	if w.Code != http.StatusOK || len(got.Religions) != 2 || got.UpdatedAt == nil {
+		t.Fatalf("expected the saved preferences, got %d: %s", w.Code, w.Body.String())
+	}
+
+	for name, body := range map[string]string{
+		"age below 18":              `{"age_min":16}`,
+		"inverted age range":        `{"age_min":40,"age_max":30}`,
+		"distance out of range":     `{"max_distance_km":0}`,
+		"unknown religion":          `{"religions":["pastafarian"]}`,
+		"unknown language":          `{"languages":["xx"]}`,
+		"unknown dealbreaker":       `{"dealbreakers":["looks"]}`,
+		"dealbreaker without value": `{"religions":["buddhist"],"dealbreakers":["height"]}`,
+	} {
+		if w := put(body); w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected status 400, got %d", name, w.Code)
+		}
+	}
+}
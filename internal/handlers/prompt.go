@@ -0,0 +1,194 @@
+package handlers
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/Viet-CodingStars/kyupi-kyupi-backend/internal/middleware"
+	"github.com/Viet-CodingStars/kyupi-kyupi-backend/internal/models"
+	"github.com/Viet-CodingStars/kyupi-kyupi-backend/internal/repo"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// PromptAnswerLister declares the answer lookup required by UserHandler.
+type PromptAnswerLister interface {
+	ListAnswers(userID uuid.UUID) ([]*models.PromptAnswer, error)
+}
+
+// PromptRepository declares the minimal persistence operations required by PromptHandler.
+type PromptRepository interface {
+	PromptAnswerLister
+	List() ([]*models.Prompt, error)
+	SetAnswers(userID uuid.UUID, answers []*models.PromptAnswer) error
+}
+
+type PromptHandler struct {
+	promptRepo PromptRepository
+}
+
+func NewPromptHandler(db *sql.DB) *PromptHandler {
+	return &PromptHandler{promptRepo: repo.NewPromptRepo(db)}
+}
+
+// PromptItem is a catalog prompt in the requested language.
+type PromptItem struct {
+	ID   string `json:"id"`
+	Text string `json:"text"`
+}
+
+// PromptsResponse lists the prompt catalog.
+type PromptsResponse struct {
+	Prompts []PromptItem `json:"prompts"`
+}
+
+// PromptAnswerItem is an answer on a profile, with the prompt in the requested language.
+type PromptAnswerItem struct {
+	PromptID string `json:"prompt_id"` // target_ref for likes on this answer
+	Prompt   string `json:"prompt"`
+	Answer   string `json:"answer"`
+}
+
+// PromptAnswersResponse lists a user's answers in profile order.
+type PromptAnswersResponse struct {
+	Answers []PromptAnswerItem `json:"answers"`
+}
+
+// PromptAnswerRequest is one answer in UpdatePromptAnswersRequest.
+type PromptAnswerRequest struct {
+	PromptID string `json:"prompt_id"`
+	Answer   string `json:"answer"`
+}
+
+// UpdatePromptAnswersRequest replaces the caller's answers; their order is the profile order.
+type UpdatePromptAnswersRequest struct {
+	Answers []PromptAnswerRequest `json:"answers"`
+}
+
+func promptAnswerItems(answers []*models.PromptAnswer, lang string) []PromptAnswerItem {
+	items := make([]PromptAnswerItem, 0, len(answers))
+	for _, a := range answers {
+		items = append(items, PromptAnswerItem{PromptID: a.PromptID, Prompt: a.Prompt.Text(lang), Answer: a.Answer})
+	}
+	return items
+}
+
+// GetCatalog lists the prompts users may answer (GET /api/prompts).
+// @Summary List prompts
+// @Description Lists the active prompts, in the language from the lang parameter or Accept-Language header, falling back to English.
+// @Tags Prompts
+// @Produce json
+// @Param lang query string false "ISO 639-1 language code, e.g. vi"
+// @Success 200 {object} PromptsResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/prompts [get]
+func (h *PromptHandler) GetCatalog(c *gin.Context) {
+	prompts, err := h.promptRepo.List()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to retrieve prompts"})
+		return
+	}
+	lang := requestLanguage(c)
+	items := make([]PromptItem, 0, len(prompts))
+	for _, p := range prompts {
+		items = append(items, PromptItem{ID: p.ID, Text: p.Text(lang)})
+	}
+	c.JSON(http.StatusOK, PromptsResponse{Prompts: items})
+}
+
+// GetAnswers lists the caller's prompt answers (GET /api/users/prompts).
+// @Summary Get my prompt answers
+// @Description Lists the caller's prompt answers in profile order, with each prompt in the language from the lang parameter or Accept-Language header.
+// @Tags Users
+// @Produce json
+// @Security BearerAuth
+// @Param lang query string false "ISO 639-1 language code, e.g. vi"
+// @Success 200 {object} PromptAnswersResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/users/prompts [get]
+func (h *PromptHandler) GetAnswers(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c.Request.Context())
+	if !ok {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "user not authenticated"})
+		return
+	}
+
+	answers, err := h.promptRepo.ListAnswers(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to retrieve prompt answers"})
+		return
+	}
+	c.JSON(http.StatusOK, PromptAnswersResponse{Answers: promptAnswerItems(answers, requestLanguage(c))})
+}
+
+// UpdateAnswers replaces the caller's prompt answers (PUT /api/users/prompts).
+// @Summary Update my prompt answers
+// @Description Replaces the caller's prompt answers with up to three answers to different active prompts, shown on the profile in the order sent. Answers are trimmed and must be 1 to 150 characters. An empty list clears them. Likes on an answer that is kept stay attached to it.
+// @Tags Users
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param payload body UpdatePromptAnswersRequest true "Prompt answers in profile order"
+// @Param lang query string false "ISO 639-1 language code, e.g. vi"
+// @Success 200 {object} PromptAnswersResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/users/prompts [put]
+func (h *PromptHandler) UpdateAnswers(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c.Request.Context())
+	if !ok {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "user not authenticated"})
+		return
+	}
+
+	var req UpdatePromptAnswersRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "invalid request body"})
+		return
+	}
+	if len(req.Answers) > models.MaxPromptAnswers {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: fmt.Sprintf("at most %d prompts may be answered", models.MaxPromptAnswers)})
+		return
+	}
+	answers := make([]*models.PromptAnswer, 0, len(req.Answers))
+	seen := make(map[string]bool, len(req.Answers))
+	for _, a := range req.Answers {
+		if seen[a.PromptID] {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: "each prompt may be answered once"})
+			return
+		}
+		seen[a.PromptID] = true
+		answer := strings.TrimSpace(a.Answer)
+		if answer == "" || utf8.RuneCountInString(answer) > models.MaxPromptAnswerLength {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: fmt.Sprintf("answers must be between 1 and %d characters", models.MaxPromptAnswerLength)})
+			return
+		}
+		answers = append(answers, &models.PromptAnswer{PromptID: a.PromptID, Answer: answer})
+	}
+
+	if err := h.promptRepo.SetAnswers(userID, answers); err != nil {
+		if err == repo.ErrUnknownPrompt {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: "unknown prompt"})
+			return
+		}
+		if err == repo.ErrUserNotFound {
+			c.JSON(http.StatusNotFound, ErrorResponse{Error: "user not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to update prompt answers"})
+		return
+	}
+
+	saved, err := h.promptRepo.ListAnswers(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to retrieve prompt answers"})
+		return
+	}
+	c.JSON(http.StatusOK, PromptAnswersResponse{Answers: promptAnswerItems(saved, requestLanguage(c))})
+}
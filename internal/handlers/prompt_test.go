@@ -0,0 +1,145 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Viet-CodingStars/kyupi-kyupi-backend/internal/middleware"
+	"github.com/Viet-CodingStars/kyupi-kyupi-backend/internal/models"
+	"github.com/Viet-CodingStars/kyupi-kyupi-backend/internal/repo"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type mockPromptRepo struct {
+	prompts map[string]*models.Prompt
+	answers map[uuid.UUID][]*models.PromptAnswer
+}
+
+func newMockPromptRepo() *mockPromptRepo {
+	m := &mockPromptRepo{prompts: make(map[string]*models.Prompt), answers: make(map[uuid.UUID][]*models.PromptAnswer)}
+	for _, p := range []*models.Prompt{
+		{ID: "green_flag", Texts: map[string]string{"en": "A green flag I look for is..."}, Active: true},
+		{ID: "ideal_sunday", Texts: map[string]string{"en": "My ideal Sunday is...", "vi": "Chủ nhật lý tưởng của mình là..."}, Active: true},
+		{ID: "unusual_skill", Texts: map[string]string{"en": "An unusual skill I have is..."}, Active: true},
+		{ID: "pet_peeve", Texts: map[string]string{"en": "My biggest pet peeve is..."}, Active: true},
+		{ID: "fax_number", Texts: map[string]string{"en": "My fax number is..."}},
+	} {
+		m.prompts[p.ID] = p
+	}
+	return m
+}
+
+func (m *mockPromptRepo) List() ([]*models.Prompt, error) {
+	prompts := make([]*models.Prompt, 0)
+	for _, id := range []string{"green_flag", "ideal_sunday", "pet_peeve", "unusual_skill", "fax_number"} {
+		if p := m.prompts[id]; p.Active {
+			prompts = append(prompts, p)
+		}
+	}
+	return prompts, nil
+}
+
+func (m *mockPromptRepo) ListAnswers(userID uuid.UUID) ([]*models.PromptAnswer, error) {
+	answers := make([]*models.PromptAnswer, 0)
+	for _, a := range m.answers[userID] {
+		if p, ok := m.prompts[a.PromptID]; ok && p.Active {
+			a.Prompt = p
+			answers = append(answers, a)
+		}
+	}
+	return answers, nil
+}
+
+func (m *mockPromptRepo) SetAnswers(userID uuid.UUID, answers []*models.PromptAnswer) error {
+	for i, a := range answers {
+		if p, ok := m.prompts[a.PromptID]; !ok || !p.Active {
+			return repo.ErrUnknownPrompt
+		}
+		a.Position = i + 1
+	}
+	m.answers[userID] = answers
+	return nil
+}
+
+func (m *mockPromptRepo) HasAnswered(userID uuid.UUID, promptID string) (bool, error) {
+	answers, _ := m.ListAnswers(userID)
+	for _, a := range answers {
+		if a.PromptID == promptID {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func TestGetPromptCatalog(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	handler := &PromptHandler{promptRepo: newMockPromptRepo()}
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/api/prompts", nil)
+	c.Request.Header.Set("Accept-Language", "vi-VN")
+	handler.GetCatalog(c)
+
+	var response PromptsResponse
+	json.Unmarshal(w.Body.Bytes(), &response)
+	if w.Code != http.StatusOK || len(response.Prompts) != 4 {
+		t.Fatalf("expected the 4 active prompts, got %d: %s", w.Code, w.Body.String())
+	}
+	if response.Prompts[0].Text != "A green flag I look for is..." || response.Prompts[1].Text != "Chủ nhật lý tưởng của mình là..." {
+		t.Fatalf("expected Vietnamese text falling back to English, got %+v", response.Prompts)
+	}
+}
+
+func TestUpdatePromptAnswers(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	promptRepo := newMockPromptRepo()
+	handler := &PromptHandler{promptRepo: promptRepo}
+	userID := uuid.New()
+
+	put := func(body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		req := httptest.NewRequest(http.MethodPut, "/api/users/prompts", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		c.Request = req.WithContext(context.WithValue(req.Context(), middleware.UserIDKey, userID))
+		handler.UpdateAnswers(c)
+		return w
+	}
+
+	w := put(`{"answers":[{"prompt_id":"unusual_skill","answer":"  Juggling durians "},{"prompt_id":"ideal_sunday","answer":"Cà phê sữa đá by the lake"}]}`)
+	var response PromptAnswersResponse
+	json.Unmarshal(w.Body.Bytes(), &response)
+	if w.Code != http.StatusOK || len(response.Answers) != 2 {
+		t.Fatalf("expected 2 answers, got %d: %s", w.Code, w.Body.String())
+	}
+	if first := response.Answers[0]; first.PromptID != "unusual_skill" || first.Answer != "Juggling durians" || first.Prompt != "An unusual skill I have is..." {
+		t.Fatalf("expected the trimmed answers in the order sent, got %+v", response.Answers)
+	}
+
+	long := strings.Repeat("ă", models.MaxPromptAnswerLength+1)
+	for name, body := range map[string]string{
+		"too many":        `{"answers":[{"prompt_id":"green_flag","answer":"a"},{"prompt_id":"ideal_sunday","answer":"b"},{"prompt_id":"unusual_skill","answer":"c"},{"prompt_id":"pet_peeve","answer":"d"}]}`,
+		"duplicate":       `{"answers":[{"prompt_id":"green_flag","answer":"a"},{"prompt_id":"green_flag","answer":"b"}]}`,
+		"blank answer":    `{"answers":[{"prompt_id":"green_flag","answer":"   "}]}`,
+		"answer too long": `{"answers":[{"prompt_id":"green_flag","answer":"` + long + `"}]}`,
+		"unknown prompt":  `{"answers":[{"prompt_id":"favourite_colour","answer":"Teal"}]}`,
+		"retired prompt":  `{"answers":[{"prompt_id":"fax_number","answer":"None"}]}`,
+	} {
+		t.Run("rejects "+name, func(t *testing.T) {
+			if w := put(body); w.Code != http.StatusBadRequest {
+				t.Fatalf("expected status 400, got %d: %s", w.Code, w.Body.String())
+			}
+		})
+	}
+
+	if w := put(`{"answers":[]}`); w.Code != http.StatusOK || len(promptRepo.answers[userID]) != 0 {
+		t.Fatalf("expected answers to be cleared, got %d: %s", w.Code, w.Body.String())
+	}
+}
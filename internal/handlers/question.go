@@ -0,0 +1,240 @@
+package handlers
+
+import (
+	"database/sql"
+	"net/http"
+
+	"github.com/Viet-CodingStars/kyupi-kyupi-backend/internal/middleware"
+	"github.com/Viet-CodingStars/kyupi-kyupi-backend/internal/models"
+	"github.com/Viet-CodingStars/kyupi-kyupi-backend/internal/repo"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// QuestionAnswerLister declares the answer lookup required by UserHandler.
+type QuestionAnswerLister interface {
+	ListAnswers(userID uuid.UUID) ([]*models.QuestionAnswer, error)
+}
+
+// QuestionRepository declares the minimal persistence operations required by QuestionHandler.
+type QuestionRepository interface {
+	QuestionAnswerLister
+	List() ([]*models.Question, error)
+	Get(questionID string) (*models.Question, error)
+	SaveAnswer(userID uuid.UUID, answer *models.QuestionAnswer) error
+	DeleteAnswer(userID uuid.UUID, questionID string) error
+}
+
+type QuestionHandler struct {
+	questionRepo QuestionRepository
+}
+
+func NewQuestionHandler(db *sql.DB) *QuestionHandler {
+	return &QuestionHandler{questionRepo: repo.NewQuestionRepo(db)}
+}
+
+// QuestionItem is a question with its options in the requested language.
+type QuestionItem struct {
+	ID      string   `json:"id"`
+	Text    string   `json:"text"`
+	Options []string `json:"options"`
+}
+
+// QuestionsResponse lists the question bank.
+type QuestionsResponse struct {
+	Questions []QuestionItem `json:"questions"`
+}
+
+// AnswerQuestionRequest answers a question. Options are referred to by their index.
+type AnswerQuestionRequest struct {
+	Answer     *int   `json:"answer"`
+	Accepted   []int  `json:"accepted"`   // options acceptable from a partner
+	Importance string `json:"importance"` // irrelevant, a_little, somewhat, very or mandatory
+}
+
+// QuestionAnswerItem is one of the caller's answers.
+type QuestionAnswerItem struct {
+	QuestionID string `json:"question_id"`
+	Question   string `json:"question"`
+	Answer     int    `json:"answer"`
+	Accepted   []int  `json:"accepted"`
+	Importance string `json:"importance"`
+}
+
+// QuestionAnswersResponse lists the caller's answers.
+type QuestionAnswersResponse struct {
+	Answers []QuestionAnswerItem `json:"answers"`
+}
+
+func questionAnswerItem(a *models.QuestionAnswer, lang string) QuestionAnswerItem {
+	item := QuestionAnswerItem{
+		QuestionID: a.QuestionID,
+		Question:   a.Question.Text(lang),
+		Answer:     a.Answer,
+		Accepted:   make([]int, 0, len(a.Question.Options)),
+		Importance: models.ImportanceName(a.Importance),
+	}
+	for option := range a.Question.Options {
+		if a.Accepts(option) {
+			item.Accepted = append(item.Accepted, option)
+		}
+	}
+	return item
+}
+
+// GetQuestions lists the compatibility questions (GET /api/questions).
+// @Summary List compatibility questions
+// @Description Lists the active compatibility questions with their options, in the language from the lang parameter or Accept-Language header, falling back to English.
+// @Tags Questions
+// @Produce json
+// @Param lang query string false "ISO 639-1 language code, e.g. vi"
+// @Success 200 {object} QuestionsResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/questions [get]
+func (h *QuestionHandler) GetQuestions(c *gin.Context) {
+	questions, err := h.questionRepo.List()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to retrieve questions"})
+		return
+	}
+	lang := requestLanguage(c)
+	items := make([]QuestionItem, 0, len(questions))
+	for _, q := range questions {
+		items = append(items, QuestionItem{ID: q.ID, Text: q.Text(lang), Options: q.OptionTexts(lang)})
+	}
+	c.JSON(http.StatusOK, QuestionsResponse{Questions: items})
+}
+
+// GetAnswers lists the caller's answers (GET /api/users/questions).
+// @Summary Get my compatibility answers
+// @Description Lists the caller's answers to active compatibility questions.
+// @Tags Users
+// @Produce json
+// @Security BearerAuth
+// @Param lang query string false "ISO 639-1 language code, e.g. vi"
+// @Success 200 {object} QuestionAnswersResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/users/questions [get]
+func (h *QuestionHandler) GetAnswers(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c.Request.Context())
+	if !ok {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "user not authenticated"})
+		return
+	}
+
+	answers, err := h.questionRepo.ListAnswers(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to retrieve answers"})
+		return
+	}
+	lang := requestLanguage(c)
+	items := make([]QuestionAnswerItem, 0, len(answers))
+	for _, a := range answers {
+		items = append(items, questionAnswerItem(a, lang))
+	}
+	c.JSON(http.StatusOK, QuestionAnswersResponse{Answers: items})
+}
+
+// AnswerQuestion records the caller's answer to a question (PUT /api/users/questions/:question_id).
+// @Summary Answer a compatibility question
+// @Description Records or replaces the caller's answer: their own option, the options they would accept from a partner and how important that is. Questions answered by both users determine their match percentage. Accepted options may only be empty when the importance is irrelevant.
+// @Tags Users
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param question_id path string true "Question ID"
+// @Param payload body AnswerQuestionRequest true "Answer"
+// @Param lang query string false "ISO 639-1 language code, e.g. vi"
+// @Success 200 {object} QuestionAnswerItem
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/users/questions/{question_id} [put]
+func (h *QuestionHandler) AnswerQuestion(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c.Request.Context())
+	if !ok {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "user not authenticated"})
+		return
+	}
+
+	var req AnswerQuestionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "invalid request body"})
+		return
+	}
+
+	question, err := h.questionRepo.Get(c.Param("question_id"))
+	if err != nil {
+		if err == repo.ErrQuestionNotFound {
+			c.JSON(http.StatusNotFound, ErrorResponse{Error: "question not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to save answer"})
+		return
+	}
+
+	validOption := func(option int) bool { return option >= 0 && option < len(question.Options) }
+	if req.Answer == nil || !validOption(*req.Answer) {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "answer must be the index of one of the question's options"})
+		return
+	}
+	importance, ok := models.ImportanceLevel(req.Importance)
+	if !ok {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "invalid importance"})
+		return
+	}
+	answer := &models.QuestionAnswer{QuestionID: question.ID, Answer: *req.Answer, Importance: importance, Question: question}
+	for _, option := range req.Accepted {
+		if !validOption(option) {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: "accepted must list indexes of the question's options"})
+			return
+		}
+		answer.Accepted |= 1 << uint(option)
+	}
+	if answer.Accepted == 0 && req.Importance != models.ImportanceIrrelevant {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "accept at least one option unless the question is irrelevant to you"})
+		return
+	}
+
+	if err := h.questionRepo.SaveAnswer(userID, answer); err != nil {
+		if err == repo.ErrUserNotFound {
+			c.JSON(http.StatusNotFound, ErrorResponse{Error: "user not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to save answer"})
+		return
+	}
+	c.JSON(http.StatusOK, questionAnswerItem(answer, requestLanguage(c)))
+}
+
+// DeleteAnswer removes the caller's answer to a question (DELETE /api/users/questions/:question_id).
+// @Summary Remove a compatibility answer
+// @Description Removes the caller's answer, so the question no longer counts towards their match percentages.
+// @Tags Users
+// @Produce json
+// @Security BearerAuth
+// @Param question_id path string true "Question ID"
+// @Success 200 {object} MessageResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/users/questions/{question_id} [delete]
+func (h *QuestionHandler) DeleteAnswer(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c.Request.Context())
+	if !ok {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "user not authenticated"})
+		return
+	}
+
+	if err := h.questionRepo.DeleteAnswer(userID, c.Param("question_id")); err != nil {
+		if err == repo.ErrAnswerNotFound {
+			c.JSON(http.StatusNotFound, ErrorResponse{Error: "answer not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to delete answer"})
+		return
+	}
+	c.JSON(http.StatusOK, MessageResponse{Message: "answer deleted"})
+}
@@ -0,0 +1,155 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"testing"
+
+	"github.com/Viet-CodingStars/kyupi-kyupi-backend/internal/middleware"
+	"github.com/Viet-CodingStars/kyupi-kyupi-backend/internal/models"
+	"github.com/Viet-CodingStars/kyupi-kyupi-backend/internal/repo"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type mockQuestionRepo struct {
+	questions map[string]*models.Question
+	answers   map[uuid.UUID]map[string]*models.QuestionAnswer
+}
+
+func newMockQuestionRepo() *mockQuestionRepo {
+	yesNo := []map[string]string{{"en": "Yes", "vi": "Có"}, {"en": "No", "vi": "Không"}, {"en": "Not sure"}}
+	m := &mockQuestionRepo{questions: make(map[string]*models.Question), answers: make(map[uuid.UUID]map[string]*models.QuestionAnswer)}
+	for _, q := range []*models.Question{
+		{ID: "pets_at_home", Texts: map[string]string{"en": "Do you want pets at home?"}, Options: yesNo, Active: true},
+		{ID: "want_children", Texts: map[string]string{"en": "Do you want children someday?", "vi": "Bạn có muốn có con trong tương lai không?"}, Options: yesNo, Active: true},
+		{ID: "landline", Texts: map[string]string{"en": "Do you have a landline?"}, Options: yesNo},
+	} {
+		m.questions[q.ID] = q
+	}
+	return m
+}
+
+func (m *mockQuestionRepo) List() ([]*models.Question, error) {
+	questions := make([]*models.Question, 0)
+	for _, id := range []string{"landline", "pets_at_home", "want_children"} {
+		if q := m.questions[id]; q.Active {
+			questions = append(questions, q)
+		}
+	}
+	return questions, nil
+}
+
+func (m *mockQuestionRepo) Get(questionID string) (*models.Question, error) {
+	if q, ok := m.questions[questionID]; ok && q.Active {
+		return q, nil
+	}
+	return nil, repo.ErrQuestionNotFound
+}
+
+func (m *mockQuestionRepo) ListAnswers(userID uuid.UUID) ([]*models.QuestionAnswer, error) {
+	answers := make([]*models.QuestionAnswer, 0)
+	for _, a := range m.answers[userID] {
+		answers = append(answers, a)
+	}
+	sort.Slice(answers, func(i, j int) bool { return answers[i].QuestionID < answers[j].QuestionID })
+	return answers, nil
+}
+
+func (m *mockQuestionRepo) SaveAnswer(userID uuid.UUID, answer *models.QuestionAnswer) error {
+	if m.answers[userID] == nil {
+		m.answers[userID] = make(map[string]*models.QuestionAnswer)
+	}
+	answer.Question = m.questions[answer.QuestionID]
+	m.answers[userID][answer.QuestionID] = answer
+	return nil
+}
+
+func (m *mockQuestionRepo) DeleteAnswer(userID uuid.UUID, questionID string) error {
+	if _, ok := m.answers[userID][questionID]; !ok {
+		return repo.ErrAnswerNotFound
+	}
+	delete(m.answers[userID], questionID)
+	return nil
+}
+
+func TestGetQuestions(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	handler := &QuestionHandler{questionRepo: newMockQuestionRepo()}
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/api/questions?lang=vi", nil)
+	handler.GetQuestions(c)
+
+	var response QuestionsResponse
+	json.Unmarshal(w.Body.Bytes(), &response)
+	if w.Code != http.StatusOK || len(response.Questions) != 2 {
+		t.Fatalf("expected the 2 active questions, got %d: %s", w.Code, w.Body.String())
+	}
+	q := response.Questions[1]
+	if q.Text != "Bạn có muốn có con trong tương lai không?" || len(q.Options) != 3 || q.Options[0] != "Có" || q.Options[2] != "Not sure" {
+		t.Fatalf("expected Vietnamese text falling back to English, got %+v", q)
+	}
+}
+
+func TestAnswerQuestion(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	questionRepo := newMockQuestionRepo()
+	handler := &QuestionHandler{questionRepo: questionRepo}
+	userID := uuid.New()
+
+	request := func(fn gin.HandlerFunc, method, questionID, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Params = gin.Params{{Key: "question_id", Value: questionID}}
+		req := httptest.NewRequest(method, "/api/users/questions/"+questionID, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		c.Request = req.WithContext(context.WithValue(req.Context(), middleware.UserIDKey, userID))
+		fn(c)
+		return w
+	}
+
+	w := request(handler.AnswerQuestion, http.MethodPut, "want_children", `{"answer":0,"accepted":[0,2],"importance":"very"}`)
+	var item QuestionAnswerItem
+	json.Unmarshal(w.Body.Bytes(), &item)
+	if w.Code != http.StatusOK || item.Importance != models.ImportanceVery || len(item.Accepted) != 2 || item.Accepted[1] != 2 {
+		t.Fatalf("expected the answer to be saved, got %d: %s", w.Code, w.Body.String())
+	}
+	if saved := questionRepo.answers[userID]["want_children"]; saved.Accepted != 0b101 || saved.Importance != 3 {
+		t.Fatalf("expected accepted options as a bitmask, got %+v", saved)
+	}
+
+	if w := request(handler.AnswerQuestion, http.MethodPut, "pets_at_home", `{"answer":1,"importance":"irrelevant"}`); w.Code != http.StatusOK {
+		t.Fatalf("expected an irrelevant question to need no accepted options, got %d: %s", w.Code, w.Body.String())
+	}
+
+	for name, tc := range map[string]struct {
+		questionID, body string
+		want             int
+	}{
+		"missing answer":        {"want_children", `{"accepted":[0],"importance":"very"}`, http.StatusBadRequest},
+		"answer out of range":   {"want_children", `{"answer":3,"accepted":[0],"importance":"very"}`, http.StatusBadRequest},
+		"accepted out of range": {"want_children", `{"answer":0,"accepted":[-1],"importance":"very"}`, http.StatusBadRequest},
+		"nothing accepted":      {"want_children", `{"answer":0,"accepted":[],"importance":"mandatory"}`, http.StatusBadRequest},
+		"unknown importance":    {"want_children", `{"answer":0,"accepted":[0],"importance":"crucial"}`, http.StatusBadRequest},
+		"retired question":      {"landline", `{"answer":0,"accepted":[0],"importance":"very"}`, http.StatusNotFound},
+	} {
+		t.Run("rejects "+name, func(t *testing.T) {
+			if w := request(handler.AnswerQuestion, http.MethodPut, tc.questionID, tc.body); w.Code != tc.want {
+				t.Fatalf("expected status %d, got %d: %s", tc.want, w.Code, w.Body.String())
+			}
+		})
+	}
+
+	if w := request(handler.DeleteAnswer, http.MethodDelete, "want_children", ""); w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", w.Code)
+	}
+	if w := request(handler.DeleteAnswer, http.MethodDelete, "want_children", ""); w.Code != http.StatusNotFound {
+		t.Fatalf("expected status 404 once deleted, got %d", w.Code)
+	}
+}
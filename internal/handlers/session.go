@@ -0,0 +1,179 @@
+package handlers
+
+import (
+	"database/sql"
+	"net/http"
+	"time"
+
+	"github.com/Viet-CodingStars/kyupi-kyupi-backend/internal/auth"
+	"github.com/Viet-CodingStars/kyupi-kyupi-backend/internal/middleware"
+	"github.com/Viet-CodingStars/kyupi-kyupi-backend/internal/models"
+	"github.com/Viet-CodingStars/kyupi-kyupi-backend/internal/repo"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+const (
+	// DeviceNameHeader lets clients label a session, e.g. "Lan's iPhone".
+	DeviceNameHeader = "X-Device-Name"
+	maxDeviceNameLen = 100
+	maxUserAgentLen  = 512
+)
+
+// SessionRepository declares the minimal persistence operations required by SessionHandler.
+type SessionRepository interface {
+	Create(session *models.Session) error
+	ListActive(userID uuid.UUID) ([]*models.Session, error)
+	Revoke(userID, sessionID uuid.UUID) error
+	RevokeAllExcept(userID, keepID uuid.UUID) (int64, error)
+}
+
+type SessionHandler struct {
+	sessionRepo SessionRepository
+}
+
+func NewSessionHandler(db *sql.DB) *SessionHandler {
+	return &SessionHandler{
+		sessionRepo: repo.NewSessionRepo(db),
+	}
+}
+
+// SessionItem is a signed-in device as shown to its owner.
+type SessionItem struct {
+	*models.Session
+	Current bool `json:"current"`
+}
+
+// GetSessionsResponse lists the caller's active sessions.
+type GetSessionsResponse struct {
+	Sessions []SessionItem `json:"sessions"`
+}
+
+// RevokeSessionsResponse reports how many sessions were signed out.
+type RevokeSessionsResponse struct {
+	Revoked int64 `json:"revoked"`
+}
+
+// issueSession records a session for the requesting device and returns a token bound to it.
+func issueSession(c *gin.Context, keys *auth.KeySet, sessionRepo SessionRepository, user *models.User) (string, error) {
+	session := &models.Session{
+		UserID:     user.ID,
+		DeviceName: truncateRunes(c.GetHeader(DeviceNameHeader), maxDeviceNameLen),
+		UserAgent:  truncateRunes(c.Request.UserAgent(), maxUserAgentLen),
+		IPAddress:  c.ClientIP(),
+		ExpiresAt:  time.Now().Add(auth.TokenTTL),
+	}
+	if err := sessionRepo.Create(session); err != nil {
+		return "", err
+	}
+	return keys.GenerateToken(user.ID, user.Email, session.ID, session.ExpiresAt)
+}
+
+func truncateRunes(s string, max int) string {
+	runes := []rune(s)
+	if len(runes) > max {
+		return string(runes[:max])
+	}
+	return s
+}
+
+// GetSessions lists the current user's active sessions (GET /api/users/sessions).
+// @Summary List active sessions
+// @Description Returns every device currently signed in to the account, most recently used first.
+// @Tags Auth
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} GetSessionsResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/users/sessions [get]
+func (h *SessionHandler) GetSessions(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c.Request.Context())
+	if !ok {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "user not authenticated"})
+		return
+	}
+	currentID, _ := middleware.GetSessionID(c.Request.Context())
+
+	sessions, err := h.sessionRepo.ListActive(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to fetch sessions"})
+		return
+	}
+
+	items := make([]SessionItem, 0, len(sessions))
+	for _, session := range sessions {
+		items = append(items, SessionItem{Session: session, Current: session.ID == currentID})
+	}
+
+	c.JSON(http.StatusOK, GetSessionsResponse{Sessions: items})
+}
+
+// RevokeSession signs out one device (DELETE /api/users/sessions/:session_id).
+// @Summary Revoke a session
+// @Description Signs out the given session. Its token stops working immediately.
+// @Tags Auth
+// @Produce json
+// @Security BearerAuth
+// @Param session_id path string true "Session ID"
+// @Success 200 {object} MessageResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/users/sessions/{session_id} [delete]
+func (h *SessionHandler) RevokeSession(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c.Request.Context())
+	if !ok {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "user not authenticated"})
+		return
+	}
+
+	sessionID, err := uuid.Parse(c.Param("session_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "invalid session_id"})
+		return
+	}
+
+	if err := h.sessionRepo.Revoke(userID, sessionID); err != nil {
+		if err == repo.ErrSessionNotFound {
+			c.JSON(http.StatusNotFound, ErrorResponse{Error: "session not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to revoke session"})
+		return
+	}
+
+	c.JSON(http.StatusOK, MessageResponse{Message: "session revoked"})
+}
+
+// RevokeOtherSessions signs out every other device (DELETE /api/users/sessions).
+// @Summary Revoke all other sessions
+// @Description Signs out every session except the one making the request.
+// @Tags Auth
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} RevokeSessionsResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/users/sessions [delete]
+func (h *SessionHandler) RevokeOtherSessions(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c.Request.Context())
+	if !ok {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "user not authenticated"})
+		return
+	}
+	currentID, ok := middleware.GetSessionID(c.Request.Context())
+	if !ok {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "user not authenticated"})
+		return
+	}
+
+	revoked, err := h.sessionRepo.RevokeAllExcept(userID, currentID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to revoke sessions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, RevokeSessionsResponse{Revoked: revoked})
+}
@@ -0,0 +1,209 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Viet-CodingStars/kyupi-kyupi-backend/internal/auth"
+	"github.com/Viet-CodingStars/kyupi-kyupi-backend/internal/middleware"
+	"github.com/Viet-CodingStars/kyupi-kyupi-backend/internal/models"
+	"github.com/Viet-CodingStars/kyupi-kyupi-backend/internal/repo"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// mockSessionRepo implements a mock session repository for testing
+type mockSessionRepo struct {
+	sessions map[uuid.UUID]*models.Session
+}
+
+func newMockSessionRepo() *mockSessionRepo {
+	return &mockSessionRepo{sessions: make(map[uuid.UUID]*models.Session)}
+}
+
+func (m *mockSessionRepo) Create(session *models.Session) error {
+	session.ID = uuid.New()
+	session.CreatedAt = time.Now()
+	session.LastSeenAt = time.Now()
+	m.sessions[session.ID] = session
+	return nil
+}
+
+func (m *mockSessionRepo) Touch(sessionID, userID uuid.UUID) error {
+	session, exists := m.sessions[sessionID]
+	if !exists || session.UserID != userID || session.RevokedAt != nil || session.ExpiresAt.Before(time.Now()) {
+		return repo.ErrSessionNotFound
+	}
+	session.LastSeenAt = time.Now()
+	return nil
+}
+
+func (m *mockSessionRepo) ListActive(userID uuid.UUID) ([]*models.Session, error) {
+	sessions := make([]*models.Session, 0)
+	for _, session := range m.sessions {
+		if session.UserID == userID && session.RevokedAt == nil && session.ExpiresAt.After(time.Now()) {
+			sessions = append(sessions, session)
+		}
+	}
+	return sessions, nil
+}
+
+func (m *mockSessionRepo) Revoke(userID, sessionID uuid.UUID) error {
+	session, exists := m.sessions[sessionID]
+	if !exists || session.UserID != userID || session.RevokedAt != nil {
+		return repo.ErrSessionNotFound
+	}
+	now := time.Now()
+	session.RevokedAt = &now
+	return nil
+}
+
+func (m *mockSessionRepo) RevokeAllExcept(userID, keepID uuid.UUID) (int64, error) {
+	var revoked int64
+	now := time.Now()
+	for id, session := range m.sessions {
+		if session.UserID == userID && id != keepID && session.RevokedAt == nil {
+			session.RevokedAt = &now
+			revoked++
+		}
+	}
+	return revoked, nil
+}
+
+// sessionTestRouter wires the session endpoints behind the real AuthMiddleware.
+func sessionTestRouter(keys *auth.KeySet, sessionRepo *mockSessionRepo) *gin.Engine {
+	handler := &SessionHandler{sessionRepo: sessionRepo}
+	router := gin.New()
+	users := router.Group("/api/users")
+	users.Use(middleware.AuthMiddleware(keys, sessionRepo))
+	users.GET("/sessions", handler.GetSessions)
+	users.DELETE("/sessions", handler.RevokeOtherSessions)
+	users.DELETE("/sessions/:session_id", handler.RevokeSession)
+	return router
+}
+
+func signInDevice(t *testing.T, keys *auth.KeySet, sessionRepo *mockSessionRepo, user *models.User, device string) string {
+	t.Helper()
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodPost, "/api/users/sign_in", nil)
+	c.Request.Header.Set(DeviceNameHeader, device)
+	c.Request.Header.Set("User-Agent", "test-agent")
+
+	token, err := issueSession(c, keys, sessionRepo, user)
+	if err != nil {
+		t.Fatalf("issue session: %v", err)
+	}
+	return token
+}
+
+func doWithToken(router *gin.Engine, method, path, token string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestSessions(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	keys := auth.NewHMACKeySet("test-secret")
+	user := &models.User{ID: uuid.New(), Email: "test@example.com"}
+
+	t.Run("list marks current session", func(t *testing.T) {
+		sessionRepo := newMockSessionRepo()
+		router := sessionTestRouter(keys, sessionRepo)
+		phone := signInDevice(t, keys, sessionRepo, user, "Phone")
+		signInDevice(t, keys, sessionRepo, user, "Laptop")
+
+		w := doWithToken(router, http.MethodGet, "/api/users/sessions", phone)
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+		}
+		var response GetSessionsResponse
+		json.Unmarshal(w.Body.Bytes(), &response)
+		if len(response.Sessions) != 2 {
+			t.Fatalf("expected 2 sessions, got %d", len(response.Sessions))
+		}
+		for _, s := range response.Sessions {
+			if s.Current != (s.DeviceName == "Phone") {
+				t.Fatalf("unexpected current flag on %s", s.DeviceName)
+			}
+			if s.UserAgent != "test-agent" {
+				t.Fatalf("expected user agent to be recorded, got %q", s.UserAgent)
+			}
+		}
+	})
+
+	t.Run("revoked session token stops working", func(t *testing.T) {
+		sessionRepo := newMockSessionRepo()
+		router := sessionTestRouter(keys, sessionRepo)
+		laptop := signInDevice(t, keys, sessionRepo, user, "Laptop")
+		lostPhone := signInDevice(t, keys, sessionRepo, user, "Lost phone")
+
+		claims, _ := keys.ValidateToken(lostPhone)
+		w := doWithToken(router, http.MethodDelete, "/api/users/sessions/"+claims.SessionID.String(), laptop)
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+		}
+
+		w = doWithToken(router, http.MethodGet, "/api/users/sessions", lostPhone)
+		if w.Code != http.StatusUnauthorized {
+			t.Fatalf("expected revoked token to be rejected, got %d", w.Code)
+		}
+	})
+
+	t.Run("cannot revoke another user's session", func(t *testing.T) {
+		sessionRepo := newMockSessionRepo()
+		router := sessionTestRouter(keys, sessionRepo)
+		mine := signInDevice(t, keys, sessionRepo, user, "Phone")
+		theirs := signInDevice(t, keys, sessionRepo, &models.User{ID: uuid.New(), Email: "other@example.com"}, "Phone")
+
+		claims, _ := keys.ValidateToken(theirs)
+		w := doWithToken(router, http.MethodDelete, "/api/users/sessions/"+claims.SessionID.String(), mine)
+		if w.Code != http.StatusNotFound {
+			t.Fatalf("expected status 404, got %d", w.Code)
+		}
+	})
+
+	t.Run("revoke all others keeps current", func(t *testing.T) {
+		sessionRepo := newMockSessionRepo()
+		router := sessionTestRouter(keys, sessionRepo)
+		current := signInDevice(t, keys, sessionRepo, user, "Phone")
+		other1 := signInDevice(t, keys, sessionRepo, user, "Laptop")
+		signInDevice(t, keys, sessionRepo, user, "Tablet")
+
+		w := doWithToken(router, http.MethodDelete, "/api/users/sessions", current)
+		var response RevokeSessionsResponse
+		json.Unmarshal(w.Body.Bytes(), &response)
+		if w.Code != http.StatusOK || response.Revoked != 2 {
+			t.Fatalf("expected 2 sessions revoked, got %d: %s", w.Code, w.Body.String())
+		}
+
+		if w := doWithToken(router, http.MethodGet, "/api/users/sessions", current); w.Code != http.StatusOK {
+			t.Fatalf("expected current session to keep working, got %d", w.Code)
+		}
+		if w := doWithToken(router, http.MethodGet, "/api/users/sessions", other1); w.Code != http.StatusUnauthorized {
+			t.Fatalf("expected other session to be revoked, got %d", w.Code)
+		}
+	})
+
+	t.Run("invalid session id", func(t *testing.T) {
+		sessionRepo := newMockSessionRepo()
+		handler := &SessionHandler{sessionRepo: sessionRepo}
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		req := httptest.NewRequest(http.MethodDelete, "/api/users/sessions/not-a-uuid", nil)
+		c.Request = req.WithContext(context.WithValue(req.Context(), middleware.UserIDKey, user.ID))
+		c.Params = gin.Params{{Key: "session_id", Value: "not-a-uuid"}}
+
+		handler.RevokeSession(c)
+		if w.Code != http.StatusBadRequest {
+			t.Fatalf("expected status 400, got %d", w.Code)
+		}
+	})
+}
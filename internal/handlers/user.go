@@ -2,11 +2,19 @@ package handlers
 
 import (
   "database/sql"
+  "encoding/base64"
+  "errors"
   "fmt"
+  "io"
+  "math"
   "net/http"
+  "strconv"
+  "strings"
   "time"
+  "unicode/utf8"
 
   "github.com/Viet-CodingStars/kyupi-kyupi-backend/internal/auth"
+  "github.com/Viet-CodingStars/kyupi-kyupi-backend/internal/discovery"
   "github.com/Viet-CodingStars/kyupi-kyupi-backend/internal/middleware"
   "github.com/Viet-CodingStars/kyupi-kyupi-backend/internal/models"
   "github.com/Viet-CodingStars/kyupi-kyupi-backend/internal/repo"
@@ -21,21 +29,53 @@ type UserRepository interface {
   GetByEmail(email string) (*models.User, error)
   GetByID(id uuid.UUID) (*models.User, error)
   Update(user *models.User) error
-  GetUsers(cursor *uuid.UUID, limit int) ([]*models.User, error)
-  GetUserDetail(id uuid.UUID) (*models.User, error)
+  GetUserDetail(viewerID, id uuid.UUID) (*models.User, error)
+  ScheduleDeletion(id uuid.UUID, purgeAt time.Time) error
+  CancelDeletion(id uuid.UUID) error
+  Pause(id uuid.UUID, resumeAt *time.Time) (time.Time, error)
+  Resume(id uuid.UUID) ([]*models.Match, error)
 }
 
+// Recommender serves the ranked discovery feed.
+type Recommender interface {
+  // Recommend returns the next page of the viewer's feed after the cursor, the end of the previous page,
+  // or discovery.ErrStaleCursor when the feed was recomputed since.
+  Recommend(viewerID uuid.UUID, cursor *discovery.Cursor, limit int) (*discovery.Page, error)
+}
+
+// maxPauseDuration bounds how far ahead an automatic resume can be scheduled.
+const maxPauseDuration = 365 * 24 * time.Hour
+
 type UserHandler struct {
   userRepo      UserRepository
-  jwtSecret     string
+  mfaRepo       MFARepository
+  sessionRepo   SessionRepository
+  keys          *auth.KeySet
   avatarStorage storage.AvatarStorage
+  likeRepo      LikeRepository
+  messageSeeder MessageSeeder
+  recommender   Recommender
+  interestRepo  ProfileInterestRepository
+  promptRepo    PromptAnswerLister
+  questionRepo  QuestionAnswerLister
+  // deletionGracePeriod is how long a deleted account can be restored before it is purged
+  deletionGracePeriod time.Duration
 }
 
-func NewUserHandler(db *sql.DB, jwtSecret string, avatarStorage storage.AvatarStorage) *UserHandler {
+func NewUserHandler(db *sql.DB, keys *auth.KeySet, avatarStorage storage.AvatarStorage, messageSeeder MessageSeeder, recommender Recommender, deletionGracePeriod time.Duration) *UserHandler {
   return &UserHandler{
-    userRepo:      repo.NewUserRepo(db),
-    jwtSecret:     jwtSecret,
-    avatarStorage: avatarStorage,
+    userRepo:            repo.NewUserRepo(db),
+    mfaRepo:             repo.NewMFARepo(db),
+    sessionRepo:         repo.NewSessionRepo(db),
+    keys:                keys,
+    avatarStorage:       avatarStorage,
+    likeRepo:            repo.NewLikeRepo(db),
+    messageSeeder:       messageSeeder,
+    recommender:         recommender,
+    interestRepo:        repo.NewInterestRepo(db),
+    promptRepo:          repo.NewPromptRepo(db),
+    questionRepo:        repo.NewQuestionRepo(db),
+    deletionGracePeriod: deletionGracePeriod,
   }
 }
 
@@ -62,6 +102,13 @@ type AuthResponse struct {
   User  *models.User `json:"user"`
 }
 
+// ProfileResponse is the current user's profile with their onboarding progress.
+type ProfileResponse struct {
+  *models.User
+  CompletenessPercent int      `json:"completeness_percent"`
+  MissingSteps        []string `json:"missing_steps"` // onboarding steps still to complete, in order
+}
+
 // ErrorResponse represents an error payload.
 type ErrorResponse struct {
   Error string `json:"error"`
@@ -81,6 +128,45 @@ type UpdateUserRequest struct {
   AvatarURL    *string `json:"avatar_url,omitempty"`
   TargetGender *int    `json:"target_gender,omitempty"`
   Intention    *string `json:"intention,omitempty"`
+  Visibility   *string `json:"visibility,omitempty"` // members, incognito or hidden
+  Timezone     *string `json:"timezone,omitempty"`   // IANA name, e.g. Asia/Ho_Chi_Minh
+  // Latitude and Longitude must be sent together.
+  Latitude  *float64 `json:"latitude,omitempty"`
+  Longitude *float64 `json:"longitude,omitempty"`
+  // Optional attributes; an empty string clears one.
+  HeightCm  *int     `json:"height_cm,omitempty"`
+  Education *string  `json:"education,omitempty"`
+  JobTitle  *string  `json:"job_title,omitempty"`
+  Languages []string `json:"languages,omitempty"` // ISO 639-1 codes; an empty list clears them
+  Religion  *string  `json:"religion,omitempty"`
+  Drinking  *string  `json:"drinking,omitempty"`
+  Smoking   *string  `json:"smoking,omitempty"`
+  Children  *string  `json:"children,omitempty"`
+}
+
+// DeleteAccountRequest re-confirms the user's identity before scheduling deletion. Accounts
+// created through social login have no password and send a reauthentication token instead.
+type DeleteAccountRequest struct {
+  Password              string `json:"password,omitempty"`
+  ReauthenticationToken string `json:"reauthentication_token,omitempty"` // from GET /api/users/reauthenticate/{provider}/authorize
+  Code                  string `json:"code,omitempty"`                   // TOTP or recovery code, required when two-factor authentication is enabled
+}
+
+// AccountDeletionResponse reports when a deleted account will be purged.
+type AccountDeletionResponse struct {
+  Message             string    `json:"message"`
+  DeletionScheduledAt time.Time `json:"deletion_scheduled_at"`
+}
+
+// PauseAccountRequest optionally schedules an automatic resume.
+type PauseAccountRequest struct {
+  ResumeAt *time.Time `json:"resume_at,omitempty"`
+}
+
+// ResumeAccountResponse returns the resumed user and the matches formed from likes received while paused.
+type ResumeAccountResponse struct {
+  User       *models.User `json:"user"`
+  NewMatches int64        `json:"new_matches"`
 }
 
 // UserListItem represents a minimal user item in the list response
@@ -90,25 +176,43 @@ type UserListItem struct {
   Gender    int       `json:"gender"`
   AvatarURL string    `json:"avatar_url"`
   Intention string    `json:"intention"`
+  Verified  bool      `json:"verified"` // photos passed selfie verification
 }
 
 // GetUsersResponse represents the response for the user list endpoint
 type GetUsersResponse struct {
   Users      []UserListItem `json:"users"`
-  NextCursor *uuid.UUID     `json:"next_cursor"`
+  NextCursor *string        `json:"next_cursor"`
 }
 
 // UserDetail represents detailed user information
 type UserDetail struct {
-  ID           uuid.UUID  `json:"id"`
-  Name         string     `json:"name"`
-  Gender       int        `json:"gender"`
-  TargetGender *int       `json:"target_gender,omitempty"`
-  Intention    string     `json:"intention"`
-  Bio          string     `json:"bio,omitempty"`
-  AvatarURL    string     `json:"avatar_url,omitempty"`
-  CreatedAt    time.Time  `json:"created_at"`
-  UpdatedAt    time.Time  `json:"updated_at"`
+  ID              uuid.UUID          `json:"id"`
+  Name            string             `json:"name"`
+  Gender          int                `json:"gender"`
+  TargetGender    *int               `json:"target_gender,omitempty"`
+  Intention       string             `json:"intention"`
+  Verified        bool               `json:"verified"` // photos passed selfie verification
+  Bio             string             `json:"bio,omitempty"`
+  AvatarURL       string             `json:"avatar_url,omitempty"`
+  HeightCm        *int               `json:"height_cm,omitempty"`
+  Education       string             `json:"education,omitempty"`
+  JobTitle        string             `json:"job_title,omitempty"`
+  Languages       []string           `json:"languages,omitempty"`
+  Religion        string             `json:"religion,omitempty"`
+  Drinking        string             `json:"drinking,omitempty"`
+  Smoking         string             `json:"smoking,omitempty"`
+  Children        string             `json:"children,omitempty"`
+  Interests       []InterestItem     `json:"interests"`
+  // SharedInterests are the interests the caller picked too
+  SharedInterests []InterestItem     `json:"shared_interests"`
+  // Prompts are the user's prompt answers in profile order
+  Prompts         []PromptAnswerItem `json:"prompts"`
+  // MatchPercent is the compatibility from the questions both users answered, 0 to 100; it is
+  // left out when they have none in common
+  MatchPercent    *int               `json:"match_percent,omitempty"`
+  CreatedAt       time.Time          `json:"created_at"`
+  UpdatedAt       time.Time          `json:"updated_at"`
 }
 
 // validateGender checks if the provided gender matches the supported enum values.
@@ -136,6 +240,46 @@ func parseBirthDate(dateString string) (time.Time, error) {
   return birthDate, nil
 }
 
+// newUserProfile validates the profile fields required at registration and returns an
+// unsaved user carrying them. Error messages are safe to return to the client.
+func newUserProfile(name string, gender int, birthDateStr string, targetGender *int, intention *string) (*models.User, error) {
+  if !validateGender(gender) {
+    return nil, fmt.Errorf("invalid gender (1=male, 2=female, 3=others)")
+  }
+
+  birthDate, err := parseBirthDate(birthDateStr)
+  if err != nil {
+    return nil, err
+  }
+  if !validateAge(birthDate) {
+    return nil, fmt.Errorf("user must be at least 18 years old")
+  }
+
+  user := &models.User{
+    Name:      name,
+    Gender:    gender,
+    BirthDate: birthDate,
+    Intention: models.DefaultIntention(),
+  }
+
+  if targetGender != nil {
+    if !validateGender(*targetGender) {
+      return nil, fmt.Errorf("invalid target_gender (1=male, 2=female, 3=others)")
+    }
+    val := *targetGender
+    user.TargetGender = &val
+  }
+
+  if intention != nil {
+    if !validateIntention(*intention) {
+      return nil, fmt.Errorf("invalid intention value")
+    }
+    user.Intention = *intention
+  }
+
+  return user, nil
+}
+
 // UploadAvatar handles avatar uploads for the current user.
 // @Summary Upload avatar image
 // @Description Uploads an avatar image for the current user and updates the profile.
@@ -193,7 +337,7 @@ func (h *UserHandler) UploadAvatar(c *gin.Context) {
     return
   }
 
-  user.AvatarURL = avatarURL
+  setAvatar(user, avatarURL)
   if err := h.userRepo.Update(user); err != nil {
     c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to update user"})
     return
@@ -202,6 +346,15 @@ func (h *UserHandler) UploadAvatar(c *gin.Context) {
   c.JSON(http.StatusOK, user)
 }
 
+// setAvatar changes the user's avatar. A photo verification only vouches for the avatar it was
+// compared with, so a different one loses the verified badge until the user verifies again.
+func setAvatar(user *models.User, avatarURL string) {
+  if avatarURL != user.AvatarURL {
+    user.VerifiedAt = nil
+  }
+  user.AvatarURL = avatarURL
+}
+
 // SignUp handles user registration (POST /api/users).
 // @Summary Register a new user
 // @Description Creates a new account and returns a JWT token for the user.
@@ -226,56 +379,21 @@ func (h *UserHandler) SignUp(c *gin.Context) {
     return
   }
 
-  if !validateGender(req.Gender) {
-    c.JSON(http.StatusBadRequest, ErrorResponse{Error: "invalid gender (1=male, 2=female, 3=others)"})
-    return
-  }
-
-  birthDate, err := parseBirthDate(req.BirthDate)
+  user, err := newUserProfile(req.Name, req.Gender, req.BirthDate, req.TargetGender, req.Intention)
   if err != nil {
     c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
     return
   }
 
-  if !validateAge(birthDate) {
-    c.JSON(http.StatusBadRequest, ErrorResponse{Error: "user must be at least 18 years old"})
-    return
-  }
-
   hashedPassword, err := auth.HashPassword(req.Password)
   if err != nil {
     c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to hash password"})
     return
   }
 
-  var targetGender *int
-  if req.TargetGender != nil {
-    if !validateGender(*req.TargetGender) {
-      c.JSON(http.StatusBadRequest, ErrorResponse{Error: "invalid target_gender (1=male, 2=female, 3=others)"})
-      return
-    }
-    val := *req.TargetGender
-    targetGender = &val
-  }
-
-  intention := models.DefaultIntention()
-  if req.Intention != nil {
-    if !validateIntention(*req.Intention) {
-      c.JSON(http.StatusBadRequest, ErrorResponse{Error: "invalid intention value"})
-      return
-    }
-    intention = *req.Intention
-  }
-
-  user := &models.User{
-    Email:        req.Email,
-    PasswordHash: hashedPassword,
-    Name:         req.Name,
-    Gender:       req.Gender,
-    BirthDate:    birthDate,
-    TargetGender: targetGender,
-    Intention:    intention,
-  }
+  user.Email = req.Email
+  user.PasswordHash = hashedPassword
+  user.SignupIP = c.ClientIP()
 
   if err := h.userRepo.Create(user); err != nil {
     if err == repo.ErrEmailAlreadyExists {
@@ -286,9 +404,9 @@ func (h *UserHandler) SignUp(c *gin.Context) {
     return
   }
 
-  token, err := auth.GenerateToken(user.ID, user.Email, h.jwtSecret)
+  token, err := issueSession(c, h.keys, h.sessionRepo, user)
   if err != nil {
-    c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to generate token"})
+    c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to create session"})
     return
   }
 
@@ -297,12 +415,13 @@ func (h *UserHandler) SignUp(c *gin.Context) {
 
 // SignIn handles user login (POST /api/users/sign_in).
 // @Summary Authenticate a user
-// @Description Verifies credentials and returns a JWT token.
+// @Description Verifies credentials and returns a JWT token. Users with two-factor authentication enabled receive an MFA challenge token instead, to be completed at /api/users/sign_in/mfa.
 // @Tags Auth
 // @Accept json
 // @Produce json
 // @Param payload body SignInRequest true "User sign in payload"
 // @Success 200 {object} AuthResponse
+// @Success 200 {object} MFAChallengeResponse
 // @Failure 400 {object} ErrorResponse
 // @Failure 401 {object} ErrorResponse
 // @Failure 500 {object} ErrorResponse
@@ -325,36 +444,49 @@ func (h *UserHandler) SignIn(c *gin.Context) {
     return
   }
 
-  token, err := auth.GenerateToken(user.ID, user.Email, h.jwtSecret)
-  if err != nil {
-    c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to generate token"})
-    return
-  }
-
-  c.JSON(http.StatusOK, AuthResponse{Token: token, User: user})
+  respondWithSession(c, h.keys, h.mfaRepo, h.sessionRepo, user)
 }
 
 // SignOut handles user logout (DELETE /api/users/sign_out).
 // @Summary Sign out the current user
-// @Description Stateless logout helper that simply acknowledges the request.
+// @Description Revokes the session of the token used for the request.
 // @Tags Auth
 // @Produce json
 // @Security BearerAuth
 // @Success 200 {object} MessageResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
 // @Router /api/users/sign_out [delete]
 func (h *UserHandler) SignOut(c *gin.Context) {
+  userID, ok := middleware.GetUserID(c.Request.Context())
+  if !ok {
+    c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "user not authenticated"})
+    return
+  }
+  sessionID, ok := middleware.GetSessionID(c.Request.Context())
+  if !ok {
+    c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "user not authenticated"})
+    return
+  }
+
+  if err := h.sessionRepo.Revoke(userID, sessionID); err != nil && err != repo.ErrSessionNotFound {
+    c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to sign out"})
+    return
+  }
+
   c.JSON(http.StatusOK, MessageResponse{Message: "logged out successfully"})
 }
 
 // GetProfile returns the current user's profile (GET /api/users/profile).
 // @Summary Get current user profile
-// @Description Returns the authenticated user's profile details.
+// @Description Returns the authenticated user's profile details, how complete the profile is and the onboarding steps still missing. Profiles below the configured completeness are not shown in discovery.
 // @Tags Users
 // @Produce json
 // @Security BearerAuth
-// @Success 200 {object} models.User
+// @Success 200 {object} ProfileResponse
 // @Failure 401 {object} ErrorResponse
 // @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
 // @Router /api/users/profile [get]
 func (h *UserHandler) GetProfile(c *gin.Context) {
   userID, ok := middleware.GetUserID(c.Request.Context())
@@ -368,8 +500,23 @@ func (h *UserHandler) GetProfile(c *gin.Context) {
     c.JSON(http.StatusNotFound, ErrorResponse{Error: "user not found"})
     return
   }
+  interests, err := h.interestRepo.ListForProfile(userID, userID)
+  if err != nil {
+    c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to retrieve profile"})
+    return
+  }
+  answers, err := h.promptRepo.ListAnswers(userID)
+  if err != nil {
+    c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to retrieve profile"})
+    return
+  }
 
-  c.JSON(http.StatusOK, user)
+  checklist := models.NewProfileChecklist(user, len(interests) > 0, len(answers) > 0)
+  c.JSON(http.StatusOK, ProfileResponse{
+    User:                user,
+    CompletenessPercent: int(math.Round(checklist.Completeness() * 100)),
+    MissingSteps:        checklist.Missing(),
+  })
 }
 
 // UpdateProfile updates the current user's profile (PATCH/PUT /api/users/profile).
@@ -435,7 +582,7 @@ func (h *UserHandler) UpdateProfile(c *gin.Context) {
     user.Bio = *req.Bio
   }
   if req.AvatarURL != nil {
-    user.AvatarURL = *req.AvatarURL
+    setAvatar(user, *req.AvatarURL)
   }
   if req.TargetGender != nil {
     if !validateGender(*req.TargetGender) {
@@ -452,6 +599,79 @@ func (h *UserHandler) UpdateProfile(c *gin.Context) {
     }
     user.Intention = *req.Intention
   }
+  if req.Visibility != nil {
+    if !models.IsValidVisibility(*req.Visibility) {
+      c.JSON(http.StatusBadRequest, ErrorResponse{Error: "invalid visibility (members, incognito or hidden)"})
+      return
+    }
+    user.Visibility = *req.Visibility
+  }
+  if req.Timezone != nil {
+    if !models.IsValidTimezone(*req.Timezone) {
+      c.JSON(http.StatusBadRequest, ErrorResponse{Error: "invalid timezone (use an IANA name such as Asia/Ho_Chi_Minh)"})
+      return
+    }
+    user.Timezone = *req.Timezone
+  }
+  if req.Latitude != nil || req.Longitude != nil {
+    if req.Latitude == nil || req.Longitude == nil || !models.IsValidLocation(*req.Latitude, *req.Longitude) {
+      c.JSON(http.StatusBadRequest, ErrorResponse{Error: "latitude and longitude must be set together (-90..90, -180..180)"})
+      return
+    }
+    lat, lng := *req.Latitude, *req.Longitude
+    user.Latitude = &lat
+    user.Longitude = &lng
+  }
+  if req.HeightCm != nil {
+    if !models.IsValidHeight(*req.HeightCm) {
+      c.JSON(http.StatusBadRequest, ErrorResponse{Error: fmt.Sprintf("height_cm must be between %d and %d", models.MinHeightCm, models.MaxHeightCm)})
+      return
+    }
+    val := *req.HeightCm
+    user.HeightCm = &val
+  }
+  if req.JobTitle != nil {
+    jobTitle := strings.TrimSpace(*req.JobTitle)
+    if utf8.RuneCountInString(jobTitle) > models.MaxJobTitleLength {
+      c.JSON(http.StatusBadRequest, ErrorResponse{Error: fmt.Sprintf("job_title must be at most %d characters", models.MaxJobTitleLength)})
+      return
+    }
+    user.JobTitle = jobTitle
+  }
+  if req.Languages != nil {
+    if len(req.Languages) > models.MaxLanguages {
+      c.JSON(http.StatusBadRequest, ErrorResponse{Error: fmt.Sprintf("at most %d languages", models.MaxLanguages)})
+      return
+    }
+    for _, language := range req.Languages {
+      if !models.IsValidLanguage(language) {
+        c.JSON(http.StatusBadRequest, ErrorResponse{Error: "invalid languages value (use ISO 639-1 codes such as vi or en)"})
+        return
+      }
+    }
+    user.Languages = req.Languages
+  }
+  for _, attr := range []struct {
+    name  string
+    value *string
+    valid func(string) bool
+    field *string
+  }{
+    {"education", req.Education, models.IsValidEducation, &user.Education},
+    {"religion", req.Religion, models.IsValidReligion, &user.Religion},
+    {"drinking", req.Drinking, models.IsValidHabit, &user.Drinking},
+    {"smoking", req.Smoking, models.IsValidHabit, &user.Smoking},
+    {"children", req.Children, models.IsValidChildren, &user.Children},
+  } {
+    if attr.value == nil {
+      continue
+    }
+    if *attr.value != "" && !attr.valid(*attr.value) {
+      c.JSON(http.StatusBadRequest, ErrorResponse{Error: "invalid " + attr.name + " value"})
+      return
+    }
+    *attr.field = *attr.value
+  }
 
   if err := h.userRepo.Update(user); err != nil {
     c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to update user"})
@@ -461,27 +681,36 @@ func (h *UserHandler) UpdateProfile(c *gin.Context) {
   c.JSON(http.StatusOK, user)
 }
 
-// GetUsers returns a paginated list of users (GET /api/users).
+// GetUsers returns a page of the caller's ranked discovery feed (GET /api/users).
 // @Summary Get list of users
-// @Description Returns a paginated list of users with minimal information for swipe screen.
+// @Description Returns a paginated list of the users visible to the caller, best matches first, with minimal information for swipe screen. Users the caller already liked or passed, users outside either side's gender preference, and users with an incompatible intention are left out. Hidden and paused profiles are never listed; incognito profiles are listed only to people they liked. The ranking is recomputed when the first page is requested after the cache expires. Pass next_cursor back as cursor for the next page; if the ranking was recomputed in the meantime the cursor is rejected with 409 and the feed has to be loaded again from the first page.
 // @Tags Users
 // @Produce json
-// @Param cursor query string false "Cursor for pagination (UUID)"
+// @Security BearerAuth
+// @Param cursor query string false "Cursor from the previous page"
 // @Param limit query int false "Number of users to return (default: 20, max: 100)"
 // @Success 200 {object} GetUsersResponse
 // @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
 // @Failure 500 {object} ErrorResponse
 // @Router /api/users [get]
 func (h *UserHandler) GetUsers(c *gin.Context) {
+  viewerID, ok := middleware.GetUserID(c.Request.Context())
+  if !ok {
+    c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "user not authenticated"})
+    return
+  }
+
   // Parse cursor parameter
-  var cursor *uuid.UUID
-  if cursorStr := c.Query("cursor"); cursorStr != "" {
-    cursorUUID, err := uuid.Parse(cursorStr)
+  var cursor *discovery.Cursor
+  if raw := c.Query("cursor"); raw != "" {
+    decoded, err := decodeFeedCursor(raw)
     if err != nil {
       c.JSON(http.StatusBadRequest, ErrorResponse{Error: "invalid cursor format"})
       return
     }
-    cursor = &cursorUUID
+    cursor = decoded
   }
 
   // Parse limit parameter with default of 20
@@ -494,12 +723,17 @@ func (h *UserHandler) GetUsers(c *gin.Context) {
     }
   }
 
-  // Get users from repository
-  users, err := h.userRepo.GetUsers(cursor, limit)
+  // Get the ranked feed
+  page, err := h.recommender.Recommend(viewerID, cursor, limit)
+  if err == discovery.ErrStaleCursor {
+    c.JSON(http.StatusConflict, ErrorResponse{Error: "feed was refreshed, start again from the first page"})
+    return
+  }
   if err != nil {
     c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to retrieve users"})
     return
   }
+  users := page.Users
 
   // Convert to response format
   userItems := make([]UserListItem, len(users))
@@ -510,14 +744,15 @@ func (h *UserHandler) GetUsers(c *gin.Context) {
       Gender:    user.Gender,
       AvatarURL: user.AvatarURL,
       Intention: user.Intention,
+      Verified:  user.VerifiedAt != nil,
     }
   }
 
   // Determine next cursor
-  var nextCursor *uuid.UUID
-  if len(users) == limit {
-    lastID := users[len(users)-1].ID
-    nextCursor = &lastID
+  var nextCursor *string
+  if len(users) == limit && page.Next != nil {
+    encoded := encodeFeedCursor(*page.Next)
+    nextCursor = &encoded
   }
 
   c.JSON(http.StatusOK, GetUsersResponse{
@@ -526,18 +761,57 @@ func (h *UserHandler) GetUsers(c *gin.Context) {
   })
 }
 
+// encodeFeedCursor packs a feed position, including the ranking generation, into an opaque URL-safe token.
+func encodeFeedCursor(cursor discovery.Cursor) string {
+  raw := strconv.FormatInt(cursor.Generation, 10) + "_" + strconv.FormatFloat(cursor.Score, 'g', -1, 64) + "_" + cursor.CandidateID.String()
+  return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodeFeedCursor(token string) (*discovery.Cursor, error) {
+  raw, err := base64.RawURLEncoding.DecodeString(token)
+  if err != nil {
+    return nil, err
+  }
+  parts := strings.Split(string(raw), "_")
+  if len(parts) != 3 {
+    return nil, errors.New("malformed cursor")
+  }
+  generation, err := strconv.ParseInt(parts[0], 10, 64)
+  if err != nil {
+    return nil, err
+  }
+  score, err := strconv.ParseFloat(parts[1], 64)
+  if err != nil {
+    return nil, err
+  }
+  candidateID, err := uuid.Parse(parts[2])
+  if err != nil {
+    return nil, err
+  }
+  return &discovery.Cursor{Generation: generation, Score: score, CandidateID: candidateID}, nil
+}
+
 // GetUserDetail returns detailed information about a specific user (GET /api/users/:user_id/detail).
 // @Summary Get user detail
-// @Description Returns detailed information about a specific user.
+// @Description Returns detailed information about a specific user, including their prompt answers, the match percentage from the compatibility questions you both answered, their interests and those the caller shares with them, labelled in the language from the lang parameter or Accept-Language header. Profiles the caller may not see are reported as not found; matches can always see each other.
 // @Tags Users
 // @Produce json
+// @Security BearerAuth
 // @Param user_id path string true "User ID (UUID)"
+// @Param lang query string false "ISO 639-1 language code, e.g. vi"
 // @Success 200 {object} UserDetail
 // @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
 // @Failure 404 {object} ErrorResponse
 // @Failure 500 {object} ErrorResponse
 // @Router /api/users/{user_id}/detail [get]
 func (h *UserHandler) GetUserDetail(c *gin.Context) {
+  viewerID, ok := middleware.GetUserID(c.Request.Context())
+  if !ok {
+    c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "user not authenticated"})
+    return
+  }
+
   userIDStr := c.Param("user_id")
   userID, err := uuid.Parse(userIDStr)
   if err != nil {
@@ -545,7 +819,7 @@ func (h *UserHandler) GetUserDetail(c *gin.Context) {
     return
   }
 
-  user, err := h.userRepo.GetUserDetail(userID)
+  user, err := h.userRepo.GetUserDetail(viewerID, userID)
   if err != nil {
     if err == repo.ErrUserNotFound {
       c.JSON(http.StatusNotFound, ErrorResponse{Error: "user not found"})
@@ -555,17 +829,299 @@ func (h *UserHandler) GetUserDetail(c *gin.Context) {
     return
   }
 
+  interests, err := h.interestRepo.ListForProfile(viewerID, userID)
+  if err != nil {
+    c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to retrieve user detail"})
+    return
+  }
+  answers, err := h.promptRepo.ListAnswers(userID)
+  if err != nil {
+    c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to retrieve user detail"})
+    return
+  }
+  matchPercent, err := h.matchPercent(viewerID, userID)
+  if err != nil {
+    c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to retrieve user detail"})
+    return
+  }
+  lang := requestLanguage(c)
+
   detail := UserDetail{
-    ID:           user.ID,
-    Name:         user.Name,
-    Gender:       user.Gender,
-    TargetGender: user.TargetGender,
-    Intention:    user.Intention,
-    Bio:          user.Bio,
-    AvatarURL:    user.AvatarURL,
-    CreatedAt:    user.CreatedAt,
-    UpdatedAt:    user.UpdatedAt,
+    ID:              user.ID,
+    Name:            user.Name,
+    Gender:          user.Gender,
+    TargetGender:    user.TargetGender,
+    Intention:       user.Intention,
+    Verified:        user.VerifiedAt != nil,
+    Bio:             user.Bio,
+    AvatarURL:       user.AvatarURL,
+    HeightCm:        user.HeightCm,
+    Education:       user.Education,
+    JobTitle:        user.JobTitle,
+    Languages:       user.Languages,
+    Religion:        user.Religion,
+    Drinking:        user.Drinking,
+    Smoking:         user.Smoking,
+    Children:        user.Children,
+    Interests:       make([]InterestItem, 0, len(interests)),
+    SharedInterests: make([]InterestItem, 0),
+    Prompts:         promptAnswerItems(answers, lang),
+    MatchPercent:    matchPercent,
+    CreatedAt:       user.CreatedAt,
+    UpdatedAt:       user.UpdatedAt,
+  }
+  for _, i := range interests {
+    item := InterestItem{Slug: i.Slug, Category: i.Category, Label: i.Label(lang)}
+    detail.Interests = append(detail.Interests, item)
+    if i.Shared {
+      detail.SharedInterests = append(detail.SharedInterests, item)
+    }
   }
 
   c.JSON(http.StatusOK, detail)
 }
+
+// matchPercent computes the compatibility between two users as a percentage, nil when they
+// have answered no questions in common.
+func (h *UserHandler) matchPercent(viewerID, userID uuid.UUID) (*int, error) {
+  var answers [2][]models.QuestionAnswer
+  for i, id := range []uuid.UUID{viewerID, userID} {
+    list, err := h.questionRepo.ListAnswers(id)
+    if err != nil {
+      return nil, err
+    }
+    for _, a := range list {
+      answers[i] = append(answers[i], *a)
+    }
+  }
+  match, ok := discovery.Compatibility(answers[0], answers[1])
+  if !ok {
+    return nil, nil
+  }
+  percent := int(math.Round(match * 100))
+  return &percent, nil
+}
+
+// DeleteAccount schedules the current user's account for deletion (DELETE /api/users/profile).
+// @Summary Delete current user account
+// @Description Re-confirms the password, or a fresh social sign-in through reauthentication_token for accounts without one (and second factor, if enabled), signs out every session and schedules the account for permanent deletion after the grace period. Signing in again during the grace period allows restoring the account.
+// @Tags Users
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param payload body DeleteAccountRequest true "Password or reauthentication token"
+// @Success 202 {object} AccountDeletionResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/users/profile [delete]
+func (h *UserHandler) DeleteAccount(c *gin.Context) {
+  userID, ok := middleware.GetUserID(c.Request.Context())
+  if !ok {
+    c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "user not authenticated"})
+    return
+  }
+
+  var req DeleteAccountRequest
+  if err := c.ShouldBindJSON(&req); err != nil || (req.Password == "" && req.ReauthenticationToken == "") {
+    c.JSON(http.StatusBadRequest, ErrorResponse{Error: "password or reauthentication_token is required"})
+    return
+  }
+
+  user, err := h.userRepo.GetByID(userID)
+  if err != nil {
+    c.JSON(http.StatusNotFound, ErrorResponse{Error: "user not found"})
+    return
+  }
+  if user.DeletionScheduledAt != nil {
+    c.JSON(http.StatusConflict, ErrorResponse{Error: "account deletion already scheduled"})
+    return
+  }
+
+  if req.ReauthenticationToken != "" {
+    if !validReauthentication(h.keys, req.ReauthenticationToken, userID) {
+      c.JSON(http.StatusForbidden, ErrorResponse{Error: "invalid or expired reauthentication token"})
+      return
+    }
+  } else if err := auth.CheckPassword(user.PasswordHash, req.Password); err != nil {
+    c.JSON(http.StatusForbidden, ErrorResponse{Error: "invalid password"})
+    return
+  }
+
+  mfa, err := h.mfaRepo.Get(userID)
+  if err != nil && err != repo.ErrMFANotFound {
+    c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to load mfa settings"})
+    return
+  }
+  if mfa != nil && mfa.TOTPEnabled {
+    valid, err := verifySecondFactor(h.mfaRepo, mfa, req.Code, true)
+    if err != nil {
+      c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to verify code"})
+      return
+    }
+    if !valid {
+      c.JSON(http.StatusForbidden, ErrorResponse{Error: "invalid code"})
+      return
+    }
+  }
+
+  purgeAt := time.Now().Add(h.deletionGracePeriod)
+  if err := h.userRepo.ScheduleDeletion(userID, purgeAt); err != nil {
+    c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to schedule deletion"})
+    return
+  }
+  if _, err := h.sessionRepo.RevokeAllExcept(userID, uuid.Nil); err != nil {
+    c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to sign out sessions"})
+    return
+  }
+
+  c.JSON(http.StatusAccepted, AccountDeletionResponse{
+    Message:             "account scheduled for deletion",
+    DeletionScheduledAt: purgeAt,
+  })
+}
+
+// RestoreAccount cancels a scheduled deletion (POST /api/users/profile/restore).
+// @Summary Restore current user account
+// @Description Cancels a pending account deletion while the grace period has not yet passed.
+// @Tags Users
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} models.User
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/users/profile/restore [post]
+func (h *UserHandler) RestoreAccount(c *gin.Context) {
+  userID, ok := middleware.GetUserID(c.Request.Context())
+  if !ok {
+    c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "user not authenticated"})
+    return
+  }
+
+  user, err := h.userRepo.GetByID(userID)
+  if err != nil {
+    c.JSON(http.StatusNotFound, ErrorResponse{Error: "user not found"})
+    return
+  }
+  if user.DeletionScheduledAt == nil {
+    c.JSON(http.StatusBadRequest, ErrorResponse{Error: "account is not scheduled for deletion"})
+    return
+  }
+
+  if err := h.userRepo.CancelDeletion(userID); err != nil {
+    if err == repo.ErrDeletionInProgress {
+      c.JSON(http.StatusConflict, ErrorResponse{Error: "grace period has passed; the account is being deleted"})
+      return
+    }
+    c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to restore account"})
+    return
+  }
+
+  user.DeletionScheduledAt = nil
+  c.JSON(http.StatusOK, user)
+}
+
+// PauseAccount hides the current user from other users (POST /api/users/profile/pause).
+// @Summary Pause current user account
+// @Description Hides the profile from user lists and discovery without deleting anything. Matches and chats stay available, and likes received while paused are held until the account resumes. Send resume_at to resume automatically; pausing again updates it.
+// @Tags Users
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param payload body PauseAccountRequest false "Pause payload"
+// @Success 200 {object} models.User
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/users/profile/pause [post]
+func (h *UserHandler) PauseAccount(c *gin.Context) {
+  userID, ok := middleware.GetUserID(c.Request.Context())
+  if !ok {
+    c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "user not authenticated"})
+    return
+  }
+
+  var req PauseAccountRequest
+  if err := c.ShouldBindJSON(&req); err != nil && err != io.EOF {
+    c.JSON(http.StatusBadRequest, ErrorResponse{Error: "invalid request body"})
+    return
+  }
+  if req.ResumeAt != nil {
+    until := time.Until(*req.ResumeAt)
+    if until <= 0 {
+      c.JSON(http.StatusBadRequest, ErrorResponse{Error: "resume_at must be in the future"})
+      return
+    }
+    if until > maxPauseDuration {
+      c.JSON(http.StatusBadRequest, ErrorResponse{Error: "resume_at must be within one year"})
+      return
+    }
+  }
+
+  user, err := h.userRepo.GetByID(userID)
+  if err != nil {
+    c.JSON(http.StatusNotFound, ErrorResponse{Error: "user not found"})
+    return
+  }
+
+  pausedAt, err := h.userRepo.Pause(userID, req.ResumeAt)
+  if err != nil {
+    c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to pause account"})
+    return
+  }
+
+  user.PausedAt = &pausedAt
+  user.ResumeAt = req.ResumeAt
+  c.JSON(http.StatusOK, user)
+}
+
+// ResumeAccount ends a pause (POST /api/users/profile/resume).
+// @Summary Resume current user account
+// @Description Makes the profile visible again and delivers the likes received while paused, creating matches where the like was already returned.
+// @Tags Users
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} ResumeAccountResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/users/profile/resume [post]
+func (h *UserHandler) ResumeAccount(c *gin.Context) {
+  userID, ok := middleware.GetUserID(c.Request.Context())
+  if !ok {
+    c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "user not authenticated"})
+    return
+  }
+
+  user, err := h.userRepo.GetByID(userID)
+  if err != nil {
+    c.JSON(http.StatusNotFound, ErrorResponse{Error: "user not found"})
+    return
+  }
+
+  matches, err := h.userRepo.Resume(userID)
+  if err != nil {
+    if err == repo.ErrNotPaused {
+      c.JSON(http.StatusBadRequest, ErrorResponse{Error: "account is not paused"})
+      return
+    }
+    c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to resume account"})
+    return
+  }
+  for _, match := range matches {
+    // The match stands even if its chat cannot be seeded
+    _ = seedMatchMessages(c.Request.Context(), h.likeRepo, h.messageSeeder, match)
+  }
+
+  user.PausedAt = nil
+  user.ResumeAt = nil
+  c.JSON(http.StatusOK, ResumeAccountResponse{User: user, NewMatches: int64(len(matches))})
+}
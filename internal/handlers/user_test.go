@@ -4,19 +4,23 @@ import (
   "bytes"
   "context"
   "encoding/json"
+  "errors"
   "io"
   "mime/multipart"
   "net/http"
   "net/http/httptest"
+  "strings"
   "testing"
   "time"
 
   "github.com/Viet-CodingStars/kyupi-kyupi-backend/internal/auth"
+  "github.com/Viet-CodingStars/kyupi-kyupi-backend/internal/discovery"
   "github.com/Viet-CodingStars/kyupi-kyupi-backend/internal/middleware"
   "github.com/Viet-CodingStars/kyupi-kyupi-backend/internal/models"
   "github.com/Viet-CodingStars/kyupi-kyupi-backend/internal/repo"
   "github.com/Viet-CodingStars/kyupi-kyupi-backend/internal/storage"
   "github.com/gin-gonic/gin"
+  "github.com/golang-jwt/jwt/v5"
   "github.com/google/uuid"
 )
 
@@ -47,6 +51,15 @@ func (m *mockAvatarStorage) Save(userID uuid.UUID, data io.Reader, originalFilen
   return path, nil
 }
 
+func (m *mockAvatarStorage) DeleteAll(userID uuid.UUID) error {
+  delete(m.saved, userID)
+  return nil
+}
+
+func (m *mockAvatarStorage) ForEach(userID uuid.UUID, fn func(filename string, data io.Reader) error) error {
+  return nil
+}
+
 func (m *mockUserRepo) Create(user *models.User) error {
   if _, exists := m.users[user.Email]; exists {
     return repo.ErrEmailAlreadyExists
@@ -89,36 +102,108 @@ func (m *mockUserRepo) Update(user *models.User) error {
   return repo.ErrUserNotFound
 }
 
-func (m *mockUserRepo) GetUsers(cursor *uuid.UUID, limit int) ([]*models.User, error) {
-  users := make([]*models.User, 0)
-  
+func (m *mockUserRepo) HasPhoto(userID uuid.UUID, photoURL string) (bool, error) {
+  user, err := m.GetByID(userID)
+  if err != nil {
+    return false, nil
+  }
+  return user.AvatarURL != "" && user.AvatarURL == photoURL, nil
+}
+
+// visibleTo mirrors the repository rule for users without likes or matches: only
+// members-visible profiles, plus the viewer's own.
+func (m *mockUserRepo) visibleTo(viewerID uuid.UUID, user *models.User) bool {
+  return user.ID == viewerID || user.Visibility == "" || user.Visibility == models.VisibilityMembers
+}
+
+// mockRecommender serves a fixed ranking, best first, under its current generation.
+type mockRecommender struct {
+  ranked     []*models.User
+  generation int64
+  err        error
+}
+
+func (m *mockRecommender) Recommend(viewerID uuid.UUID, cursor *discovery.Cursor, limit int) (*discovery.Page, error) {
+  if m.err != nil {
+    return nil, m.err
+  }
+  start := 0
+  if cursor != nil {
+    if cursor.Generation != m.generation {
+      return nil, discovery.ErrStaleCursor
+    }
+    for i, user := range m.ranked {
+      if user.ID == cursor.CandidateID {
+        start = i + 1
+      }
+    }
+  }
+  page := &discovery.Page{Users: make([]*models.User, 0)}
+  for i, user := range m.ranked[start:] {
+    if len(page.Users) == limit {
+      break
+    }
+    page.Users = append(page.Users, user)
+    page.Next = &discovery.Cursor{Generation: m.generation, Score: float64(len(m.ranked) - start - i), CandidateID: user.ID}
+  }
+  return page, nil
+}
+
+func (m *mockUserRepo) GetUserDetail(viewerID, id uuid.UUID) (*models.User, error) {
   for _, user := range m.users {
-    if cursor == nil || user.ID.String() > cursor.String() {
-      users = append(users, user)
+    if user.ID == id && m.visibleTo(viewerID, user) {
+      return user, nil
     }
   }
-  
-  // Sort by ID to simulate database ordering
-  // Simple sort for test purposes
-  for i := 0; i < len(users)-1; i++ {
-    for j := i + 1; j < len(users); j++ {
-      if users[i].ID.String() > users[j].ID.String() {
-        users[i], users[j] = users[j], users[i]
+  return nil, repo.ErrUserNotFound
+}
+
+func (m *mockUserRepo) ScheduleDeletion(id uuid.UUID, purgeAt time.Time) error {
+  for _, user := range m.users {
+    if user.ID == id {
+      user.DeletionScheduledAt = &purgeAt
+      return nil
+    }
+  }
+  return repo.ErrUserNotFound
+}
+
+func (m *mockUserRepo) CancelDeletion(id uuid.UUID) error {
+  for _, user := range m.users {
+    if user.ID == id {
+      if user.DeletionScheduledAt == nil || !user.DeletionScheduledAt.After(time.Now()) {
+        return repo.ErrDeletionInProgress
       }
+      user.DeletionScheduledAt = nil
+      return nil
     }
   }
-  
-  if len(users) > limit {
-    users = users[:limit]
+  return repo.ErrUserNotFound
+}
+
+func (m *mockUserRepo) Pause(id uuid.UUID, resumeAt *time.Time) (time.Time, error) {
+  for _, user := range m.users {
+    if user.ID == id {
+      if user.PausedAt == nil {
+        now := time.Now()
+        user.PausedAt = &now
+      }
+      user.ResumeAt = resumeAt
+      return *user.PausedAt, nil
+    }
   }
-  
-  return users, nil
+  return time.Time{}, repo.ErrUserNotFound
 }
 
-func (m *mockUserRepo) GetUserDetail(id uuid.UUID) (*models.User, error) {
+func (m *mockUserRepo) Resume(id uuid.UUID) ([]*models.Match, error) {
   for _, user := range m.users {
     if user.ID == id {
-      return user, nil
+      if user.PausedAt == nil {
+        return nil, repo.ErrNotPaused
+      }
+      user.PausedAt = nil
+      user.ResumeAt = nil
+      return nil, nil
     }
   }
   return nil, repo.ErrUserNotFound
@@ -127,7 +212,7 @@ func (m *mockUserRepo) GetUserDetail(id uuid.UUID) (*models.User, error) {
 func TestSignUp(t *testing.T) {
   gin.SetMode(gin.TestMode)
   mockRepo := newMockUserRepo()
-  handler := &UserHandler{userRepo: mockRepo, jwtSecret: "test-secret", avatarStorage: &mockAvatarStorage{}}
+  handler := &UserHandler{userRepo: mockRepo, mfaRepo: newMockMFARepo(), sessionRepo: newMockSessionRepo(), keys: auth.NewHMACKeySet("test-secret"), avatarStorage: &mockAvatarStorage{}}
 
   t.Run("successful signup", func(t *testing.T) {
     w := httptest.NewRecorder()
@@ -164,6 +249,9 @@ func TestSignUp(t *testing.T) {
     if response.User.Intention != models.IntentionLongTermPartner {
       t.Fatalf("expected intention %s, got %s", models.IntentionLongTermPartner, response.User.Intention)
     }
+    if ip := mockRepo.users["test@example.com"].SignupIP; ip != "192.0.2.1" {
+      t.Fatalf("expected the sign-up IP to be recorded, got %q", ip)
+    }
   })
 
   t.Run("missing required fields", func(t *testing.T) {
@@ -267,7 +355,7 @@ func TestSignIn(t *testing.T) {
     Gender:       models.GenderMale,
   })
 
-  handler := &UserHandler{userRepo: mockRepo, jwtSecret: "test-secret", avatarStorage: &mockAvatarStorage{}}
+  handler := &UserHandler{userRepo: mockRepo, mfaRepo: newMockMFARepo(), sessionRepo: newMockSessionRepo(), keys: auth.NewHMACKeySet("test-secret"), avatarStorage: &mockAvatarStorage{}}
 
   t.Run("successful login", func(t *testing.T) {
     w := httptest.NewRecorder()
@@ -313,11 +401,19 @@ func TestSignIn(t *testing.T) {
 
 func TestSignOut(t *testing.T) {
   gin.SetMode(gin.TestMode)
-  handler := &UserHandler{jwtSecret: "test-secret"}
+  sessionRepo := newMockSessionRepo()
+  handler := &UserHandler{sessionRepo: sessionRepo, keys: auth.NewHMACKeySet("test-secret")}
+
+  userID := uuid.New()
+  session := &models.Session{UserID: userID, ExpiresAt: time.Now().Add(time.Hour)}
+  sessionRepo.Create(session)
 
   w := httptest.NewRecorder()
   c, _ := gin.CreateTestContext(w)
-  c.Request = httptest.NewRequest(http.MethodDelete, "/api/users/sign_out", nil)
+  req := httptest.NewRequest(http.MethodDelete, "/api/users/sign_out", nil)
+  ctx := context.WithValue(req.Context(), middleware.UserIDKey, userID)
+  ctx = context.WithValue(ctx, middleware.SessionIDKey, session.ID)
+  c.Request = req.WithContext(ctx)
 
   handler.SignOut(c)
 
@@ -333,6 +429,10 @@ func TestSignOut(t *testing.T) {
   if response["message"] != "logged out successfully" {
     t.Fatalf("expected logout message, got %s", response["message"])
   }
+
+  if sessionRepo.sessions[session.ID].RevokedAt == nil {
+    t.Fatal("expected current session to be revoked")
+  }
 }
 
 func TestGetProfile(t *testing.T) {
@@ -347,7 +447,8 @@ func TestGetProfile(t *testing.T) {
   }
   mockRepo.users[testUser.Email] = testUser
 
-  handler := &UserHandler{userRepo: mockRepo, jwtSecret: "test-secret", avatarStorage: &mockAvatarStorage{}}
+  interestRepo := newMockInterestRepo()
+  handler := &UserHandler{userRepo: mockRepo, mfaRepo: newMockMFARepo(), sessionRepo: newMockSessionRepo(), keys: auth.NewHMACKeySet("test-secret"), avatarStorage: &mockAvatarStorage{}, interestRepo: interestRepo, promptRepo: newMockPromptRepo()}
 
   t.Run("successful get profile", func(t *testing.T) {
     w := httptest.NewRecorder()
@@ -364,13 +465,35 @@ func TestGetProfile(t *testing.T) {
       t.Fatalf("expected status 200, got %d", w.Code)
     }
 
-    var user models.User
-    if err := json.Unmarshal(w.Body.Bytes(), &user); err != nil {
+    var response ProfileResponse
+    if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
       t.Fatalf("failed to decode response: %v", err)
     }
 
-    if user.Email != "test@example.com" {
-      t.Fatalf("expected email test@example.com, got %s", user.Email)
+    if response.User == nil || response.Email != "test@example.com" {
+      t.Fatalf("expected email test@example.com, got %+v", response.User)
+    }
+    if response.CompletenessPercent != 0 || len(response.MissingSteps) != 5 || response.MissingSteps[0] != models.StepAvatar {
+      t.Fatalf("expected every onboarding step to be missing after sign up, got %d%% %v", response.CompletenessPercent, response.MissingSteps)
+    }
+  })
+
+  t.Run("reports progress through onboarding", func(t *testing.T) {
+    testUser.Bio = "Coffee first"
+    interestRepo.SetForUser(testUser.ID, []string{"coffee"})
+
+    w := httptest.NewRecorder()
+    c, _ := gin.CreateTestContext(w)
+    req := httptest.NewRequest(http.MethodGet, "/api/users/profile", nil)
+    c.Request = req.WithContext(context.WithValue(req.Context(), middleware.UserIDKey, testUser.ID))
+
+    handler.GetProfile(c)
+
+    var response ProfileResponse
+    json.Unmarshal(w.Body.Bytes(), &response)
+    want := []string{models.StepAvatar, models.StepLocation, models.StepPrompts}
+    if response.CompletenessPercent != 40 || strings.Join(response.MissingSteps, ",") != strings.Join(want, ",") {
+      t.Fatalf("expected 40%% with %v missing, got %d%% %v", want, response.CompletenessPercent, response.MissingSteps)
     }
   })
 }
@@ -385,10 +508,13 @@ func TestUploadAvatar(t *testing.T) {
     Name:      "Test User",
     Gender:    models.GenderMale,
     Intention: models.DefaultIntention(),
+    AvatarURL: "/avatars/verified.png",
   }
+  verifiedAt := time.Now()
+  testUser.VerifiedAt = &verifiedAt
   mockRepo.users[testUser.Email] = testUser
 
-  handler := &UserHandler{userRepo: mockRepo, jwtSecret: "test-secret", avatarStorage: mockStorage}
+  handler := &UserHandler{userRepo: mockRepo, mfaRepo: newMockMFARepo(), sessionRepo: newMockSessionRepo(), keys: auth.NewHMACKeySet("test-secret"), avatarStorage: mockStorage}
 
   body := &bytes.Buffer{}
   writer := multipart.NewWriter(body)
@@ -427,12 +553,16 @@ func TestUploadAvatar(t *testing.T) {
   if _, exists := mockStorage.saved[testUser.ID]; !exists {
     t.Fatal("expected avatar to be saved in storage")
   }
+  if user.VerifiedAt != nil || testUser.VerifiedAt != nil {
+    t.Fatal("expected a new avatar to clear the verified badge")
+  }
 }
 
 func TestGetUsers(t *testing.T) {
   gin.SetMode(gin.TestMode)
-  mockRepo := newMockUserRepo()
-  handler := &UserHandler{userRepo: mockRepo, jwtSecret: "test-secret", avatarStorage: &mockAvatarStorage{}}
+  recommender := &mockRecommender{}
+  handler := &UserHandler{userRepo: newMockUserRepo(), mfaRepo: newMockMFARepo(), sessionRepo: newMockSessionRepo(), keys: auth.NewHMACKeySet("test-secret"), avatarStorage: &mockAvatarStorage{}, recommender: recommender}
+  viewerID := uuid.New()
 
   // Create test users
   user1 := &models.User{
@@ -451,14 +581,14 @@ func TestGetUsers(t *testing.T) {
     Intention: "new_friends",
     AvatarURL: "https://cdn.app/avatar2.jpg",
   }
-  mockRepo.users[user1.Email] = user1
-  mockRepo.users[user2.Email] = user2
+  // The better match comes first regardless of ID
+  recommender.ranked = []*models.User{user2, user1}
 
   t.Run("successful get users without cursor", func(t *testing.T) {
     w := httptest.NewRecorder()
     c, _ := gin.CreateTestContext(w)
     req := httptest.NewRequest(http.MethodGet, "/api/users?limit=10", nil)
-    c.Request = req
+    c.Request = req.WithContext(context.WithValue(req.Context(), middleware.UserIDKey, viewerID))
 
     handler.GetUsers(c)
 
@@ -474,13 +604,17 @@ func TestGetUsers(t *testing.T) {
     if len(response.Users) != 2 {
       t.Fatalf("expected 2 users, got %d", len(response.Users))
     }
+    if response.Users[0].ID != user2.ID || response.Users[1].ID != user1.ID {
+      t.Fatalf("expected users in ranked order, got %+v", response.Users)
+    }
   })
 
   t.Run("successful get users with cursor", func(t *testing.T) {
     w := httptest.NewRecorder()
     c, _ := gin.CreateTestContext(w)
-    req := httptest.NewRequest(http.MethodGet, "/api/users?cursor=00000000-0000-0000-0000-000000000001&limit=10", nil)
-    c.Request = req
+    cursor := encodeFeedCursor(discovery.Cursor{Score: 2, CandidateID: user2.ID})
+    req := httptest.NewRequest(http.MethodGet, "/api/users?cursor="+cursor+"&limit=10", nil)
+    c.Request = req.WithContext(context.WithValue(req.Context(), middleware.UserIDKey, viewerID))
 
     handler.GetUsers(c)
 
@@ -493,8 +627,53 @@ func TestGetUsers(t *testing.T) {
       t.Fatalf("failed to decode response: %v", err)
     }
 
-    if len(response.Users) != 1 {
-      t.Fatalf("expected 1 user, got %d", len(response.Users))
+    if len(response.Users) != 1 || response.Users[0].ID != user1.ID {
+      t.Fatalf("expected the rest of the ranking, got %+v", response.Users)
+    }
+  })
+
+  getPage := func(t *testing.T, query string) (int, GetUsersResponse) {
+    w := httptest.NewRecorder()
+    c, _ := gin.CreateTestContext(w)
+    req := httptest.NewRequest(http.MethodGet, "/api/users?"+query, nil)
+    c.Request = req.WithContext(context.WithValue(req.Context(), middleware.UserIDKey, viewerID))
+
+    handler.GetUsers(c)
+
+    var response GetUsersResponse
+    if w.Code == http.StatusOK {
+      if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+        t.Fatalf("failed to decode response: %v", err)
+      }
+    }
+    return w.Code, response
+  }
+
+  t.Run("full page returns a next cursor that continues the feed", func(t *testing.T) {
+    _, first := getPage(t, "limit=1")
+    if first.NextCursor == nil {
+      t.Fatal("expected a next cursor")
+    }
+
+    code, second := getPage(t, "limit=1&cursor="+*first.NextCursor)
+    if code != http.StatusOK {
+      t.Fatalf("expected status 200, got %d", code)
+    }
+    if len(second.Users) != 1 || second.Users[0].ID != user1.ID {
+      t.Fatalf("expected the second ranked user, got %+v", second.Users)
+    }
+  })
+
+  t.Run("cursor from a recomputed feed is rejected", func(t *testing.T) {
+    _, first := getPage(t, "limit=1")
+    if first.NextCursor == nil {
+      t.Fatal("expected a next cursor")
+    }
+    recommender.generation++
+    defer func() { recommender.generation-- }()
+
+    if code, _ := getPage(t, "limit=1&cursor="+*first.NextCursor); code != http.StatusConflict {
+      t.Fatalf("expected status 409, got %d", code)
     }
   })
 
@@ -502,7 +681,7 @@ func TestGetUsers(t *testing.T) {
     w := httptest.NewRecorder()
     c, _ := gin.CreateTestContext(w)
     req := httptest.NewRequest(http.MethodGet, "/api/users?cursor=invalid-uuid", nil)
-    c.Request = req
+    c.Request = req.WithContext(context.WithValue(req.Context(), middleware.UserIDKey, viewerID))
 
     handler.GetUsers(c)
 
@@ -515,7 +694,7 @@ func TestGetUsers(t *testing.T) {
     w := httptest.NewRecorder()
     c, _ := gin.CreateTestContext(w)
     req := httptest.NewRequest(http.MethodGet, "/api/users?limit=101", nil)
-    c.Request = req
+    c.Request = req.WithContext(context.WithValue(req.Context(), middleware.UserIDKey, viewerID))
 
     handler.GetUsers(c)
 
@@ -523,12 +702,43 @@ func TestGetUsers(t *testing.T) {
       t.Fatalf("expected status 400, got %d", w.Code)
     }
   })
+  t.Run("requires authentication", func(t *testing.T) {
+    w := httptest.NewRecorder()
+    c, _ := gin.CreateTestContext(w)
+    c.Request = httptest.NewRequest(http.MethodGet, "/api/users", nil)
+
+    handler.GetUsers(c)
+
+    if w.Code != http.StatusUnauthorized {
+      t.Fatalf("expected status 401, got %d", w.Code)
+    }
+  })
+
+  t.Run("ranking failure", func(t *testing.T) {
+    recommender.err = errors.New("db unavailable")
+    defer func() { recommender.err = nil }()
+
+    w := httptest.NewRecorder()
+    c, _ := gin.CreateTestContext(w)
+    req := httptest.NewRequest(http.MethodGet, "/api/users", nil)
+    c.Request = req.WithContext(context.WithValue(req.Context(), middleware.UserIDKey, viewerID))
+
+    handler.GetUsers(c)
+
+    if w.Code != http.StatusInternalServerError {
+      t.Fatalf("expected status 500, got %d", w.Code)
+    }
+  })
 }
 
 func TestGetUserDetail(t *testing.T) {
   gin.SetMode(gin.TestMode)
   mockRepo := newMockUserRepo()
-  handler := &UserHandler{userRepo: mockRepo, jwtSecret: "test-secret", avatarStorage: &mockAvatarStorage{}}
+  interestRepo := newMockInterestRepo()
+  promptRepo := newMockPromptRepo()
+  questionRepo := newMockQuestionRepo()
+  handler := &UserHandler{userRepo: mockRepo, mfaRepo: newMockMFARepo(), sessionRepo: newMockSessionRepo(), keys: auth.NewHMACKeySet("test-secret"), avatarStorage: &mockAvatarStorage{}, interestRepo: interestRepo, promptRepo: promptRepo, questionRepo: questionRepo}
+  viewerID := uuid.New()
 
   // Create test user
   userID := uuid.New()
@@ -546,13 +756,20 @@ func TestGetUserDetail(t *testing.T) {
     UpdatedAt:    time.Now(),
   }
   mockRepo.users[user.Email] = user
+  interestRepo.picked[userID] = []string{"coffee", "hiking"}
+  interestRepo.picked[viewerID] = []string{"hiking", "kpop"}
+  for _, id := range []uuid.UUID{viewerID, userID} {
+    questionRepo.SaveAnswer(id, &models.QuestionAnswer{QuestionID: "want_children", Answer: 0, Accepted: 0b001, Importance: 3})
+    questionRepo.SaveAnswer(id, &models.QuestionAnswer{QuestionID: "pets_at_home", Answer: 1, Accepted: 0b010, Importance: 1})
+  }
+  promptRepo.SetAnswers(userID, []*models.PromptAnswer{{PromptID: "green_flag", Answer: "Kindness to waiters"}, {PromptID: "ideal_sunday", Answer: "Phở, then a nap"}})
 
   t.Run("successful get user detail", func(t *testing.T) {
     w := httptest.NewRecorder()
     c, _ := gin.CreateTestContext(w)
     c.Params = gin.Params{{Key: "user_id", Value: userID.String()}}
-    req := httptest.NewRequest(http.MethodGet, "/api/users/"+userID.String()+"/detail", nil)
-    c.Request = req
+    req := httptest.NewRequest(http.MethodGet, "/api/users/"+userID.String()+"/detail?lang=vi", nil)
+    c.Request = req.WithContext(context.WithValue(req.Context(), middleware.UserIDKey, viewerID))
 
     handler.GetUserDetail(c)
 
@@ -574,6 +791,16 @@ func TestGetUserDetail(t *testing.T) {
     if response.Bio != "Test bio" {
       t.Fatalf("expected bio 'Test bio', got %s", response.Bio)
     }
+    if len(response.Interests) != 2 || len(response.SharedInterests) != 1 || response.SharedInterests[0].Label != "Leo núi" {
+      t.Fatalf("expected hiking as the one shared interest, got %+v and %+v", response.Interests, response.SharedInterests)
+    }
+    if len(response.Prompts) != 2 || response.Prompts[0].PromptID != "green_flag" || response.Prompts[1].Prompt != "Chủ nhật lý tưởng của mình là..." {
+      t.Fatalf("expected both prompt answers in order, got %+v", response.Prompts)
+    }
+    // Both agree on both questions, less the margin for two questions in common
+    if response.MatchPercent == nil || *response.MatchPercent != 50 {
+      t.Fatalf("expected a 50%% match, got %v", response.MatchPercent)
+    }
   })
 
   t.Run("invalid user ID format", func(t *testing.T) {
@@ -581,7 +808,7 @@ func TestGetUserDetail(t *testing.T) {
     c, _ := gin.CreateTestContext(w)
     c.Params = gin.Params{{Key: "user_id", Value: "invalid-uuid"}}
     req := httptest.NewRequest(http.MethodGet, "/api/users/invalid-uuid/detail", nil)
-    c.Request = req
+    c.Request = req.WithContext(context.WithValue(req.Context(), middleware.UserIDKey, viewerID))
 
     handler.GetUserDetail(c)
 
@@ -596,7 +823,7 @@ func TestGetUserDetail(t *testing.T) {
     notFoundID := uuid.New()
     c.Params = gin.Params{{Key: "user_id", Value: notFoundID.String()}}
     req := httptest.NewRequest(http.MethodGet, "/api/users/"+notFoundID.String()+"/detail", nil)
-    c.Request = req
+    c.Request = req.WithContext(context.WithValue(req.Context(), middleware.UserIDKey, viewerID))
 
     handler.GetUserDetail(c)
 
@@ -604,4 +831,421 @@ func TestGetUserDetail(t *testing.T) {
       t.Fatalf("expected status 404, got %d", w.Code)
     }
   })
+  t.Run("requires authentication", func(t *testing.T) {
+    w := httptest.NewRecorder()
+    c, _ := gin.CreateTestContext(w)
+    c.Params = gin.Params{{Key: "user_id", Value: userID.String()}}
+    c.Request = httptest.NewRequest(http.MethodGet, "/api/users/"+userID.String()+"/detail", nil)
+
+    handler.GetUserDetail(c)
+
+    if w.Code != http.StatusUnauthorized {
+      t.Fatalf("expected status 401, got %d", w.Code)
+    }
+  })
+
+  t.Run("hidden user is reported as not found", func(t *testing.T) {
+    user.Visibility = models.VisibilityHidden
+    defer func() { user.Visibility = "" }()
+
+    w := httptest.NewRecorder()
+    c, _ := gin.CreateTestContext(w)
+    c.Params = gin.Params{{Key: "user_id", Value: userID.String()}}
+    req := httptest.NewRequest(http.MethodGet, "/api/users/"+userID.String()+"/detail", nil)
+    c.Request = req.WithContext(context.WithValue(req.Context(), middleware.UserIDKey, viewerID))
+
+    handler.GetUserDetail(c)
+
+    if w.Code != http.StatusNotFound {
+      t.Fatalf("expected status 404, got %d", w.Code)
+    }
+  })
+}
+
+func TestDeleteAccount(t *testing.T) {
+  gin.SetMode(gin.TestMode)
+
+  setup := func() (*UserHandler, *models.User, *mockSessionRepo) {
+    mockRepo := newMockUserRepo()
+    hashedPassword, _ := auth.HashPassword("password123")
+    user := &models.User{Email: "test@example.com", PasswordHash: hashedPassword, Name: "Test User", Gender: models.GenderMale}
+    mockRepo.Create(user)
+    sessionRepo := newMockSessionRepo()
+    handler := &UserHandler{
+      userRepo:            mockRepo,
+      mfaRepo:             newMockMFARepo(),
+      sessionRepo:         sessionRepo,
+      keys:                auth.NewHMACKeySet("test-secret"),
+      deletionGracePeriod: 30 * 24 * time.Hour,
+    }
+    return handler, user, sessionRepo
+  }
+
+  request := func(fn gin.HandlerFunc, method string, userID uuid.UUID, body string) *httptest.ResponseRecorder {
+    w := httptest.NewRecorder()
+    c, _ := gin.CreateTestContext(w)
+    req := httptest.NewRequest(method, "/api/users/profile", bytes.NewBufferString(body))
+    req.Header.Set("Content-Type", "application/json")
+    c.Request = req.WithContext(context.WithValue(req.Context(), middleware.UserIDKey, userID))
+    fn(c)
+    return w
+  }
+
+  t.Run("wrong password", func(t *testing.T) {
+    handler, user, _ := setup()
+    w := request(handler.DeleteAccount, http.MethodDelete, user.ID, `{"password":"wrong"}`)
+    if w.Code != http.StatusForbidden {
+      t.Fatalf("expected status 403, got %d", w.Code)
+    }
+    if user.DeletionScheduledAt != nil {
+      t.Fatal("expected no deletion to be scheduled")
+    }
+  })
+
+  t.Run("reauthentication token must be for this user", func(t *testing.T) {
+    handler, user, _ := setup()
+    signReauthentication := func(purpose, subject string) string {
+      token, _ := handler.keys.SignClaims(oidcReauthenticationClaims{
+        Purpose: purpose,
+        RegisteredClaims: jwt.RegisteredClaims{
+          Subject:   subject,
+          ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute)),
+        },
+      })
+      return token
+    }
+
+    if w := request(handler.DeleteAccount, http.MethodDelete, user.ID, `{}`); w.Code != http.StatusBadRequest {
+      t.Fatalf("expected status 400 without password or token, got %d", w.Code)
+    }
+    for _, token := range []string{
+      signReauthentication(purposeOIDCReauthentication, uuid.NewString()),
+      signReauthentication(purposeOIDCRegistration, user.ID.String()),
+      "not-a-token",
+    } {
+      w := request(handler.DeleteAccount, http.MethodDelete, user.ID, `{"reauthentication_token":"`+token+`"}`)
+      if w.Code != http.StatusForbidden {
+        t.Fatalf("expected status 403, got %d", w.Code)
+      }
+    }
+    if user.DeletionScheduledAt != nil {
+      t.Fatal("expected no deletion to be scheduled")
+    }
+  })
+
+  t.Run("schedules deletion and signs out everywhere", func(t *testing.T) {
+    handler, user, sessionRepo := setup()
+    sessionRepo.Create(&models.Session{UserID: user.ID, ExpiresAt: time.Now().Add(time.Hour)})
+    sessionRepo.Create(&models.Session{UserID: user.ID, ExpiresAt: time.Now().Add(time.Hour)})
+
+    w := request(handler.DeleteAccount, http.MethodDelete, user.ID, `{"password":"password123"}`)
+    if w.Code != http.StatusAccepted {
+      t.Fatalf("expected status 202, got %d: %s", w.Code, w.Body.String())
+    }
+    if user.DeletionScheduledAt == nil || user.DeletionScheduledAt.Before(time.Now().Add(29*24*time.Hour)) {
+      t.Fatalf("expected deletion scheduled after the grace period, got %v", user.DeletionScheduledAt)
+    }
+    if active, _ := sessionRepo.ListActive(user.ID); len(active) != 0 {
+      t.Fatalf("expected all sessions revoked, got %d active", len(active))
+    }
+
+    w = request(handler.DeleteAccount, http.MethodDelete, user.ID, `{"password":"password123"}`)
+    if w.Code != http.StatusConflict {
+      t.Fatalf("expected status 409 for repeated request, got %d", w.Code)
+    }
+  })
+
+  t.Run("requires second factor when enabled", func(t *testing.T) {
+    handler, user, _ := setup()
+    mfaRepo := handler.mfaRepo.(*mockMFARepo)
+    mfaRepo.SaveTOTPSecret(user.ID, "JBSWY3DPEHPK3PXP")
+    mfaRepo.Enable(user.ID, 0, nil)
+
+    w := request(handler.DeleteAccount, http.MethodDelete, user.ID, `{"password":"password123"}`)
+    if w.Code != http.StatusForbidden {
+      t.Fatalf("expected status 403 without code, got %d", w.Code)
+    }
+
+    code, _ := auth.TOTPCode("JBSWY3DPEHPK3PXP", auth.TOTPStep(time.Now()))
+    w = request(handler.DeleteAccount, http.MethodDelete, user.ID, `{"password":"password123","code":"`+code+`"}`)
+    if w.Code != http.StatusAccepted {
+      t.Fatalf("expected status 202 with code, got %d: %s", w.Code, w.Body.String())
+    }
+  })
+
+  t.Run("restore during grace period", func(t *testing.T) {
+    handler, user, _ := setup()
+    w := request(handler.RestoreAccount, http.MethodPost, user.ID, "")
+    if w.Code != http.StatusBadRequest {
+      t.Fatalf("expected status 400 when nothing is scheduled, got %d", w.Code)
+    }
+
+    request(handler.DeleteAccount, http.MethodDelete, user.ID, `{"password":"password123"}`)
+    w = request(handler.RestoreAccount, http.MethodPost, user.ID, "")
+    if w.Code != http.StatusOK {
+      t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+    }
+    if user.DeletionScheduledAt != nil {
+      t.Fatal("expected deletion to be cancelled")
+    }
+  })
+
+  t.Run("restore after grace period", func(t *testing.T) {
+    handler, user, _ := setup()
+    handler.deletionGracePeriod = 0
+    request(handler.DeleteAccount, http.MethodDelete, user.ID, `{"password":"password123"}`)
+
+    w := request(handler.RestoreAccount, http.MethodPost, user.ID, "")
+    if w.Code != http.StatusConflict {
+      t.Fatalf("expected status 409, got %d", w.Code)
+    }
+  })
+}
+
+func TestPauseAccount(t *testing.T) {
+  gin.SetMode(gin.TestMode)
+
+  setup := func() (*UserHandler, *models.User) {
+    mockRepo := newMockUserRepo()
+    user := &models.User{Email: "test@example.com", Name: "Test User", Gender: models.GenderMale}
+    mockRepo.Create(user)
+    return &UserHandler{userRepo: mockRepo}, user
+  }
+
+  request := func(fn gin.HandlerFunc, userID uuid.UUID, body string) *httptest.ResponseRecorder {
+    w := httptest.NewRecorder()
+    c, _ := gin.CreateTestContext(w)
+    req := httptest.NewRequest(http.MethodPost, "/api/users/profile/pause", bytes.NewBufferString(body))
+    req.Header.Set("Content-Type", "application/json")
+    c.Request = req.WithContext(context.WithValue(req.Context(), middleware.UserIDKey, userID))
+    fn(c)
+    return w
+  }
+
+  t.Run("pause without resume date", func(t *testing.T) {
+    handler, user := setup()
+    w := request(handler.PauseAccount, user.ID, "")
+    if w.Code != http.StatusOK {
+      t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+    }
+    if user.PausedAt == nil || user.ResumeAt != nil {
+      t.Fatalf("expected open-ended pause, got paused_at=%v resume_at=%v", user.PausedAt, user.ResumeAt)
+    }
+  })
+
+  t.Run("pause with resume date", func(t *testing.T) {
+    handler, user := setup()
+    resumeAt := time.Now().Add(7 * 24 * time.Hour).UTC().Format(time.RFC3339)
+    w := request(handler.PauseAccount, user.ID, `{"resume_at":"`+resumeAt+`"}`)
+    if w.Code != http.StatusOK {
+      t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+    }
+    var response models.User
+    json.Unmarshal(w.Body.Bytes(), &response)
+    if response.PausedAt == nil || response.ResumeAt == nil {
+      t.Fatalf("expected paused_at and resume_at in response, got %s", w.Body.String())
+    }
+  })
+
+  t.Run("rejects resume date in the past or too far ahead", func(t *testing.T) {
+    handler, user := setup()
+    for _, resumeAt := range []time.Time{time.Now().Add(-time.Hour), time.Now().Add(2 * maxPauseDuration)} {
+      w := request(handler.PauseAccount, user.ID, `{"resume_at":"`+resumeAt.UTC().Format(time.RFC3339)+`"}`)
+      if w.Code != http.StatusBadRequest {
+        t.Fatalf("expected status 400 for %v, got %d", resumeAt, w.Code)
+      }
+    }
+    if user.PausedAt != nil {
+      t.Fatal("expected account to stay active")
+    }
+  })
+
+  t.Run("resume", func(t *testing.T) {
+    handler, user := setup()
+    if w := request(handler.ResumeAccount, user.ID, ""); w.Code != http.StatusBadRequest {
+      t.Fatalf("expected status 400 when not paused, got %d", w.Code)
+    }
+
+    request(handler.PauseAccount, user.ID, "")
+    w := request(handler.ResumeAccount, user.ID, "")
+    if w.Code != http.StatusOK {
+      t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+    }
+    if user.PausedAt != nil {
+      t.Fatal("expected account to be active again")
+    }
+  })
+}
+
+func TestUpdateProfileVisibility(t *testing.T) {
+  gin.SetMode(gin.TestMode)
+  mockRepo := newMockUserRepo()
+  user := &models.User{Email: "test@example.com", Name: "Test User", Gender: models.GenderMale}
+  mockRepo.Create(user)
+  handler := &UserHandler{userRepo: mockRepo}
+
+  request := func(body string) *httptest.ResponseRecorder {
+    w := httptest.NewRecorder()
+    c, _ := gin.CreateTestContext(w)
+    req := httptest.NewRequest(http.MethodPatch, "/api/users/profile", bytes.NewBufferString(body))
+    req.Header.Set("Content-Type", "application/json")
+    c.Request = req.WithContext(context.WithValue(req.Context(), middleware.UserIDKey, user.ID))
+    handler.UpdateProfile(c)
+    return w
+  }
+
+  if w := request(`{"visibility":"incognito"}`); w.Code != http.StatusOK {
+    t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+  }
+  if user.Visibility != models.VisibilityIncognito {
+    t.Fatalf("expected visibility incognito, got %q", user.Visibility)
+  }
+
+  if w := request(`{"visibility":"everyone"}`); w.Code != http.StatusBadRequest {
+    t.Fatalf("expected status 400, got %d", w.Code)
+  }
+}
+
+func TestUpdateProfileAvatarURL(t *testing.T) {
+  gin.SetMode(gin.TestMode)
+  mockRepo := newMockUserRepo()
+  verifiedAt := time.Now()
+  user := &models.User{Email: "test@example.com", Name: "Test User", Gender: models.GenderMale}
+  mockRepo.Create(user)
+  user.AvatarURL, user.VerifiedAt = "/avatars/verified.png", &verifiedAt
+  handler := &UserHandler{userRepo: mockRepo}
+
+  request := func(body string) *httptest.ResponseRecorder {
+    w := httptest.NewRecorder()
+    c, _ := gin.CreateTestContext(w)
+    req := httptest.NewRequest(http.MethodPatch, "/api/users/profile", bytes.NewBufferString(body))
+    req.Header.Set("Content-Type", "application/json")
+    c.Request = req.WithContext(context.WithValue(req.Context(), middleware.UserIDKey, user.ID))
+    handler.UpdateProfile(c)
+    return w
+  }
+
+  if w := request(`{"avatar_url":"/avatars/verified.png","bio":"Hello"}`); w.Code != http.StatusOK {
+    t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+  }
+  if user.VerifiedAt == nil {
+    t.Fatal("expected the badge to stay while the avatar is unchanged")
+  }
+
+  w := request(`{"avatar_url":"https://elsewhere.example/someone-else.jpg"}`)
+  if w.Code != http.StatusOK {
+    t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+  }
+  var response models.User
+  json.Unmarshal(w.Body.Bytes(), &response)
+  if user.VerifiedAt != nil || response.VerifiedAt != nil {
+    t.Fatal("expected a different avatar to clear the verified badge")
+  }
+}
+
+func TestUpdateProfileTimezone(t *testing.T) {
+  gin.SetMode(gin.TestMode)
+  mockRepo := newMockUserRepo()
+  user := &models.User{Email: "test@example.com", Name: "Test User", Gender: models.GenderMale}
+  mockRepo.Create(user)
+  handler := &UserHandler{userRepo: mockRepo}
+
+  request := func(body string) *httptest.ResponseRecorder {
+    w := httptest.NewRecorder()
+    c, _ := gin.CreateTestContext(w)
+    req := httptest.NewRequest(http.MethodPatch, "/api/users/profile", bytes.NewBufferString(body))
+    req.Header.Set("Content-Type", "application/json")
+    c.Request = req.WithContext(context.WithValue(req.Context(), middleware.UserIDKey, user.ID))
+    handler.UpdateProfile(c)
+    return w
+  }
+
+  if w := request(`{"timezone":"Asia/Ho_Chi_Minh"}`); w.Code != http.StatusOK {
+    t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+  }
+  if user.Timezone != "Asia/Ho_Chi_Minh" {
+    t.Fatalf("expected timezone Asia/Ho_Chi_Minh, got %q", user.Timezone)
+  }
+
+  for _, tz := range []string{"Mars/Olympus_Mons", "Local", ""} {
+    if w := request(`{"timezone":"` + tz + `"}`); w.Code != http.StatusBadRequest {
+      t.Fatalf("expected status 400 for %q, got %d", tz, w.Code)
+    }
+  }
+}
+
+func TestUpdateProfileLocation(t *testing.T) {
+  gin.SetMode(gin.TestMode)
+  mockRepo := newMockUserRepo()
+  user := &models.User{Email: "test@example.com", Name: "Test User", Gender: models.GenderMale}
+  mockRepo.Create(user)
+  handler := &UserHandler{userRepo: mockRepo}
+
+  request := func(body string) *httptest.ResponseRecorder {
+    w := httptest.NewRecorder()
+    c, _ := gin.CreateTestContext(w)
+    req := httptest.NewRequest(http.MethodPatch, "/api/users/profile", bytes.NewBufferString(body))
+    req.Header.Set("Content-Type", "application/json")
+    c.Request = req.WithContext(context.WithValue(req.Context(), middleware.UserIDKey, user.ID))
+    handler.UpdateProfile(c)
+    return w
+  }
+
+  if w := request(`{"latitude":10.7769,"longitude":106.7009}`); w.Code != http.StatusOK {
+    t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+  }
+  if user.Latitude == nil || *user.Latitude != 10.7769 || user.Longitude == nil || *user.Longitude != 106.7009 {
+    t.Fatalf("expected location to be saved, got %v, %v", user.Latitude, user.Longitude)
+  }
+
+  for _, body := range []string{`{"latitude":10.7769}`, `{"latitude":91,"longitude":0}`, `{"latitude":0,"longitude":-181}`} {
+    if w := request(body); w.Code != http.StatusBadRequest {
+      t.Fatalf("expected status 400 for %s, got %d", body, w.Code)
+    }
+  }
+}
+
+func TestUpdateProfileAttributes(t *testing.T) {
+  gin.SetMode(gin.TestMode)
+  mockRepo := newMockUserRepo()
+  user := &models.User{Email: "test@example.com", Name: "Test User", Gender: models.GenderMale}
+  mockRepo.Create(user)
+  handler := &UserHandler{userRepo: mockRepo}
+
+  request := func(body string) *httptest.ResponseRecorder {
+    w := httptest.NewRecorder()
+    c, _ := gin.CreateTestContext(w)
+    req := httptest.NewRequest(http.MethodPatch, "/api/users/profile", bytes.NewBufferString(body))
+    req.Header.Set("Content-Type", "application/json")
+    c.Request = req.WithContext(context.WithValue(req.Context(), middleware.UserIDKey, user.ID))
+    handler.UpdateProfile(c)
+    return w
+  }
+
+  w := request(`{"height_cm":172,"education":"postgraduate","job_title":" Engineer ","languages":["vi","en"],"religion":"buddhist","drinking":"sometimes","smoking":"never","children":"want"}`)
+  if w.Code != http.StatusOK {
+    t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+  }
+  if user.HeightCm == nil || *user.HeightCm != 172 || user.Education != models.EducationPostgraduate || user.JobTitle != "Engineer" ||
+    len(user.Languages) != 2 || user.Religion != models.ReligionBuddhist || user.Drinking != models.HabitSometimes ||
+    user.Smoking != models.HabitNever || user.Children != models.ChildrenWant {
+    t.Fatalf("expected attributes to be saved, got %+v", user)
+  }
+
+  if w := request(`{"religion":""}`); w.Code != http.StatusOK || user.Religion != "" || user.Smoking != models.HabitNever {
+    t.Fatalf("expected an empty value to clear only that attribute, got %d and %+v", w.Code, user)
+  }
+
+  for _, body := range []string{
+    `{"height_cm":40}`,
+    `{"education":"kindergarten"}`,
+    `{"languages":["xx"]}`,
+    `{"drinking":"daily"}`,
+    `{"children":"maybe"}`,
+    `{"job_title":"` + strings.Repeat("a", models.MaxJobTitleLength+1) + `"}`,
+  } {
+    if w := request(body); w.Code != http.StatusBadRequest {
+      t.Fatalf("expected status 400 for %s, got %d", body, w.Code)
+    }
+  }
 }
@@ -0,0 +1,378 @@
+package handlers
+
+import (
+	"database/sql"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/Viet-CodingStars/kyupi-kyupi-backend/internal/middleware"
+	"github.com/Viet-CodingStars/kyupi-kyupi-backend/internal/models"
+	"github.com/Viet-CodingStars/kyupi-kyupi-backend/internal/repo"
+	"github.com/Viet-CodingStars/kyupi-kyupi-backend/internal/storage"
+	"github.com/Viet-CodingStars/kyupi-kyupi-backend/internal/verification"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// VerificationRepository declares the minimal persistence operations required by VerificationHandler.
+type VerificationRepository interface {
+	Start(userID uuid.UUID, pose string) (*models.PhotoVerification, error)
+	Latest(userID uuid.UUID) (*models.PhotoVerification, error)
+	Submit(userID uuid.UUID, selfieURL string) (*models.PhotoVerification, error)
+	ListPending(limit int) ([]*models.PhotoVerification, error)
+	Review(id uuid.UUID, reviewerID *uuid.UUID, status, reason string) (*models.PhotoVerification, error)
+}
+
+// maxSelfieSize bounds an uploaded verification selfie.
+const maxSelfieSize = 5 << 20
+
+type VerificationHandler struct {
+	verificationRepo VerificationRepository
+	userRepo         UserRepository
+	// selfies live apart from avatars and are only served to admins through GetSelfie
+	selfieStorage storage.SelfieStorage
+	verifier      verification.Verifier
+}
+
+func NewVerificationHandler(db *sql.DB, selfieStorage storage.SelfieStorage, verifier verification.Verifier) *VerificationHandler {
+	return &VerificationHandler{
+		verificationRepo: repo.NewVerificationRepo(db),
+		userRepo:         repo.NewUserRepo(db),
+		selfieStorage:    selfieStorage,
+		verifier:         verifier,
+	}
+}
+
+// RejectVerificationRequest carries the reason a selfie was rejected, shown to the user.
+type RejectVerificationRequest struct {
+	Reason string `json:"reason"`
+}
+
+// VerificationsResponse lists the verification review queue.
+type VerificationsResponse struct {
+	Verifications []*models.PhotoVerification `json:"verifications"`
+}
+
+// StartVerification asks the caller for a selfie in a random pose (POST /api/users/verification).
+// @Summary Start photo verification
+// @Description Picks a random pose for the caller to mimic in a verification selfie. Asking again before the selfie is uploaded picks a new pose. An avatar is required, since the selfie is compared with it.
+// @Tags Verification
+// @Produce json
+// @Security BearerAuth
+// @Success 201 {object} models.PhotoVerification
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/users/verification [post]
+func (h *VerificationHandler) StartVerification(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c.Request.Context())
+	if !ok {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "user not authenticated"})
+		return
+	}
+
+	user, err := h.userRepo.GetByID(userID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, ErrorResponse{Error: "user not found"})
+		return
+	}
+	if user.VerifiedAt != nil {
+		c.JSON(http.StatusConflict, ErrorResponse{Error: "photos are already verified"})
+		return
+	}
+	if user.AvatarURL == "" {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "upload an avatar before verifying it"})
+		return
+	}
+
+	v, err := h.verificationRepo.Start(userID, models.RandomVerificationPose())
+	if err == repo.ErrVerificationInProgress {
+		c.JSON(http.StatusConflict, ErrorResponse{Error: "a verification selfie is already waiting for review"})
+		return
+	}
+	if err == repo.ErrUserNotFound {
+		c.JSON(http.StatusNotFound, ErrorResponse{Error: "user not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to start verification"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, v)
+}
+
+// GetVerification returns the caller's latest verification (GET /api/users/verification).
+// @Summary Get photo verification status
+// @Description Returns the caller's most recent verification request, with the pose to mimic and, once reviewed, whether it was approved or why it was rejected.
+// @Tags Verification
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} models.PhotoVerification
+// @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/users/verification [get]
+func (h *VerificationHandler) GetVerification(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c.Request.Context())
+	if !ok {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "user not authenticated"})
+		return
+	}
+
+	v, err := h.verificationRepo.Latest(userID)
+	if err == repo.ErrVerificationNotFound {
+		c.JSON(http.StatusNotFound, ErrorResponse{Error: "no verification requested"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to retrieve verification"})
+		return
+	}
+
+	c.JSON(http.StatusOK, v)
+}
+
+// SubmitSelfie uploads the verification selfie and queues it for review (POST /api/users/verification/selfie).
+// @Summary Upload verification selfie
+// @Description Uploads a selfie mimicking the requested pose. It is checked by the configured verifier; unless that decides, it waits for an admin to review it.
+// @Tags Verification
+// @Accept multipart/form-data
+// @Produce json
+// @Security BearerAuth
+// @Param selfie formData file true "Selfie image"
+// @Success 200 {object} models.PhotoVerification
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/users/verification/selfie [post]
+func (h *VerificationHandler) SubmitSelfie(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c.Request.Context())
+	if !ok {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "user not authenticated"})
+		return
+	}
+
+	if h.selfieStorage == nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "selfie storage not configured"})
+		return
+	}
+
+	fileHeader, err := c.FormFile("selfie")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "selfie file is required"})
+		return
+	}
+
+	if fileHeader.Size > maxSelfieSize {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: fmt.Sprintf("selfie file too large (max %d bytes)", maxSelfieSize)})
+		return
+	}
+
+	latest, err := h.verificationRepo.Latest(userID)
+	if err != nil && err != repo.ErrVerificationNotFound {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to retrieve verification"})
+		return
+	}
+	if latest == nil || latest.Status != models.VerificationAwaitingSelfie {
+		c.JSON(http.StatusNotFound, ErrorResponse{Error: "no verification is waiting for a selfie"})
+		return
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "unable to read selfie file"})
+		return
+	}
+	defer file.Close()
+
+	selfieURL, err := h.selfieStorage.Save(userID, file, fileHeader.Filename)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to store selfie"})
+		return
+	}
+
+	v, err := h.verificationRepo.Submit(userID, selfieURL)
+	if err == repo.ErrVerificationNotFound {
+		c.JSON(http.StatusNotFound, ErrorResponse{Error: "no verification is waiting for a selfie"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to submit selfie"})
+		return
+	}
+
+	// A verifier that fails or does not decide leaves the selfie in the review queue. It compares
+	// with the avatar recorded on submission, which is the one an approval verifies.
+	result, err := h.verifier.Verify(c.Request.Context(), v, v.AvatarURL)
+	if err == nil && (result.Status == models.VerificationApproved || result.Status == models.VerificationRejected) {
+		reviewed, err := h.verificationRepo.Review(v.ID, nil, result.Status, result.Reason)
+		if err != nil && err != repo.ErrVerificationNotFound {
+			c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to record verification result"})
+			return
+		}
+		if err == nil {
+			v = reviewed
+		}
+	}
+
+	c.JSON(http.StatusOK, v)
+}
+
+// ListVerificationQueue returns selfies waiting for review (GET /api/admin/verifications).
+// @Summary List the verification review queue
+// @Description Returns pending verification selfies, longest waiting first, with the pose asked for and the avatar the selfie is compared with. Admins only.
+// @Tags Admin
+// @Produce json
+// @Security BearerAuth
+// @Param limit query int false "Number of verifications to return (default: 20, max: 100)"
+// @Success 200 {object} VerificationsResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/admin/verifications [get]
+func (h *VerificationHandler) ListVerificationQueue(c *gin.Context) {
+	limit := 20
+	if limitStr := c.Query("limit"); limitStr != "" {
+		fmt.Sscanf(limitStr, "%d", &limit)
+		if limit < 1 || limit > 100 {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: "limit must be between 1 and 100"})
+			return
+		}
+	}
+
+	queue, err := h.verificationRepo.ListPending(limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to retrieve verifications"})
+		return
+	}
+
+	c.JSON(http.StatusOK, VerificationsResponse{Verifications: queue})
+}
+
+// GetSelfie returns a verification selfie to a reviewer (GET /api/admin/verifications/selfies/:user_id/:filename).
+// @Summary Get a verification selfie
+// @Description Returns the image behind a verification's selfie_url. Selfies are biometric data and are not publicly served. Admins only.
+// @Tags Admin
+// @Produce octet-stream
+// @Security BearerAuth
+// @Param user_id path string true "User ID"
+// @Param filename path string true "Selfie file name"
+// @Success 200 {file} file
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/admin/verifications/selfies/{user_id}/{filename} [get]
+func (h *VerificationHandler) GetSelfie(c *gin.Context) {
+	userID, err := uuid.Parse(c.Param("user_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "invalid user ID"})
+		return
+	}
+	if h.selfieStorage == nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "selfie storage not configured"})
+		return
+	}
+
+	file, err := h.selfieStorage.Open(userID, c.Param("filename"))
+	if err == storage.ErrFileNotFound {
+		c.JSON(http.StatusNotFound, ErrorResponse{Error: "selfie not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to read selfie"})
+		return
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(io.LimitReader(file, maxSelfieSize))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to read selfie"})
+		return
+	}
+	c.Header("Cache-Control", "no-store")
+	c.Data(http.StatusOK, http.DetectContentType(data), data)
+}
+
+// ApproveVerification marks a pending selfie as matching (POST /api/admin/verifications/:verification_id/approve).
+// @Summary Approve a verification selfie
+// @Description Approves a pending verification; the user's profile then shows the verified badge. If the user changed their avatar since submitting the selfie, the verification is rejected instead, asking them to verify the new photo. Admins only.
+// @Tags Admin
+// @Produce json
+// @Security BearerAuth
+// @Param verification_id path string true "Verification ID"
+// @Success 200 {object} models.PhotoVerification
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/admin/verifications/{verification_id}/approve [post]
+func (h *VerificationHandler) ApproveVerification(c *gin.Context) {
+	h.review(c, models.VerificationApproved, "")
+}
+
+// RejectVerification marks a pending selfie as not matching (POST /api/admin/verifications/:verification_id/reject).
+// @Summary Reject a verification selfie
+// @Description Rejects a pending verification with a reason shown to the user, who may then start again with a new pose. Admins only.
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param verification_id path string true "Verification ID"
+// @Param payload body RejectVerificationRequest true "Rejection reason"
+// @Success 200 {object} models.PhotoVerification
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/admin/verifications/{verification_id}/reject [post]
+func (h *VerificationHandler) RejectVerification(c *gin.Context) {
+	var req RejectVerificationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "invalid request body"})
+		return
+	}
+	reason := strings.TrimSpace(req.Reason)
+	if reason == "" || utf8.RuneCountInString(reason) > models.MaxVerificationReasonLength {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: fmt.Sprintf("reason must be 1 to %d characters", models.MaxVerificationReasonLength)})
+		return
+	}
+	h.review(c, models.VerificationRejected, reason)
+}
+
+func (h *VerificationHandler) review(c *gin.Context, status, reason string) {
+	reviewerID, ok := middleware.GetUserID(c.Request.Context())
+	if !ok {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "user not authenticated"})
+		return
+	}
+
+	id, err := uuid.Parse(c.Param("verification_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "invalid verification ID"})
+		return
+	}
+
+	v, err := h.verificationRepo.Review(id, &reviewerID, status, reason)
+	if err == repo.ErrVerificationNotFound {
+		c.JSON(http.StatusNotFound, ErrorResponse{Error: "verification not found or already reviewed"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to review verification"})
+		return
+	}
+
+	c.JSON(http.StatusOK, v)
+}
@@ -0,0 +1,278 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Viet-CodingStars/kyupi-kyupi-backend/internal/middleware"
+	"github.com/Viet-CodingStars/kyupi-kyupi-backend/internal/models"
+	"github.com/Viet-CodingStars/kyupi-kyupi-backend/internal/repo"
+	"github.com/Viet-CodingStars/kyupi-kyupi-backend/internal/storage"
+	"github.com/Viet-CodingStars/kyupi-kyupi-backend/internal/verification"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type mockVerificationRepo struct {
+	verifications []*models.PhotoVerification
+	users         *mockUserRepo
+}
+
+func (m *mockVerificationRepo) Start(userID uuid.UUID, pose string) (*models.PhotoVerification, error) {
+	kept := m.verifications[:0]
+	for _, v := range m.verifications {
+		if v.UserID == userID && v.Status == models.VerificationPending {
+			return nil, repo.ErrVerificationInProgress
+		}
+		if v.UserID != userID || v.Status != models.VerificationAwaitingSelfie {
+			kept = append(kept, v)
+		}
+	}
+	v := &models.PhotoVerification{ID: uuid.New(), UserID: userID, Pose: pose, Status: models.VerificationAwaitingSelfie, CreatedAt: time.Now()}
+	m.verifications = append(kept, v)
+	return v, nil
+}
+
+func (m *mockVerificationRepo) Latest(userID uuid.UUID) (*models.PhotoVerification, error) {
+	for i := len(m.verifications) - 1; i >= 0; i-- {
+		if m.verifications[i].UserID == userID {
+			return m.verifications[i], nil
+		}
+	}
+	return nil, repo.ErrVerificationNotFound
+}
+
+func (m *mockVerificationRepo) Submit(userID uuid.UUID, selfieURL string) (*models.PhotoVerification, error) {
+	v, err := m.Latest(userID)
+	if err != nil || v.Status != models.VerificationAwaitingSelfie {
+		return nil, repo.ErrVerificationNotFound
+	}
+	now := time.Now()
+	v.SelfieURL, v.Status, v.SubmittedAt = selfieURL, models.VerificationPending, &now
+	if user, err := m.users.GetByID(userID); err == nil {
+		v.AvatarURL = user.AvatarURL
+	}
+	return v, nil
+}
+
+func (m *mockVerificationRepo) ListPending(limit int) ([]*models.PhotoVerification, error) {
+	queue := make([]*models.PhotoVerification, 0)
+	for _, v := range m.verifications {
+		if v.Status == models.VerificationPending && len(queue) < limit {
+			queue = append(queue, v)
+		}
+	}
+	return queue, nil
+}
+
+func (m *mockVerificationRepo) Review(id uuid.UUID, reviewerID *uuid.UUID, status, reason string) (*models.PhotoVerification, error) {
+	for _, v := range m.verifications {
+		if v.ID == id && v.Status == models.VerificationPending {
+			now := time.Now()
+			user, _ := m.users.GetByID(v.UserID)
+			if status == models.VerificationApproved && user.AvatarURL != v.AvatarURL {
+				status, reason = models.VerificationRejected, models.VerificationReasonAvatarChanged
+			}
+			v.Status, v.Reason, v.ReviewedBy, v.ReviewedAt = status, reason, reviewerID, &now
+			if status == models.VerificationApproved {
+				user.VerifiedAt = &now
+			}
+			return v, nil
+		}
+	}
+	return nil, repo.ErrVerificationNotFound
+}
+
+// mockSelfieStorage keeps selfies in memory, keyed by user ID and file name
+type mockSelfieStorage struct {
+	files map[string][]byte
+}
+
+var _ storage.SelfieStorage = (*mockSelfieStorage)(nil)
+
+func (m *mockSelfieStorage) Save(userID uuid.UUID, data io.Reader, originalFilename string) (string, error) {
+	if m.files == nil {
+		m.files = make(map[string][]byte)
+	}
+	content, _ := io.ReadAll(data)
+	filename := uuid.NewString() + ".png"
+	m.files[userID.String()+"/"+filename] = content
+	return "/api/admin/verifications/selfies/" + userID.String() + "/" + filename, nil
+}
+
+func (m *mockSelfieStorage) DeleteAll(userID uuid.UUID) error {
+	return nil
+}
+
+func (m *mockSelfieStorage) ForEach(userID uuid.UUID, fn func(filename string, data io.Reader) error) error {
+	return nil
+}
+
+func (m *mockSelfieStorage) Open(userID uuid.UUID, filename string) (io.ReadCloser, error) {
+	content, ok := m.files[userID.String()+"/"+filename]
+	if !ok {
+		return nil, storage.ErrFileNotFound
+	}
+	return io.NopCloser(bytes.NewReader(content)), nil
+}
+
+// stubVerifier decides every selfie the same way
+type stubVerifier struct {
+	result verification.Result
+}
+
+func (s *stubVerifier) Verify(ctx context.Context, v *models.PhotoVerification, avatarURL string) (verification.Result, error) {
+	return s.result, nil
+}
+
+func TestPhotoVerification(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	userRepo := newMockUserRepo()
+	user := &models.User{ID: uuid.New(), Email: "selfie@example.com", Name: "Selfie", AvatarURL: "/avatars/me.png"}
+	userRepo.users[user.Email] = user
+	adminID := uuid.New()
+	verificationRepo := &mockVerificationRepo{users: userRepo}
+	verifier := &stubVerifier{result: verification.Result{Status: models.VerificationPending}}
+	handler := &VerificationHandler{verificationRepo: verificationRepo, userRepo: userRepo, selfieStorage: &mockSelfieStorage{}, verifier: verifier}
+
+	request := func(fn gin.HandlerFunc, callerID uuid.UUID, method, path, body string, params gin.Params) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Params = params
+		req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		c.Request = req.WithContext(context.WithValue(req.Context(), middleware.UserIDKey, callerID))
+		fn(c)
+		return w
+	}
+	upload := func() *httptest.ResponseRecorder {
+		body := &bytes.Buffer{}
+		writer := multipart.NewWriter(body)
+		fileWriter, _ := writer.CreateFormFile("selfie", "selfie.png")
+		fileWriter.Write([]byte("fakeimagecontent"))
+		writer.Close()
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		req := httptest.NewRequest(http.MethodPost, "/api/users/verification/selfie", body)
+		req.Header.Set("Content-Type", writer.FormDataContentType())
+		c.Request = req.WithContext(context.WithValue(req.Context(), middleware.UserIDKey, user.ID))
+		handler.SubmitSelfie(c)
+		return w
+	}
+	decode := func(w *httptest.ResponseRecorder) *models.PhotoVerification {
+		var v models.PhotoVerification
+		json.Unmarshal(w.Body.Bytes(), &v)
+		return &v
+	}
+
+	if w := upload(); w.Code != http.StatusNotFound {
+		t.Fatalf("expected a selfie without a pose to be rejected, got %d", w.Code)
+	}
+
+	w := request(handler.StartVerification, user.ID, http.MethodPost, "/api/users/verification", "", nil)
+	started := decode(w)
+	if w.Code != http.StatusCreated || started.Status != models.VerificationAwaitingSelfie || started.Pose == "" {
+		t.Fatalf("expected a pose to mimic, got %d: %s", w.Code, w.Body.String())
+	}
+
+	w = upload()
+	submitted := decode(w)
+	if w.Code != http.StatusOK || submitted.Status != models.VerificationPending || submitted.SelfieURL == "" {
+		t.Fatalf("expected the selfie to be queued for review, got %d: %s", w.Code, w.Body.String())
+	}
+	if w := request(handler.StartVerification, user.ID, http.MethodPost, "/api/users/verification", "", nil); w.Code != http.StatusConflict {
+		t.Fatalf("expected no new request while one is in review, got %d", w.Code)
+	}
+
+	w = request(handler.ListVerificationQueue, adminID, http.MethodGet, "/api/admin/verifications", "", nil)
+	var queue VerificationsResponse
+	json.Unmarshal(w.Body.Bytes(), &queue)
+	if w.Code != http.StatusOK || len(queue.Verifications) != 1 || queue.Verifications[0].ID != submitted.ID {
+		t.Fatalf("expected the selfie in the review queue, got %d: %s", w.Code, w.Body.String())
+	}
+
+	// The selfie is served to reviewers by the admin handler, not from the public avatar prefix.
+	if !strings.HasPrefix(submitted.SelfieURL, "/api/admin/verifications/selfies/"+user.ID.String()+"/") {
+		t.Fatalf("expected the selfie under the admin route, got %s", submitted.SelfieURL)
+	}
+	selfieParams := gin.Params{{Key: "user_id", Value: user.ID.String()}, {Key: "filename", Value: submitted.SelfieURL[strings.LastIndex(submitted.SelfieURL, "/")+1:]}}
+	w = request(handler.GetSelfie, adminID, http.MethodGet, submitted.SelfieURL, "", selfieParams)
+	if w.Code != http.StatusOK || w.Body.String() != "fakeimagecontent" || w.Header().Get("Cache-Control") != "no-store" {
+		t.Fatalf("expected the selfie, got %d: %s", w.Code, w.Body.String())
+	}
+	missingParams := gin.Params{{Key: "user_id", Value: user.ID.String()}, {Key: "filename", Value: "missing.png"}}
+	if w := request(handler.GetSelfie, adminID, http.MethodGet, "/missing.png", "", missingParams); w.Code != http.StatusNotFound {
+		t.Fatalf("expected status 404 for a missing selfie, got %d", w.Code)
+	}
+
+	params := gin.Params{{Key: "verification_id", Value: submitted.ID.String()}}
+	if w := request(handler.RejectVerification, adminID, http.MethodPost, "/reject", `{"reason":"  "}`, params); w.Code != http.StatusBadRequest {
+		t.Fatalf("expected a rejection to need a reason, got %d", w.Code)
+	}
+	w = request(handler.RejectVerification, adminID, http.MethodPost, "/reject", `{"reason":"Face not visible"}`, params)
+	if rejected := decode(w); w.Code != http.StatusOK || rejected.Status != models.VerificationRejected || rejected.Reason != "Face not visible" {
+		t.Fatalf("expected the selfie to be rejected, got %d: %s", w.Code, w.Body.String())
+	}
+	if user.VerifiedAt != nil {
+		t.Fatal("expected a rejected user to stay unverified")
+	}
+	if w := request(handler.ApproveVerification, adminID, http.MethodPost, "/approve", "", params); w.Code != http.StatusNotFound {
+		t.Fatalf("expected a reviewed verification not to be reviewed again, got %d", w.Code)
+	}
+
+	t.Run("an automated verifier can decide", func(t *testing.T) {
+		verifier.result = verification.Result{Status: models.VerificationApproved}
+		request(handler.StartVerification, user.ID, http.MethodPost, "/api/users/verification", "", nil)
+		w := upload()
+		if approved := decode(w); w.Code != http.StatusOK || approved.Status != models.VerificationApproved {
+			t.Fatalf("expected the verifier to approve, got %d: %s", w.Code, w.Body.String())
+		}
+		if latest, _ := verificationRepo.Latest(user.ID); latest.ReviewedBy != nil {
+			t.Fatal("expected no reviewer recorded for an automated decision")
+		}
+		if user.VerifiedAt == nil {
+			t.Fatal("expected the user to be verified")
+		}
+		if w := request(handler.StartVerification, user.ID, http.MethodPost, "/api/users/verification", "", nil); w.Code != http.StatusConflict {
+			t.Fatalf("expected a verified user not to verify again, got %d", w.Code)
+		}
+	})
+
+	t.Run("approval does not verify an avatar changed after the selfie", func(t *testing.T) {
+		swapper := &models.User{ID: uuid.New(), Email: "swapper@example.com", AvatarURL: "/avatars/original.png"}
+		userRepo.users[swapper.Email] = swapper
+		verifier.result = verification.Result{Status: models.VerificationPending}
+		request(handler.StartVerification, swapper.ID, http.MethodPost, "/api/users/verification", "", nil)
+		submitted, _ := verificationRepo.Submit(swapper.ID, "/api/admin/verifications/selfies/x.png")
+		if submitted.AvatarURL != "/avatars/original.png" {
+			t.Fatalf("expected the avatar to be recorded with the selfie, got %q", submitted.AvatarURL)
+		}
+
+		swapper.AvatarURL = "/avatars/someone-else.png"
+		params := gin.Params{{Key: "verification_id", Value: submitted.ID.String()}}
+		w := request(handler.ApproveVerification, adminID, http.MethodPost, "/approve", "", params)
+		if reviewed := decode(w); w.Code != http.StatusOK || reviewed.Status != models.VerificationRejected || reviewed.Reason != models.VerificationReasonAvatarChanged {
+			t.Fatalf("expected the approval to be turned into a rejection, got %d: %s", w.Code, w.Body.String())
+		}
+		if swapper.VerifiedAt != nil {
+			t.Fatal("expected the swapped avatar not to be verified")
+		}
+	})
+
+	t.Run("needs an avatar to compare with", func(t *testing.T) {
+		plain := &models.User{ID: uuid.New(), Email: "plain@example.com"}
+		userRepo.users[plain.Email] = plain
+		if w := request(handler.StartVerification, plain.ID, http.MethodPost, "/api/users/verification", "", nil); w.Code != http.StatusBadRequest {
+			t.Fatalf("expected status 400, got %d", w.Code)
+		}
+	})
+}
@@ -0,0 +1,64 @@
+package jobs
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/Viet-CodingStars/kyupi-kyupi-backend/internal/discovery"
+	"github.com/Viet-CodingStars/kyupi-kyupi-backend/internal/repo"
+	"github.com/google/uuid"
+)
+
+// SwipeHistoryStore declares the operations required by DesirabilityScorer.
+type SwipeHistoryStore interface {
+	SwiperStats() (map[uuid.UUID]discovery.SwiperStats, error)
+	ForEachSwipe(fn func(discovery.Swipe) error) error
+	ReplaceScores(scores []discovery.UserScore, computedAt time.Time) error
+}
+
+// DesirabilityScorer recomputes every user's desirability rating and swiping behaviour from
+// the full like and pass history. The scores only feed discovery ranking.
+type DesirabilityScorer struct {
+	swipes SwipeHistoryStore
+	now    func() time.Time
+}
+
+func NewDesirabilityScorer(db *sql.DB) *DesirabilityScorer {
+	return &DesirabilityScorer{swipes: repo.NewDiscoveryRepo(db), now: time.Now}
+}
+
+// Run replays the swipe history and replaces the stored scores.
+func (s *DesirabilityScorer) Run(ctx context.Context) error {
+	stats, err := s.swipes.SwiperStats()
+	if err != nil {
+		return fmt.Errorf("load swiper stats: %w", err)
+	}
+
+	rater := discovery.NewRater(stats)
+	err = s.swipes.ForEachSwipe(func(swipe discovery.Swipe) error {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return rater.Observe(swipe)
+	})
+	if err != nil {
+		return fmt.Errorf("replay swipes: %w", err)
+	}
+
+	scores := rater.Scores()
+	if err := s.swipes.ReplaceScores(scores, s.now()); err != nil {
+		return fmt.Errorf("store desirability scores: %w", err)
+	}
+
+	indiscriminate := 0
+	for _, score := range scores {
+		if score.Indiscriminate {
+			indiscriminate++
+		}
+	}
+	log.Printf("scored desirability of %d users (%d indiscriminate swipers)", len(scores), indiscriminate)
+	return nil
+}
@@ -0,0 +1,88 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Viet-CodingStars/kyupi-kyupi-backend/internal/discovery"
+	"github.com/google/uuid"
+)
+
+type mockSwipeHistoryStore struct {
+	stats      map[uuid.UUID]discovery.SwiperStats
+	swipes     []discovery.Swipe
+	scores     []discovery.UserScore
+	computedAt time.Time
+	replayErr  error
+}
+
+func (m *mockSwipeHistoryStore) SwiperStats() (map[uuid.UUID]discovery.SwiperStats, error) {
+	return m.stats, nil
+}
+
+func (m *mockSwipeHistoryStore) ForEachSwipe(fn func(discovery.Swipe) error) error {
+	if m.replayErr != nil {
+		return m.replayErr
+	}
+	for _, s := range m.swipes {
+		if err := fn(s); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *mockSwipeHistoryStore) ReplaceScores(scores []discovery.UserScore, computedAt time.Time) error {
+	m.scores, m.computedAt = scores, computedAt
+	return nil
+}
+
+func TestDesirabilityScorer(t *testing.T) {
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	swiper, liked, passed := uuid.New(), uuid.New(), uuid.New()
+
+	t.Run("scores users from their swipe history", func(t *testing.T) {
+		store := &mockSwipeHistoryStore{
+			stats: map[uuid.UUID]discovery.SwiperStats{swiper: {Likes: 1, Passes: 1, FirstAt: now, LastAt: now}},
+			swipes: []discovery.Swipe{
+				{SwiperID: swiper, TargetID: liked, Liked: true},
+				{SwiperID: swiper, TargetID: passed},
+			},
+		}
+		scorer := &DesirabilityScorer{swipes: store, now: func() time.Time { return now }}
+
+		if err := scorer.Run(context.Background()); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if !store.computedAt.Equal(now) {
+			t.Fatalf("expected scores computed at %v, got %v", now, store.computedAt)
+		}
+		ratings := make(map[uuid.UUID]discovery.UserScore)
+		for _, s := range store.scores {
+			ratings[s.UserID] = s
+		}
+		if len(ratings) != 3 {
+			t.Fatalf("expected 3 scored users, got %d", len(ratings))
+		}
+		if ratings[liked].Rating <= ratings[passed].Rating {
+			t.Fatalf("expected the liked user to rate above the passed one, got %+v", ratings)
+		}
+		if ratings[swiper].Likes != 1 || ratings[swiper].Passes != 1 {
+			t.Fatalf("expected the swiper's stats to be kept, got %+v", ratings[swiper])
+		}
+	})
+
+	t.Run("keeps the previous scores when the history cannot be read", func(t *testing.T) {
+		store := &mockSwipeHistoryStore{replayErr: errors.New("db unavailable")}
+		scorer := &DesirabilityScorer{swipes: store, now: func() time.Time { return now }}
+
+		if err := scorer.Run(context.Background()); err == nil {
+			t.Fatal("expected error")
+		}
+		if store.scores != nil {
+			t.Fatal("expected scores not to be replaced")
+		}
+	})
+}
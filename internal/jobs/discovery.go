@@ -0,0 +1,71 @@
+package jobs
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/Viet-CodingStars/kyupi-kyupi-backend/internal/repo"
+	"github.com/google/uuid"
+)
+
+const (
+	// discoveryRefreshBatchSize bounds how many rankings a single run recomputes.
+	discoveryRefreshBatchSize = 200
+	// discoveryActiveWindow limits refreshes to users who were seen recently; anyone else
+	// gets a fresh ranking when they next open discovery.
+	discoveryActiveWindow = 7 * 24 * time.Hour
+)
+
+// StaleRankingStore declares the operations required by CandidateRefresher.
+type StaleRankingStore interface {
+	ListStaleViewers(activeSince, staleBefore time.Time, limit int) ([]uuid.UUID, error)
+}
+
+// RankingRefresher recomputes a user's discovery ranking.
+type RankingRefresher interface {
+	Refresh(viewerID uuid.UUID) error
+}
+
+// CandidateRefresher recomputes the discovery rankings of active users before they go stale,
+// so opening discovery rarely has to wait for one.
+type CandidateRefresher struct {
+	store    StaleRankingStore
+	rankings RankingRefresher
+	cacheTTL time.Duration
+	now      func() time.Time
+}
+
+func NewCandidateRefresher(db *sql.DB, rankings RankingRefresher, cacheTTL time.Duration) *CandidateRefresher {
+	return &CandidateRefresher{store: repo.NewDiscoveryRepo(db), rankings: rankings, cacheTTL: cacheTTL, now: time.Now}
+}
+
+// Run recomputes one batch of missing or expired rankings. A failure for one user does not
+// stop the others.
+func (r *CandidateRefresher) Run(ctx context.Context) error {
+	now := r.now()
+	ids, err := r.store.ListStaleViewers(now.Add(-discoveryActiveWindow), now.Add(-r.cacheTTL), discoveryRefreshBatchSize)
+	if err != nil {
+		return fmt.Errorf("list stale discovery rankings: %w", err)
+	}
+
+	var errs []error
+	refreshed := 0
+	for _, id := range ids {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err := r.rankings.Refresh(id); err != nil {
+			errs = append(errs, fmt.Errorf("refresh discovery ranking for %s: %w", id, err))
+			continue
+		}
+		refreshed++
+	}
+	if refreshed > 0 {
+		log.Printf("refreshed %d discovery rankings", refreshed)
+	}
+	return errors.Join(errs...)
+}
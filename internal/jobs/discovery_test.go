@@ -0,0 +1,74 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type mockStaleRankingStore struct {
+	ids                      []uuid.UUID
+	activeSince, staleBefore time.Time
+	limit                    int
+}
+
+func (m *mockStaleRankingStore) ListStaleViewers(activeSince, staleBefore time.Time, limit int) ([]uuid.UUID, error) {
+	m.activeSince, m.staleBefore, m.limit = activeSince, staleBefore, limit
+	return m.ids, nil
+}
+
+type mockRankingRefresher struct {
+	refreshed []uuid.UUID
+	failFor   uuid.UUID
+}
+
+func (m *mockRankingRefresher) Refresh(viewerID uuid.UUID) error {
+	if viewerID == m.failFor {
+		return errors.New("db unavailable")
+	}
+	m.refreshed = append(m.refreshed, viewerID)
+	return nil
+}
+
+func TestCandidateRefresher(t *testing.T) {
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	t.Run("refreshes stale rankings of active users", func(t *testing.T) {
+		store := &mockStaleRankingStore{ids: []uuid.UUID{uuid.New(), uuid.New()}}
+		rankings := &mockRankingRefresher{}
+		refresher := &CandidateRefresher{store: store, rankings: rankings, cacheTTL: time.Hour, now: func() time.Time { return now }}
+
+		if err := refresher.Run(context.Background()); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if len(rankings.refreshed) != 2 {
+			t.Fatalf("expected 2 refreshes, got %d", len(rankings.refreshed))
+		}
+		if want := now.Add(-time.Hour); !store.staleBefore.Equal(want) {
+			t.Fatalf("expected rankings computed before %v to be stale, got %v", want, store.staleBefore)
+		}
+		if want := now.Add(-discoveryActiveWindow); !store.activeSince.Equal(want) {
+			t.Fatalf("expected users active since %v, got %v", want, store.activeSince)
+		}
+		if store.limit != discoveryRefreshBatchSize {
+			t.Fatalf("expected batch size %d, got %d", discoveryRefreshBatchSize, store.limit)
+		}
+	})
+
+	t.Run("keeps going after a failure and reports it", func(t *testing.T) {
+		failing := uuid.New()
+		store := &mockStaleRankingStore{ids: []uuid.UUID{failing, uuid.New()}}
+		rankings := &mockRankingRefresher{failFor: failing}
+		refresher := &CandidateRefresher{store: store, rankings: rankings, cacheTTL: time.Hour, now: func() time.Time { return now }}
+
+		if err := refresher.Run(context.Background()); err == nil {
+			t.Fatal("expected error")
+		}
+		if len(rankings.refreshed) != 1 {
+			t.Fatalf("expected the other ranking to be refreshed, got %d", len(rankings.refreshed))
+		}
+	})
+}
@@ -0,0 +1,339 @@
+package jobs
+
+import (
+	"archive/zip"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"time"
+
+	"github.com/Viet-CodingStars/kyupi-kyupi-backend/internal/models"
+	"github.com/Viet-CodingStars/kyupi-kyupi-backend/internal/notify"
+	"github.com/Viet-CodingStars/kyupi-kyupi-backend/internal/repo"
+	"github.com/Viet-CodingStars/kyupi-kyupi-backend/internal/storage"
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// exportBatchSize bounds how many exports a single run builds or expires.
+const exportBatchSize = 10
+
+// ExportStore declares the export queue operations required by DataExporter.
+type ExportStore interface {
+	ClaimNext() (*models.DataExport, error)
+	MarkReady(id uuid.UUID, filePath string, expiresAt time.Time) error
+	MarkFailed(id uuid.UUID, reason string) error
+	ListExpired(limit int) ([]*models.DataExport, error)
+	MarkExpired(id uuid.UUID) error
+}
+
+// ExportSources declares the reads DataExporter makes to collect a user's data.
+type ExportSources interface {
+	GetUser(userID uuid.UUID) (*models.User, error)
+	GetPreferences(userID uuid.UUID) (*models.Preferences, error)
+	ListInterests(userID uuid.UUID) ([]*models.Interest, error)
+	ListPromptAnswers(userID uuid.UUID) ([]*models.PromptAnswer, error)
+	ListQuestionAnswers(userID uuid.UUID) ([]*models.QuestionAnswer, error)
+	ListVerifications(userID uuid.UUID) ([]*models.PhotoVerification, error)
+	ListLikes(userID uuid.UUID) ([]*models.Like, error)
+	ListMatches(userID uuid.UUID) ([]*models.Match, error)
+	ListMessages(ctx context.Context, userID uuid.UUID) ([]*models.Message, error)
+}
+
+// repoSources adapts the repositories to ExportSources.
+type repoSources struct {
+	users         *repo.UserRepo
+	prefs         *repo.PreferenceRepo
+	interests     *repo.InterestRepo
+	prompts       *repo.PromptRepo
+	questions     *repo.QuestionRepo
+	verifications *repo.VerificationRepo
+	likes         *repo.LikeRepo
+	matches       *repo.MatchRepo
+	messages      *repo.MessageRepo
+}
+
+func (s *repoSources) GetUser(userID uuid.UUID) (*models.User, error) {
+	return s.users.GetByID(userID)
+}
+
+func (s *repoSources) GetPreferences(userID uuid.UUID) (*models.Preferences, error) {
+	return s.prefs.Get(userID)
+}
+
+func (s *repoSources) ListInterests(userID uuid.UUID) ([]*models.Interest, error) {
+	return s.interests.ListForUser(userID)
+}
+
+func (s *repoSources) ListPromptAnswers(userID uuid.UUID) ([]*models.PromptAnswer, error) {
+	return s.prompts.ListAnswers(userID)
+}
+
+func (s *repoSources) ListQuestionAnswers(userID uuid.UUID) ([]*models.QuestionAnswer, error) {
+	return s.questions.ListAnswers(userID)
+}
+
+func (s *repoSources) ListVerifications(userID uuid.UUID) ([]*models.PhotoVerification, error) {
+	return s.verifications.ListByUser(userID)
+}
+
+func (s *repoSources) ListLikes(userID uuid.UUID) ([]*models.Like, error) {
+	return s.likes.ListByUser(userID)
+}
+
+func (s *repoSources) ListMatches(userID uuid.UUID) ([]*models.Match, error) {
+	return s.matches.GetByUserID(userID)
+}
+
+func (s *repoSources) ListMessages(ctx context.Context, userID uuid.UUID) ([]*models.Message, error) {
+	return s.messages.ListByUser(ctx, userID)
+}
+
+// DataExporter builds personal data export archives and removes them once they expire.
+type DataExporter struct {
+	exports   ExportStore
+	sources   ExportSources
+	avatars   storage.AvatarStorage
+	selfies   storage.AvatarStorage // photo verification selfies
+	notifier  notify.Notifier
+	dir       string
+	retention time.Duration
+	now       func() time.Time
+}
+
+func NewDataExporter(db *sql.DB, mongoDB *mongo.Database, avatars, selfies storage.AvatarStorage, notifier notify.Notifier, dir string, retention time.Duration) *DataExporter {
+	return &DataExporter{
+		exports: repo.NewExportRepo(db),
+		sources: &repoSources{
+			users:         repo.NewUserRepo(db),
+			prefs:         repo.NewPreferenceRepo(db),
+			interests:     repo.NewInterestRepo(db),
+			prompts:       repo.NewPromptRepo(db),
+			questions:     repo.NewQuestionRepo(db),
+			verifications: repo.NewVerificationRepo(db),
+			likes:         repo.NewLikeRepo(db),
+			matches:       repo.NewMatchRepo(db),
+			messages:      repo.NewMessageRepo(mongoDB),
+		},
+		avatars:   avatars,
+		selfies:   selfies,
+		notifier:  notifier,
+		dir:       dir,
+		retention: retention,
+		now:       time.Now,
+	}
+}
+
+// Run removes expired archives and then builds up to one batch of pending exports.
+func (e *DataExporter) Run(ctx context.Context) error {
+	var errs []error
+	if err := e.expire(); err != nil {
+		errs = append(errs, err)
+	}
+
+	for i := 0; i < exportBatchSize; i++ {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		export, err := e.exports.ClaimNext()
+		if errors.Is(err, repo.ErrExportNotFound) {
+			break
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("claim export: %w", err))
+			break
+		}
+		if err := e.process(ctx, export); err != nil {
+			errs = append(errs, fmt.Errorf("export %s: %w", export.ID, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// process builds one export and records the outcome. Only failures to record the outcome are
+// returned; a failed build is stored on the export so the user can see it.
+func (e *DataExporter) process(ctx context.Context, export *models.DataExport) error {
+	filePath, err := e.build(ctx, export)
+	if err != nil {
+		log.Printf("data export %s failed: %v", export.ID, err)
+		return e.exports.MarkFailed(export.ID, "failed to build export")
+	}
+
+	if err := e.exports.MarkReady(export.ID, filePath, e.now().Add(e.retention)); err != nil {
+		os.Remove(filePath)
+		return fmt.Errorf("mark ready: %w", err)
+	}
+	log.Printf("data export %s ready", export.ID)
+
+	if e.notifier != nil {
+		err := e.notifier.Notify(ctx, export.UserID, notify.Notification{
+			Type:  notify.TypeDataExportReady,
+			Title: "Your data export is ready",
+			Body:  "Your Kyupi Kyupi data is ready to download.",
+			Data:  map[string]string{"export_id": export.ID.String()},
+		})
+		if err != nil {
+			log.Printf("notify data export %s: %v", export.ID, err)
+		}
+	}
+	return nil
+}
+
+// build writes the archive to a temporary file and renames it into place so a partially
+// written archive is never served. Archives live in a per-user directory so that purging an
+// account can remove them in one step.
+func (e *DataExporter) build(ctx context.Context, export *models.DataExport) (string, error) {
+	userDir := filepath.Join(e.dir, export.UserID.String())
+	if err := os.MkdirAll(userDir, 0o700); err != nil {
+		return "", fmt.Errorf("create export dir: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(userDir, export.ID.String()+"-*.zip.tmp")
+	if err != nil {
+		return "", fmt.Errorf("create archive: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := e.writeArchive(ctx, tmp, export.UserID); err != nil {
+		tmp.Close()
+		return "", err
+	}
+	if err := tmp.Close(); err != nil {
+		return "", fmt.Errorf("close archive: %w", err)
+	}
+
+	filePath := filepath.Join(userDir, export.ID.String()+".zip")
+	if err := os.Rename(tmp.Name(), filePath); err != nil {
+		return "", fmt.Errorf("move archive: %w", err)
+	}
+	return filePath, nil
+}
+
+func (e *DataExporter) writeArchive(ctx context.Context, w io.Writer, userID uuid.UUID) error {
+	user, err := e.sources.GetUser(userID)
+	if err != nil {
+		return fmt.Errorf("load profile: %w", err)
+	}
+	prefs, err := e.sources.GetPreferences(userID)
+	if err != nil {
+		return fmt.Errorf("load preferences: %w", err)
+	}
+	interests, err := e.sources.ListInterests(userID)
+	if err != nil {
+		return fmt.Errorf("load interests: %w", err)
+	}
+	answers, err := e.sources.ListPromptAnswers(userID)
+	if err != nil {
+		return fmt.Errorf("load prompt answers: %w", err)
+	}
+	questionAnswers, err := e.sources.ListQuestionAnswers(userID)
+	if err != nil {
+		return fmt.Errorf("load question answers: %w", err)
+	}
+	verifications, err := e.sources.ListVerifications(userID)
+	if err != nil {
+		return fmt.Errorf("load verifications: %w", err)
+	}
+	likes, err := e.sources.ListLikes(userID)
+	if err != nil {
+		return fmt.Errorf("load likes: %w", err)
+	}
+	matches, err := e.sources.ListMatches(userID)
+	if err != nil {
+		return fmt.Errorf("load matches: %w", err)
+	}
+	messages, err := e.sources.ListMessages(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("load messages: %w", err)
+	}
+
+	zw := zip.NewWriter(w)
+	files := []struct {
+		name string
+		data interface{}
+	}{
+		{"profile.json", user},
+		{"preferences.json", prefs},
+		{"interests.json", interests},
+		{"prompts.json", answers},
+		{"questions.json", questionAnswers},
+		{"verifications.json", verifications},
+		{"likes.json", likes},
+		{"matches.json", matches},
+		{"messages.json", messages},
+	}
+	for _, f := range files {
+		if err := writeJSONEntry(zw, f.name, f.data); err != nil {
+			return err
+		}
+	}
+
+	if err := writeFiles(zw, e.avatars, userID, "photos"); err != nil {
+		return fmt.Errorf("add photos: %w", err)
+	}
+	if err := writeFiles(zw, e.selfies, userID, "selfies"); err != nil {
+		return fmt.Errorf("add verification selfies: %w", err)
+	}
+
+	if err := zw.Close(); err != nil {
+		return fmt.Errorf("finish archive: %w", err)
+	}
+	return nil
+}
+
+// writeFiles copies every file stored for the user into dir in the archive. A nil store is skipped.
+func writeFiles(zw *zip.Writer, files storage.AvatarStorage, userID uuid.UUID, dir string) error {
+	if files == nil {
+		return nil
+	}
+	return files.ForEach(userID, func(filename string, data io.Reader) error {
+		entry, err := zw.Create(path.Join(dir, filepath.Base(filename)))
+		if err != nil {
+			return err
+		}
+		_, err = io.Copy(entry, data)
+		return err
+	})
+}
+
+func writeJSONEntry(zw *zip.Writer, name string, data interface{}) error {
+	entry, err := zw.Create(name)
+	if err != nil {
+		return fmt.Errorf("add %s: %w", name, err)
+	}
+	enc := json.NewEncoder(entry)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(data); err != nil {
+		return fmt.Errorf("write %s: %w", name, err)
+	}
+	return nil
+}
+
+// expire deletes archives whose download window has closed.
+func (e *DataExporter) expire() error {
+	expired, err := e.exports.ListExpired(exportBatchSize)
+	if err != nil {
+		return fmt.Errorf("list expired exports: %w", err)
+	}
+
+	var errs []error
+	for _, export := range expired {
+		if export.FilePath != "" {
+			if err := os.Remove(export.FilePath); err != nil && !os.IsNotExist(err) {
+				errs = append(errs, fmt.Errorf("remove export %s: %w", export.ID, err))
+				continue
+			}
+		}
+		if err := e.exports.MarkExpired(export.ID); err != nil {
+			errs = append(errs, fmt.Errorf("mark export %s expired: %w", export.ID, err))
+		}
+	}
+	return errors.Join(errs...)
+}
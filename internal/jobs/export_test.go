@@ -0,0 +1,258 @@
+package jobs
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Viet-CodingStars/kyupi-kyupi-backend/internal/models"
+	"github.com/Viet-CodingStars/kyupi-kyupi-backend/internal/notify"
+	"github.com/Viet-CodingStars/kyupi-kyupi-backend/internal/repo"
+	"github.com/google/uuid"
+)
+
+type mockExportStore struct {
+	pending []*models.DataExport
+	ready   map[uuid.UUID]string
+	failed  map[uuid.UUID]string
+	expired []*models.DataExport
+	marked  []uuid.UUID
+}
+
+func newMockExportStore(pending ...*models.DataExport) *mockExportStore {
+	return &mockExportStore{
+		pending: pending,
+		ready:   make(map[uuid.UUID]string),
+		failed:  make(map[uuid.UUID]string),
+	}
+}
+
+func (m *mockExportStore) ClaimNext() (*models.DataExport, error) {
+	if len(m.pending) == 0 {
+		return nil, repo.ErrExportNotFound
+	}
+	export := m.pending[0]
+	m.pending = m.pending[1:]
+	return export, nil
+}
+
+func (m *mockExportStore) MarkReady(id uuid.UUID, filePath string, expiresAt time.Time) error {
+	m.ready[id] = filePath
+	return nil
+}
+
+func (m *mockExportStore) MarkFailed(id uuid.UUID, reason string) error {
+	m.failed[id] = reason
+	return nil
+}
+
+func (m *mockExportStore) ListExpired(limit int) ([]*models.DataExport, error) {
+	return m.expired, nil
+}
+
+func (m *mockExportStore) MarkExpired(id uuid.UUID) error {
+	m.marked = append(m.marked, id)
+	return nil
+}
+
+type mockExportSources struct {
+	user     *models.User
+	likes    []*models.Like
+	matches  []*models.Match
+	messages []*models.Message
+}
+
+func (m *mockExportSources) GetUser(userID uuid.UUID) (*models.User, error) {
+	if m.user == nil || m.user.ID != userID {
+		return nil, repo.ErrUserNotFound
+	}
+	return m.user, nil
+}
+
+func (m *mockExportSources) GetPreferences(userID uuid.UUID) (*models.Preferences, error) {
+	return &models.Preferences{}, nil
+}
+
+func (m *mockExportSources) ListInterests(userID uuid.UUID) ([]*models.Interest, error) {
+	return []*models.Interest{}, nil
+}
+
+func (m *mockExportSources) ListPromptAnswers(userID uuid.UUID) ([]*models.PromptAnswer, error) {
+	return []*models.PromptAnswer{}, nil
+}
+
+func (m *mockExportSources) ListQuestionAnswers(userID uuid.UUID) ([]*models.QuestionAnswer, error) {
+	return []*models.QuestionAnswer{}, nil
+}
+
+func (m *mockExportSources) ListVerifications(userID uuid.UUID) ([]*models.PhotoVerification, error) {
+	return []*models.PhotoVerification{}, nil
+}
+
+func (m *mockExportSources) ListLikes(userID uuid.UUID) ([]*models.Like, error) {
+	return m.likes, nil
+}
+
+func (m *mockExportSources) ListMatches(userID uuid.UUID) ([]*models.Match, error) {
+	return m.matches, nil
+}
+
+func (m *mockExportSources) ListMessages(ctx context.Context, userID uuid.UUID) ([]*models.Message, error) {
+	return m.messages, nil
+}
+
+type mockNotifier struct {
+	sent map[uuid.UUID]notify.Notification
+}
+
+func (m *mockNotifier) Notify(ctx context.Context, userID uuid.UUID, n notify.Notification) error {
+	m.sent[userID] = n
+	return nil
+}
+
+func TestDataExporter(t *testing.T) {
+	userID := uuid.New()
+	otherID := uuid.New()
+	sources := &mockExportSources{
+		user: &models.User{ID: userID, Email: "user@example.com", PasswordHash: "secret-hash", Name: "User"},
+		likes: []*models.Like{
+			{ID: uuid.New(), UserID: userID, TargetUserID: otherID, Status: "like"},
+			{ID: uuid.New(), UserID: userID, TargetUserID: uuid.New(), Status: "pass"},
+		},
+		matches:  []*models.Match{{ID: uuid.New(), User1ID: userID, User2ID: otherID}},
+		messages: []*models.Message{{SenderID: userID, ReceiverID: otherID, Content: "hi"}},
+	}
+
+	t.Run("builds archive and notifies", func(t *testing.T) {
+		export := &models.DataExport{ID: uuid.New(), UserID: userID, Status: models.ExportStatusProcessing}
+		store := newMockExportStore(export)
+		notifier := &mockNotifier{sent: make(map[uuid.UUID]notify.Notification)}
+		avatars := &mockAvatarStorage{files: map[string]string{"avatar.png": "png-bytes"}}
+		selfies := &mockAvatarStorage{files: map[string]string{"selfie.jpg": "jpg-bytes"}}
+		exporter := &DataExporter{
+			exports: store, sources: sources, avatars: avatars, selfies: selfies, notifier: notifier,
+			dir: t.TempDir(), retention: time.Hour, now: time.Now,
+		}
+
+		if err := exporter.Run(context.Background()); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		filePath, ok := store.ready[export.ID]
+		if !ok {
+			t.Fatalf("expected export to be ready, failed=%v", store.failed)
+		}
+		if notifier.sent[userID].Type != notify.TypeDataExportReady {
+			t.Fatalf("expected ready notification, got %+v", notifier.sent)
+		}
+
+		entries := readArchive(t, filePath)
+		for _, name := range []string{"profile.json", "preferences.json", "interests.json", "prompts.json", "questions.json", "verifications.json", "likes.json", "matches.json", "messages.json", "photos/avatar.png", "selfies/selfie.jpg"} {
+			if _, ok := entries[name]; !ok {
+				t.Fatalf("expected %s in archive, got %v", name, keys(entries))
+			}
+		}
+		if entries["photos/avatar.png"] != "png-bytes" {
+			t.Fatalf("unexpected photo content %q", entries["photos/avatar.png"])
+		}
+		if entries["selfies/selfie.jpg"] != "jpg-bytes" {
+			t.Fatalf("unexpected selfie content %q", entries["selfies/selfie.jpg"])
+		}
+
+		var profile map[string]interface{}
+		if err := json.Unmarshal([]byte(entries["profile.json"]), &profile); err != nil {
+			t.Fatalf("invalid profile.json: %v", err)
+		}
+		if profile["email"] != "user@example.com" {
+			t.Fatalf("expected email in profile, got %v", profile)
+		}
+		if _, leaked := profile["password_hash"]; leaked {
+			t.Fatal("expected password hash to be excluded")
+		}
+
+		var likes []models.Like
+		if err := json.Unmarshal([]byte(entries["likes.json"]), &likes); err != nil || len(likes) != 2 {
+			t.Fatalf("expected 2 likes, got %v (err %v)", likes, err)
+		}
+	})
+
+	t.Run("records failure without notifying", func(t *testing.T) {
+		export := &models.DataExport{ID: uuid.New(), UserID: uuid.New(), Status: models.ExportStatusProcessing}
+		store := newMockExportStore(export)
+		notifier := &mockNotifier{sent: make(map[uuid.UUID]notify.Notification)}
+		dir := t.TempDir()
+		exporter := &DataExporter{
+			exports: store, sources: sources, notifier: notifier,
+			dir: dir, retention: time.Hour, now: time.Now,
+		}
+
+		if err := exporter.Run(context.Background()); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if _, ok := store.failed[export.ID]; !ok {
+			t.Fatal("expected export to be marked failed")
+		}
+		if len(notifier.sent) != 0 {
+			t.Fatalf("expected no notification, got %v", notifier.sent)
+		}
+		if files, _ := os.ReadDir(filepath.Join(dir, export.UserID.String())); len(files) != 0 {
+			t.Fatalf("expected temporary archive to be removed, got %d files", len(files))
+		}
+	})
+
+	t.Run("removes expired archives", func(t *testing.T) {
+		dir := t.TempDir()
+		filePath := filepath.Join(dir, "old.zip")
+		if err := os.WriteFile(filePath, []byte("zip"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		expired := &models.DataExport{ID: uuid.New(), UserID: userID, Status: models.ExportStatusReady, FilePath: filePath}
+		store := newMockExportStore()
+		store.expired = []*models.DataExport{expired}
+		exporter := &DataExporter{exports: store, sources: sources, dir: dir, retention: time.Hour, now: time.Now}
+
+		if err := exporter.Run(context.Background()); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if _, err := os.Stat(filePath); !errors.Is(err, os.ErrNotExist) {
+			t.Fatalf("expected archive to be deleted, got %v", err)
+		}
+		if len(store.marked) != 1 || store.marked[0] != expired.ID {
+			t.Fatalf("expected export to be marked expired, got %v", store.marked)
+		}
+	})
+}
+
+func readArchive(t *testing.T, filePath string) map[string]string {
+	t.Helper()
+	zr, err := zip.OpenReader(filePath)
+	if err != nil {
+		t.Fatalf("open archive: %v", err)
+	}
+	defer zr.Close()
+
+	entries := make(map[string]string)
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatalf("open %s: %v", f.Name, err)
+		}
+		data, _ := io.ReadAll(rc)
+		rc.Close()
+		entries[f.Name] = string(data)
+	}
+	return entries
+}
+
+func keys(m map[string]string) []string {
+	out := make([]string, 0, len(m))
+	for k := range m {
+		out = append(out, k)
+	}
+	return out
+}
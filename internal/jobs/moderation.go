@@ -0,0 +1,99 @@
+package jobs
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"log"
+	"path"
+	"path/filepath"
+	"time"
+
+	"github.com/Viet-CodingStars/kyupi-kyupi-backend/internal/models"
+	"github.com/Viet-CodingStars/kyupi-kyupi-backend/internal/moderation"
+	"github.com/Viet-CodingStars/kyupi-kyupi-backend/internal/repo"
+	"github.com/Viet-CodingStars/kyupi-kyupi-backend/internal/storage"
+	"github.com/google/uuid"
+)
+
+const (
+	// avatarHashBatchSize bounds how many avatars a single run hashes.
+	avatarHashBatchSize = 200
+	// screeningLookback is how far back a run looks for new accounts and activity. Overlapping
+	// runs are harmless because a suspicion is only flagged once.
+	screeningLookback = 24 * time.Hour
+)
+
+// ModerationStore declares the operations required by AccountScreener.
+type ModerationStore interface {
+	ListUnhashedAvatars(limit int) (map[uuid.UUID]string, error)
+	SetAvatarHash(userID uuid.UUID, avatarURL string, hash *uint64) error
+	DetectSuspects(lookback time.Duration) ([]*models.ModerationFlag, error)
+	RecordFlags(flags []*models.ModerationFlag, restrict bool) (int, error)
+}
+
+// AccountScreener flags suspected duplicate and fake accounts for the moderation queue and,
+// when autoRestrict is set, shadow-restricts them until an admin reviews the flag.
+type AccountScreener struct {
+	flags        ModerationStore
+	avatars      storage.AvatarStorage
+	autoRestrict bool
+}
+
+func NewAccountScreener(db *sql.DB, avatars storage.AvatarStorage, autoRestrict bool) *AccountScreener {
+	return &AccountScreener{flags: repo.NewModerationRepo(db), avatars: avatars, autoRestrict: autoRestrict}
+}
+
+// Run hashes new avatars, then flags every account matching a heuristic over the lookback.
+func (s *AccountScreener) Run(ctx context.Context) error {
+	if err := s.hashAvatars(ctx); err != nil {
+		return err
+	}
+
+	suspects, err := s.flags.DetectSuspects(screeningLookback)
+	if err != nil {
+		return fmt.Errorf("detect suspects: %w", err)
+	}
+	added, err := s.flags.RecordFlags(suspects, s.autoRestrict)
+	if err != nil {
+		return fmt.Errorf("record flags: %w", err)
+	}
+	if added > 0 {
+		log.Printf("flagged %d suspected fake accounts for moderation", added)
+	}
+	return nil
+}
+
+// hashAvatars stores the perceptual hash of one batch of avatars not hashed yet. Avatars that
+// cannot be read or decoded are stored without a hash so they are not retried.
+func (s *AccountScreener) hashAvatars(ctx context.Context) error {
+	avatars, err := s.flags.ListUnhashedAvatars(avatarHashBatchSize)
+	if err != nil {
+		return fmt.Errorf("list unhashed avatars: %w", err)
+	}
+	for userID, avatarURL := range avatars {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		var hash *uint64
+		err := s.avatars.ForEach(userID, func(filename string, data io.Reader) error {
+			if filepath.Base(filename) != path.Base(avatarURL) {
+				return nil
+			}
+			h, err := moderation.HashImage(data)
+			if err != nil {
+				return err
+			}
+			hash = &h
+			return nil
+		})
+		if err != nil {
+			log.Printf("hash avatar of user %s: %v", userID, err)
+		}
+		if err := s.flags.SetAvatarHash(userID, avatarURL, hash); err != nil {
+			return fmt.Errorf("store avatar hash: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,93 @@
+package jobs
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"image"
+	"image/png"
+	"testing"
+	"time"
+
+	"github.com/Viet-CodingStars/kyupi-kyupi-backend/internal/models"
+	"github.com/google/uuid"
+)
+
+type mockModerationStore struct {
+	unhashed map[uuid.UUID]string
+	hashes   map[uuid.UUID]*uint64
+	suspects []*models.ModerationFlag
+	lookback time.Duration
+	recorded []*models.ModerationFlag
+	restrict bool
+	err      error
+}
+
+func (m *mockModerationStore) ListUnhashedAvatars(limit int) (map[uuid.UUID]string, error) {
+	return m.unhashed, nil
+}
+
+func (m *mockModerationStore) SetAvatarHash(userID uuid.UUID, avatarURL string, hash *uint64) error {
+	m.hashes[userID] = hash
+	return nil
+}
+
+func (m *mockModerationStore) DetectSuspects(lookback time.Duration) ([]*models.ModerationFlag, error) {
+	m.lookback = lookback
+	return m.suspects, m.err
+}
+
+func (m *mockModerationStore) RecordFlags(flags []*models.ModerationFlag, restrict bool) (int, error) {
+	m.recorded, m.restrict = flags, restrict
+	return len(flags), nil
+}
+
+func TestAccountScreener(t *testing.T) {
+	var avatar bytes.Buffer
+	if err := png.Encode(&avatar, image.NewGray(image.Rect(0, 0, 16, 16))); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("hashes avatars and flags suspects", func(t *testing.T) {
+		withAvatar, withBrokenAvatar := uuid.New(), uuid.New()
+		suspect := &models.ModerationFlag{UserID: withAvatar, Reason: models.FlagEmailAlias}
+		store := &mockModerationStore{
+			unhashed: map[uuid.UUID]string{
+				withAvatar:       "/avatars/" + withAvatar.String() + "/avatar.png",
+				withBrokenAvatar: "/avatars/" + withBrokenAvatar.String() + "/broken.png",
+			},
+			hashes:   make(map[uuid.UUID]*uint64),
+			suspects: []*models.ModerationFlag{suspect},
+		}
+		avatars := &mockAvatarStorage{files: map[string]string{
+			"avatar.png": avatar.String(),
+			"broken.png": "not-an-image",
+			"selfie.png": "not-an-image",
+		}}
+		screener := &AccountScreener{flags: store, avatars: avatars, autoRestrict: true}
+		if err := screener.Run(context.Background()); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+
+		if store.hashes[withAvatar] == nil {
+			t.Fatal("expected the avatar to be hashed")
+		}
+		if hash, ok := store.hashes[withBrokenAvatar]; !ok || hash != nil {
+			t.Fatal("expected an unreadable avatar to be stored without a hash")
+		}
+		if store.lookback != screeningLookback {
+			t.Fatalf("expected a lookback of %v, got %v", screeningLookback, store.lookback)
+		}
+		if len(store.recorded) != 1 || store.recorded[0] != suspect || !store.restrict {
+			t.Fatalf("expected the suspect flagged and restricted, got %v", store.recorded)
+		}
+	})
+
+	t.Run("reports failures", func(t *testing.T) {
+		store := &mockModerationStore{hashes: make(map[uuid.UUID]*uint64), err: errors.New("db unavailable")}
+		screener := &AccountScreener{flags: store, avatars: &mockAvatarStorage{}}
+		if err := screener.Run(context.Background()); err == nil {
+			t.Fatal("expected error")
+		}
+	})
+}
@@ -0,0 +1,42 @@
+package jobs
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/Viet-CodingStars/kyupi-kyupi-backend/internal/repo"
+)
+
+// passExpiryBatchSize bounds how many passes a single run removes.
+const passExpiryBatchSize = 1000
+
+// ExpiredPassStore declares the operations required by PassExpirer.
+type ExpiredPassStore interface {
+	DeleteExpiredPasses(olderThan time.Duration, limit int) (int64, error)
+}
+
+// PassExpirer removes passes older than the configured expiry so those profiles can be shown
+// again in discovery.
+type PassExpirer struct {
+	likes  ExpiredPassStore
+	expiry time.Duration
+}
+
+func NewPassExpirer(db *sql.DB, expiry time.Duration) *PassExpirer {
+	return &PassExpirer{likes: repo.NewLikeRepo(db), expiry: expiry}
+}
+
+// Run removes one batch of expired passes. Anything left over is removed on the next run.
+func (e *PassExpirer) Run(ctx context.Context) error {
+	removed, err := e.likes.DeleteExpiredPasses(e.expiry, passExpiryBatchSize)
+	if err != nil {
+		return fmt.Errorf("delete expired passes: %w", err)
+	}
+	if removed > 0 {
+		log.Printf("expired %d passes", removed)
+	}
+	return nil
+}
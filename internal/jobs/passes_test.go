@@ -0,0 +1,43 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+type mockExpiredPassStore struct {
+	olderThan time.Duration
+	limit     int
+	err       error
+}
+
+func (m *mockExpiredPassStore) DeleteExpiredPasses(olderThan time.Duration, limit int) (int64, error) {
+	m.olderThan, m.limit = olderThan, limit
+	return 3, m.err
+}
+
+func TestPassExpirer(t *testing.T) {
+	t.Run("removes passes older than the expiry", func(t *testing.T) {
+		store := &mockExpiredPassStore{}
+		expirer := &PassExpirer{likes: store, expiry: 30 * 24 * time.Hour}
+		if err := expirer.Run(context.Background()); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if store.olderThan != 30*24*time.Hour {
+			t.Fatalf("expected passes older than 30 days, got %v", store.olderThan)
+		}
+		if store.limit != passExpiryBatchSize {
+			t.Fatalf("expected batch size %d, got %d", passExpiryBatchSize, store.limit)
+		}
+	})
+
+	t.Run("reports failures", func(t *testing.T) {
+		store := &mockExpiredPassStore{err: errors.New("db unavailable")}
+		expirer := &PassExpirer{likes: store, expiry: time.Hour}
+		if err := expirer.Run(context.Background()); err == nil {
+			t.Fatal("expected error")
+		}
+	})
+}
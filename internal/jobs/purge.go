@@ -0,0 +1,99 @@
+package jobs
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/Viet-CodingStars/kyupi-kyupi-backend/internal/repo"
+	"github.com/Viet-CodingStars/kyupi-kyupi-backend/internal/storage"
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// purgeBatchSize bounds how many accounts a single run removes.
+const purgeBatchSize = 100
+
+// AccountStore declares the Postgres operations required by AccountPurger.
+type AccountStore interface {
+	ListDueForPurge(limit int) ([]uuid.UUID, error)
+	Purge(userID uuid.UUID) error
+}
+
+// MessageStore declares the MongoDB operations required by AccountPurger.
+type MessageStore interface {
+	DeleteByUser(ctx context.Context, userID uuid.UUID) (int64, error)
+}
+
+// AccountPurger permanently removes accounts whose deletion grace period has passed.
+type AccountPurger struct {
+	accounts AccountStore
+	messages MessageStore
+	avatars  storage.AvatarStorage
+	selfies  storage.AvatarStorage // photo verification selfies
+	// exportDir holds per-user data export archives; empty skips them.
+	exportDir string
+}
+
+func NewAccountPurger(db *sql.DB, mongoDB *mongo.Database, avatars, selfies storage.AvatarStorage, exportDir string) *AccountPurger {
+	return &AccountPurger{
+		accounts:  repo.NewAccountRepo(db),
+		messages:  repo.NewMessageRepo(mongoDB),
+		avatars:   avatars,
+		selfies:   selfies,
+		exportDir: exportDir,
+	}
+}
+
+// Run purges one batch of due accounts. A failure for one account does not stop the others;
+// the failed account is retried on the next run.
+func (p *AccountPurger) Run(ctx context.Context) error {
+	ids, err := p.accounts.ListDueForPurge(purgeBatchSize)
+	if err != nil {
+		return fmt.Errorf("list accounts due for purge: %w", err)
+	}
+
+	var errs []error
+	for _, id := range ids {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err := p.purge(ctx, id); err != nil {
+			errs = append(errs, fmt.Errorf("purge %s: %w", id, err))
+			continue
+		}
+		log.Printf("purged account %s", id)
+	}
+	return errors.Join(errs...)
+}
+
+// purge removes the user's data from every store. The Postgres row goes last so that a
+// partial failure leaves the account due and the whole purge is retried.
+func (p *AccountPurger) purge(ctx context.Context, userID uuid.UUID) error {
+	if _, err := p.messages.DeleteByUser(ctx, userID); err != nil {
+		return fmt.Errorf("delete messages: %w", err)
+	}
+	if p.avatars != nil {
+		if err := p.avatars.DeleteAll(userID); err != nil {
+			return fmt.Errorf("delete avatars: %w", err)
+		}
+	}
+	if p.selfies != nil {
+		if err := p.selfies.DeleteAll(userID); err != nil {
+			return fmt.Errorf("delete verification selfies: %w", err)
+		}
+	}
+	if p.exportDir != "" {
+		if err := os.RemoveAll(filepath.Join(p.exportDir, userID.String())); err != nil {
+			return fmt.Errorf("delete data exports: %w", err)
+		}
+	}
+	if err := p.accounts.Purge(userID); err != nil && err != repo.ErrUserNotFound {
+		return fmt.Errorf("delete user: %w", err)
+	}
+	return nil
+}
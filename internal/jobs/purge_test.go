@@ -0,0 +1,102 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+type mockAccountStore struct {
+	due    []uuid.UUID
+	purged []uuid.UUID
+}
+
+func (m *mockAccountStore) ListDueForPurge(limit int) ([]uuid.UUID, error) {
+	return m.due, nil
+}
+
+func (m *mockAccountStore) Purge(userID uuid.UUID) error {
+	m.purged = append(m.purged, userID)
+	return nil
+}
+
+type mockMessageStore struct {
+	deleted []uuid.UUID
+	failFor uuid.UUID
+}
+
+func (m *mockMessageStore) DeleteByUser(ctx context.Context, userID uuid.UUID) (int64, error) {
+	if userID == m.failFor {
+		return 0, errors.New("mongo unavailable")
+	}
+	m.deleted = append(m.deleted, userID)
+	return 1, nil
+}
+
+type mockAvatarStorage struct {
+	deleted []uuid.UUID
+	files   map[string]string
+}
+
+func (m *mockAvatarStorage) Save(userID uuid.UUID, data io.Reader, originalFilename string) (string, error) {
+	return "", nil
+}
+
+func (m *mockAvatarStorage) DeleteAll(userID uuid.UUID) error {
+	m.deleted = append(m.deleted, userID)
+	return nil
+}
+
+func (m *mockAvatarStorage) ForEach(userID uuid.UUID, fn func(filename string, data io.Reader) error) error {
+	for name, content := range m.files {
+		if err := fn(name, strings.NewReader(content)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func TestAccountPurger(t *testing.T) {
+	t.Run("removes data from every store", func(t *testing.T) {
+		userID := uuid.New()
+		accounts := &mockAccountStore{due: []uuid.UUID{userID}}
+		messages := &mockMessageStore{}
+		avatars := &mockAvatarStorage{}
+		selfies := &mockAvatarStorage{}
+		exportDir := t.TempDir()
+		userExports := filepath.Join(exportDir, userID.String())
+		if err := os.MkdirAll(userExports, 0o700); err != nil {
+			t.Fatal(err)
+		}
+		purger := &AccountPurger{accounts: accounts, messages: messages, avatars: avatars, selfies: selfies, exportDir: exportDir}
+
+		if err := purger.Run(context.Background()); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if len(messages.deleted) != 1 || len(avatars.deleted) != 1 || len(selfies.deleted) != 1 || len(accounts.purged) != 1 {
+			t.Fatalf("expected one purge per store, got messages=%v avatars=%v selfies=%v accounts=%v", messages.deleted, avatars.deleted, selfies.deleted, accounts.purged)
+		}
+		if _, err := os.Stat(userExports); !os.IsNotExist(err) {
+			t.Fatalf("expected data exports to be removed, got %v", err)
+		}
+	})
+
+	t.Run("failure keeps the user row for retry", func(t *testing.T) {
+		failing, ok := uuid.New(), uuid.New()
+		accounts := &mockAccountStore{due: []uuid.UUID{failing, ok}}
+		purger := &AccountPurger{accounts: accounts, messages: &mockMessageStore{failFor: failing}, avatars: &mockAvatarStorage{}}
+
+		if err := purger.Run(context.Background()); err == nil {
+			t.Fatal("expected error for failing account")
+		}
+		if len(accounts.purged) != 1 || accounts.purged[0] != ok {
+			t.Fatalf("expected only the healthy account to be purged, got %v", accounts.purged)
+		}
+	})
+}
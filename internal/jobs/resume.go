@@ -0,0 +1,87 @@
+package jobs
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/Viet-CodingStars/kyupi-kyupi-backend/internal/models"
+	"github.com/Viet-CodingStars/kyupi-kyupi-backend/internal/repo"
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// resumeBatchSize bounds how many accounts a single run resumes.
+const resumeBatchSize = 100
+
+// PausedUserStore declares the operations required by PauseResumer.
+type PausedUserStore interface {
+	ListDueForResume(limit int) ([]uuid.UUID, error)
+	Resume(id uuid.UUID) ([]*models.Match, error)
+}
+
+// LikeCommentStore lists the comments sent with a pair's likes.
+type LikeCommentStore interface {
+	ListComments(userID, otherUserID uuid.UUID) ([]*models.Like, error)
+}
+
+// MatchMessageSeeder opens a new match's chat with like comments.
+type MatchMessageSeeder interface {
+	SeedFromLikes(ctx context.Context, matchID uuid.UUID, likes []*models.Like) error
+}
+
+// PauseResumer ends pauses whose scheduled resume time has passed.
+type PauseResumer struct {
+	users    PausedUserStore
+	likes    LikeCommentStore
+	messages MatchMessageSeeder
+}
+
+func NewPauseResumer(db *sql.DB, mongoDB *mongo.Database) *PauseResumer {
+	return &PauseResumer{
+		users:    repo.NewUserRepo(db),
+		likes:    repo.NewLikeRepo(db),
+		messages: repo.NewMessageRepo(mongoDB),
+	}
+}
+
+// Run resumes one batch of due accounts. An account the user resumed in the meantime is skipped.
+func (r *PauseResumer) Run(ctx context.Context) error {
+	ids, err := r.users.ListDueForResume(resumeBatchSize)
+	if err != nil {
+		return fmt.Errorf("list accounts due for resume: %w", err)
+	}
+
+	var errs []error
+	for _, id := range ids {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		matches, err := r.users.Resume(id)
+		if err != nil {
+			if err == repo.ErrNotPaused {
+				continue
+			}
+			errs = append(errs, fmt.Errorf("resume %s: %w", id, err))
+			continue
+		}
+		for _, match := range matches {
+			if err := r.seedMessages(ctx, match); err != nil {
+				errs = append(errs, fmt.Errorf("seed chat for match %s: %w", match.ID, err))
+			}
+		}
+		log.Printf("resumed paused account %s (%d new matches)", id, len(matches))
+	}
+	return errors.Join(errs...)
+}
+
+// seedMessages copies the comments sent with the pair's likes into a new match's chat.
+func (r *PauseResumer) seedMessages(ctx context.Context, match *models.Match) error {
+	comments, err := r.likes.ListComments(match.User1ID, match.User2ID)
+	if err != nil {
+		return err
+	}
+	return r.messages.SeedFromLikes(ctx, match.ID, comments)
+}
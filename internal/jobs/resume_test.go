@@ -0,0 +1,108 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/Viet-CodingStars/kyupi-kyupi-backend/internal/models"
+	"github.com/Viet-CodingStars/kyupi-kyupi-backend/internal/repo"
+	"github.com/google/uuid"
+)
+
+type mockPausedUserStore struct {
+	due     []uuid.UUID
+	resumed []uuid.UUID
+	errFor  map[uuid.UUID]error
+	matches map[uuid.UUID][]*models.Match
+}
+
+func (m *mockPausedUserStore) ListDueForResume(limit int) ([]uuid.UUID, error) {
+	return m.due, nil
+}
+
+func (m *mockPausedUserStore) Resume(id uuid.UUID) ([]*models.Match, error) {
+	if err := m.errFor[id]; err != nil {
+		return nil, err
+	}
+	m.resumed = append(m.resumed, id)
+	return m.matches[id], nil
+}
+
+type mockLikeComments struct {
+	comments []*models.Like
+}
+
+func (m *mockLikeComments) ListComments(userID, otherUserID uuid.UUID) ([]*models.Like, error) {
+	var pair []*models.Like
+	for _, like := range m.comments {
+		if (like.UserID == userID && like.TargetUserID == otherUserID) ||
+			(like.UserID == otherUserID && like.TargetUserID == userID) {
+			pair = append(pair, like)
+		}
+	}
+	return pair, nil
+}
+
+type mockMessageSeeder struct {
+	seeded map[uuid.UUID][]*models.Like
+}
+
+func (m *mockMessageSeeder) SeedFromLikes(ctx context.Context, matchID uuid.UUID, likes []*models.Like) error {
+	m.seeded[matchID] = likes
+	return nil
+}
+
+func TestPauseResumer(t *testing.T) {
+	t.Run("resumes due accounts", func(t *testing.T) {
+		store := &mockPausedUserStore{due: []uuid.UUID{uuid.New(), uuid.New()}}
+		if err := (&PauseResumer{users: store}).Run(context.Background()); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if len(store.resumed) != 2 {
+			t.Fatalf("expected 2 resumed accounts, got %v", store.resumed)
+		}
+	})
+
+	t.Run("skips accounts already resumed and reports failures", func(t *testing.T) {
+		alreadyActive, failing, ok := uuid.New(), uuid.New(), uuid.New()
+		store := &mockPausedUserStore{
+			due: []uuid.UUID{alreadyActive, failing, ok},
+			errFor: map[uuid.UUID]error{
+				alreadyActive: repo.ErrNotPaused,
+				failing:       errors.New("db unavailable"),
+			},
+		}
+		err := (&PauseResumer{users: store}).Run(context.Background())
+		if err == nil {
+			t.Fatal("expected error for failing account")
+		}
+		if len(store.resumed) != 1 || store.resumed[0] != ok {
+			t.Fatalf("expected only the healthy account to be resumed, got %v", store.resumed)
+		}
+	})
+
+	t.Run("seeds the chat of new matches with like comments", func(t *testing.T) {
+		paused, liker := uuid.New(), uuid.New()
+		match := &models.Match{ID: uuid.New(), User1ID: paused, User2ID: liker}
+		store := &mockPausedUserStore{
+			due:     []uuid.UUID{paused},
+			matches: map[uuid.UUID][]*models.Match{paused: {match}},
+		}
+		comment := &models.Like{UserID: liker, TargetUserID: paused, Status: models.LikeStatusLike, Comment: "Great hiking photo!"}
+		likes := &mockLikeComments{comments: []*models.Like{
+			comment,
+			{UserID: liker, TargetUserID: uuid.New(), Status: models.LikeStatusLike, Comment: "Someone else"},
+		}}
+		messages := &mockMessageSeeder{seeded: make(map[uuid.UUID][]*models.Like)}
+
+		resumer := &PauseResumer{users: store, likes: likes, messages: messages}
+		if err := resumer.Run(context.Background()); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		seeded := messages.seeded[match.ID]
+		if len(seeded) != 1 || seeded[0] != comment {
+			t.Fatalf("expected the pair's comment to be seeded, got %v", seeded)
+		}
+	})
+}
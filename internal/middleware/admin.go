@@ -0,0 +1,39 @@
+package middleware
+
+import (
+  "net/http"
+
+  "github.com/Viet-CodingStars/kyupi-kyupi-backend/internal/models"
+  "github.com/gin-gonic/gin"
+  "github.com/google/uuid"
+)
+
+// RoleStore declares the role lookup required by RequireAdmin.
+type RoleStore interface {
+  GetRole(userID uuid.UUID) (string, error)
+}
+
+// RequireAdmin only lets administrators through. It must run after AuthMiddleware. The role is
+// read on every request rather than from the token, so promoting or demoting a user takes
+// effect immediately.
+func RequireAdmin(roles RoleStore) gin.HandlerFunc {
+  return func(c *gin.Context) {
+    userID, ok := GetUserID(c.Request.Context())
+    if !ok {
+      c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "user not authenticated"})
+      return
+    }
+
+    role, err := roles.GetRole(userID)
+    if err != nil {
+      c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "failed to verify role"})
+      return
+    }
+    if role != models.RoleAdmin {
+      c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "admin access required"})
+      return
+    }
+
+    c.Next()
+  }
+}
@@ -0,0 +1,65 @@
+package middleware
+
+import (
+  "context"
+  "net/http"
+  "net/http/httptest"
+  "testing"
+
+  "github.com/Viet-CodingStars/kyupi-kyupi-backend/internal/models"
+  "github.com/gin-gonic/gin"
+  "github.com/google/uuid"
+)
+
+type mockRoleStore struct {
+  roles map[uuid.UUID]string
+}
+
+func (m *mockRoleStore) GetRole(userID uuid.UUID) (string, error) {
+  return m.roles[userID], nil
+}
+
+func TestRequireAdmin(t *testing.T) {
+  gin.SetMode(gin.TestMode)
+  adminID := uuid.New()
+  memberID := uuid.New()
+  roles := &mockRoleStore{roles: map[uuid.UUID]string{adminID: models.RoleAdmin, memberID: models.RoleMember}}
+
+  request := func(userID *uuid.UUID) (int, bool) {
+    handlerCalled := false
+    router := gin.New()
+    router.Use(func(c *gin.Context) {
+      if userID != nil {
+        c.Request = c.Request.WithContext(context.WithValue(c.Request.Context(), UserIDKey, *userID))
+      }
+      c.Next()
+    })
+    router.Use(RequireAdmin(roles))
+    router.GET("/test", func(c *gin.Context) {
+      handlerCalled = true
+      c.Status(http.StatusOK)
+    })
+
+    rr := httptest.NewRecorder()
+    router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/test", nil))
+    return rr.Code, handlerCalled
+  }
+
+  t.Run("admin", func(t *testing.T) {
+    if code, called := request(&adminID); code != http.StatusOK || !called {
+      t.Fatalf("expected the handler to run, got %d", code)
+    }
+  })
+
+  t.Run("member", func(t *testing.T) {
+    if code, called := request(&memberID); code != http.StatusForbidden || called {
+      t.Fatalf("expected status 403 without running the handler, got %d", code)
+    }
+  })
+
+  t.Run("unauthenticated", func(t *testing.T) {
+    if code, called := request(nil); code != http.StatusUnauthorized || called {
+      t.Fatalf("expected status 401 without running the handler, got %d", code)
+    }
+  })
+}
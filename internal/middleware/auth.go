@@ -6,16 +6,27 @@ import (
   "strings"
 
   "github.com/Viet-CodingStars/kyupi-kyupi-backend/internal/auth"
+  "github.com/Viet-CodingStars/kyupi-kyupi-backend/internal/repo"
   "github.com/gin-gonic/gin"
   "github.com/google/uuid"
 )
 
 type contextKey string
 
-const UserIDKey contextKey = "user_id"
+const (
+  UserIDKey    contextKey = "user_id"
+  SessionIDKey contextKey = "session_id"
+)
+
+// SessionStore declares the session lookup required by AuthMiddleware.
+type SessionStore interface {
+  // Touch returns repo.ErrSessionNotFound when the session is revoked, expired or unknown.
+  Touch(sessionID, userID uuid.UUID) error
+}
 
-// AuthMiddleware validates JWT tokens and sets user context for Gin handlers.
-func AuthMiddleware(jwtSecret string) gin.HandlerFunc {
+// AuthMiddleware validates JWT tokens against the key set, checks that the token's session
+// has not been revoked and sets user context for Gin handlers.
+func AuthMiddleware(keys *auth.KeySet, sessions SessionStore) gin.HandlerFunc {
   return func(c *gin.Context) {
     authHeader := c.GetHeader("Authorization")
     if authHeader == "" {
@@ -29,14 +40,25 @@ func AuthMiddleware(jwtSecret string) gin.HandlerFunc {
       return
     }
 
-    claims, err := auth.ValidateToken(parts[1], jwtSecret)
-    if err != nil {
+    claims, err := keys.ValidateToken(parts[1])
+    if err != nil || claims.SessionID == uuid.Nil {
       c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid or expired token"})
       return
     }
 
+    if err := sessions.Touch(claims.SessionID, claims.UserID); err != nil {
+      if err == repo.ErrSessionNotFound {
+        c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "session has been revoked"})
+        return
+      }
+      c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "failed to verify session"})
+      return
+    }
+
     ctx := context.WithValue(c.Request.Context(), UserIDKey, claims.UserID)
+    ctx = context.WithValue(ctx, SessionIDKey, claims.SessionID)
     c.Set(string(UserIDKey), claims.UserID)
+    c.Set(string(SessionIDKey), claims.SessionID)
     c.Request = c.Request.WithContext(ctx)
     c.Next()
   }
@@ -47,3 +69,9 @@ func GetUserID(ctx context.Context) (uuid.UUID, bool) {
   userID, ok := ctx.Value(UserIDKey).(uuid.UUID)
   return userID, ok
 }
+
+// GetSessionID retrieves the current session ID from the request context
+func GetSessionID(ctx context.Context) (uuid.UUID, bool) {
+  sessionID, ok := ctx.Value(SessionIDKey).(uuid.UUID)
+  return sessionID, ok
+}
@@ -4,19 +4,37 @@ import (
   "net/http"
   "net/http/httptest"
   "testing"
+  "time"
 
   "github.com/Viet-CodingStars/kyupi-kyupi-backend/internal/auth"
+  "github.com/Viet-CodingStars/kyupi-kyupi-backend/internal/repo"
   "github.com/gin-gonic/gin"
   "github.com/google/uuid"
 )
 
+// mockSessionStore treats every session in active as valid
+type mockSessionStore struct {
+  active map[uuid.UUID]uuid.UUID // session ID -> user ID
+}
+
+func (m *mockSessionStore) Touch(sessionID, userID uuid.UUID) error {
+  if owner, ok := m.active[sessionID]; !ok || owner != userID {
+    return repo.ErrSessionNotFound
+  }
+  return nil
+}
+
 func TestAuthMiddleware(t *testing.T) {
   gin.SetMode(gin.TestMode)
   secret := "test-secret"
   userID := uuid.New()
+  sessionID := uuid.New()
   email := "test@example.com"
 
-  token, err := auth.GenerateToken(userID, email, secret)
+  keys := auth.NewHMACKeySet(secret)
+  sessions := &mockSessionStore{active: map[uuid.UUID]uuid.UUID{sessionID: userID}}
+
+  token, err := keys.GenerateToken(userID, email, sessionID, time.Now().Add(auth.TokenTTL))
   if err != nil {
     t.Fatalf("failed to generate token: %v", err)
   }
@@ -24,7 +42,7 @@ func TestAuthMiddleware(t *testing.T) {
   t.Run("valid token", func(t *testing.T) {
     handlerCalled := false
     router := gin.New()
-    router.Use(AuthMiddleware(secret))
+    router.Use(AuthMiddleware(keys, sessions))
     router.GET("/test", func(c *gin.Context) {
       handlerCalled = true
       id, ok := GetUserID(c.Request.Context())
@@ -41,6 +59,9 @@ func TestAuthMiddleware(t *testing.T) {
       if idFromGin, ok := ginUserID.(uuid.UUID); !ok || idFromGin != userID {
         t.Fatalf("expected user ID %v in gin context, got %v", userID, ginUserID)
       }
+      if sid, ok := GetSessionID(c.Request.Context()); !ok || sid != sessionID {
+        t.Fatalf("expected session ID %v, got %v", sessionID, sid)
+      }
       c.Status(http.StatusOK)
     })
 
@@ -60,7 +81,7 @@ func TestAuthMiddleware(t *testing.T) {
   t.Run("missing authorization header", func(t *testing.T) {
     handlerCalled := false
     router := gin.New()
-    router.Use(AuthMiddleware(secret))
+    router.Use(AuthMiddleware(keys, sessions))
     router.GET("/test", func(c *gin.Context) {
       handlerCalled = true
       c.Status(http.StatusOK)
@@ -81,7 +102,7 @@ func TestAuthMiddleware(t *testing.T) {
   t.Run("invalid authorization header format", func(t *testing.T) {
     handlerCalled := false
     router := gin.New()
-    router.Use(AuthMiddleware(secret))
+    router.Use(AuthMiddleware(keys, sessions))
     router.GET("/test", func(c *gin.Context) {
       handlerCalled = true
       c.Status(http.StatusOK)
@@ -103,7 +124,7 @@ func TestAuthMiddleware(t *testing.T) {
   t.Run("invalid token", func(t *testing.T) {
     handlerCalled := false
     router := gin.New()
-    router.Use(AuthMiddleware(secret))
+    router.Use(AuthMiddleware(keys, sessions))
     router.GET("/test", func(c *gin.Context) {
       handlerCalled = true
       c.Status(http.StatusOK)
@@ -121,4 +142,40 @@ func TestAuthMiddleware(t *testing.T) {
       t.Fatal("expected handler not to execute")
     }
   })
+
+  t.Run("revoked session", func(t *testing.T) {
+    revokedToken, _ := keys.GenerateToken(userID, email, uuid.New(), time.Now().Add(auth.TokenTTL))
+    router := gin.New()
+    router.Use(AuthMiddleware(keys, sessions))
+    router.GET("/test", func(c *gin.Context) {
+      t.Fatal("expected handler not to execute")
+    })
+
+    req := httptest.NewRequest(http.MethodGet, "/test", nil)
+    req.Header.Set("Authorization", "Bearer "+revokedToken)
+    rr := httptest.NewRecorder()
+    router.ServeHTTP(rr, req)
+
+    if rr.Code != http.StatusUnauthorized {
+      t.Fatalf("expected status 401, got %d", rr.Code)
+    }
+  })
+
+  t.Run("token without session", func(t *testing.T) {
+    legacyToken, _ := auth.GenerateToken(userID, email, secret)
+    router := gin.New()
+    router.Use(AuthMiddleware(keys, sessions))
+    router.GET("/test", func(c *gin.Context) {
+      t.Fatal("expected handler not to execute")
+    })
+
+    req := httptest.NewRequest(http.MethodGet, "/test", nil)
+    req.Header.Set("Authorization", "Bearer "+legacyToken)
+    rr := httptest.NewRecorder()
+    router.ServeHTTP(rr, req)
+
+    if rr.Code != http.StatusUnauthorized {
+      t.Fatalf("expected status 401, got %d", rr.Code)
+    }
+  })
 }
@@ -0,0 +1,106 @@
+package models
+
+const (
+	// MinHeightCm and MaxHeightCm bound the height a user may enter.
+	MinHeightCm = 100
+	MaxHeightCm = 250
+	// MaxJobTitleLength is the longest job title a user may enter.
+	MaxJobTitleLength = 100
+	// MaxLanguages is how many languages a user may list.
+	MaxLanguages = 10
+)
+
+const (
+	EducationHighSchool    = "high_school"
+	EducationVocational    = "vocational"
+	EducationUndergraduate = "undergraduate"
+	EducationPostgraduate  = "postgraduate"
+	EducationDoctorate     = "doctorate"
+)
+
+const (
+	ReligionAgnostic  = "agnostic"
+	ReligionAtheist   = "atheist"
+	ReligionBuddhist  = "buddhist"
+	ReligionCatholic  = "catholic"
+	ReligionChristian = "christian"
+	ReligionHindu     = "hindu"
+	ReligionJewish    = "jewish"
+	ReligionMuslim    = "muslim"
+	ReligionSpiritual = "spiritual"
+	ReligionOther     = "other"
+)
+
+// Habit values describe how often a user drinks or smokes.
+const (
+	HabitNever     = "never"
+	HabitSometimes = "sometimes"
+	HabitOften     = "often"
+)
+
+const (
+	ChildrenWant                = "want"
+	ChildrenDontWant            = "dont_want"
+	ChildrenHaveAndWantMore     = "have_and_want_more"
+	ChildrenHaveAndDontWantMore = "have_and_dont_want_more"
+	ChildrenNotSure             = "not_sure"
+)
+
+// languages lists the supported ISO 639-1 language codes.
+var languages = map[string]bool{
+	"ar": true, "bn": true, "de": true, "el": true, "en": true, "es": true, "fa": true,
+	"fr": true, "he": true, "hi": true, "id": true, "it": true, "ja": true, "km": true, "ko": true,
+	"lo": true, "ms": true, "my": true, "nl": true, "pl": true, "pt": true, "ru": true, "sv": true,
+	"ta": true, "th": true, "tl": true, "tr": true, "uk": true, "ur": true, "vi": true, "zh": true,
+}
+
+// IsValidHeight returns true when the height in centimetres is within the supported range.
+func IsValidHeight(cm int) bool {
+	return cm >= MinHeightCm && cm <= MaxHeightCm
+}
+
+// IsValidEducation returns true when the provided education level matches a supported enum value.
+func IsValidEducation(val string) bool {
+	switch val {
+	case EducationHighSchool, EducationVocational, EducationUndergraduate, EducationPostgraduate, EducationDoctorate:
+		return true
+	default:
+		return false
+	}
+}
+
+// IsValidReligion returns true when the provided religion matches a supported enum value.
+func IsValidReligion(val string) bool {
+	switch val {
+	case ReligionAgnostic, ReligionAtheist, ReligionBuddhist, ReligionCatholic, ReligionChristian,
+		ReligionHindu, ReligionJewish, ReligionMuslim, ReligionSpiritual, ReligionOther:
+		return true
+	default:
+		return false
+	}
+}
+
+// IsValidHabit returns true when the provided drinking or smoking habit matches a supported enum value.
+func IsValidHabit(val string) bool {
+	switch val {
+	case HabitNever, HabitSometimes, HabitOften:
+		return true
+	default:
+		return false
+	}
+}
+
+// IsValidChildren returns true when the provided family plan matches a supported enum value.
+func IsValidChildren(val string) bool {
+	switch val {
+	case ChildrenWant, ChildrenDontWant, ChildrenHaveAndWantMore, ChildrenHaveAndDontWantMore, ChildrenNotSure:
+		return true
+	default:
+		return false
+	}
+}
+
+// IsValidLanguage returns true when the provided value is a supported ISO 639-1 language code such as "vi".
+func IsValidLanguage(val string) bool {
+	return languages[val]
+}
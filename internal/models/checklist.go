@@ -0,0 +1,72 @@
+package models
+
+// Onboarding steps, in the order a new user is asked to complete them.
+const (
+	StepAvatar    = "avatar"
+	StepBio       = "bio"
+	StepLocation  = "location"
+	StepInterests = "interests"
+	StepPrompts   = "prompts"
+)
+
+// ProfileChecklist records which onboarding steps a user has completed. A freshly signed-up
+// user has completed none. Gender preference and intention are not steps: no preference means
+// open to everyone and still_figuring_out is a valid intention, so neither can be missing.
+type ProfileChecklist struct {
+	Avatar    bool
+	Bio       bool
+	Location  bool
+	Interests bool // at least one active interest
+	Prompts   bool // at least one prompt answered
+}
+
+// NewProfileChecklist builds the checklist for a user, given whether they picked interests and
+// answered prompts.
+func NewProfileChecklist(u *User, hasInterests, hasPrompts bool) ProfileChecklist {
+	return ProfileChecklist{
+		Avatar:    u.AvatarURL != "",
+		Bio:       u.Bio != "",
+		Location:  u.Latitude != nil && u.Longitude != nil,
+		Interests: hasInterests,
+		Prompts:   hasPrompts,
+	}
+}
+
+func (c ProfileChecklist) steps() []struct {
+	name string
+	done bool
+} {
+	return []struct {
+		name string
+		done bool
+	}{
+		{StepAvatar, c.Avatar},
+		{StepBio, c.Bio},
+		{StepLocation, c.Location},
+		{StepInterests, c.Interests},
+		{StepPrompts, c.Prompts},
+	}
+}
+
+// Missing returns the steps still to complete, in onboarding order.
+func (c ProfileChecklist) Missing() []string {
+	missing := make([]string, 0)
+	for _, step := range c.steps() {
+		if !step.done {
+			missing = append(missing, step.name)
+		}
+	}
+	return missing
+}
+
+// Completeness is the share of steps completed, in [0, 1].
+func (c ProfileChecklist) Completeness() float64 {
+	steps := c.steps()
+	done := 0
+	for _, step := range steps {
+		if step.done {
+			done++
+		}
+	}
+	return float64(done) / float64(len(steps))
+}
@@ -0,0 +1,70 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+)
+
+// DefaultLanguage is the language every catalog entry, such as an interest or prompt, must
+// have a label in. It is shown when there is no label in the requested language.
+const DefaultLanguage = "en"
+
+// MaxInterestLabelLength is the longest label an interest may have in any language.
+const MaxInterestLabelLength = 50
+
+var interestSlugPattern = regexp.MustCompile(`^[a-z0-9]+(_[a-z0-9]+)*$`)
+
+// Interest is an entry in the curated interests catalog. Users pick interests by slug.
+type Interest struct {
+	Slug     string            `json:"slug"`
+	Category string            `json:"category"`
+	Labels   map[string]string `json:"labels"` // ISO 639-1 language code -> label
+	// Active is false for retired interests, which can no longer be picked and are hidden from profiles
+	Active    bool      `json:"active"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// ProfileInterest is an interest on someone's profile, as seen by another user.
+type ProfileInterest struct {
+	Interest
+	// Shared is true when the viewer picked the interest too
+	Shared bool
+}
+
+// IsValidInterestSlug returns true for lowercase snake_case slugs such as "street_food".
+func IsValidInterestSlug(val string) bool {
+	return len(val) <= 50 && interestSlugPattern.MatchString(val)
+}
+
+// Label returns the interest's label in the given language, falling back to the default language.
+func (i *Interest) Label(lang string) string {
+	if label, ok := i.Labels[lang]; ok {
+		return label
+	}
+	return i.Labels[DefaultLanguage]
+}
+
+// Validate checks the slug, category and labels. A label in DefaultLanguage is required.
+func (i *Interest) Validate() error {
+	if !IsValidInterestSlug(i.Slug) {
+		return errors.New("slug must be lowercase letters, digits and underscores")
+	}
+	if !IsValidInterestSlug(i.Category) {
+		return errors.New("category must be lowercase letters, digits and underscores")
+	}
+	if i.Labels[DefaultLanguage] == "" {
+		return fmt.Errorf("a label in %q is required", DefaultLanguage)
+	}
+	for lang, label := range i.Labels {
+		if !IsValidLanguage(lang) {
+			return fmt.Errorf("invalid label language %q", lang)
+		}
+		if label == "" || len([]rune(label)) > MaxInterestLabelLength {
+			return fmt.Errorf("labels must be between 1 and %d characters", MaxInterestLabelLength)
+		}
+	}
+	return nil
+}
@@ -6,12 +6,56 @@ import (
 	"github.com/google/uuid"
 )
 
+const (
+	LikeStatusLike      = "like"
+	LikeStatusSuperLike = "super_like"
+	LikeStatusPass      = "pass"
+)
+
+const (
+	// LikeTargetPhoto anchors a like to one of the target's photos; the ref is the photo URL.
+	LikeTargetPhoto = "photo"
+	// LikeTargetPrompt anchors a like to one of the target's prompts; the ref is the prompt ID.
+	LikeTargetPrompt = "prompt"
+)
+
+// MaxLikeCommentLength is the longest comment, in characters, that may accompany a like.
+const MaxLikeCommentLength = 200
+
+// IsValidLikeTargetType returns true when the provided type matches a supported enum value.
+func IsValidLikeTargetType(val string) bool {
+	return val == LikeTargetPhoto || val == LikeTargetPrompt
+}
+
+// IsPositiveLikeStatus reports whether the status expresses interest and can form a match.
+func IsPositiveLikeStatus(status string) bool {
+	return status == LikeStatusLike || status == LikeStatusSuperLike
+}
+
 // Like represents a user liking or passing another user
 type Like struct {
 	ID           uuid.UUID `json:"id"`
 	UserID       uuid.UUID `json:"user_id"`
 	TargetUserID uuid.UUID `json:"target_user_id"`
-	Status       string    `json:"status"` // "like" or "pass"
+	Status       string    `json:"status"` // "like", "super_like" or "pass"
 	CreatedAt    time.Time `json:"created_at"`
 	UpdatedAt    time.Time `json:"updated_at"`
+	// Comment is an optional message sent with a like, optionally about a single profile item
+	Comment    string `json:"comment,omitempty"`
+	TargetType string `json:"target_type,omitempty"` // "photo" or "prompt"
+	TargetRef  string `json:"target_ref,omitempty"`
+	// QueuedAt is set on likes sent while the target had paused their account. Queued likes
+	// cannot form a match until the target resumes.
+	QueuedAt *time.Time `json:"-"`
+}
+
+// ReceivedLike is a like the user has not yet answered, with the liker's public profile
+type ReceivedLike struct {
+	LikeID     uuid.UUID `json:"-"`
+	User       *User     `json:"-"`
+	SuperLike  bool      `json:"super_like"`
+	Comment    string    `json:"comment,omitempty"`
+	TargetType string    `json:"target_type,omitempty"`
+	TargetRef  string    `json:"target_ref,omitempty"`
+	LikedAt    time.Time `json:"liked_at"`
 }
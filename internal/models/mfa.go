@@ -0,0 +1,20 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// UserMFA holds a user's TOTP second factor. A secret exists from enrolment onwards but
+// only counts once the user has confirmed it with a valid code.
+type UserMFA struct {
+	UserID       uuid.UUID
+	TOTPSecret   string
+	TOTPEnabled  bool
+	TOTPLastStep int64 // last accepted time step, so a code cannot be replayed
+	Locked       bool  // too many failed attempts; second-factor sign-in is refused for a while
+	EnabledAt    *time.Time
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+}
@@ -0,0 +1,45 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Reasons an account is flagged as a suspected duplicate or fake.
+const (
+	// FlagDuplicateAvatar is an avatar that looks the same as another account's.
+	FlagDuplicateAvatar = "duplicate_avatar"
+	// FlagEmailAlias is an email address that is an alias of another account's, such as
+	// j.doe+2@gmail.com for jdoe@gmail.com.
+	FlagEmailAlias = "email_alias"
+	// FlagSignupBurst is one of many accounts created from the same IP address in a short time.
+	FlagSignupBurst = "signup_burst"
+	// FlagDuplicateBio is a bio copied word for word from another account.
+	FlagDuplicateBio = "duplicate_bio"
+	// FlagRapidLiking is a user liking every profile they are shown, seconds apart.
+	FlagRapidLiking = "rapid_liking"
+)
+
+const (
+	FlagOpen      = "open"
+	FlagDismissed = "dismissed"
+	FlagConfirmed = "confirmed"
+)
+
+// ModerationFlag is a suspicion raised about an account, waiting in the moderation queue until
+// an admin dismisses or confirms it.
+type ModerationFlag struct {
+	ID     uuid.UUID `json:"id"`
+	UserID uuid.UUID `json:"user_id"`
+	Reason string    `json:"reason"`
+	// RelatedUserID is the older account this one duplicates, if any
+	RelatedUserID *uuid.UUID `json:"related_user_id,omitempty"`
+	Details       string     `json:"details,omitempty"`
+	Status        string     `json:"status"`
+	CreatedAt     time.Time  `json:"created_at"`
+	ResolvedBy    *uuid.UUID `json:"-"`
+	ResolvedAt    *time.Time `json:"resolved_at,omitempty"`
+	// Restricted is whether the account is currently shadow-restricted
+	Restricted bool `json:"restricted"`
+}
@@ -0,0 +1,8 @@
+package models
+
+// DailyPick is one of the candidates picked for a user for the day, with their public profile
+type DailyPick struct {
+	User     *User  `json:"-"`
+	Position int    `json:"position"`
+	Status   string `json:"status,omitempty"` // the user's like or pass on the pick, if any
+}
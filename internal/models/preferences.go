@@ -0,0 +1,148 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Filter names, as used in Preferences.Dealbreakers.
+const (
+	FilterAge       = "age"
+	FilterDistance  = "distance"
+	FilterHeight    = "height"
+	FilterEducation = "education"
+	FilterLanguages = "languages"
+	FilterReligion  = "religion"
+	FilterDrinking  = "drinking"
+	FilterSmoking   = "smoking"
+	FilterChildren  = "children"
+)
+
+const (
+	// MinPreferredAge and MaxPreferredAge bound the age range a user may ask for.
+	MinPreferredAge = 18
+	MaxPreferredAge = 100
+	// MaxPreferredDistanceKm is the largest distance filter a user may set.
+	MaxPreferredDistanceKm = 1000
+)
+
+// Preferences describe who a user wants to be shown. Every filter is optional. Filters named in
+// Dealbreakers hide everyone who does not meet them; the others only rank people who meet them
+// higher. People who have not filled in an attribute are never hidden by a filter on it.
+// VerifiedOnly always hides people whose photos are not verified.
+type Preferences struct {
+	AgeMin        *int       `json:"age_min,omitempty"`
+	AgeMax        *int       `json:"age_max,omitempty"`
+	MaxDistanceKm *int       `json:"max_distance_km,omitempty"`
+	HeightMinCm   *int       `json:"height_min_cm,omitempty"`
+	HeightMaxCm   *int       `json:"height_max_cm,omitempty"`
+	Educations    []string   `json:"educations"`
+	Languages     []string   `json:"languages"` // at least one in common
+	Religions     []string   `json:"religions"`
+	Drinking      []string   `json:"drinking"`
+	Smoking       []string   `json:"smoking"`
+	Children      []string   `json:"children"`
+	Dealbreakers  []string   `json:"dealbreakers"`
+	VerifiedOnly  bool       `json:"verified_only"`
+	UpdatedAt     *time.Time `json:"updated_at,omitempty"`
+}
+
+// IsValidFilter returns true when the provided filter name matches a supported enum value.
+func IsValidFilter(val string) bool {
+	switch val {
+	case FilterAge, FilterDistance, FilterHeight, FilterEducation, FilterLanguages,
+		FilterReligion, FilterDrinking, FilterSmoking, FilterChildren:
+		return true
+	default:
+		return false
+	}
+}
+
+// HasFilter reports whether the named filter is set.
+func (p *Preferences) HasFilter(filter string) bool {
+	switch filter {
+	case FilterAge:
+		return p.AgeMin != nil || p.AgeMax != nil
+	case FilterDistance:
+		return p.MaxDistanceKm != nil
+	case FilterHeight:
+		return p.HeightMinCm != nil || p.HeightMaxCm != nil
+	case FilterEducation:
+		return len(p.Educations) > 0
+	case FilterLanguages:
+		return len(p.Languages) > 0
+	case FilterReligion:
+		return len(p.Religions) > 0
+	case FilterDrinking:
+		return len(p.Drinking) > 0
+	case FilterSmoking:
+		return len(p.Smoking) > 0
+	case FilterChildren:
+		return len(p.Children) > 0
+	default:
+		return false
+	}
+}
+
+// IsDealbreaker reports whether the named filter hides people who do not meet it.
+func (p *Preferences) IsDealbreaker(filter string) bool {
+	for _, d := range p.Dealbreakers {
+		if d == filter {
+			return true
+		}
+	}
+	return false
+}
+
+// Validate checks every filter against its enum registry or range. Only filters that are set
+// may be dealbreakers.
+func (p *Preferences) Validate() error {
+	if err := validateRange("age", p.AgeMin, p.AgeMax, MinPreferredAge, MaxPreferredAge); err != nil {
+		return err
+	}
+	if p.MaxDistanceKm != nil && (*p.MaxDistanceKm < 1 || *p.MaxDistanceKm > MaxPreferredDistanceKm) {
+		return fmt.Errorf("max_distance_km must be between 1 and %d", MaxPreferredDistanceKm)
+	}
+	if err := validateRange("height", p.HeightMinCm, p.HeightMaxCm, MinHeightCm, MaxHeightCm); err != nil {
+		return err
+	}
+	for _, list := range []struct {
+		name   string
+		values []string
+		valid  func(string) bool
+	}{
+		{"educations", p.Educations, IsValidEducation},
+		{"languages", p.Languages, IsValidLanguage},
+		{"religions", p.Religions, IsValidReligion},
+		{"drinking", p.Drinking, IsValidHabit},
+		{"smoking", p.Smoking, IsValidHabit},
+		{"children", p.Children, IsValidChildren},
+	} {
+		for _, v := range list.values {
+			if !list.valid(v) {
+				return fmt.Errorf("invalid %s value %q", list.name, v)
+			}
+		}
+	}
+	for _, d := range p.Dealbreakers {
+		if !IsValidFilter(d) {
+			return fmt.Errorf("invalid dealbreaker %q", d)
+		}
+		if !p.HasFilter(d) {
+			return fmt.Errorf("dealbreaker %q needs its filter to be set", d)
+		}
+	}
+	return nil
+}
+
+func validateRange(name string, min, max *int, lowest, highest int) error {
+	inRange := func(v *int) bool { return v == nil || (*v >= lowest && *v <= highest) }
+	if !inRange(min) || !inRange(max) {
+		return fmt.Errorf("%s range must be within %d..%d", name, lowest, highest)
+	}
+	if min != nil && max != nil && *min > *max {
+		return errors.New(name + " range minimum must not exceed its maximum")
+	}
+	return nil
+}
@@ -0,0 +1,38 @@
+package models
+
+import "time"
+
+const (
+	// MaxPromptAnswers is how many prompts a user may answer on their profile.
+	MaxPromptAnswers = 3
+	// MaxPromptAnswerLength is the longest answer a user may give to a prompt.
+	MaxPromptAnswerLength = 150
+)
+
+// Prompt is an entry in the prompt catalog, such as "My ideal Sunday is...". Prompts are
+// identified by a permanent slug, which likes use to target an answer.
+type Prompt struct {
+	ID    string            `json:"id"`
+	Texts map[string]string `json:"texts"` // ISO 639-1 language code -> prompt text
+	// Active is false for retired prompts, which can no longer be answered and are hidden from profiles
+	Active    bool      `json:"active"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// PromptAnswer is a user's answer to a prompt, shown on their profile in Position order.
+type PromptAnswer struct {
+	PromptID  string    `json:"prompt_id"`
+	Answer    string    `json:"answer"`
+	Position  int       `json:"position"`
+	UpdatedAt time.Time `json:"updated_at"`
+	// Prompt is the answered catalog entry, loaded with the answer
+	Prompt *Prompt `json:"-"`
+}
+
+// Text returns the prompt in the given language, falling back to DefaultLanguage.
+func (p *Prompt) Text(lang string) string {
+	if text, ok := p.Texts[lang]; ok {
+		return text
+	}
+	return p.Texts[DefaultLanguage]
+}
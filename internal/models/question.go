@@ -0,0 +1,86 @@
+package models
+
+import "time"
+
+// Importance names say how much a user cares about a partner's answer to a question.
+const (
+	ImportanceIrrelevant = "irrelevant"
+	ImportanceALittle    = "a_little"
+	ImportanceSomewhat   = "somewhat"
+	ImportanceVery       = "very"
+	ImportanceMandatory  = "mandatory"
+)
+
+// MaxQuestionOptions bounds the options a question may have, so the options a user accepts fit
+// in an 8-bit mask.
+const MaxQuestionOptions = 8
+
+// importanceLevels orders the importance names from least to most important; a level is an
+// index into it.
+var importanceLevels = []string{ImportanceIrrelevant, ImportanceALittle, ImportanceSomewhat, ImportanceVery, ImportanceMandatory}
+
+// Question is an entry in the compatibility question bank.
+type Question struct {
+	ID      string              `json:"id"`
+	Texts   map[string]string   `json:"texts"`   // ISO 639-1 language code -> question text
+	Options []map[string]string `json:"options"` // each option's text by language, in display order
+	// Active is false for retired questions, which can no longer be answered and no longer count
+	Active    bool      `json:"active"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// QuestionAnswer is a user's answer to a question: the option they picked, the options they
+// accept from a partner and how much that matters to them.
+type QuestionAnswer struct {
+	QuestionID string    `json:"question_id"`
+	Answer     int       `json:"answer"`     // index into the question's options
+	Accepted   uint8     `json:"accepted"`   // bit i is set when option i is acceptable
+	Importance int       `json:"importance"` // level, see ImportanceLevel
+	UpdatedAt  time.Time `json:"updated_at"`
+	// Question is the answered question, loaded with the answer
+	Question *Question `json:"-"`
+}
+
+// Text returns the question in the given language, falling back to DefaultLanguage.
+func (q *Question) Text(lang string) string {
+	if text, ok := q.Texts[lang]; ok {
+		return text
+	}
+	return q.Texts[DefaultLanguage]
+}
+
+// OptionTexts returns every option in the given language, falling back to DefaultLanguage.
+func (q *Question) OptionTexts(lang string) []string {
+	texts := make([]string, len(q.Options))
+	for i, option := range q.Options {
+		if text, ok := option[lang]; ok {
+			texts[i] = text
+		} else {
+			texts[i] = option[DefaultLanguage]
+		}
+	}
+	return texts
+}
+
+// Accepts reports whether the given option is acceptable from a partner.
+func (a *QuestionAnswer) Accepts(option int) bool {
+	return option >= 0 && option < MaxQuestionOptions && a.Accepted&(1<<uint(option)) != 0
+}
+
+// ImportanceLevel returns the level of an importance name, and false for unknown names.
+func ImportanceLevel(name string) (int, bool) {
+	for level, n := range importanceLevels {
+		if n == name {
+			return level, true
+		}
+	}
+	return 0, false
+}
+
+// ImportanceName returns the name of an importance level.
+func ImportanceName(level int) string {
+	if level < 0 || level >= len(importanceLevels) {
+		return ""
+	}
+	return importanceLevels[level]
+}
@@ -16,10 +16,33 @@ type User struct {
   BirthDate    time.Time `json:"birth_date"`
   TargetGender *int      `json:"target_gender,omitempty"`
   Intention    string    `json:"intention"`
+  Visibility   string    `json:"visibility"`
+  Timezone     string    `json:"timezone"` // IANA name; daily quotas reset at local midnight
   Bio          string    `json:"bio,omitempty"`
   AvatarURL    string    `json:"avatar_url,omitempty"`
+  // Latitude and Longitude are the user's approximate location, used to rank discovery by distance.
+  Latitude     *float64  `json:"latitude,omitempty"`
+  Longitude    *float64  `json:"longitude,omitempty"`
+  // Optional profile attributes; enum values are listed in attributes.go.
+  HeightCm  *int     `json:"height_cm,omitempty"`
+  Education string   `json:"education,omitempty"`
+  JobTitle  string   `json:"job_title,omitempty"`
+  Languages []string `json:"languages,omitempty"` // ISO 639-1 codes
+  Religion  string   `json:"religion,omitempty"`
+  Drinking  string   `json:"drinking,omitempty"`
+  Smoking   string   `json:"smoking,omitempty"`
+  Children  string   `json:"children,omitempty"`
   CreatedAt    time.Time `json:"created_at"`
   UpdatedAt    time.Time `json:"updated_at"`
+  // DeletionScheduledAt is set while a requested account deletion is in its grace period.
+  DeletionScheduledAt *time.Time `json:"deletion_scheduled_at,omitempty"`
+  // PausedAt is set while the user is taking a break; ResumeAt optionally ends the pause automatically.
+  PausedAt *time.Time `json:"paused_at,omitempty"`
+  ResumeAt *time.Time `json:"resume_at,omitempty"`
+  // VerifiedAt is set once a selfie verification of the user's photos was approved.
+  VerifiedAt *time.Time `json:"verified_at,omitempty"`
+  // SignupIP is the address the account was created from, kept to spot bursts of sign-ups.
+  SignupIP string `json:"-"`
 }
 
 const (
@@ -37,6 +60,22 @@ const (
   IntentionStillFiguringOut    = "still_figuring_out"
 )
 
+const (
+  // VisibilityMembers shows the profile to every signed-in member.
+  VisibilityMembers = "members"
+  // VisibilityIncognito shows the profile only to people the user has liked.
+  VisibilityIncognito = "incognito"
+  // VisibilityHidden keeps the profile out of every list; only matches can still open it.
+  VisibilityHidden = "hidden"
+)
+
+const (
+  // RoleMember is every regular user.
+  RoleMember = "member"
+  // RoleAdmin may also manage shared data such as the interests catalog.
+  RoleAdmin = "admin"
+)
+
 // IsValidGender returns true when the provided gender matches a supported enum value.
 func IsValidGender(g int) bool {
   switch g {
@@ -66,3 +105,35 @@ func IsValidIntention(val string) bool {
 func DefaultIntention() string {
   return IntentionStillFiguringOut
 }
+
+// IsValidVisibility returns true when the provided visibility matches a supported enum value.
+func IsValidVisibility(val string) bool {
+  switch val {
+  case VisibilityMembers, VisibilityIncognito, VisibilityHidden:
+    return true
+  default:
+    return false
+  }
+}
+
+// DefaultVisibility returns the default profile visibility for new users.
+func DefaultVisibility() string {
+  return VisibilityMembers
+}
+
+// DefaultTimezone is used until the user sets their own.
+const DefaultTimezone = "UTC"
+
+// IsValidTimezone returns true when the provided value is an IANA time zone name such as "Asia/Ho_Chi_Minh".
+func IsValidTimezone(val string) bool {
+  if val == "" || val == "Local" {
+    return false
+  }
+  _, err := time.LoadLocation(val)
+  return err == nil
+}
+
+// IsValidLocation returns true when the coordinates are a valid latitude and longitude in degrees.
+func IsValidLocation(latitude, longitude float64) bool {
+  return latitude >= -90 && latitude <= 90 && longitude >= -180 && longitude <= 180
+}
@@ -0,0 +1,52 @@
+package models
+
+import (
+	"math/rand"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// VerificationAwaitingSelfie is a request that has a pose but no selfie yet.
+	VerificationAwaitingSelfie = "awaiting_selfie"
+	// VerificationPending is a submitted selfie waiting in the review queue.
+	VerificationPending  = "pending"
+	VerificationApproved = "approved"
+	VerificationRejected = "rejected"
+)
+
+// MaxVerificationReasonLength is the longest rejection reason a reviewer may give.
+const MaxVerificationReasonLength = 300
+
+// VerificationReasonAvatarChanged rejects an approval that came after the user changed the
+// avatar the selfie was compared with.
+const VerificationReasonAvatarChanged = "Your profile photo changed after this selfie was taken. Verify your new photo."
+
+// verificationPoses lists the poses a verification selfie may be asked to mimic.
+var verificationPoses = []string{
+	"thumbs_up", "peace_sign", "hand_on_chin", "wave", "point_up", "cover_one_eye", "touch_ear", "fist_raised",
+}
+
+// PhotoVerification is a user's request to have their photos verified with a selfie mimicking
+// a random pose.
+type PhotoVerification struct {
+	ID          uuid.UUID  `json:"id"`
+	UserID      uuid.UUID  `json:"user_id"`
+	Pose        string     `json:"pose"`
+	SelfieURL   string     `json:"selfie_url,omitempty"`
+	Status      string     `json:"status"`
+	Reason      string     `json:"reason,omitempty"` // why the selfie was rejected
+	ReviewedBy  *uuid.UUID `json:"-"`                // nil when an automated check decided
+	CreatedAt   time.Time  `json:"created_at"`
+	SubmittedAt *time.Time `json:"submitted_at,omitempty"`
+	ReviewedAt  *time.Time `json:"reviewed_at,omitempty"`
+	// AvatarURL is the avatar the selfie is compared with, recorded when it was submitted.
+	// Approving it only verifies the user while that is still their avatar.
+	AvatarURL string `json:"avatar_url,omitempty"`
+}
+
+// RandomVerificationPose picks the pose a new verification selfie has to mimic.
+func RandomVerificationPose() string {
+	return verificationPoses[rand.Intn(len(verificationPoses))]
+}
@@ -0,0 +1,69 @@
+package moderation
+
+import (
+	"fmt"
+	"image"
+	_ "image/gif" // register decoders for the avatar formats users upload
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"math/bits"
+)
+
+// HashImage decodes a GIF, JPEG or PNG image and returns its DifferenceHash.
+func HashImage(r io.Reader) (uint64, error) {
+	img, _, err := image.Decode(r)
+	if err != nil {
+		return 0, fmt.Errorf("decode image: %w", err)
+	}
+	return DifferenceHash(img), nil
+}
+
+// DifferenceHash is a perceptual hash of the image: it is shrunk to 9x8 grey cells and each bit
+// records whether a cell is brighter than its right neighbour. Copies of a picture that were
+// re-encoded or resized hash the same or a few bits apart.
+func DifferenceHash(img image.Image) uint64 {
+	const width, height = 9, 8
+	var cells [height][width]float64
+	bounds := img.Bounds()
+	if bounds.Empty() {
+		return 0
+	}
+	for y := 0; y < height; y++ {
+		y0 := bounds.Min.Y + y*bounds.Dy()/height
+		y1 := max(bounds.Min.Y+(y+1)*bounds.Dy()/height, y0+1)
+		for x := 0; x < width; x++ {
+			x0 := bounds.Min.X + x*bounds.Dx()/width
+			x1 := max(bounds.Min.X+(x+1)*bounds.Dx()/width, x0+1)
+			cells[y][x] = meanLuminance(img, x0, y0, x1, y1)
+		}
+	}
+
+	var hash uint64
+	for y := 0; y < height; y++ {
+		for x := 0; x < width-1; x++ {
+			hash <<= 1
+			if cells[y][x] > cells[y][x+1] {
+				hash |= 1
+			}
+		}
+	}
+	return hash
+}
+
+// meanLuminance averages the brightness of the pixels in [x0, x1) x [y0, y1).
+func meanLuminance(img image.Image, x0, y0, x1, y1 int) float64 {
+	var sum float64
+	for y := y0; y < y1; y++ {
+		for x := x0; x < x1; x++ {
+			r, g, b, _ := img.At(x, y).RGBA()
+			sum += 0.299*float64(r) + 0.587*float64(g) + 0.114*float64(b)
+		}
+	}
+	return sum / float64((x1-x0)*(y1-y0))
+}
+
+// Distance is the number of bits two hashes differ by.
+func Distance(a, b uint64) int {
+	return bits.OnesCount64(a ^ b)
+}
@@ -0,0 +1,75 @@
+package moderation
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"math"
+	"testing"
+)
+
+// portrait draws a picture with some structure, scaled to the given size
+func portrait(width, height int, invert bool) *image.Gray {
+	img := image.NewGray(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			u, v := float64(x)/float64(width), float64(y)/float64(height)
+			shade := 128 + 100*math.Sin(7*u+3*v)*math.Cos(5*v)
+			if invert {
+				shade = 255 - shade
+			}
+			img.SetGray(x, y, color.Gray{Y: uint8(shade)})
+		}
+	}
+	return img
+}
+
+func TestDifferenceHash(t *testing.T) {
+	original := DifferenceHash(portrait(360, 480, false))
+
+	t.Run("matches a resized copy", func(t *testing.T) {
+		if d := Distance(original, DifferenceHash(portrait(90, 120, false))); d > MaxAvatarDistance {
+			t.Fatalf("expected a resized copy to match, got distance %d", d)
+		}
+	})
+
+	t.Run("matches a re-encoded copy", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := jpeg.Encode(&buf, portrait(360, 480, false), &jpeg.Options{Quality: 40}); err != nil {
+			t.Fatal(err)
+		}
+		hash, err := HashImage(&buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if d := Distance(original, hash); d > MaxAvatarDistance {
+			t.Fatalf("expected a re-encoded copy to match, got distance %d", d)
+		}
+	})
+
+	t.Run("tells different pictures apart", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := png.Encode(&buf, portrait(360, 480, true)); err != nil {
+			t.Fatal(err)
+		}
+		hash, err := HashImage(&buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if d := Distance(original, hash); d <= MaxAvatarDistance {
+			t.Fatalf("expected different pictures not to match, got distance %d", d)
+		}
+	})
+
+	t.Run("handles images smaller than the hash", func(t *testing.T) {
+		DifferenceHash(portrait(3, 2, false))
+	})
+
+	t.Run("rejects files that are not images", func(t *testing.T) {
+		if _, err := HashImage(bytes.NewBufferString("fakeimagecontent")); err == nil {
+			t.Fatal("expected an error")
+		}
+	})
+}
@@ -0,0 +1,23 @@
+// Package moderation holds the heuristics used to spot duplicate and fake accounts.
+package moderation
+
+import "time"
+
+const (
+	// MaxAvatarDistance is the most bits two avatar hashes may differ by for the avatars to
+	// count as the same picture. It tolerates re-encoding, resizing and light edits.
+	MaxAvatarDistance = 6
+	// SignupBurstSize is how many accounts created from one IP address within SignupBurstWindow
+	// make a burst.
+	SignupBurstSize   = 5
+	SignupBurstWindow = time.Hour
+	// MinDuplicateBioLength is the shortest bio compared with others; short bios such as
+	// "hi" are shared by chance.
+	MinDuplicateBioLength = 30
+	// RapidLikingMinSwipes is how many swipes a user needs within the lookback before their
+	// pace is judged.
+	RapidLikingMinSwipes = 30
+	// RapidLikingMaxInterval is the average time between swipes at or below which a user who
+	// liked every profile cannot have looked at them.
+	RapidLikingMaxInterval = 3 * time.Second
+)
@@ -0,0 +1,40 @@
+// Package notify delivers user-facing notifications about events that happen outside a request,
+// such as a finished background job.
+package notify
+
+import (
+	"context"
+	"log"
+
+	"github.com/google/uuid"
+)
+
+const TypeDataExportReady = "data_export_ready"
+
+// Notification is a message addressed to a single user.
+type Notification struct {
+	Type  string            `json:"type"`
+	Title string            `json:"title"`
+	Body  string            `json:"body"`
+	Data  map[string]string `json:"data,omitempty"`
+}
+
+// Notifier sends notifications to users. Implementations decide the channel (push, email, ...).
+type Notifier interface {
+	Notify(ctx context.Context, userID uuid.UUID, n Notification) error
+}
+
+// LogNotifier writes notifications to the application log. It is the default until a push or
+// email provider is configured.
+type LogNotifier struct{}
+
+// NewLogNotifier creates a LogNotifier
+func NewLogNotifier() *LogNotifier {
+	return &LogNotifier{}
+}
+
+// Notify logs the notification type and recipient; the body is not logged.
+func (n *LogNotifier) Notify(ctx context.Context, userID uuid.UUID, notification Notification) error {
+	log.Printf("notification %s for user %s", notification.Type, userID)
+	return nil
+}
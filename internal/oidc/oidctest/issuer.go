@@ -0,0 +1,189 @@
+// Package oidctest provides an in-process OpenID Connect issuer for tests.
+package oidctest
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const keyID = "oidctest"
+
+// User is the identity the issuer asserts for an authorization.
+type User struct {
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+}
+
+type grant struct {
+	user          User
+	nonce         string
+	codeChallenge string
+	redirectURI   string
+}
+
+// Issuer is a minimal OpenID Connect provider serving discovery, JWKS and the token endpoint.
+// Authorization is simulated with Authorize instead of a browser round trip.
+type Issuer struct {
+	Server       *httptest.Server
+	ClientID     string
+	ClientSecret string
+
+	key    *rsa.PrivateKey
+	mu     sync.Mutex
+	grants map[string]grant
+}
+
+// NewIssuer starts an issuer for the given client credentials. Call Close when done.
+func NewIssuer(clientID, clientSecret string) *Issuer {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		panic(fmt.Sprintf("oidctest: generate key: %v", err))
+	}
+
+	iss := &Issuer{ClientID: clientID, ClientSecret: clientSecret, key: key, grants: map[string]grant{}}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", iss.handleDiscovery)
+	mux.HandleFunc("/jwks", iss.handleJWKS)
+	mux.HandleFunc("/token", iss.handleToken)
+	iss.Server = httptest.NewServer(mux)
+	return iss
+}
+
+// URL returns the issuer identifier.
+func (iss *Issuer) URL() string {
+	return iss.Server.URL
+}
+
+// Close shuts down the issuer.
+func (iss *Issuer) Close() {
+	iss.Server.Close()
+}
+
+// Authorize simulates the user approving the request at authURL and returns the authorization
+// code the issuer would redirect back with.
+func (iss *Issuer) Authorize(authURL string, user User) (string, error) {
+	u, err := url.Parse(authURL)
+	if err != nil {
+		return "", err
+	}
+	q := u.Query()
+	if q.Get("client_id") != iss.ClientID {
+		return "", fmt.Errorf("oidctest: unexpected client_id %q", q.Get("client_id"))
+	}
+	if q.Get("code_challenge_method") != "S256" || q.Get("code_challenge") == "" {
+		return "", fmt.Errorf("oidctest: PKCE S256 challenge required")
+	}
+
+	code := fmt.Sprintf("code-%d", time.Now().UnixNano())
+	iss.mu.Lock()
+	iss.grants[code] = grant{
+		user:          user,
+		nonce:         q.Get("nonce"),
+		codeChallenge: q.Get("code_challenge"),
+		redirectURI:   q.Get("redirect_uri"),
+	}
+	iss.mu.Unlock()
+	return code, nil
+}
+
+// SignIDToken signs arbitrary ID token claims with the issuer key.
+func (iss *Issuer) SignIDToken(claims jwt.MapClaims) string {
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = keyID
+	signed, err := token.SignedString(iss.key)
+	if err != nil {
+		panic(fmt.Sprintf("oidctest: sign id_token: %v", err))
+	}
+	return signed
+}
+
+func (iss *Issuer) handleDiscovery(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"issuer":                                iss.URL(),
+		"authorization_endpoint":                iss.URL() + "/authorize",
+		"token_endpoint":                        iss.URL() + "/token",
+		"jwks_uri":                              iss.URL() + "/jwks",
+		"response_types_supported":              []string{"code"},
+		"subject_types_supported":               []string{"public"},
+		"id_token_signing_alg_values_supported": []string{"RS256"},
+		"code_challenge_methods_supported":      []string{"S256"},
+	})
+}
+
+func (iss *Issuer) handleJWKS(w http.ResponseWriter, r *http.Request) {
+	pub := iss.key.PublicKey
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": keyID,
+			"alg": "RS256",
+			"use": "sig",
+			"n":   base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+		}},
+	})
+}
+
+func (iss *Issuer) handleToken(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil || r.Form.Get("grant_type") != "authorization_code" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "unsupported_grant_type"})
+		return
+	}
+	if r.Form.Get("client_id") != iss.ClientID || r.Form.Get("client_secret") != iss.ClientSecret {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid_client"})
+		return
+	}
+
+	iss.mu.Lock()
+	g, ok := iss.grants[r.Form.Get("code")]
+	delete(iss.grants, r.Form.Get("code"))
+	iss.mu.Unlock()
+	if !ok || g.redirectURI != r.Form.Get("redirect_uri") {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+		return
+	}
+
+	sum := sha256.Sum256([]byte(r.Form.Get("code_verifier")))
+	if base64.RawURLEncoding.EncodeToString(sum[:]) != g.codeChallenge {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+		return
+	}
+
+	now := time.Now()
+	idToken := iss.SignIDToken(jwt.MapClaims{
+		"iss":            iss.URL(),
+		"sub":            g.user.Subject,
+		"aud":            iss.ClientID,
+		"iat":            now.Unix(),
+		"exp":            now.Add(5 * time.Minute).Unix(),
+		"nonce":          g.nonce,
+		"email":          g.user.Email,
+		"email_verified": g.user.EmailVerified,
+		"name":           g.user.Name,
+	})
+	writeJSON(w, http.StatusOK, map[string]string{
+		"access_token": "access-" + r.Form.Get("code"),
+		"token_type":   "Bearer",
+		"id_token":     idToken,
+	})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,118 @@
+package oidc
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Viet-CodingStars/kyupi-kyupi-backend/internal/oidc/oidctest"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func newTestProvider(iss *oidctest.Issuer) *Provider {
+	return NewProvider(ProviderConfig{
+		Name:         "mock",
+		Issuer:       iss.URL(),
+		ClientID:     iss.ClientID,
+		ClientSecret: iss.ClientSecret,
+		RedirectURL:  "http://localhost:5174/auth/callback",
+	}, nil)
+}
+
+func TestProviderAuthenticate(t *testing.T) {
+	iss := oidctest.NewIssuer("client-id", "client-secret")
+	defer iss.Close()
+	provider := newTestProvider(iss)
+	ctx := context.Background()
+
+	verifier, _ := RandomToken()
+	authURL, err := provider.AuthCodeURL(ctx, "state-1", "nonce-1", CodeChallengeS256(verifier))
+	if err != nil {
+		t.Fatalf("expected no error building auth URL, got %v", err)
+	}
+
+	user := oidctest.User{Subject: "sub-123", Email: "Test@Example.com", EmailVerified: true, Name: "Test User"}
+
+	t.Run("successful exchange", func(t *testing.T) {
+		code, err := iss.Authorize(authURL, user)
+		if err != nil {
+			t.Fatalf("authorize: %v", err)
+		}
+		identity, err := provider.Authenticate(ctx, code, verifier, "nonce-1")
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if identity.Subject != "sub-123" || identity.Email != "test@example.com" || !identity.EmailVerified {
+			t.Fatalf("unexpected identity: %+v", identity)
+		}
+	})
+
+	t.Run("wrong PKCE verifier", func(t *testing.T) {
+		code, _ := iss.Authorize(authURL, user)
+		if _, err := provider.Authenticate(ctx, code, "wrong-verifier", "nonce-1"); !errors.Is(err, ErrTokenExchange) {
+			t.Fatalf("expected ErrTokenExchange, got %v", err)
+		}
+	})
+
+	t.Run("nonce mismatch", func(t *testing.T) {
+		code, _ := iss.Authorize(authURL, user)
+		if _, err := provider.Authenticate(ctx, code, verifier, "other-nonce"); !errors.Is(err, ErrNonceMismatch) {
+			t.Fatalf("expected ErrNonceMismatch, got %v", err)
+		}
+	})
+
+	t.Run("code cannot be reused", func(t *testing.T) {
+		code, _ := iss.Authorize(authURL, user)
+		if _, err := provider.Authenticate(ctx, code, verifier, "nonce-1"); err != nil {
+			t.Fatalf("expected first exchange to succeed, got %v", err)
+		}
+		if _, err := provider.Authenticate(ctx, code, verifier, "nonce-1"); err == nil {
+			t.Fatal("expected second exchange to fail")
+		}
+	})
+}
+
+func TestVerifyIDToken(t *testing.T) {
+	iss := oidctest.NewIssuer("client-id", "client-secret")
+	defer iss.Close()
+	provider := newTestProvider(iss)
+	ctx := context.Background()
+	now := time.Now()
+
+	valid := func() jwt.MapClaims {
+		return jwt.MapClaims{
+			"iss":            iss.URL(),
+			"sub":            "sub-123",
+			"aud":            "client-id",
+			"exp":            now.Add(time.Minute).Unix(),
+			"nonce":          "n",
+			"email":          "test@example.com",
+			"email_verified": "true",
+		}
+	}
+
+	identity, err := provider.VerifyIDToken(ctx, iss.SignIDToken(valid()), "n")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if !identity.EmailVerified {
+		t.Fatal("expected string email_verified to be honoured")
+	}
+
+	cases := map[string]func(jwt.MapClaims){
+		"wrong audience": func(c jwt.MapClaims) { c["aud"] = "other-client" },
+		"wrong issuer":   func(c jwt.MapClaims) { c["iss"] = "https://evil.example.com" },
+		"expired":        func(c jwt.MapClaims) { c["exp"] = now.Add(-time.Minute).Unix() },
+		"missing expiry": func(c jwt.MapClaims) { delete(c, "exp") },
+	}
+	for name, mutate := range cases {
+		t.Run(name, func(t *testing.T) {
+			claims := valid()
+			mutate(claims)
+			if _, err := provider.VerifyIDToken(ctx, iss.SignIDToken(claims), "n"); !errors.Is(err, ErrInvalidIDToken) {
+				t.Fatalf("expected ErrInvalidIDToken, got %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,74 @@
+package repo
+
+import (
+	"database/sql"
+
+	"github.com/google/uuid"
+)
+
+// AccountRepo handles the final, irreversible removal of accounts whose deletion grace period has passed
+type AccountRepo struct {
+	db *sql.DB
+}
+
+// NewAccountRepo creates a new AccountRepo
+func NewAccountRepo(db *sql.DB) *AccountRepo {
+	return &AccountRepo{db: db}
+}
+
+// ListDueForPurge returns users whose scheduled deletion time has passed, oldest first
+func (r *AccountRepo) ListDueForPurge(limit int) ([]uuid.UUID, error) {
+	rows, err := r.db.Query(`
+		SELECT id FROM users
+		WHERE deletion_scheduled_at <= NOW()
+		ORDER BY deletion_scheduled_at
+		LIMIT $1
+	`, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := make([]uuid.UUID, 0)
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// Purge records a tombstone and deletes the user row. Likes, matches, sessions, identities
+// and MFA settings go with it through ON DELETE CASCADE.
+func (r *AccountRepo) Purge(userID uuid.UUID) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`
+		INSERT INTO account_tombstones (user_id, email_sha256, deletion_requested_at)
+		SELECT id, encode(sha256(convert_to(lower(email), 'UTF8')), 'hex'), deletion_requested_at
+		FROM users WHERE id = $1 AND deletion_scheduled_at <= NOW()
+		ON CONFLICT (user_id) DO NOTHING
+	`, userID)
+	if err != nil {
+		return err
+	}
+
+	result, err := tx.Exec(`DELETE FROM users WHERE id = $1 AND deletion_scheduled_at <= NOW()`, userID)
+	if err != nil {
+		return err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrUserNotFound
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,409 @@
+package repo
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/Viet-CodingStars/kyupi-kyupi-backend/internal/discovery"
+	"github.com/Viet-CodingStars/kyupi-kyupi-backend/internal/models"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// DiscoveryRepo loads ranking inputs and stores each viewer's precomputed ranking
+type DiscoveryRepo struct {
+	db *sql.DB
+}
+
+// NewDiscoveryRepo creates a new DiscoveryRepo
+func NewDiscoveryRepo(db *sql.DB) *DiscoveryRepo {
+	return &DiscoveryRepo{db: db}
+}
+
+// discoverableBy is the condition for user u to be shown while swiping to the viewer v bound
+// to $1: listable, not the viewer, each side's gender preference and dealbreakers satisfied,
+// and not already liked or passed by the viewer.
+var discoverableBy = listableBy + ` AND u.id <> $1
+	AND (v.target_gender IS NULL OR u.gender = v.target_gender)
+	AND (u.target_gender IS NULL OR u.target_gender = v.gender)
+	AND NOT EXISTS (SELECT 1 FROM likes d WHERE d.user_id = $1 AND d.target_user_id = u.id)
+	AND ` + dealbreakersMet("v", "u") + `
+	AND ` + dealbreakersMet("u", "v")
+
+// profileColumns selects a discovery.Profile for u, given profileJoins
+const profileColumns = `u.id, u.gender, u.target_gender, u.intention, u.birth_date,
+	COALESCE(u.bio, '') <> '', COALESCE(u.avatar_url, '') <> '', u.verified_at IS NOT NULL, u.latitude, u.longitude, a.last_active_at,
+	u.height_cm, u.education, u.languages, u.religion, u.drinking, u.smoking, u.children,
+	ARRAY(SELECT ui.interest FROM user_interests ui JOIN interests i ON i.slug = ui.interest WHERE ui.user_id = u.id AND i.active),
+	EXISTS(SELECT 1 FROM user_prompts upr JOIN prompts pr ON pr.id = upr.prompt_id WHERE upr.user_id = u.id AND pr.active),
+	` + preferenceColumns + `, ` + answerColumns
+
+// profileJoins adds u's last activity as a, their preferences as up and their compatibility
+// answers as qa
+const profileJoins = `LEFT JOIN LATERAL (
+		SELECT MAX(s.last_seen_at) AS last_active_at FROM sessions s WHERE s.user_id = u.id
+	) a ON TRUE
+	LEFT JOIN user_preferences up ON up.user_id = u.id
+	` + answerJoin
+
+func scanProfile(row rowScanner, p *discovery.Profile, extra ...interface{}) error {
+	var targetGender sql.NullInt64
+	var latitude, longitude sql.NullFloat64
+	var lastActiveAt sql.NullTime
+	var heightCm sql.NullInt64
+	var education, religion, drinking, smoking, children sql.NullString
+	var languages, interests pq.StringArray
+	var prefs preferenceRow
+	var answers answerRow
+	dest := append([]interface{}{
+		&p.ID, &p.Gender, &targetGender, &p.Intention, &p.BirthDate,
+		&p.HasBio, &p.HasAvatar, &p.Verified, &latitude, &longitude, &lastActiveAt,
+		&heightCm, &education, &languages, &religion, &drinking, &smoking, &children, &interests, &p.HasPrompts,
+	}, prefs.dest()...)
+	dest = append(dest, answers.dest()...)
+	dest = append(dest, extra...)
+	if err := row.Scan(dest...); err != nil {
+		return err
+	}
+	if targetGender.Valid {
+		val := int(targetGender.Int64)
+		p.TargetGender = &val
+	}
+	if latitude.Valid && longitude.Valid {
+		p.Latitude = &latitude.Float64
+		p.Longitude = &longitude.Float64
+	}
+	if lastActiveAt.Valid {
+		p.LastActiveAt = &lastActiveAt.Time
+	}
+	if heightCm.Valid {
+		val := int(heightCm.Int64)
+		p.HeightCm = &val
+	}
+	p.Education, p.Religion, p.Drinking = education.String, religion.String, drinking.String
+	p.Smoking, p.Children = smoking.String, children.String
+	p.Languages = languages
+	p.Interests = interests
+	p.Answers = answers.list()
+	if prefs.saved() {
+		p.Preferences = prefs.preferences()
+	}
+	return nil
+}
+
+// GetProfile returns the ranking attributes of a user
+func (r *DiscoveryRepo) GetProfile(userID uuid.UUID) (*discovery.Profile, error) {
+	query := `
+		SELECT ` + profileColumns + `
+		FROM users u ` + profileJoins + `
+		WHERE u.id = $1
+	`
+	p := &discovery.Profile{}
+	err := scanProfile(r.db.QueryRow(query, userID), p)
+	if err == sql.ErrNoRows {
+		return nil, ErrUserNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// ListCandidates returns up to limit users the viewer may discover, most recently active
+// first, with their desirability rating and their swipe history overall and towards people of
+// the viewer's gender and intention.
+func (r *DiscoveryRepo) ListCandidates(viewerID uuid.UUID, limit int) ([]*discovery.Candidate, error) {
+	query := `
+		SELECT ` + profileColumns + `,
+			st.similar_likes, st.similar_swipes, st.likes, st.swipes, sc.rating
+		FROM users u
+		JOIN users v ON v.id = $1
+		LEFT JOIN user_scores sc ON sc.user_id = u.id
+		` + profileJoins + `
+		LEFT JOIN LATERAL (
+			SELECT
+				COUNT(*) FILTER (WHERE l.status IN ('like', 'super_like') AND t.gender = v.gender AND t.intention = v.intention) AS similar_likes,
+				COUNT(*) FILTER (WHERE t.gender = v.gender AND t.intention = v.intention) AS similar_swipes,
+				COUNT(*) FILTER (WHERE l.status IN ('like', 'super_like')) AS likes,
+				COUNT(*) AS swipes
+			FROM likes l JOIN users t ON t.id = l.target_user_id
+			WHERE l.user_id = u.id
+		) st ON TRUE
+		WHERE ` + discoverableBy + `
+		ORDER BY a.last_active_at DESC NULLS LAST, u.id
+		LIMIT $2
+	`
+	rows, err := r.db.Query(query, viewerID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	candidates := make([]*discovery.Candidate, 0)
+	for rows.Next() {
+		c := &discovery.Candidate{}
+		var rating sql.NullFloat64
+		if err := scanProfile(rows, &c.Profile, &c.SimilarLikes, &c.SimilarSwipes, &c.Likes, &c.Swipes, &rating); err != nil {
+			return nil, err
+		}
+		if rating.Valid {
+			c.Rating = &rating.Float64
+		}
+		candidates = append(candidates, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return candidates, nil
+}
+
+// ReplaceCache swaps the viewer's cached ranking for a freshly computed one and bumps its
+// generation, which invalidates the cursors handed out for the old one
+func (r *DiscoveryRepo) ReplaceCache(viewerID uuid.UUID, ranked []discovery.Scored, computedAt time.Time) error {
+	ids := make([]string, len(ranked))
+	scores := make([]float64, len(ranked))
+	for i, s := range ranked {
+		ids[i] = s.CandidateID.String()
+		scores[i] = s.Score
+	}
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM discovery_candidates WHERE user_id = $1`, viewerID); err != nil {
+		return err
+	}
+	if len(ranked) > 0 {
+		_, err := tx.Exec(`
+			INSERT INTO discovery_candidates (user_id, candidate_id, score)
+			SELECT $1, c.candidate_id, c.score
+			FROM unnest($2::uuid[], $3::double precision[]) AS c(candidate_id, score)
+		`, viewerID, pq.Array(ids), pq.Array(scores))
+		if err != nil {
+			return err
+		}
+	}
+	_, err = tx.Exec(`
+		INSERT INTO discovery_caches (user_id, computed_at) VALUES ($1, $2)
+		ON CONFLICT (user_id) DO UPDATE
+		SET computed_at = EXCLUDED.computed_at, generation = discovery_caches.generation + 1
+	`, viewerID, computedAt)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// CacheComputedAt returns when the viewer's ranking was last computed, or nil if it never was
+func (r *DiscoveryRepo) CacheComputedAt(viewerID uuid.UUID) (*time.Time, error) {
+	var computedAt time.Time
+	err := r.db.QueryRow(`SELECT computed_at FROM discovery_caches WHERE user_id = $1`, viewerID).Scan(&computedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &computedAt, nil
+}
+
+// ListCached pages through the viewer's cached ranking, best first, starting after the cursor.
+// Candidates who stopped being discoverable since the ranking was computed are skipped. A
+// cursor from an older generation of the ranking returns discovery.ErrStaleCursor.
+func (r *DiscoveryRepo) ListCached(viewerID uuid.UUID, cursor *discovery.Cursor, limit int) (*discovery.Page, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	// Holding the cache row keeps ReplaceCache from swapping the ranking under this page
+	var generation int64
+	err = tx.QueryRow(`SELECT generation FROM discovery_caches WHERE user_id = $1 FOR SHARE`, viewerID).Scan(&generation)
+	if err == sql.ErrNoRows {
+		if cursor != nil {
+			return nil, discovery.ErrStaleCursor
+		}
+		return &discovery.Page{Users: make([]*models.User, 0)}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if cursor != nil && cursor.Generation != generation {
+		return nil, discovery.ErrStaleCursor
+	}
+
+	var afterScore sql.NullFloat64
+	var afterID uuid.NullUUID
+	if cursor != nil {
+		afterScore = sql.NullFloat64{Float64: cursor.Score, Valid: true}
+		afterID = uuid.NullUUID{UUID: cursor.CandidateID, Valid: true}
+	}
+	rows, err := tx.Query(`
+		SELECT c.score, u.id, u.name, u.gender, u.avatar_url, u.intention, u.verified_at
+		FROM discovery_candidates c
+		JOIN users u ON u.id = c.candidate_id
+		JOIN users v ON v.id = $1
+		WHERE c.user_id = $1 AND `+discoverableBy+`
+			AND ($2::double precision IS NULL OR (c.score, c.candidate_id) < ($2, $3::uuid))
+		ORDER BY c.score DESC, c.candidate_id DESC
+		LIMIT $4
+	`, viewerID, afterScore, afterID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	page := &discovery.Page{Users: make([]*models.User, 0)}
+	for rows.Next() {
+		user := &models.User{}
+		var score float64
+		var avatarURL, intention sql.NullString
+		var verifiedAt sql.NullTime
+		if err := rows.Scan(&score, &user.ID, &user.Name, &user.Gender, &avatarURL, &intention, &verifiedAt); err != nil {
+			return nil, err
+		}
+		if verifiedAt.Valid {
+			user.VerifiedAt = &verifiedAt.Time
+		}
+		if avatarURL.Valid {
+			user.AvatarURL = avatarURL.String
+		}
+		if intention.Valid {
+			user.Intention = intention.String
+		} else {
+			user.Intention = models.DefaultIntention()
+		}
+		page.Users = append(page.Users, user)
+		page.Next = &discovery.Cursor{Generation: generation, Score: score, CandidateID: user.ID}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return page, tx.Commit()
+}
+
+// ListStaleViewers returns users active since activeSince whose ranking is missing or was
+// computed before staleBefore, least recently computed first.
+func (r *DiscoveryRepo) ListStaleViewers(activeSince, staleBefore time.Time, limit int) ([]uuid.UUID, error) {
+	rows, err := r.db.Query(`
+		SELECT u.id
+		FROM users u
+		LEFT JOIN discovery_caches c ON c.user_id = u.id
+		WHERE u.deletion_scheduled_at IS NULL AND u.paused_at IS NULL
+			AND (c.computed_at IS NULL OR c.computed_at < $2)
+			AND EXISTS (SELECT 1 FROM sessions s WHERE s.user_id = u.id AND s.last_seen_at >= $1)
+		ORDER BY c.computed_at NULLS FIRST, u.id
+		LIMIT $3
+	`, activeSince, staleBefore, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := make([]uuid.UUID, 0)
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// SwiperStats summarises the likes and passes each user has given
+func (r *DiscoveryRepo) SwiperStats() (map[uuid.UUID]discovery.SwiperStats, error) {
+	rows, err := r.db.Query(`
+		SELECT user_id,
+			COUNT(*) FILTER (WHERE status IN ('like', 'super_like')),
+			COUNT(*) FILTER (WHERE status = 'pass'),
+			MIN(created_at), MAX(created_at)
+		FROM likes
+		GROUP BY user_id
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	stats := make(map[uuid.UUID]discovery.SwiperStats)
+	for rows.Next() {
+		var id uuid.UUID
+		var s discovery.SwiperStats
+		if err := rows.Scan(&id, &s.Likes, &s.Passes, &s.FirstAt, &s.LastAt); err != nil {
+			return nil, err
+		}
+		stats[id] = s
+	}
+	return stats, rows.Err()
+}
+
+// ForEachSwipe calls fn with every like and pass, oldest first, stopping at the first error
+func (r *DiscoveryRepo) ForEachSwipe(fn func(discovery.Swipe) error) error {
+	rows, err := r.db.Query(`
+		SELECT user_id, target_user_id, status <> 'pass'
+		FROM likes
+		ORDER BY created_at, id
+	`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var s discovery.Swipe
+		if err := rows.Scan(&s.SwiperID, &s.TargetID, &s.Liked); err != nil {
+			return err
+		}
+		if err := fn(s); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// ReplaceScores swaps every stored user score for a freshly computed set. Scores of users
+// deleted in the meantime are skipped.
+func (r *DiscoveryRepo) ReplaceScores(scores []discovery.UserScore, computedAt time.Time) error {
+	ids := make([]string, len(scores))
+	ratings := make([]float64, len(scores))
+	likes := make([]int64, len(scores))
+	passes := make([]int64, len(scores))
+	swipesPerDay := make([]float64, len(scores))
+	indiscriminate := make([]bool, len(scores))
+	for i, s := range scores {
+		ids[i] = s.UserID.String()
+		ratings[i] = s.Rating
+		likes[i] = int64(s.Likes)
+		passes[i] = int64(s.Passes)
+		swipesPerDay[i] = s.SwipesPerDay
+		indiscriminate[i] = s.Indiscriminate
+	}
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM user_scores`); err != nil {
+		return err
+	}
+	if len(scores) > 0 {
+		_, err := tx.Exec(`
+			INSERT INTO user_scores (user_id, rating, likes_given, passes_given, swipes_per_day, indiscriminate, computed_at)
+			SELECT s.user_id, s.rating, s.likes_given, s.passes_given, s.swipes_per_day, s.indiscriminate, $7
+			FROM unnest($1::uuid[], $2::double precision[], $3::int[], $4::int[], $5::double precision[], $6::boolean[])
+				AS s(user_id, rating, likes_given, passes_given, swipes_per_day, indiscriminate)
+			JOIN users u ON u.id = s.user_id
+		`, pq.Array(ids), pq.Array(ratings), pq.Array(likes), pq.Array(passes), pq.Array(swipesPerDay), pq.Array(indiscriminate), computedAt)
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,200 @@
+package repo
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/Viet-CodingStars/kyupi-kyupi-backend/internal/models"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrExportNotFound   = errors.New("export not found")
+	ErrExportInProgress = errors.New("an export is already in progress")
+	ErrDownloadLinkUsed = errors.New("download link is invalid, expired or already used")
+)
+
+// exportClaimTimeout is how long an export may stay in processing before another worker
+// assumes the first one died and claims it again.
+const exportClaimTimeout = time.Hour
+
+const exportColumns = `id, user_id, status, file_path, error, created_at, completed_at, expires_at`
+
+// ExportRepo handles database operations for personal data exports
+type ExportRepo struct {
+	db *sql.DB
+}
+
+// NewExportRepo creates a new ExportRepo
+func NewExportRepo(db *sql.DB) *ExportRepo {
+	return &ExportRepo{db: db}
+}
+
+// Create queues a new export for the user. Only one export per user may be pending or processing.
+func (r *ExportRepo) Create(export *models.DataExport) error {
+	query := `
+		INSERT INTO data_exports (user_id, status)
+		VALUES ($1, $2)
+		RETURNING id, status, created_at
+	`
+	err := r.db.QueryRow(query, export.UserID, models.ExportStatusPending).
+		Scan(&export.ID, &export.Status, &export.CreatedAt)
+	if err != nil {
+		if strings.Contains(err.Error(), "idx_data_exports_active") {
+			return ErrExportInProgress
+		}
+		return err
+	}
+	return nil
+}
+
+// GetByID retrieves one of the user's exports
+func (r *ExportRepo) GetByID(userID, id uuid.UUID) (*models.DataExport, error) {
+	query := `SELECT ` + exportColumns + ` FROM data_exports WHERE id = $1 AND user_id = $2`
+	export, err := scanExport(r.db.QueryRow(query, id, userID))
+	if err == sql.ErrNoRows {
+		return nil, ErrExportNotFound
+	}
+	return export, err
+}
+
+// CreateDownloadLink stores a one-time download link for the export, valid until expiresAt.
+// Only the hash of the token is kept. The export's expired links are purged on the way.
+func (r *ExportRepo) CreateDownloadLink(exportID uuid.UUID, token string, expiresAt time.Time) error {
+	if _, err := r.db.Exec(`DELETE FROM export_download_links WHERE export_id = $1 AND expires_at <= NOW()`, exportID); err != nil {
+		return err
+	}
+	_, err := r.db.Exec(`
+		INSERT INTO export_download_links (token_hash, export_id, expires_at) VALUES ($1, $2, $3)
+	`, hashDownloadToken(token), exportID, expiresAt)
+	return err
+}
+
+// ConsumeDownloadLink deletes the link for token and returns its export. Returns
+// ErrDownloadLinkUsed when the link does not exist, has expired or was used already.
+func (r *ExportRepo) ConsumeDownloadLink(token string) (*models.DataExport, error) {
+	query := `
+		WITH link AS (
+			DELETE FROM export_download_links WHERE token_hash = $1 AND expires_at > NOW()
+			RETURNING export_id
+		)
+		SELECT ` + exportColumns + ` FROM data_exports WHERE id = (SELECT export_id FROM link)
+	`
+	export, err := scanExport(r.db.QueryRow(query, hashDownloadToken(token)))
+	if err == sql.ErrNoRows {
+		return nil, ErrDownloadLinkUsed
+	}
+	return export, err
+}
+
+func hashDownloadToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// ListByUser returns the user's exports, newest first
+func (r *ExportRepo) ListByUser(userID uuid.UUID) ([]*models.DataExport, error) {
+	query := `SELECT ` + exportColumns + ` FROM data_exports WHERE user_id = $1 ORDER BY created_at DESC LIMIT 20`
+	return r.query(query, userID)
+}
+
+// ClaimNext marks the oldest pending export as processing and returns it. Exports stuck in
+// processing longer than exportClaimTimeout are claimed again. Returns ErrExportNotFound
+// when there is nothing to do.
+func (r *ExportRepo) ClaimNext() (*models.DataExport, error) {
+	query := `
+		UPDATE data_exports SET status = $1, started_at = NOW()
+		WHERE id = (
+			SELECT id FROM data_exports
+			WHERE status = $2 OR (status = $1 AND started_at < NOW() - $3 * INTERVAL '1 second')
+			ORDER BY created_at
+			LIMIT 1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING ` + exportColumns
+	export, err := scanExport(r.db.QueryRow(query,
+		models.ExportStatusProcessing, models.ExportStatusPending, exportClaimTimeout.Seconds(),
+	))
+	if err == sql.ErrNoRows {
+		return nil, ErrExportNotFound
+	}
+	return export, err
+}
+
+// MarkReady records the finished archive
+func (r *ExportRepo) MarkReady(id uuid.UUID, filePath string, expiresAt time.Time) error {
+	_, err := r.db.Exec(`
+		UPDATE data_exports SET status = $2, file_path = $3, completed_at = NOW(), expires_at = $4
+		WHERE id = $1
+	`, id, models.ExportStatusReady, filePath, expiresAt)
+	return err
+}
+
+// MarkFailed records why an export could not be built
+func (r *ExportRepo) MarkFailed(id uuid.UUID, reason string) error {
+	_, err := r.db.Exec(`
+		UPDATE data_exports SET status = $2, error = $3, completed_at = NOW()
+		WHERE id = $1
+	`, id, models.ExportStatusFailed, reason)
+	return err
+}
+
+// ListExpired returns ready exports whose download window has closed
+func (r *ExportRepo) ListExpired(limit int) ([]*models.DataExport, error) {
+	query := `SELECT ` + exportColumns + ` FROM data_exports WHERE status = $1 AND expires_at <= NOW() ORDER BY expires_at LIMIT $2`
+	return r.query(query, models.ExportStatusReady, limit)
+}
+
+// MarkExpired records that an export's archive has been removed
+func (r *ExportRepo) MarkExpired(id uuid.UUID) error {
+	_, err := r.db.Exec(`UPDATE data_exports SET status = $2, file_path = NULL WHERE id = $1`, id, models.ExportStatusExpired)
+	return err
+}
+
+func (r *ExportRepo) query(query string, args ...interface{}) ([]*models.DataExport, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	exports := make([]*models.DataExport, 0)
+	for rows.Next() {
+		export, err := scanExport(rows)
+		if err != nil {
+			return nil, err
+		}
+		exports = append(exports, export)
+	}
+	return exports, rows.Err()
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanExport(row rowScanner) (*models.DataExport, error) {
+	export := &models.DataExport{}
+	var filePath, exportErr sql.NullString
+	var completedAt, expiresAt sql.NullTime
+	err := row.Scan(
+		&export.ID, &export.UserID, &export.Status, &filePath, &exportErr,
+		&export.CreatedAt, &completedAt, &expiresAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	export.FilePath = filePath.String
+	export.Error = exportErr.String
+	if completedAt.Valid {
+		export.CompletedAt = &completedAt.Time
+	}
+	if expiresAt.Valid {
+		export.ExpiresAt = &expiresAt.Time
+	}
+	return export, nil
+}
@@ -0,0 +1,97 @@
+package repo
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+
+	"github.com/Viet-CodingStars/kyupi-kyupi-backend/internal/models"
+)
+
+var (
+	ErrIdentityNotFound      = errors.New("identity not found")
+	ErrIdentityAlreadyExists = errors.New("identity already linked")
+	ErrLoginStateNotFound    = errors.New("login state not found or expired")
+)
+
+// IdentityRepo handles database operations for external identities and OIDC login state
+type IdentityRepo struct {
+	db *sql.DB
+}
+
+// NewIdentityRepo creates a new IdentityRepo
+func NewIdentityRepo(db *sql.DB) *IdentityRepo {
+	return &IdentityRepo{db: db}
+}
+
+// Create links a provider subject to a user
+func (r *IdentityRepo) Create(identity *models.Identity) error {
+	query := `
+		INSERT INTO identities (user_id, provider, subject, email)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at, updated_at
+	`
+	err := r.db.QueryRow(query, identity.UserID, identity.Provider, identity.Subject, identity.Email).
+		Scan(&identity.ID, &identity.CreatedAt, &identity.UpdatedAt)
+	if err != nil {
+		if strings.Contains(err.Error(), "identities_provider_subject_key") {
+			return ErrIdentityAlreadyExists
+		}
+		return err
+	}
+	return nil
+}
+
+// GetByProviderSubject retrieves the identity for a provider subject
+func (r *IdentityRepo) GetByProviderSubject(provider, subject string) (*models.Identity, error) {
+	query := `
+		SELECT id, user_id, provider, subject, email, created_at, updated_at
+		FROM identities WHERE provider = $1 AND subject = $2
+	`
+	identity := &models.Identity{}
+	var email sql.NullString
+	err := r.db.QueryRow(query, provider, subject).Scan(
+		&identity.ID, &identity.UserID, &identity.Provider, &identity.Subject,
+		&email, &identity.CreatedAt, &identity.UpdatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, ErrIdentityNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	identity.Email = email.String
+	return identity, nil
+}
+
+// SaveLoginState stores the state, nonce and PKCE verifier of a started login
+func (r *IdentityRepo) SaveLoginState(state *models.OIDCLoginState) error {
+	query := `
+		INSERT INTO oidc_login_states (state, provider, nonce, code_verifier, user_id, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`
+	_, err := r.db.Exec(query, state.State, state.Provider, state.Nonce, state.CodeVerifier, state.UserID, state.ExpiresAt)
+	return err
+}
+
+// ConsumeLoginState deletes and returns an unexpired login state so it can only be used once.
+// Expired states are purged on the way.
+func (r *IdentityRepo) ConsumeLoginState(state string) (*models.OIDCLoginState, error) {
+	if _, err := r.db.Exec(`DELETE FROM oidc_login_states WHERE expires_at < NOW()`); err != nil {
+		return nil, err
+	}
+
+	query := `
+		DELETE FROM oidc_login_states WHERE state = $1
+		RETURNING state, provider, nonce, code_verifier, user_id, expires_at
+	`
+	s := &models.OIDCLoginState{}
+	err := r.db.QueryRow(query, state).Scan(&s.State, &s.Provider, &s.Nonce, &s.CodeVerifier, &s.UserID, &s.ExpiresAt)
+	if err == sql.ErrNoRows {
+		return nil, ErrLoginStateNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
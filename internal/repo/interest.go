@@ -0,0 +1,191 @@
+package repo
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+
+	"github.com/Viet-CodingStars/kyupi-kyupi-backend/internal/models"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+var (
+	ErrInterestNotFound      = errors.New("interest not found")
+	ErrInterestAlreadyExists = errors.New("interest already exists")
+	// ErrUnknownInterest is returned when a user picks an interest that is not in the active catalog
+	ErrUnknownInterest = errors.New("unknown interest")
+)
+
+// InterestRepo handles database operations for the interests catalog and users' picks from it
+type InterestRepo struct {
+	db *sql.DB
+}
+
+// NewInterestRepo creates a new InterestRepo
+func NewInterestRepo(db *sql.DB) *InterestRepo {
+	return &InterestRepo{db: db}
+}
+
+// interestColumns selects a models.Interest from interests i
+const interestColumns = `i.slug, i.category, i.labels, i.active, i.created_at, i.updated_at`
+
+func scanInterest(row rowScanner, i *models.Interest, extra ...interface{}) error {
+	var labels []byte
+	dest := append([]interface{}{&i.Slug, &i.Category, &labels, &i.Active, &i.CreatedAt, &i.UpdatedAt}, extra...)
+	if err := row.Scan(dest...); err != nil {
+		return err
+	}
+	return json.Unmarshal(labels, &i.Labels)
+}
+
+func (r *InterestRepo) list(query string, args ...interface{}) ([]*models.Interest, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	interests := make([]*models.Interest, 0)
+	for rows.Next() {
+		i := &models.Interest{}
+		if err := scanInterest(rows, i); err != nil {
+			return nil, err
+		}
+		interests = append(interests, i)
+	}
+	return interests, rows.Err()
+}
+
+// List returns the catalog ordered by category and slug, leaving out retired interests unless
+// includeInactive is set
+func (r *InterestRepo) List(includeInactive bool) ([]*models.Interest, error) {
+	return r.list(`
+		SELECT `+interestColumns+` FROM interests i
+		WHERE i.active OR $1
+		ORDER BY i.category, i.slug
+	`, includeInactive)
+}
+
+// Create adds an interest to the catalog
+func (r *InterestRepo) Create(interest *models.Interest) error {
+	labels, err := json.Marshal(interest.Labels)
+	if err != nil {
+		return err
+	}
+	err = r.db.QueryRow(`
+		INSERT INTO interests (slug, category, labels, active)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (slug) DO NOTHING
+		RETURNING created_at, updated_at
+	`, interest.Slug, interest.Category, string(labels), interest.Active).Scan(&interest.CreatedAt, &interest.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return ErrInterestAlreadyExists
+	}
+	return err
+}
+
+// Update replaces an interest's category, labels and active flag
+func (r *InterestRepo) Update(interest *models.Interest) error {
+	labels, err := json.Marshal(interest.Labels)
+	if err != nil {
+		return err
+	}
+	err = r.db.QueryRow(`
+		UPDATE interests SET category = $2, labels = $3, active = $4, updated_at = NOW()
+		WHERE slug = $1
+		RETURNING created_at, updated_at
+	`, interest.Slug, interest.Category, string(labels), interest.Active).Scan(&interest.CreatedAt, &interest.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return ErrInterestNotFound
+	}
+	return err
+}
+
+// Delete removes an interest from the catalog and from every user who picked it
+func (r *InterestRepo) Delete(slug string) error {
+	result, err := r.db.Exec(`DELETE FROM interests WHERE slug = $1`, slug)
+	if err != nil {
+		return err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrInterestNotFound
+	}
+	return nil
+}
+
+// ListForUser returns the active interests the user picked
+func (r *InterestRepo) ListForUser(userID uuid.UUID) ([]*models.Interest, error) {
+	return r.list(`
+		SELECT `+interestColumns+`
+		FROM user_interests ui JOIN interests i ON i.slug = ui.interest
+		WHERE ui.user_id = $1 AND i.active
+		ORDER BY i.category, i.slug
+	`, userID)
+}
+
+// SetForUser replaces the user's interests. Every slug must be an active interest.
+func (r *InterestRepo) SetForUser(userID uuid.UUID, slugs []string) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var exists bool
+	if err := tx.QueryRow(`SELECT EXISTS(SELECT 1 FROM users WHERE id = $1)`, userID).Scan(&exists); err != nil {
+		return err
+	}
+	if !exists {
+		return ErrUserNotFound
+	}
+	if _, err := tx.Exec(`DELETE FROM user_interests WHERE user_id = $1`, userID); err != nil {
+		return err
+	}
+	result, err := tx.Exec(`
+		INSERT INTO user_interests (user_id, interest)
+		SELECT $1, i.slug FROM interests i
+		WHERE i.slug = ANY($2) AND i.active
+	`, userID, pq.Array(slugs))
+	if err != nil {
+		return err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if int(n) != len(slugs) {
+		return ErrUnknownInterest
+	}
+	return tx.Commit()
+}
+
+// ListForProfile returns the active interests on the user's profile, marking those the viewer
+// picked too
+func (r *InterestRepo) ListForProfile(viewerID, userID uuid.UUID) ([]*models.ProfileInterest, error) {
+	rows, err := r.db.Query(`
+		SELECT `+interestColumns+`,
+			EXISTS(SELECT 1 FROM user_interests vi WHERE vi.user_id = $1 AND vi.interest = i.slug)
+		FROM user_interests ui JOIN interests i ON i.slug = ui.interest
+		WHERE ui.user_id = $2 AND i.active
+		ORDER BY i.category, i.slug
+	`, viewerID, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	interests := make([]*models.ProfileInterest, 0)
+	for rows.Next() {
+		i := &models.ProfileInterest{}
+		if err := scanInterest(rows, &i.Interest, &i.Shared); err != nil {
+			return nil, err
+		}
+		interests = append(interests, i)
+	}
+	return interests, rows.Err()
+}
@@ -3,6 +3,7 @@ package repo
 import (
 	"database/sql"
 	"errors"
+	"time"
 
 	"github.com/Viet-CodingStars/kyupi-kyupi-backend/internal/models"
 	"github.com/google/uuid"
@@ -11,6 +12,12 @@ import (
 var (
 	ErrLikeNotFound      = errors.New("like not found")
 	ErrLikeAlreadyExists = errors.New("like already exists for this pair")
+	// ErrSuperLikeQuotaExceeded is returned when the sender has used today's super likes
+	ErrSuperLikeQuotaExceeded = errors.New("daily super like quota exceeded")
+	// ErrNoPassToRewind is returned when the user has no pass recent enough to undo
+	ErrNoPassToRewind = errors.New("no pass to rewind")
+	// ErrRewindLimitExceeded is returned when the user has used today's rewinds
+	ErrRewindLimitExceeded = errors.New("daily rewind limit exceeded")
 )
 
 // LikeRepo handles database operations for likes
@@ -23,35 +30,205 @@ func NewLikeRepo(db *sql.DB) *LikeRepo {
 	return &LikeRepo{db: db}
 }
 
-// Create inserts a new like into the database
+// Create inserts a new like into the database. A like sent to a paused user is queued;
+// the target row is share-locked so the insert cannot race with the target resuming. A like
+// or super like replaces an earlier pass on the same user, dated as a new decision; any other
+// earlier decision returns ErrLikeAlreadyExists.
 func (r *LikeRepo) Create(like *models.Like) error {
+	return insertLike(r.db, like)
+}
+
+// queryRower is satisfied by both *sql.DB and *sql.Tx
+type queryRower interface {
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+func insertLike(q queryRower, like *models.Like) error {
 	query := `
-		INSERT INTO likes (user_id, target_user_id, status)
-		VALUES ($1, $2, $3)
-		RETURNING id, created_at, updated_at
+		INSERT INTO likes (user_id, target_user_id, status, queued_at, comment, target_type, target_ref)
+		SELECT $1, id, $3, CASE WHEN $3 IN ('like', 'super_like') AND paused_at IS NOT NULL THEN NOW() END, $4, $5, $6
+		FROM users WHERE id = $2
+		FOR SHARE
+		ON CONFLICT (user_id, target_user_id) DO UPDATE SET
+			status = EXCLUDED.status, queued_at = EXCLUDED.queued_at, comment = EXCLUDED.comment,
+			target_type = EXCLUDED.target_type, target_ref = EXCLUDED.target_ref,
+			created_at = NOW(), updated_at = NOW()
+		WHERE likes.status = 'pass' AND EXCLUDED.status IN ('like', 'super_like')
+		RETURNING id, created_at, updated_at, queued_at
 	`
-	err := r.db.QueryRow(query, like.UserID, like.TargetUserID, like.Status).
-		Scan(&like.ID, &like.CreatedAt, &like.UpdatedAt)
+	var queuedAt sql.NullTime
+	err := q.QueryRow(query, like.UserID, like.TargetUserID, like.Status,
+		sql.NullString{String: like.Comment, Valid: like.Comment != ""},
+		sql.NullString{String: like.TargetType, Valid: like.TargetType != ""},
+		sql.NullString{String: like.TargetRef, Valid: like.TargetRef != ""},
+	).Scan(&like.ID, &like.CreatedAt, &like.UpdatedAt, &queuedAt)
 	if err != nil {
+		if err == sql.ErrNoRows {
+			// Either the target does not exist or the conflicting decision cannot be replaced
+			var exists bool
+			if err := q.QueryRow(`SELECT EXISTS(SELECT 1 FROM users WHERE id = $1)`, like.TargetUserID).Scan(&exists); err != nil {
+				return err
+			}
+			if exists {
+				return ErrLikeAlreadyExists
+			}
+			return ErrUserNotFound
+		}
 		if err.Error() == "pq: duplicate key value violates unique constraint \"likes_user_id_target_user_id_key\"" {
 			return ErrLikeAlreadyExists
 		}
 		return err
 	}
+	if queuedAt.Valid {
+		like.QueuedAt = &queuedAt.Time
+	}
 	return nil
 }
 
+// CreateSuperLike inserts a super like and counts it against the sender's daily quota, which
+// resets at midnight in the sender's timezone. Returns the super likes left for the day, or
+// ErrSuperLikeQuotaExceeded without inserting anything.
+func (r *LikeRepo) CreateSuperLike(like *models.Like, dailyQuota int) (int, error) {
+	if dailyQuota <= 0 {
+		return 0, ErrSuperLikeQuotaExceeded
+	}
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var used int
+	err = tx.QueryRow(`
+		INSERT INTO super_like_usage (user_id, day, used)
+		SELECT id, (NOW() AT TIME ZONE timezone)::date, 1 FROM users WHERE id = $1
+		ON CONFLICT (user_id, day) DO UPDATE SET used = super_like_usage.used + 1
+		WHERE super_like_usage.used < $2
+		RETURNING used
+	`, like.UserID, dailyQuota).Scan(&used)
+	if err == sql.ErrNoRows {
+		return 0, ErrSuperLikeQuotaExceeded
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	like.Status = models.LikeStatusSuperLike
+	if err := insertLike(tx, like); err != nil {
+		return 0, err
+	}
+	// Earlier days are no longer needed
+	if _, err := tx.Exec(`DELETE FROM super_like_usage WHERE user_id = $1 AND day < CURRENT_DATE - 1`, like.UserID); err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return dailyQuota - used, nil
+}
+
+// SuperLikeUsage returns how many super likes the user sent today in their timezone and when
+// the count resets.
+func (r *LikeRepo) SuperLikeUsage(userID uuid.UUID) (int, time.Time, error) {
+	query := `
+		SELECT COALESCE(s.used, 0), (((NOW() AT TIME ZONE u.timezone)::date + 1)::timestamp AT TIME ZONE u.timezone)
+		FROM users u
+		LEFT JOIN super_like_usage s ON s.user_id = u.id AND s.day = (NOW() AT TIME ZONE u.timezone)::date
+		WHERE u.id = $1
+	`
+	var used int
+	var resetsAt time.Time
+	err := r.db.QueryRow(query, userID).Scan(&used, &resetsAt)
+	if err == sql.ErrNoRows {
+		return 0, time.Time{}, ErrUserNotFound
+	}
+	return used, resetsAt, err
+}
+
+// RewindLastPass deletes the user's most recent pass if it was made within window, so the
+// profile can be shown again. Rewinds count against a daily limit that resets at midnight in
+// the user's timezone. Returns the deleted pass and the rewinds left for the day.
+func (r *LikeRepo) RewindLastPass(userID uuid.UUID, window time.Duration, dailyLimit int) (*models.Like, int, error) {
+	if dailyLimit <= 0 {
+		return nil, 0, ErrRewindLimitExceeded
+	}
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, 0, err
+	}
+	defer tx.Rollback()
+
+	pass, err := scanLike(tx.QueryRow(`
+		SELECT `+likeColumns+`
+		FROM likes
+		WHERE user_id = $1 AND status = 'pass' AND created_at >= NOW() - $2 * INTERVAL '1 second'
+		ORDER BY created_at DESC
+		LIMIT 1
+		FOR UPDATE
+	`, userID, window.Seconds()))
+	if err == sql.ErrNoRows {
+		return nil, 0, ErrNoPassToRewind
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var used int
+	err = tx.QueryRow(`
+		INSERT INTO rewind_usage (user_id, day, used)
+		SELECT id, (NOW() AT TIME ZONE timezone)::date, 1 FROM users WHERE id = $1
+		ON CONFLICT (user_id, day) DO UPDATE SET used = rewind_usage.used + 1
+		WHERE rewind_usage.used < $2
+		RETURNING used
+	`, userID, dailyLimit).Scan(&used)
+	if err == sql.ErrNoRows {
+		return nil, 0, ErrRewindLimitExceeded
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if _, err := tx.Exec(`DELETE FROM likes WHERE id = $1`, pass.ID); err != nil {
+		return nil, 0, err
+	}
+	// Earlier days are no longer needed
+	if _, err := tx.Exec(`DELETE FROM rewind_usage WHERE user_id = $1 AND day < CURRENT_DATE - 1`, userID); err != nil {
+		return nil, 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, 0, err
+	}
+	return pass, dailyLimit - used, nil
+}
+
+// DeleteExpiredPasses removes up to limit passes decided longer than olderThan ago, so those
+// profiles can be shown again. Returns the number of passes removed.
+func (r *LikeRepo) DeleteExpiredPasses(olderThan time.Duration, limit int) (int64, error) {
+	result, err := r.db.Exec(`
+		DELETE FROM likes
+		WHERE id IN (
+			SELECT id FROM likes
+			WHERE status = 'pass' AND updated_at < NOW() - make_interval(secs => $1)
+			LIMIT $2
+		)
+	`, olderThan.Seconds(), limit)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
 // GetByUserAndTarget retrieves a like by user_id and target_user_id
 func (r *LikeRepo) GetByUserAndTarget(userID, targetUserID uuid.UUID) (*models.Like, error) {
 	query := `
-		SELECT id, user_id, target_user_id, status, created_at, updated_at
+		SELECT ` + likeColumns + `
 		FROM likes WHERE user_id = $1 AND target_user_id = $2
 	`
-	like := &models.Like{}
-	err := r.db.QueryRow(query, userID, targetUserID).Scan(
-		&like.ID, &like.UserID, &like.TargetUserID, &like.Status,
-		&like.CreatedAt, &like.UpdatedAt,
-	)
+	like, err := scanLike(r.db.QueryRow(query, userID, targetUserID))
 	if err == sql.ErrNoRows {
 		return nil, ErrLikeNotFound
 	}
@@ -61,13 +238,32 @@ func (r *LikeRepo) GetByUserAndTarget(userID, targetUserID uuid.UUID) (*models.L
 	return like, nil
 }
 
+// likeColumns is the column list read by scanLike
+const likeColumns = `id, user_id, target_user_id, status, created_at, updated_at, comment, target_type, target_ref`
+
+func scanLike(row rowScanner) (*models.Like, error) {
+	like := &models.Like{}
+	var comment, targetType, targetRef sql.NullString
+	err := row.Scan(
+		&like.ID, &like.UserID, &like.TargetUserID, &like.Status,
+		&like.CreatedAt, &like.UpdatedAt, &comment, &targetType, &targetRef,
+	)
+	if err != nil {
+		return nil, err
+	}
+	like.Comment = comment.String
+	like.TargetType = targetType.String
+	like.TargetRef = targetRef.String
+	return like, nil
+}
+
 // CheckMutualLike checks if there is a mutual like between two users
 // Returns true if targetUser has liked the originalUser
 func (r *LikeRepo) CheckMutualLike(userID, targetUserID uuid.UUID) (bool, error) {
 	query := `
 		SELECT EXISTS(
 			SELECT 1 FROM likes 
-			WHERE user_id = $1 AND target_user_id = $2 AND status = 'like'
+			WHERE user_id = $1 AND target_user_id = $2 AND status IN ('like', 'super_like')
 		)
 	`
 	var exists bool
@@ -77,3 +273,120 @@ func (r *LikeRepo) CheckMutualLike(userID, targetUserID uuid.UUID) (bool, error)
 	}
 	return exists, nil
 }
+
+// ListByUser returns every like and pass the user has given, oldest first
+func (r *LikeRepo) ListByUser(userID uuid.UUID) ([]*models.Like, error) {
+	query := `
+		SELECT ` + likeColumns + `
+		FROM likes WHERE user_id = $1
+		ORDER BY created_at
+	`
+	return r.queryLikes(query, userID)
+}
+
+// ListComments returns the likes between two users, in either direction, that carry a
+// comment, oldest first. Used to open the chat once the pair matches.
+func (r *LikeRepo) ListComments(userID, otherUserID uuid.UUID) ([]*models.Like, error) {
+	query := `
+		SELECT ` + likeColumns + `
+		FROM likes
+		WHERE ((user_id = $1 AND target_user_id = $2) OR (user_id = $2 AND target_user_id = $1))
+			AND status IN ('like', 'super_like') AND comment IS NOT NULL
+		ORDER BY created_at
+	`
+	return r.queryLikes(query, userID, otherUserID)
+}
+
+func (r *LikeRepo) queryLikes(query string, args ...interface{}) ([]*models.Like, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	likes := make([]*models.Like, 0)
+	for rows.Next() {
+		like, err := scanLike(rows)
+		if err != nil {
+			return nil, err
+		}
+		likes = append(likes, like)
+	}
+	return likes, rows.Err()
+}
+
+// LikeCursor marks a position in a list of received likes
+type LikeCursor struct {
+	SuperLike bool
+	CreatedAt time.Time
+	ID        uuid.UUID
+}
+
+// ListReceived returns likes sent to the user that the user has not liked or passed back,
+// super likes first and then newest first, starting after cursor. Likers the user may not
+// list (deleted, paused or hidden) are left out, as are likes still queued while the user is
+// paused. Incognito likers are included since they have liked the user.
+//
+// Blocked likers are not filtered: there is no way to block a user yet, so there is no block
+// relation to exclude. Whoever adds blocking must exclude both directions here.
+func (r *LikeRepo) ListReceived(userID uuid.UUID, cursor *LikeCursor, limit int) ([]*models.ReceivedLike, error) {
+	var cursorAt sql.NullTime
+	var cursorID uuid.NullUUID
+	cursorRank := 0
+	if cursor != nil {
+		cursorAt = sql.NullTime{Time: cursor.CreatedAt, Valid: true}
+		cursorID = uuid.NullUUID{UUID: cursor.ID, Valid: true}
+		if cursor.SuperLike {
+			cursorRank = 1
+		}
+	}
+
+	query := `
+		SELECT l.id, l.status = 'super_like', l.comment, l.target_type, l.target_ref, l.created_at, u.id, u.name, u.gender, u.avatar_url, u.intention, u.verified_at
+		FROM likes l
+		JOIN users u ON u.id = l.user_id
+		WHERE l.target_user_id = $1 AND l.status IN ('like', 'super_like') AND l.queued_at IS NULL
+			AND NOT EXISTS (
+				SELECT 1 FROM likes answered
+				WHERE answered.user_id = $1 AND answered.target_user_id = l.user_id
+			)
+			AND ` + listableBy + `
+			AND ($2::timestamp IS NULL OR
+				((CASE WHEN l.status = 'super_like' THEN 1 ELSE 0 END), l.created_at, l.id) < ($4::int, $2, $3))
+		ORDER BY (l.status = 'super_like') DESC, l.created_at DESC, l.id DESC
+		LIMIT $5
+	`
+	rows, err := r.db.Query(query, userID, cursorAt, cursorID, cursorRank, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	received := make([]*models.ReceivedLike, 0)
+	for rows.Next() {
+		item := &models.ReceivedLike{User: &models.User{}}
+		var avatarURL, intention, comment, targetType, targetRef sql.NullString
+		var verifiedAt sql.NullTime
+		err := rows.Scan(
+			&item.LikeID, &item.SuperLike, &comment, &targetType, &targetRef, &item.LikedAt,
+			&item.User.ID, &item.User.Name, &item.User.Gender, &avatarURL, &intention, &verifiedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		if verifiedAt.Valid {
+			item.User.VerifiedAt = &verifiedAt.Time
+		}
+		item.User.AvatarURL = avatarURL.String
+		item.Comment = comment.String
+		item.TargetType = targetType.String
+		item.TargetRef = targetRef.String
+		if intention.Valid {
+			item.User.Intention = intention.String
+		} else {
+			item.User.Intention = models.DefaultIntention()
+		}
+		received = append(received, item)
+	}
+	return received, rows.Err()
+}
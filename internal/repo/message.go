@@ -41,6 +41,29 @@ func (r *MessageRepo) Create(ctx context.Context, message *models.Message) error
 	return nil
 }
 
+// SeedFromLikes opens a new match's chat with the comments sent along with the pair's likes.
+// Each comment becomes a message from the liker, dated when the like was sent.
+func (r *MessageRepo) SeedFromLikes(ctx context.Context, matchID uuid.UUID, likes []*models.Like) error {
+	docs := make([]interface{}, 0, len(likes))
+	for _, like := range likes {
+		if like.Comment == "" {
+			continue
+		}
+		docs = append(docs, &models.Message{
+			MatchID:    matchID,
+			SenderID:   like.UserID,
+			ReceiverID: like.TargetUserID,
+			Content:    like.Comment,
+			CreatedAt:  like.CreatedAt,
+		})
+	}
+	if len(docs) == 0 {
+		return nil
+	}
+	_, err := r.collection.InsertMany(ctx, docs)
+	return err
+}
+
 // GetByMatchID retrieves all messages for a specific match, ordered by creation time
 func (r *MessageRepo) GetByMatchID(ctx context.Context, matchID uuid.UUID) ([]*models.Message, error) {
 	filter := bson.M{"match_id": matchID.String()}
@@ -67,3 +90,41 @@ func (r *MessageRepo) GetByMatchID(ctx context.Context, matchID uuid.UUID) ([]*m
 
 	return messages, nil
 }
+
+// DeleteByUser removes every message the user sent or received
+func (r *MessageRepo) DeleteByUser(ctx context.Context, userID uuid.UUID) (int64, error) {
+	filter := bson.M{"$or": bson.A{
+		bson.M{"sender_id": userID},
+		bson.M{"receiver_id": userID},
+	}}
+	result, err := r.collection.DeleteMany(ctx, filter)
+	if err != nil {
+		return 0, err
+	}
+	return result.DeletedCount, nil
+}
+
+// ListByUser retrieves every message the user sent or received, ordered by creation time
+func (r *MessageRepo) ListByUser(ctx context.Context, userID uuid.UUID) ([]*models.Message, error) {
+	filter := bson.M{"$or": bson.A{
+		bson.M{"sender_id": userID},
+		bson.M{"receiver_id": userID},
+	}}
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}})
+
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	messages := make([]*models.Message, 0)
+	for cursor.Next(ctx) {
+		var msg models.Message
+		if err := cursor.Decode(&msg); err != nil {
+			return nil, err
+		}
+		messages = append(messages, &msg)
+	}
+	return messages, cursor.Err()
+}
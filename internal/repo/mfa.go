@@ -0,0 +1,239 @@
+package repo
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/Viet-CodingStars/kyupi-kyupi-backend/internal/models"
+	"github.com/google/uuid"
+)
+
+var ErrMFANotFound = errors.New("mfa not configured")
+
+// MFARepo handles database operations for TOTP secrets and recovery codes
+type MFARepo struct {
+	db *sql.DB
+}
+
+// NewMFARepo creates a new MFARepo
+func NewMFARepo(db *sql.DB) *MFARepo {
+	return &MFARepo{db: db}
+}
+
+// Get retrieves the MFA settings of a user
+func (r *MFARepo) Get(userID uuid.UUID) (*models.UserMFA, error) {
+	query := `
+		SELECT user_id, totp_secret, totp_enabled, totp_last_step, COALESCE(locked_until > NOW(), FALSE),
+			enabled_at, created_at, updated_at
+		FROM user_mfa WHERE user_id = $1
+	`
+	mfa := &models.UserMFA{}
+	var lastStep sql.NullInt64
+	var enabledAt sql.NullTime
+	err := r.db.QueryRow(query, userID).Scan(
+		&mfa.UserID, &mfa.TOTPSecret, &mfa.TOTPEnabled, &lastStep, &mfa.Locked, &enabledAt, &mfa.CreatedAt, &mfa.UpdatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, ErrMFANotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	mfa.TOTPLastStep = lastStep.Int64
+	if enabledAt.Valid {
+		mfa.EnabledAt = &enabledAt.Time
+	}
+	return mfa, nil
+}
+
+// SaveTOTPSecret stores a pending TOTP secret, replacing any earlier unconfirmed one.
+// An enabled secret is left untouched.
+func (r *MFARepo) SaveTOTPSecret(userID uuid.UUID, secret string) error {
+	query := `
+		INSERT INTO user_mfa (user_id, totp_secret)
+		VALUES ($1, $2)
+		ON CONFLICT (user_id) DO UPDATE
+		SET totp_secret = EXCLUDED.totp_secret, totp_last_step = NULL, updated_at = NOW()
+		WHERE user_mfa.totp_enabled = FALSE
+	`
+	_, err := r.db.Exec(query, userID, secret)
+	return err
+}
+
+// Enable confirms the pending TOTP secret and replaces the user's recovery codes
+func (r *MFARepo) Enable(userID uuid.UUID, step int64, recoveryCodeHashes []string) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`
+		UPDATE user_mfa
+		SET totp_enabled = TRUE, totp_last_step = $2, enabled_at = NOW(), updated_at = NOW()
+		WHERE user_id = $1
+	`, userID, step)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return ErrMFANotFound
+	}
+
+	if err := replaceRecoveryCodes(tx, userID, recoveryCodeHashes); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// ConsumeTOTPStep records step as used. It returns false when that step or a later one
+// was already accepted, which makes every code single-use.
+func (r *MFARepo) ConsumeTOTPStep(userID uuid.UUID, step int64) (bool, error) {
+	result, err := r.db.Exec(`
+		UPDATE user_mfa SET totp_last_step = $2, updated_at = NOW()
+		WHERE user_id = $1 AND (totp_last_step IS NULL OR totp_last_step < $2)
+	`, userID, step)
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n == 1, nil
+}
+
+// CreateChallenge opens a sign-in challenge for the user that expires after ttl
+func (r *MFARepo) CreateChallenge(userID uuid.UUID, ttl time.Duration) (uuid.UUID, error) {
+	var id uuid.UUID
+	err := r.db.QueryRow(`
+		INSERT INTO mfa_challenges (user_id, expires_at) VALUES ($1, NOW() + make_interval(secs => $2))
+		RETURNING id
+	`, userID, ttl.Seconds()).Scan(&id)
+	return id, err
+}
+
+// ChallengeActive reports whether the user's challenge exists, has not expired and was not used
+func (r *MFARepo) ChallengeActive(id, userID uuid.UUID) (bool, error) {
+	var active bool
+	err := r.db.QueryRow(`
+		SELECT EXISTS (
+			SELECT 1 FROM mfa_challenges
+			WHERE id = $1 AND user_id = $2 AND consumed_at IS NULL AND expires_at > NOW()
+		)
+	`, id, userID).Scan(&active)
+	return active, err
+}
+
+// ConsumeChallenge marks an active challenge as used, returning false if it was not active
+func (r *MFARepo) ConsumeChallenge(id, userID uuid.UUID) (bool, error) {
+	result, err := r.db.Exec(`
+		UPDATE mfa_challenges SET consumed_at = NOW()
+		WHERE id = $1 AND user_id = $2 AND consumed_at IS NULL AND expires_at > NOW()
+	`, id, userID)
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n == 1, nil
+}
+
+// RecordFailedAttempt counts a wrong second-factor code. Reaching maxAttempts locks sign-in
+// for the lockout period and starts the count again; it returns whether the user is now locked.
+func (r *MFARepo) RecordFailedAttempt(userID uuid.UUID, maxAttempts int, lockout time.Duration) (bool, error) {
+	var locked bool
+	err := r.db.QueryRow(`
+		UPDATE user_mfa SET
+			failed_attempts = CASE WHEN failed_attempts + 1 >= $2 THEN 0 ELSE failed_attempts + 1 END,
+			locked_until = CASE WHEN failed_attempts + 1 >= $2 THEN NOW() + make_interval(secs => $3) ELSE locked_until END,
+			updated_at = NOW()
+		WHERE user_id = $1
+		RETURNING COALESCE(locked_until > NOW(), FALSE)
+	`, userID, maxAttempts, lockout.Seconds()).Scan(&locked)
+	if err == sql.ErrNoRows {
+		return false, ErrMFANotFound
+	}
+	return locked, err
+}
+
+// ResetFailedAttempts clears the failed attempt count after a successful sign-in
+func (r *MFARepo) ResetFailedAttempts(userID uuid.UUID) error {
+	_, err := r.db.Exec(`UPDATE user_mfa SET failed_attempts = 0 WHERE user_id = $1`, userID)
+	return err
+}
+
+// ConsumeRecoveryCode marks an unused recovery code as used, returning false if none matched
+func (r *MFARepo) ConsumeRecoveryCode(userID uuid.UUID, codeHash string) (bool, error) {
+	result, err := r.db.Exec(`
+		UPDATE mfa_recovery_codes SET used_at = NOW()
+		WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL
+	`, userID, codeHash)
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n == 1, nil
+}
+
+// CountRecoveryCodes returns the number of unused recovery codes
+func (r *MFARepo) CountRecoveryCodes(userID uuid.UUID) (int, error) {
+	var count int
+	err := r.db.QueryRow(`
+		SELECT COUNT(*) FROM mfa_recovery_codes WHERE user_id = $1 AND used_at IS NULL
+	`, userID).Scan(&count)
+	return count, err
+}
+
+// ReplaceRecoveryCodes invalidates all recovery codes and stores a new set
+func (r *MFARepo) ReplaceRecoveryCodes(userID uuid.UUID, codeHashes []string) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := replaceRecoveryCodes(tx, userID, codeHashes); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Disable removes the TOTP secret and all recovery codes
+func (r *MFARepo) Disable(userID uuid.UUID) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM mfa_recovery_codes WHERE user_id = $1`, userID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`DELETE FROM user_mfa WHERE user_id = $1`, userID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func replaceRecoveryCodes(tx *sql.Tx, userID uuid.UUID, codeHashes []string) error {
+	if _, err := tx.Exec(`DELETE FROM mfa_recovery_codes WHERE user_id = $1`, userID); err != nil {
+		return err
+	}
+	for _, hash := range codeHashes {
+		if _, err := tx.Exec(`
+			INSERT INTO mfa_recovery_codes (user_id, code_hash) VALUES ($1, $2)
+		`, userID, hash); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,292 @@
+package repo
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/Viet-CodingStars/kyupi-kyupi-backend/internal/models"
+	"github.com/Viet-CodingStars/kyupi-kyupi-backend/internal/moderation"
+	"github.com/google/uuid"
+)
+
+var ErrFlagNotFound = errors.New("moderation flag not found")
+
+const flagColumns = `f.id, f.user_id, f.reason, f.related_user_id, f.details, f.status,
+	f.created_at, f.resolved_by, f.resolved_at`
+
+// avatarDistance is the number of bits the avatar hashes of u and o differ by
+const avatarDistance = `length(replace((u.avatar_hash # o.avatar_hash)::bit(64)::text, '0', ''))`
+
+// ModerationRepo handles database operations for suspected fake accounts
+type ModerationRepo struct {
+	db *sql.DB
+}
+
+// NewModerationRepo creates a new ModerationRepo
+func NewModerationRepo(db *sql.DB) *ModerationRepo {
+	return &ModerationRepo{db: db}
+}
+
+// ListUnhashedAvatars returns up to limit users whose current avatar has not been hashed yet,
+// mapped to the avatar's URL
+func (r *ModerationRepo) ListUnhashedAvatars(limit int) (map[uuid.UUID]string, error) {
+	rows, err := r.db.Query(`
+		SELECT id, avatar_url FROM users
+		WHERE avatar_url IS NOT NULL AND avatar_url <> ''
+			AND avatar_hashed_url IS DISTINCT FROM avatar_url
+			AND deletion_scheduled_at IS NULL
+		ORDER BY updated_at
+		LIMIT $1
+	`, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	avatars := make(map[uuid.UUID]string)
+	for rows.Next() {
+		var id uuid.UUID
+		var avatarURL string
+		if err := rows.Scan(&id, &avatarURL); err != nil {
+			return nil, err
+		}
+		avatars[id] = avatarURL
+	}
+	return avatars, rows.Err()
+}
+
+// SetAvatarHash stores the hash of the avatar at avatarURL, or no hash when the file could not
+// be read, so it is not tried again. It does nothing if the user changed avatar meanwhile.
+func (r *ModerationRepo) SetAvatarHash(userID uuid.UUID, avatarURL string, hash *uint64) error {
+	var stored interface{}
+	if hash != nil {
+		stored = int64(*hash)
+	}
+	_, err := r.db.Exec(`
+		UPDATE users SET avatar_hash = $3, avatar_hashed_url = $2, avatar_hashed_at = NOW()
+		WHERE id = $1 AND avatar_url = $2
+	`, userID, avatarURL, stored)
+	return err
+}
+
+// DetectSuspects runs every heuristic over accounts created or changed within the lookback and
+// returns one flag per suspicion. Accounts duplicating an older one are related to the oldest
+// such account.
+func (r *ModerationRepo) DetectSuspects(lookback time.Duration) ([]*models.ModerationFlag, error) {
+	since := lookback.Seconds()
+	// recent is the start of the lookback, bound to $1
+	recent := `NOW() - make_interval(secs => $1)`
+	active := `u.deletion_scheduled_at IS NULL`
+	older := `o.id <> u.id AND (o.created_at, o.id) < (u.created_at, u.id)`
+	heuristics := []struct {
+		reason string
+		query  string
+		args   []interface{}
+	}{
+		{models.FlagDuplicateAvatar, `
+			SELECT DISTINCT ON (u.id) u.id, o.id, 'avatars differ by ' || ` + avatarDistance + ` || ' bits'
+			FROM users u
+			JOIN users o ON ` + older + ` AND o.avatar_hash IS NOT NULL AND ` + avatarDistance + ` <= $2
+			WHERE u.avatar_hashed_at >= ` + recent + ` AND u.avatar_hash IS NOT NULL AND ` + active + `
+			ORDER BY u.id, o.created_at
+		`, []interface{}{since, moderation.MaxAvatarDistance}},
+		{models.FlagEmailAlias, `
+			SELECT DISTINCT ON (u.id) u.id, o.id, 'both are ' || normalized_email(u.email)
+			FROM users u
+			JOIN users o ON ` + older + ` AND normalized_email(o.email) = normalized_email(u.email)
+			WHERE u.created_at >= ` + recent + ` AND ` + active + `
+			ORDER BY u.id, o.created_at
+		`, []interface{}{since}},
+		{models.FlagSignupBurst, `
+			SELECT u.id, NULL::uuid, b.accounts || ' sign-ups from ' || u.signup_ip
+			FROM users u
+			JOIN LATERAL (
+				SELECT COUNT(*) AS accounts FROM users o
+				WHERE o.signup_ip = u.signup_ip
+					AND o.created_at BETWEEN u.created_at - make_interval(secs => $2) AND u.created_at + make_interval(secs => $2)
+			) b ON TRUE
+			WHERE u.created_at >= ` + recent + ` AND u.signup_ip IS NOT NULL AND b.accounts >= $3 AND ` + active + `
+		`, []interface{}{since, moderation.SignupBurstWindow.Seconds(), moderation.SignupBurstSize}},
+		{models.FlagDuplicateBio, `
+			SELECT DISTINCT ON (u.id) u.id, o.id, ''
+			FROM users u
+			JOIN users o ON ` + older + ` AND lower(btrim(o.bio)) = lower(btrim(u.bio))
+			WHERE u.updated_at >= ` + recent + ` AND length(btrim(u.bio)) >= $2 AND ` + active + `
+			ORDER BY u.id, o.created_at
+		`, []interface{}{since, moderation.MinDuplicateBioLength}},
+		{models.FlagRapidLiking, `
+			SELECT l.user_id, NULL::uuid, COUNT(*) || ' likes in ' || EXTRACT(EPOCH FROM MAX(l.created_at) - MIN(l.created_at))::int || ' seconds'
+			FROM likes l
+			JOIN users u ON u.id = l.user_id
+			WHERE l.created_at >= ` + recent + ` AND ` + active + `
+			GROUP BY l.user_id
+			HAVING COUNT(*) >= $2
+				AND COUNT(*) FILTER (WHERE l.status = 'pass') = 0
+				AND EXTRACT(EPOCH FROM MAX(l.created_at) - MIN(l.created_at)) <= COUNT(*) * $3::float8
+		`, []interface{}{since, moderation.RapidLikingMinSwipes, moderation.RapidLikingMaxInterval.Seconds()}},
+	}
+
+	flags := make([]*models.ModerationFlag, 0)
+	for _, h := range heuristics {
+		rows, err := r.db.Query(h.query, h.args...)
+		if err != nil {
+			return nil, err
+		}
+		for rows.Next() {
+			f := &models.ModerationFlag{Reason: h.reason, Status: models.FlagOpen}
+			var relatedUserID uuid.NullUUID
+			if err := rows.Scan(&f.UserID, &relatedUserID, &f.Details); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			if relatedUserID.Valid {
+				f.RelatedUserID = &relatedUserID.UUID
+			}
+			flags = append(flags, f)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+	}
+	return flags, nil
+}
+
+// RecordFlags adds the flags to the moderation queue, skipping any raised before for the same
+// user, reason and related account whatever became of them, and optionally shadow-restricts
+// the newly flagged users. Returns how many flags were added.
+func (r *ModerationRepo) RecordFlags(flags []*models.ModerationFlag, restrict bool) (int, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	added := 0
+	for _, f := range flags {
+		result, err := tx.Exec(`
+			INSERT INTO moderation_flags (user_id, reason, related_user_id, details)
+			SELECT $1::uuid, $2, $3::uuid, $4
+			WHERE NOT EXISTS (
+				SELECT 1 FROM moderation_flags f
+				WHERE f.user_id = $1 AND f.reason = $2 AND f.related_user_id IS NOT DISTINCT FROM $3::uuid
+			)
+		`, f.UserID, f.Reason, f.RelatedUserID, f.Details)
+		if err != nil {
+			return 0, err
+		}
+		if n, _ := result.RowsAffected(); n == 0 {
+			continue
+		}
+		added++
+		if restrict {
+			if _, err := tx.Exec(`UPDATE users SET restricted_at = NOW() WHERE id = $1 AND restricted_at IS NULL`, f.UserID); err != nil {
+				return 0, err
+			}
+		}
+	}
+	return added, tx.Commit()
+}
+
+// ListOpen returns the moderation queue, oldest first
+func (r *ModerationRepo) ListOpen(limit int) ([]*models.ModerationFlag, error) {
+	rows, err := r.db.Query(`
+		SELECT `+flagColumns+`, u.restricted_at IS NOT NULL
+		FROM moderation_flags f
+		JOIN users u ON u.id = f.user_id
+		WHERE f.status = $1 AND u.deletion_scheduled_at IS NULL
+		ORDER BY f.created_at, f.id
+		LIMIT $2
+	`, models.FlagOpen, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	queue := make([]*models.ModerationFlag, 0)
+	for rows.Next() {
+		f, err := scanFlag(rows)
+		if err != nil {
+			return nil, err
+		}
+		queue = append(queue, f)
+	}
+	return queue, rows.Err()
+}
+
+// Dismiss closes an open flag as a false alarm. The user's shadow restriction is lifted unless
+// another flag against them is still open or was confirmed. Returns ErrFlagNotFound when the
+// flag is not open.
+func (r *ModerationRepo) Dismiss(id, adminID uuid.UUID) (*models.ModerationFlag, error) {
+	return r.resolve(id, adminID, models.FlagDismissed, `
+		UPDATE users SET restricted_at = NULL
+		WHERE id = $1 AND NOT EXISTS (
+			SELECT 1 FROM moderation_flags f WHERE f.user_id = $1 AND f.status IN ('open', 'confirmed')
+		)
+	`)
+}
+
+// Confirm closes an open flag as a fake account and shadow-restricts the user for good.
+// Returns ErrFlagNotFound when the flag is not open.
+func (r *ModerationRepo) Confirm(id, adminID uuid.UUID) (*models.ModerationFlag, error) {
+	return r.resolve(id, adminID, models.FlagConfirmed,
+		`UPDATE users SET restricted_at = NOW() WHERE id = $1 AND restricted_at IS NULL`)
+}
+
+// resolve moves an open flag to status and then runs restriction, bound to the flagged user
+func (r *ModerationRepo) resolve(id, adminID uuid.UUID, status, restriction string) (*models.ModerationFlag, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var userID uuid.UUID
+	err = tx.QueryRow(`
+		UPDATE moderation_flags SET status = $3, resolved_by = $4, resolved_at = NOW()
+		WHERE id = $1 AND status = $2
+		RETURNING user_id
+	`, id, models.FlagOpen, status, adminID).Scan(&userID)
+	if err == sql.ErrNoRows {
+		return nil, ErrFlagNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if _, err := tx.Exec(restriction, userID); err != nil {
+		return nil, err
+	}
+	f, err := scanFlag(tx.QueryRow(`
+		SELECT `+flagColumns+`, u.restricted_at IS NOT NULL
+		FROM moderation_flags f
+		JOIN users u ON u.id = f.user_id
+		WHERE f.id = $1
+	`, id))
+	if err != nil {
+		return nil, err
+	}
+	return f, tx.Commit()
+}
+
+// scanFlag scans flagColumns followed by whether the user is restricted
+func scanFlag(row rowScanner) (*models.ModerationFlag, error) {
+	f := &models.ModerationFlag{}
+	var relatedUserID, resolvedBy uuid.NullUUID
+	var resolvedAt sql.NullTime
+	err := row.Scan(&f.ID, &f.UserID, &f.Reason, &relatedUserID, &f.Details, &f.Status,
+		&f.CreatedAt, &resolvedBy, &resolvedAt, &f.Restricted)
+	if err != nil {
+		return nil, err
+	}
+	if relatedUserID.Valid {
+		f.RelatedUserID = &relatedUserID.UUID
+	}
+	if resolvedBy.Valid {
+		f.ResolvedBy = &resolvedBy.UUID
+	}
+	if resolvedAt.Valid {
+		f.ResolvedAt = &resolvedAt.Time
+	}
+	return f, nil
+}
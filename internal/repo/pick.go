@@ -0,0 +1,213 @@
+package repo
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/Viet-CodingStars/kyupi-kyupi-backend/internal/discovery"
+	"github.com/Viet-CodingStars/kyupi-kyupi-backend/internal/models"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+var (
+	// ErrNotPicked is returned when a pick like targets someone outside today's picks
+	ErrNotPicked = errors.New("user is not in today's picks")
+	// ErrPickLikeQuotaExceeded is returned when the user has used today's pick likes
+	ErrPickLikeQuotaExceeded = errors.New("daily pick like quota exceeded")
+)
+
+// PickRepo handles database operations for daily picks. A user's day runs from midnight to
+// midnight in their timezone.
+type PickRepo struct {
+	db *sql.DB
+}
+
+// NewPickRepo creates a new PickRepo
+func NewPickRepo(db *sql.DB) *PickRepo {
+	return &PickRepo{db: db}
+}
+
+// HasPicks reports whether the user's picks have been selected for today
+func (r *PickRepo) HasPicks(userID uuid.UUID) (bool, error) {
+	var exists bool
+	err := r.db.QueryRow(`
+		SELECT EXISTS (
+			SELECT 1 FROM users u
+			JOIN daily_pick_sets s ON s.user_id = u.id AND s.day = (NOW() AT TIME ZONE u.timezone)::date
+			WHERE u.id = $1
+		)
+	`, userID).Scan(&exists)
+	return exists, err
+}
+
+// SavePicks stores the user's picks for today, best first. If today's picks were already
+// saved, for example by a concurrent request, they are kept and picks is ignored.
+func (r *PickRepo) SavePicks(userID uuid.UUID, picks []discovery.Scored) error {
+	ids := make([]string, len(picks))
+	for i, p := range picks {
+		ids[i] = p.CandidateID.String()
+	}
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var day time.Time
+	err = tx.QueryRow(`
+		INSERT INTO daily_pick_sets (user_id, day)
+		SELECT id, (NOW() AT TIME ZONE timezone)::date FROM users WHERE id = $1
+		ON CONFLICT (user_id, day) DO NOTHING
+		RETURNING day
+	`, userID).Scan(&day)
+	if err == sql.ErrNoRows {
+		// Already saved, or the user no longer exists
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if len(ids) > 0 {
+		_, err := tx.Exec(`
+			INSERT INTO daily_picks (user_id, day, candidate_id, position)
+			SELECT $1, $2, p.candidate_id, p.position
+			FROM unnest($3::uuid[]) WITH ORDINALITY AS p(candidate_id, position)
+		`, userID, day, pq.Array(ids))
+		if err != nil {
+			return err
+		}
+	}
+	// Earlier days are no longer needed
+	if _, err := tx.Exec(`DELETE FROM daily_pick_sets WHERE user_id = $1 AND day < $2`, userID, day); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// ListPicks returns the user's picks for today in order, with the user's like or pass on each.
+// Picks who have since become hidden, paused or deleted are left out.
+func (r *PickRepo) ListPicks(userID uuid.UUID) ([]*models.DailyPick, error) {
+	query := `
+		SELECT u.id, u.name, u.gender, u.avatar_url, u.intention, u.verified_at, p.position, d.status
+		FROM users v
+		JOIN daily_picks p ON p.user_id = v.id AND p.day = (NOW() AT TIME ZONE v.timezone)::date
+		JOIN users u ON u.id = p.candidate_id
+		LEFT JOIN likes d ON d.user_id = v.id AND d.target_user_id = u.id
+		WHERE v.id = $1 AND ` + listableBy + `
+		ORDER BY p.position
+	`
+	rows, err := r.db.Query(query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	picks := make([]*models.DailyPick, 0)
+	for rows.Next() {
+		pick := &models.DailyPick{User: &models.User{}}
+		var avatarURL, intention, status sql.NullString
+		var verifiedAt sql.NullTime
+		if err := rows.Scan(&pick.User.ID, &pick.User.Name, &pick.User.Gender, &avatarURL, &intention, &verifiedAt, &pick.Position, &status); err != nil {
+			return nil, err
+		}
+		if verifiedAt.Valid {
+			pick.User.VerifiedAt = &verifiedAt.Time
+		}
+		if avatarURL.Valid {
+			pick.User.AvatarURL = avatarURL.String
+		}
+		if intention.Valid {
+			pick.User.Intention = intention.String
+		} else {
+			pick.User.Intention = models.DefaultIntention()
+		}
+		if status.Valid {
+			pick.Status = status.String
+		}
+		picks = append(picks, pick)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return picks, nil
+}
+
+// CreatePickLike likes one of today's picks and counts it against the sender's daily pick
+// like quota instead of anything else. Returns the pick likes left for the day,
+// ErrNotPicked if the target is not among today's picks, or ErrPickLikeQuotaExceeded.
+func (r *PickRepo) CreatePickLike(like *models.Like, dailyQuota int) (int, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var picked bool
+	err = tx.QueryRow(`
+		SELECT EXISTS (
+			SELECT 1 FROM users u
+			JOIN daily_picks p ON p.user_id = u.id AND p.day = (NOW() AT TIME ZONE u.timezone)::date
+			WHERE u.id = $1 AND p.candidate_id = $2
+		)
+	`, like.UserID, like.TargetUserID).Scan(&picked)
+	if err != nil {
+		return 0, err
+	}
+	if !picked {
+		return 0, ErrNotPicked
+	}
+	if dailyQuota <= 0 {
+		return 0, ErrPickLikeQuotaExceeded
+	}
+
+	var used int
+	err = tx.QueryRow(`
+		INSERT INTO pick_like_usage (user_id, day, used)
+		SELECT id, (NOW() AT TIME ZONE timezone)::date, 1 FROM users WHERE id = $1
+		ON CONFLICT (user_id, day) DO UPDATE SET used = pick_like_usage.used + 1
+		WHERE pick_like_usage.used < $2
+		RETURNING used
+	`, like.UserID, dailyQuota).Scan(&used)
+	if err == sql.ErrNoRows {
+		return 0, ErrPickLikeQuotaExceeded
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	like.Status = models.LikeStatusLike
+	if err := insertLike(tx, like); err != nil {
+		return 0, err
+	}
+	// Earlier days are no longer needed
+	if _, err := tx.Exec(`DELETE FROM pick_like_usage WHERE user_id = $1 AND day < CURRENT_DATE - 1`, like.UserID); err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return dailyQuota - used, nil
+}
+
+// PickLikeUsage returns how many picks the user liked today in their timezone and when the
+// picks and the count reset.
+func (r *PickRepo) PickLikeUsage(userID uuid.UUID) (int, time.Time, error) {
+	query := `
+		SELECT COALESCE(s.used, 0), (((NOW() AT TIME ZONE u.timezone)::date + 1)::timestamp AT TIME ZONE u.timezone)
+		FROM users u
+		LEFT JOIN pick_like_usage s ON s.user_id = u.id AND s.day = (NOW() AT TIME ZONE u.timezone)::date
+		WHERE u.id = $1
+	`
+	var used int
+	var resetsAt time.Time
+	err := r.db.QueryRow(query, userID).Scan(&used, &resetsAt)
+	if err == sql.ErrNoRows {
+		return 0, time.Time{}, ErrUserNotFound
+	}
+	return used, resetsAt, err
+}
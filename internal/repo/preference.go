@@ -0,0 +1,163 @@
+package repo
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/Viet-CodingStars/kyupi-kyupi-backend/internal/models"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// PreferenceRepo handles database operations for discovery filters
+type PreferenceRepo struct {
+	db *sql.DB
+}
+
+// NewPreferenceRepo creates a new PreferenceRepo
+func NewPreferenceRepo(db *sql.DB) *PreferenceRepo {
+	return &PreferenceRepo{db: db}
+}
+
+// preferenceColumns selects the preferences in user_preferences up
+const preferenceColumns = `up.age_min, up.age_max, up.max_distance_km, up.height_min_cm, up.height_max_cm,
+	up.educations, up.languages, up.religions, up.drinking, up.smoking, up.children, up.dealbreakers, up.verified_only, up.updated_at`
+
+// preferenceRow holds scanned preferenceColumns, which are all NULL when the user has none
+type preferenceRow struct {
+	ageMin, ageMax, maxDistanceKm, heightMinCm, heightMaxCm sql.NullInt64
+	educations, languages, religions                        pq.StringArray
+	drinking, smoking, children, dealbreakers               pq.StringArray
+	verifiedOnly                                            sql.NullBool
+	updatedAt                                               sql.NullTime
+}
+
+func (r *preferenceRow) dest() []interface{} {
+	return []interface{}{
+		&r.ageMin, &r.ageMax, &r.maxDistanceKm, &r.heightMinCm, &r.heightMaxCm,
+		&r.educations, &r.languages, &r.religions, &r.drinking, &r.smoking, &r.children, &r.dealbreakers, &r.verifiedOnly, &r.updatedAt,
+	}
+}
+
+// saved reports whether the row held a user's preferences rather than NULLs from an outer join
+func (r *preferenceRow) saved() bool {
+	return r.updatedAt.Valid
+}
+
+func (r *preferenceRow) preferences() *models.Preferences {
+	optional := func(v sql.NullInt64) *int {
+		if !v.Valid {
+			return nil
+		}
+		val := int(v.Int64)
+		return &val
+	}
+	list := func(values pq.StringArray) []string {
+		if values == nil {
+			return []string{}
+		}
+		return values
+	}
+	prefs := &models.Preferences{
+		AgeMin:        optional(r.ageMin),
+		AgeMax:        optional(r.ageMax),
+		MaxDistanceKm: optional(r.maxDistanceKm),
+		HeightMinCm:   optional(r.heightMinCm),
+		HeightMaxCm:   optional(r.heightMaxCm),
+		Educations:    list(r.educations),
+		Languages:     list(r.languages),
+		Religions:     list(r.religions),
+		Drinking:      list(r.drinking),
+		Smoking:       list(r.smoking),
+		Children:      list(r.children),
+		Dealbreakers:  list(r.dealbreakers),
+		VerifiedOnly:  r.verifiedOnly.Bool,
+	}
+	if r.updatedAt.Valid {
+		prefs.UpdatedAt = &r.updatedAt.Time
+	}
+	return prefs
+}
+
+// Get returns the user's preferences, empty if they have not saved any
+func (r *PreferenceRepo) Get(userID uuid.UUID) (*models.Preferences, error) {
+	var row preferenceRow
+	err := r.db.QueryRow(`SELECT `+preferenceColumns+` FROM user_preferences up WHERE up.user_id = $1`, userID).Scan(row.dest()...)
+	if err == sql.ErrNoRows {
+		return row.preferences(), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return row.preferences(), nil
+}
+
+// Save replaces the user's preferences and drops their cached discovery ranking so the next
+// feed request applies them.
+func (r *PreferenceRepo) Save(userID uuid.UUID, prefs *models.Preferences) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	list := func(values []string) interface{} {
+		if values == nil {
+			values = []string{}
+		}
+		return pq.Array(values)
+	}
+	var updatedAt sql.NullTime
+	err = tx.QueryRow(`
+		INSERT INTO user_preferences (user_id, age_min, age_max, max_distance_km, height_min_cm, height_max_cm,
+			educations, languages, religions, drinking, smoking, children, dealbreakers, verified_only, updated_at)
+		SELECT id, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, NOW() FROM users WHERE id = $1
+		ON CONFLICT (user_id) DO UPDATE SET
+			age_min = EXCLUDED.age_min, age_max = EXCLUDED.age_max, max_distance_km = EXCLUDED.max_distance_km,
+			height_min_cm = EXCLUDED.height_min_cm, height_max_cm = EXCLUDED.height_max_cm,
+			educations = EXCLUDED.educations, languages = EXCLUDED.languages, religions = EXCLUDED.religions,
+			drinking = EXCLUDED.drinking, smoking = EXCLUDED.smoking, children = EXCLUDED.children,
+			dealbreakers = EXCLUDED.dealbreakers, verified_only = EXCLUDED.verified_only, updated_at = NOW()
+		RETURNING updated_at
+	`, userID, prefs.AgeMin, prefs.AgeMax, prefs.MaxDistanceKm, prefs.HeightMinCm, prefs.HeightMaxCm,
+		list(prefs.Educations), list(prefs.Languages), list(prefs.Religions),
+		list(prefs.Drinking), list(prefs.Smoking), list(prefs.Children), list(prefs.Dealbreakers),
+		prefs.VerifiedOnly,
+	).Scan(&updatedAt)
+	if err == sql.ErrNoRows {
+		return ErrUserNotFound
+	}
+	if err != nil {
+		return err
+	}
+	prefs.UpdatedAt = &updatedAt.Time
+
+	if _, err := tx.Exec(`DELETE FROM discovery_caches WHERE user_id = $1`, userID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// dealbreakersMet is the condition for the user aliased other to meet every dealbreaker of
+// the user aliased chooser, and to be verified if chooser only wants verified users. A filter
+// on an attribute other has not filled in is not applied.
+func dealbreakersMet(chooser, other string) string {
+	return fmt.Sprintf(`NOT EXISTS (
+		SELECT 1 FROM user_preferences p WHERE p.user_id = %[1]s.id AND (
+			(p.verified_only AND %[2]s.verified_at IS NULL)
+			OR ('age' = ANY(p.dealbreakers) AND (date_part('year', age(%[2]s.birth_date)) < p.age_min OR date_part('year', age(%[2]s.birth_date)) > p.age_max))
+			OR ('distance' = ANY(p.dealbreakers) AND 2 * 6371 * asin(least(1, sqrt(
+				power(sin(radians(%[2]s.latitude - %[1]s.latitude) / 2), 2)
+				+ cos(radians(%[1]s.latitude)) * cos(radians(%[2]s.latitude)) * power(sin(radians(%[2]s.longitude - %[1]s.longitude) / 2), 2)
+			))) > p.max_distance_km)
+			OR ('height' = ANY(p.dealbreakers) AND (%[2]s.height_cm < p.height_min_cm OR %[2]s.height_cm > p.height_max_cm))
+			OR ('education' = ANY(p.dealbreakers) AND cardinality(p.educations) > 0 AND %[2]s.education <> ALL(p.educations))
+			OR ('languages' = ANY(p.dealbreakers) AND cardinality(p.languages) > 0 AND cardinality(%[2]s.languages) > 0
+				AND NOT (%[2]s.languages && p.languages))
+			OR ('religion' = ANY(p.dealbreakers) AND cardinality(p.religions) > 0 AND %[2]s.religion <> ALL(p.religions))
+			OR ('drinking' = ANY(p.dealbreakers) AND cardinality(p.drinking) > 0 AND %[2]s.drinking <> ALL(p.drinking))
+			OR ('smoking' = ANY(p.dealbreakers) AND cardinality(p.smoking) > 0 AND %[2]s.smoking <> ALL(p.smoking))
+			OR ('children' = ANY(p.dealbreakers) AND cardinality(p.children) > 0 AND %[2]s.children <> ALL(p.children))
+		)
+	)`, chooser, other)
+}
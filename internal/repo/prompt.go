@@ -0,0 +1,140 @@
+package repo
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+
+	"github.com/Viet-CodingStars/kyupi-kyupi-backend/internal/models"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// ErrUnknownPrompt is returned when a user answers a prompt that is not in the active catalog
+var ErrUnknownPrompt = errors.New("unknown prompt")
+
+// PromptRepo handles database operations for the prompt catalog and users' answers
+type PromptRepo struct {
+	db *sql.DB
+}
+
+// NewPromptRepo creates a new PromptRepo
+func NewPromptRepo(db *sql.DB) *PromptRepo {
+	return &PromptRepo{db: db}
+}
+
+// promptColumns selects a models.Prompt from prompts p
+const promptColumns = `p.id, p.texts, p.active, p.created_at`
+
+func scanPrompt(row rowScanner, p *models.Prompt, extra ...interface{}) error {
+	var texts []byte
+	dest := append([]interface{}{&p.ID, &texts, &p.Active, &p.CreatedAt}, extra...)
+	if err := row.Scan(dest...); err != nil {
+		return err
+	}
+	return json.Unmarshal(texts, &p.Texts)
+}
+
+// List returns the active prompts ordered by ID
+func (r *PromptRepo) List() ([]*models.Prompt, error) {
+	rows, err := r.db.Query(`SELECT ` + promptColumns + ` FROM prompts p WHERE p.active ORDER BY p.id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	prompts := make([]*models.Prompt, 0)
+	for rows.Next() {
+		p := &models.Prompt{}
+		if err := scanPrompt(rows, p); err != nil {
+			return nil, err
+		}
+		prompts = append(prompts, p)
+	}
+	return prompts, rows.Err()
+}
+
+// ListAnswers returns the user's answers to active prompts in profile order, each with its prompt
+func (r *PromptRepo) ListAnswers(userID uuid.UUID) ([]*models.PromptAnswer, error) {
+	rows, err := r.db.Query(`
+		SELECT `+promptColumns+`, up.answer, up.position, up.updated_at
+		FROM user_prompts up JOIN prompts p ON p.id = up.prompt_id
+		WHERE up.user_id = $1 AND p.active
+		ORDER BY up.position
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	answers := make([]*models.PromptAnswer, 0)
+	for rows.Next() {
+		a := &models.PromptAnswer{Prompt: &models.Prompt{}}
+		if err := scanPrompt(rows, a.Prompt, &a.Answer, &a.Position, &a.UpdatedAt); err != nil {
+			return nil, err
+		}
+		a.PromptID = a.Prompt.ID
+		answers = append(answers, a)
+	}
+	return answers, rows.Err()
+}
+
+// SetAnswers replaces the user's answers, positioned in the given order. Every prompt must be
+// active. Answers that did not change keep their updated_at.
+func (r *PromptRepo) SetAnswers(userID uuid.UUID, answers []*models.PromptAnswer) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var exists bool
+	if err := tx.QueryRow(`SELECT EXISTS(SELECT 1 FROM users WHERE id = $1)`, userID).Scan(&exists); err != nil {
+		return err
+	}
+	if !exists {
+		return ErrUserNotFound
+	}
+
+	promptIDs := make([]string, len(answers))
+	texts := make([]string, len(answers))
+	for i, a := range answers {
+		promptIDs[i] = a.PromptID
+		texts[i] = a.Answer
+	}
+	if _, err := tx.Exec(`DELETE FROM user_prompts WHERE user_id = $1 AND prompt_id <> ALL($2)`, userID, pq.Array(promptIDs)); err != nil {
+		return err
+	}
+	result, err := tx.Exec(`
+		INSERT INTO user_prompts (user_id, prompt_id, answer, position)
+		SELECT $1, a.prompt_id, a.answer, a.position
+		FROM unnest($2::text[], $3::text[]) WITH ORDINALITY AS a(prompt_id, answer, position)
+		JOIN prompts p ON p.id = a.prompt_id AND p.active
+		ON CONFLICT (user_id, prompt_id) DO UPDATE SET
+			answer = EXCLUDED.answer, position = EXCLUDED.position,
+			updated_at = CASE WHEN user_prompts.answer = EXCLUDED.answer THEN user_prompts.updated_at ELSE NOW() END
+	`, userID, pq.Array(promptIDs), pq.Array(texts))
+	if err != nil {
+		return err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if int(n) != len(answers) {
+		return ErrUnknownPrompt
+	}
+	return tx.Commit()
+}
+
+// HasAnswered reports whether the user has an answer to the active prompt on their profile
+func (r *PromptRepo) HasAnswered(userID uuid.UUID, promptID string) (bool, error) {
+	var answered bool
+	err := r.db.QueryRow(`
+		SELECT EXISTS(
+			SELECT 1 FROM user_prompts up JOIN prompts p ON p.id = up.prompt_id
+			WHERE up.user_id = $1 AND up.prompt_id = $2 AND p.active
+		)
+	`, userID, promptID).Scan(&answered)
+	return answered, err
+}
@@ -0,0 +1,167 @@
+package repo
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+
+	"github.com/Viet-CodingStars/kyupi-kyupi-backend/internal/models"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+var (
+	ErrQuestionNotFound = errors.New("question not found")
+	ErrAnswerNotFound   = errors.New("answer not found")
+)
+
+// QuestionRepo handles database operations for the compatibility question bank and users' answers
+type QuestionRepo struct {
+	db *sql.DB
+}
+
+// NewQuestionRepo creates a new QuestionRepo
+func NewQuestionRepo(db *sql.DB) *QuestionRepo {
+	return &QuestionRepo{db: db}
+}
+
+// questionColumns selects a models.Question from questions q
+const questionColumns = `q.id, q.texts, q.options, q.active, q.created_at`
+
+func scanQuestion(row rowScanner, q *models.Question, extra ...interface{}) error {
+	var texts, options []byte
+	dest := append([]interface{}{&q.ID, &texts, &options, &q.Active, &q.CreatedAt}, extra...)
+	if err := row.Scan(dest...); err != nil {
+		return err
+	}
+	if err := json.Unmarshal(texts, &q.Texts); err != nil {
+		return err
+	}
+	return json.Unmarshal(options, &q.Options)
+}
+
+// List returns the active questions ordered by ID
+func (r *QuestionRepo) List() ([]*models.Question, error) {
+	rows, err := r.db.Query(`SELECT ` + questionColumns + ` FROM questions q WHERE q.active ORDER BY q.id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	questions := make([]*models.Question, 0)
+	for rows.Next() {
+		q := &models.Question{}
+		if err := scanQuestion(rows, q); err != nil {
+			return nil, err
+		}
+		questions = append(questions, q)
+	}
+	return questions, rows.Err()
+}
+
+// Get returns an active question
+func (r *QuestionRepo) Get(questionID string) (*models.Question, error) {
+	q := &models.Question{}
+	err := scanQuestion(r.db.QueryRow(`SELECT `+questionColumns+` FROM questions q WHERE q.id = $1 AND q.active`, questionID), q)
+	if err == sql.ErrNoRows {
+		return nil, ErrQuestionNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return q, nil
+}
+
+// ListAnswers returns the user's answers to active questions in byte order of question ID, as
+// discovery.Compatibility expects, each with its question
+func (r *QuestionRepo) ListAnswers(userID uuid.UUID) ([]*models.QuestionAnswer, error) {
+	rows, err := r.db.Query(`
+		SELECT `+questionColumns+`, qa.answer, qa.accepted, qa.importance, qa.updated_at
+		FROM question_answers qa JOIN questions q ON q.id = qa.question_id
+		WHERE qa.user_id = $1 AND q.active
+		ORDER BY q.id COLLATE "C"
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	answers := make([]*models.QuestionAnswer, 0)
+	for rows.Next() {
+		a := &models.QuestionAnswer{Question: &models.Question{}}
+		if err := scanQuestion(rows, a.Question, &a.Answer, &a.Accepted, &a.Importance, &a.UpdatedAt); err != nil {
+			return nil, err
+		}
+		a.QuestionID = a.Question.ID
+		answers = append(answers, a)
+	}
+	return answers, rows.Err()
+}
+
+// SaveAnswer records or replaces the user's answer to a question
+func (r *QuestionRepo) SaveAnswer(userID uuid.UUID, answer *models.QuestionAnswer) error {
+	err := r.db.QueryRow(`
+		INSERT INTO question_answers (user_id, question_id, answer, accepted, importance, updated_at)
+		SELECT id, $2, $3, $4, $5, NOW() FROM users WHERE id = $1
+		ON CONFLICT (user_id, question_id) DO UPDATE SET
+			answer = EXCLUDED.answer, accepted = EXCLUDED.accepted, importance = EXCLUDED.importance, updated_at = NOW()
+		RETURNING updated_at
+	`, userID, answer.QuestionID, answer.Answer, answer.Accepted, answer.Importance).Scan(&answer.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return ErrUserNotFound
+	}
+	return err
+}
+
+// DeleteAnswer removes the user's answer to a question
+func (r *QuestionRepo) DeleteAnswer(userID uuid.UUID, questionID string) error {
+	result, err := r.db.Exec(`DELETE FROM question_answers WHERE user_id = $1 AND question_id = $2`, userID, questionID)
+	if err != nil {
+		return err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrAnswerNotFound
+	}
+	return nil
+}
+
+// answerColumns selects u's answers to active questions as parallel arrays in byte order of
+// question ID, given answerJoin
+const answerColumns = `qa.question_ids, qa.answers, qa.accepted, qa.importance`
+
+// answerJoin aggregates u's answers as qa
+const answerJoin = `LEFT JOIN LATERAL (
+		SELECT array_agg(ans.question_id ORDER BY ans.question_id COLLATE "C") AS question_ids,
+			array_agg(ans.answer ORDER BY ans.question_id COLLATE "C") AS answers,
+			array_agg(ans.accepted ORDER BY ans.question_id COLLATE "C") AS accepted,
+			array_agg(ans.importance ORDER BY ans.question_id COLLATE "C") AS importance
+		FROM question_answers ans JOIN questions aq ON aq.id = ans.question_id AND aq.active
+		WHERE ans.user_id = u.id
+	) qa ON TRUE`
+
+// answerRow holds scanned answerColumns
+type answerRow struct {
+	questionIDs                    pq.StringArray
+	answers, accepted, importances pq.Int64Array
+}
+
+func (r *answerRow) dest() []interface{} {
+	return []interface{}{&r.questionIDs, &r.answers, &r.accepted, &r.importances}
+}
+
+func (r *answerRow) list() []models.QuestionAnswer {
+	answers := make([]models.QuestionAnswer, len(r.questionIDs))
+	for i, id := range r.questionIDs {
+		answers[i] = models.QuestionAnswer{
+			QuestionID: id,
+			Answer:     int(r.answers[i]),
+			Accepted:   uint8(r.accepted[i]),
+			Importance: int(r.importances[i]),
+		}
+	}
+	return answers
+}
@@ -0,0 +1,127 @@
+package repo
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/Viet-CodingStars/kyupi-kyupi-backend/internal/models"
+	"github.com/google/uuid"
+)
+
+var ErrSessionNotFound = errors.New("session not found or revoked")
+
+// sessionTouchInterval limits how often last_seen_at is written for a busy session.
+const sessionTouchInterval = time.Minute
+
+// SessionRepo handles database operations for signed-in sessions
+type SessionRepo struct {
+	db *sql.DB
+}
+
+// NewSessionRepo creates a new SessionRepo
+func NewSessionRepo(db *sql.DB) *SessionRepo {
+	return &SessionRepo{db: db}
+}
+
+// Create records a new session. The user's expired sessions are purged on the way.
+func (r *SessionRepo) Create(session *models.Session) error {
+	if _, err := r.db.Exec(`DELETE FROM sessions WHERE user_id = $1 AND expires_at < NOW()`, session.UserID); err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO sessions (user_id, device_name, user_agent, ip_address, expires_at)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at, last_seen_at
+	`
+	return r.db.QueryRow(query,
+		session.UserID,
+		sql.NullString{String: session.DeviceName, Valid: session.DeviceName != ""},
+		sql.NullString{String: session.UserAgent, Valid: session.UserAgent != ""},
+		sql.NullString{String: session.IPAddress, Valid: session.IPAddress != ""},
+		session.ExpiresAt,
+	).Scan(&session.ID, &session.CreatedAt, &session.LastSeenAt)
+}
+
+// Touch checks that a session is active and records activity on it
+func (r *SessionRepo) Touch(sessionID, userID uuid.UUID) error {
+	var due bool
+	err := r.db.QueryRow(`
+		SELECT last_seen_at < NOW() - make_interval(secs => $3) FROM sessions
+		WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL AND expires_at > NOW()
+	`, sessionID, userID, sessionTouchInterval.Seconds()).Scan(&due)
+	if err == sql.ErrNoRows {
+		return ErrSessionNotFound
+	}
+	if err != nil || !due {
+		return err
+	}
+
+	_, err = r.db.Exec(`UPDATE sessions SET last_seen_at = NOW() WHERE id = $1`, sessionID)
+	return err
+}
+
+// ListActive returns the user's unrevoked, unexpired sessions, most recently used first
+func (r *SessionRepo) ListActive(userID uuid.UUID) ([]*models.Session, error) {
+	query := `
+		SELECT id, user_id, device_name, user_agent, ip_address, created_at, last_seen_at, expires_at
+		FROM sessions
+		WHERE user_id = $1 AND revoked_at IS NULL AND expires_at > NOW()
+		ORDER BY last_seen_at DESC
+	`
+	rows, err := r.db.Query(query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sessions := make([]*models.Session, 0)
+	for rows.Next() {
+		session := &models.Session{}
+		var deviceName, userAgent, ipAddress sql.NullString
+		err := rows.Scan(
+			&session.ID, &session.UserID, &deviceName, &userAgent, &ipAddress,
+			&session.CreatedAt, &session.LastSeenAt, &session.ExpiresAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		session.DeviceName = deviceName.String
+		session.UserAgent = userAgent.String
+		session.IPAddress = ipAddress.String
+		sessions = append(sessions, session)
+	}
+	return sessions, rows.Err()
+}
+
+// Revoke ends one of the user's active sessions
+func (r *SessionRepo) Revoke(userID, sessionID uuid.UUID) error {
+	result, err := r.db.Exec(`
+		UPDATE sessions SET revoked_at = NOW()
+		WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL
+	`, sessionID, userID)
+	if err != nil {
+		return err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrSessionNotFound
+	}
+	return nil
+}
+
+// RevokeAllExcept ends every active session of the user other than keepID and returns how many were revoked
+func (r *SessionRepo) RevokeAllExcept(userID, keepID uuid.UUID) (int64, error) {
+	result, err := r.db.Exec(`
+		UPDATE sessions SET revoked_at = NOW()
+		WHERE user_id = $1 AND id <> $2 AND revoked_at IS NULL
+	`, userID, keepID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
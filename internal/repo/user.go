@@ -8,11 +8,14 @@ import (
 
   "github.com/Viet-CodingStars/kyupi-kyupi-backend/internal/models"
   "github.com/google/uuid"
+  "github.com/lib/pq"
 )
 
 var (
   ErrUserNotFound       = errors.New("user not found")
   ErrEmailAlreadyExists = errors.New("email already exists")
+  ErrDeletionInProgress = errors.New("account deletion already in progress")
+  ErrNotPaused          = errors.New("account is not paused")
 )
 
 // UserRepo handles database operations for users
@@ -30,10 +33,16 @@ func (r *UserRepo) Create(user *models.User) error {
   if user.Intention == "" {
     user.Intention = models.DefaultIntention()
   }
+  if user.Visibility == "" {
+    user.Visibility = models.DefaultVisibility()
+  }
+  if user.Timezone == "" {
+    user.Timezone = models.DefaultTimezone
+  }
 
   query := `
-    INSERT INTO users (email, password_hash, name, gender, birth_date, target_gender, intention, created_at, updated_at)
-    VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+    INSERT INTO users (email, password_hash, name, gender, birth_date, target_gender, intention, visibility, timezone, signup_ip, created_at, updated_at)
+    VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, NULLIF($10, ''), $11, $12)
     RETURNING id
   `
   now := time.Now()
@@ -49,7 +58,7 @@ func (r *UserRepo) Create(user *models.User) error {
 
   err := r.db.QueryRow(query,
     user.Email, user.PasswordHash, user.Name, user.Gender, user.BirthDate,
-    targetGender, user.Intention, user.CreatedAt, user.UpdatedAt,
+    targetGender, user.Intention, user.Visibility, user.Timezone, user.SignupIP, user.CreatedAt, user.UpdatedAt,
   ).Scan(&user.ID)
 
   if err != nil {
@@ -61,11 +70,24 @@ func (r *UserRepo) Create(user *models.User) error {
   return nil
 }
 
+// GetRole returns the user's role, such as models.RoleAdmin
+func (r *UserRepo) GetRole(id uuid.UUID) (string, error) {
+  var role string
+  err := r.db.QueryRow(`SELECT role FROM users WHERE id = $1`, id).Scan(&role)
+  if err == sql.ErrNoRows {
+    return "", ErrUserNotFound
+  }
+  if err != nil {
+    return "", err
+  }
+  return role, nil
+}
+
 // GetByEmail retrieves a user by email
 func (r *UserRepo) GetByEmail(email string) (*models.User, error) {
   query := `
-    SELECT id, email, password_hash, name, gender, birth_date, target_gender, intention, bio, avatar_url, created_at, updated_at
-    FROM users WHERE email = $1
+    SELECT id, email, password_hash, name, gender, birth_date, target_gender, intention, visibility, timezone, bio, avatar_url, latitude, longitude, created_at, updated_at, deletion_scheduled_at, paused_at, resume_at, ` + attributeColumns + `
+    FROM users u WHERE u.email = $1
   `
   user := &models.User{}
 
@@ -73,12 +95,16 @@ func (r *UserRepo) GetByEmail(email string) (*models.User, error) {
   var intention sql.NullString
   var bio sql.NullString
   var avatarURL sql.NullString
+  var latitude, longitude sql.NullFloat64
+  var deletionScheduledAt sql.NullTime
+  var pausedAt, resumeAt sql.NullTime
+  var attrs profileAttributes
 
-  err := r.db.QueryRow(query, email).Scan(
+  err := r.db.QueryRow(query, email).Scan(append([]interface{}{
     &user.ID, &user.Email, &user.PasswordHash, &user.Name, &user.Gender,
-    &user.BirthDate, &targetGender, &intention, &bio, &avatarURL,
-    &user.CreatedAt, &user.UpdatedAt,
-  )
+    &user.BirthDate, &targetGender, &intention, &user.Visibility, &user.Timezone, &bio, &avatarURL, &latitude, &longitude,
+    &user.CreatedAt, &user.UpdatedAt, &deletionScheduledAt, &pausedAt, &resumeAt,
+  }, attrs.dest()...)...)
   if err == sql.ErrNoRows {
     return nil, ErrUserNotFound
   }
@@ -101,6 +127,20 @@ func (r *UserRepo) GetByEmail(email string) (*models.User, error) {
   if avatarURL.Valid {
     user.AvatarURL = avatarURL.String
   }
+  if latitude.Valid && longitude.Valid {
+    user.Latitude = &latitude.Float64
+    user.Longitude = &longitude.Float64
+  }
+  if deletionScheduledAt.Valid {
+    user.DeletionScheduledAt = &deletionScheduledAt.Time
+  }
+  if pausedAt.Valid {
+    user.PausedAt = &pausedAt.Time
+  }
+  if resumeAt.Valid {
+    user.ResumeAt = &resumeAt.Time
+  }
+  attrs.apply(user)
 
   return user, nil
 }
@@ -108,8 +148,8 @@ func (r *UserRepo) GetByEmail(email string) (*models.User, error) {
 // GetByID retrieves a user by ID
 func (r *UserRepo) GetByID(id uuid.UUID) (*models.User, error) {
   query := `
-    SELECT id, email, password_hash, name, gender, birth_date, target_gender, intention, bio, avatar_url, created_at, updated_at
-    FROM users WHERE id = $1
+    SELECT id, email, password_hash, name, gender, birth_date, target_gender, intention, visibility, timezone, bio, avatar_url, latitude, longitude, created_at, updated_at, deletion_scheduled_at, paused_at, resume_at, ` + attributeColumns + `
+    FROM users u WHERE u.id = $1
   `
   user := &models.User{}
 
@@ -117,12 +157,16 @@ func (r *UserRepo) GetByID(id uuid.UUID) (*models.User, error) {
   var intention sql.NullString
   var bio sql.NullString
   var avatarURL sql.NullString
+  var latitude, longitude sql.NullFloat64
+  var deletionScheduledAt sql.NullTime
+  var pausedAt, resumeAt sql.NullTime
+  var attrs profileAttributes
 
-  err := r.db.QueryRow(query, id).Scan(
+  err := r.db.QueryRow(query, id).Scan(append([]interface{}{
     &user.ID, &user.Email, &user.PasswordHash, &user.Name, &user.Gender,
-    &user.BirthDate, &targetGender, &intention, &bio, &avatarURL,
-    &user.CreatedAt, &user.UpdatedAt,
-  )
+    &user.BirthDate, &targetGender, &intention, &user.Visibility, &user.Timezone, &bio, &avatarURL, &latitude, &longitude,
+    &user.CreatedAt, &user.UpdatedAt, &deletionScheduledAt, &pausedAt, &resumeAt,
+  }, attrs.dest()...)...)
   if err == sql.ErrNoRows {
     return nil, ErrUserNotFound
   }
@@ -145,22 +189,49 @@ func (r *UserRepo) GetByID(id uuid.UUID) (*models.User, error) {
   if avatarURL.Valid {
     user.AvatarURL = avatarURL.String
   }
+  if latitude.Valid && longitude.Valid {
+    user.Latitude = &latitude.Float64
+    user.Longitude = &longitude.Float64
+  }
+  if deletionScheduledAt.Valid {
+    user.DeletionScheduledAt = &deletionScheduledAt.Time
+  }
+  if pausedAt.Valid {
+    user.PausedAt = &pausedAt.Time
+  }
+  if resumeAt.Valid {
+    user.ResumeAt = &resumeAt.Time
+  }
+  attrs.apply(user)
 
   return user, nil
 }
 
-// Update updates a user's information
+// Update updates a user's information. Changing the avatar clears verified_at, since the
+// verification selfie was compared with the old one.
 func (r *UserRepo) Update(user *models.User) error {
   if user.Intention == "" {
     user.Intention = models.DefaultIntention()
   }
+  if user.Visibility == "" {
+    user.Visibility = models.DefaultVisibility()
+  }
+  if user.Timezone == "" {
+    user.Timezone = models.DefaultTimezone
+  }
 
   query := `
     UPDATE users
-    SET name = $1, gender = $2, birth_date = $3, bio = $4, avatar_url = $5, target_gender = $6, intention = $7, updated_at = NOW()
-    WHERE id = $8
-    RETURNING updated_at
+    SET name = $1, gender = $2, birth_date = $3, bio = $4, avatar_url = $5, target_gender = $6, intention = $7, visibility = $8, timezone = $9, latitude = $10, longitude = $11,
+      height_cm = $12, education = $13, job_title = $14, languages = $15, religion = $16, drinking = $17, smoking = $18, children = $19, updated_at = NOW(),
+      verified_at = CASE WHEN avatar_url IS DISTINCT FROM $5 THEN NULL ELSE verified_at END
+    WHERE id = $20
+    RETURNING updated_at, verified_at
   `
+  languages := user.Languages
+  if languages == nil {
+    languages = []string{}
+  }
   var targetGender interface{}
   if user.TargetGender != nil && models.IsValidGender(*user.TargetGender) {
     targetGender = *user.TargetGender
@@ -168,82 +239,104 @@ func (r *UserRepo) Update(user *models.User) error {
     targetGender = nil
   }
 
+  var verifiedAt sql.NullTime
   err := r.db.QueryRow(query,
     user.Name, user.Gender, user.BirthDate, user.Bio, user.AvatarURL,
-    targetGender, user.Intention, user.ID,
-  ).Scan(&user.UpdatedAt)
+    targetGender, user.Intention, user.Visibility, user.Timezone, user.Latitude, user.Longitude,
+    user.HeightCm, optionalString(user.Education), optionalString(user.JobTitle), pq.Array(languages),
+    optionalString(user.Religion), optionalString(user.Drinking), optionalString(user.Smoking), optionalString(user.Children), user.ID,
+  ).Scan(&user.UpdatedAt, &verifiedAt)
 
   if err == sql.ErrNoRows {
     return ErrUserNotFound
   }
-  return err
-}
-
-// GetUsers retrieves a paginated list of users with cursor-based pagination
-func (r *UserRepo) GetUsers(cursor *uuid.UUID, limit int) ([]*models.User, error) {
-  var query string
-  var args []interface{}
-
-  if cursor != nil {
-    query = `
-      SELECT id, name, gender, avatar_url, intention
-      FROM users
-      WHERE id > $1
-      ORDER BY id
-      LIMIT $2
-    `
-    args = []interface{}{cursor, limit}
-  } else {
-    query = `
-      SELECT id, name, gender, avatar_url, intention
-      FROM users
-      ORDER BY id
-      LIMIT $1
-    `
-    args = []interface{}{limit}
-  }
-
-  rows, err := r.db.Query(query, args...)
   if err != nil {
-    return nil, err
+    return err
   }
-  defer rows.Close()
+  user.VerifiedAt = nil
+  if verifiedAt.Valid {
+    user.VerifiedAt = &verifiedAt.Time
+  }
+  return nil
+}
 
-  users := make([]*models.User, 0)
-  for rows.Next() {
-    user := &models.User{}
-    var avatarURL sql.NullString
-    var intention sql.NullString
+// attributeColumns selects the optional profile attributes of u and when its photos were verified
+const attributeColumns = `u.height_cm, u.education, u.job_title, u.languages, u.religion, u.drinking, u.smoking, u.children, u.verified_at`
 
-    err := rows.Scan(&user.ID, &user.Name, &user.Gender, &avatarURL, &intention)
-    if err != nil {
-      return nil, err
-    }
+// profileAttributes holds scanned attributeColumns until they are copied to a user
+type profileAttributes struct {
+  heightCm                                                   sql.NullInt64
+  education, jobTitle, religion, drinking, smoking, children sql.NullString
+  languages                                                  pq.StringArray
+  verifiedAt                                                 sql.NullTime
+}
 
-    if avatarURL.Valid {
-      user.AvatarURL = avatarURL.String
-    }
-    if intention.Valid {
-      user.Intention = intention.String
-    } else {
-      user.Intention = models.DefaultIntention()
-    }
+func (a *profileAttributes) dest() []interface{} {
+  return []interface{}{&a.heightCm, &a.education, &a.jobTitle, &a.languages, &a.religion, &a.drinking, &a.smoking, &a.children, &a.verifiedAt}
+}
 
-    users = append(users, user)
+func (a *profileAttributes) apply(user *models.User) {
+  if a.heightCm.Valid {
+    val := int(a.heightCm.Int64)
+    user.HeightCm = &val
   }
-
-  if err = rows.Err(); err != nil {
-    return nil, err
+  user.Education = a.education.String
+  user.JobTitle = a.jobTitle.String
+  user.Languages = a.languages
+  user.Religion = a.religion.String
+  user.Drinking = a.drinking.String
+  user.Smoking = a.smoking.String
+  user.Children = a.children.String
+  if a.verifiedAt.Valid {
+    user.VerifiedAt = &a.verifiedAt.Time
   }
+}
+
+// optionalString stores an empty attribute as NULL
+func optionalString(s string) sql.NullString {
+  return sql.NullString{String: s, Valid: s != ""}
+}
 
-  return users, nil
+// HasPhoto reports whether photoURL is one of the user's photos. The avatar is the only photo
+// for now.
+func (r *UserRepo) HasPhoto(userID uuid.UUID, photoURL string) (bool, error) {
+  var exists bool
+  err := r.db.QueryRow(`
+    SELECT EXISTS (SELECT 1 FROM users WHERE id = $1 AND avatar_url = $2)
+  `, userID, photoURL).Scan(&exists)
+  return exists, err
 }
 
-// GetUserDetail retrieves detailed information about a user by ID
-func (r *UserRepo) GetUserDetail(id uuid.UUID) (*models.User, error) {
+// listableBy is the condition for a user u to appear in lists shown to the viewer bound to $1.
+// Deleted and paused accounts never appear, and shadow-restricted ones appear only to themselves;
+// incognito profiles appear only to people they liked.
+const listableBy = `
+  u.deletion_scheduled_at IS NULL AND u.paused_at IS NULL AND (u.restricted_at IS NULL OR u.id = $1) AND (
+    u.id = $1
+    OR u.visibility = 'members'
+    OR (u.visibility = 'incognito' AND EXISTS (
+      SELECT 1 FROM likes l
+      WHERE l.user_id = u.id AND l.target_user_id = $1 AND l.status IN ('like', 'super_like')
+    ))
+  )`
+
+// visibleTo is the condition for the viewer bound to $1 to open user u's profile: anything
+// listable, plus the profiles of the viewer's matches whatever their visibility or pause state.
+const visibleTo = `
+  u.deletion_scheduled_at IS NULL AND (
+    (` + listableBy + `)
+    OR EXISTS (
+      SELECT 1 FROM matches m
+      WHERE (m.user1_id = u.id AND m.user2_id = $1) OR (m.user1_id = $1 AND m.user2_id = u.id)
+    )
+  )`
+
+// GetUserDetail retrieves detailed information about a user by ID. Users the viewer may not
+// see are reported as ErrUserNotFound.
+func (r *UserRepo) GetUserDetail(viewerID, id uuid.UUID) (*models.User, error) {
   query := `
-    SELECT id, name, gender, target_gender, intention, bio, avatar_url, created_at, updated_at
-    FROM users WHERE id = $1
+    SELECT u.id, u.name, u.gender, u.target_gender, u.intention, u.bio, u.avatar_url, u.created_at, u.updated_at, ` + attributeColumns + `
+    FROM users u WHERE u.id = $2 AND ` + visibleTo + `
   `
   user := &models.User{}
 
@@ -251,11 +344,12 @@ func (r *UserRepo) GetUserDetail(id uuid.UUID) (*models.User, error) {
   var intention sql.NullString
   var bio sql.NullString
   var avatarURL sql.NullString
+  var attrs profileAttributes
 
-  err := r.db.QueryRow(query, id).Scan(
+  err := r.db.QueryRow(query, viewerID, id).Scan(append([]interface{}{
     &user.ID, &user.Name, &user.Gender, &targetGender, &intention,
     &bio, &avatarURL, &user.CreatedAt, &user.UpdatedAt,
-  )
+  }, attrs.dest()...)...)
   if err == sql.ErrNoRows {
     return nil, ErrUserNotFound
   }
@@ -278,6 +372,157 @@ func (r *UserRepo) GetUserDetail(id uuid.UUID) (*models.User, error) {
   if avatarURL.Valid {
     user.AvatarURL = avatarURL.String
   }
+  attrs.apply(user)
 
   return user, nil
 }
+
+// ScheduleDeletion marks the user for purging at purgeAt. The account stays usable, but
+// hidden from other users, until then.
+func (r *UserRepo) ScheduleDeletion(id uuid.UUID, purgeAt time.Time) error {
+  query := `
+    UPDATE users
+    SET deletion_requested_at = NOW(), deletion_scheduled_at = $2, updated_at = NOW()
+    WHERE id = $1
+  `
+  result, err := r.db.Exec(query, id, purgeAt)
+  if err != nil {
+    return err
+  }
+  n, err := result.RowsAffected()
+  if err != nil {
+    return err
+  }
+  if n == 0 {
+    return ErrUserNotFound
+  }
+  return nil
+}
+
+// CancelDeletion clears a scheduled deletion. Once the grace period has passed the account
+// belongs to the purge job and ErrDeletionInProgress is returned.
+func (r *UserRepo) CancelDeletion(id uuid.UUID) error {
+  query := `
+    UPDATE users
+    SET deletion_requested_at = NULL, deletion_scheduled_at = NULL, updated_at = NOW()
+    WHERE id = $1 AND deletion_scheduled_at > NOW()
+  `
+  result, err := r.db.Exec(query, id)
+  if err != nil {
+    return err
+  }
+  n, err := result.RowsAffected()
+  if err != nil {
+    return err
+  }
+  if n == 0 {
+    return ErrDeletionInProgress
+  }
+  return nil
+}
+
+// Pause hides the user from other users until Resume is called or resumeAt passes. Pausing
+// an already paused account only changes resumeAt. Returns when the pause started.
+func (r *UserRepo) Pause(id uuid.UUID, resumeAt *time.Time) (time.Time, error) {
+  query := `
+    UPDATE users
+    SET paused_at = COALESCE(paused_at, NOW()), resume_at = $2, updated_at = NOW()
+    WHERE id = $1
+    RETURNING paused_at
+  `
+  var pausedAt time.Time
+  err := r.db.QueryRow(query, id, resumeAt).Scan(&pausedAt)
+  if err == sql.ErrNoRows {
+    return time.Time{}, ErrUserNotFound
+  }
+  return pausedAt, err
+}
+
+// Resume ends a pause, releases the likes received while paused and creates a match for
+// each released like the user had already returned. Returns the new matches.
+func (r *UserRepo) Resume(id uuid.UUID) ([]*models.Match, error) {
+  tx, err := r.db.Begin()
+  if err != nil {
+    return nil, err
+  }
+  defer tx.Rollback()
+
+  result, err := tx.Exec(`
+    UPDATE users SET paused_at = NULL, resume_at = NULL, updated_at = NOW()
+    WHERE id = $1 AND paused_at IS NOT NULL
+  `, id)
+  if err != nil {
+    return nil, err
+  }
+  n, err := result.RowsAffected()
+  if err != nil {
+    return nil, err
+  }
+  if n == 0 {
+    return nil, ErrNotPaused
+  }
+
+  rows, err := tx.Query(`
+    WITH released AS (
+      UPDATE likes SET queued_at = NULL
+      WHERE target_user_id = $1 AND queued_at IS NOT NULL
+      RETURNING user_id
+    )
+    INSERT INTO matches (user1_id, user2_id)
+    SELECT LEAST(released.user_id, $1::uuid), GREATEST(released.user_id, $1::uuid)
+    FROM released
+    WHERE EXISTS (
+      SELECT 1 FROM likes
+      WHERE user_id = $1 AND target_user_id = released.user_id AND status IN ('like', 'super_like')
+    )
+    ON CONFLICT (user1_id, user2_id) DO NOTHING
+    RETURNING id, user1_id, user2_id, created_at, updated_at
+  `, id)
+  if err != nil {
+    return nil, err
+  }
+  defer rows.Close()
+
+  matches := make([]*models.Match, 0)
+  for rows.Next() {
+    match := &models.Match{}
+    if err := rows.Scan(&match.ID, &match.User1ID, &match.User2ID, &match.CreatedAt, &match.UpdatedAt); err != nil {
+      return nil, err
+    }
+    matches = append(matches, match)
+  }
+  if err := rows.Err(); err != nil {
+    return nil, err
+  }
+  rows.Close()
+
+  if err := tx.Commit(); err != nil {
+    return nil, err
+  }
+  return matches, nil
+}
+
+// ListDueForResume returns paused users whose automatic resume time has passed
+func (r *UserRepo) ListDueForResume(limit int) ([]uuid.UUID, error) {
+  query := `
+    SELECT id FROM users
+    WHERE paused_at IS NOT NULL AND resume_at <= NOW()
+    ORDER BY resume_at
+    LIMIT $1
+  `
+  rows, err := r.db.Query(query, limit)
+  if err != nil {
+    return nil, err
+  }
+  defer rows.Close()
+
+  ids := make([]uuid.UUID, 0)
+  for rows.Next() {
+    var id uuid.UUID
+    if err := rows.Scan(&id); err != nil {
+      return nil, err
+    }
+    ids = append(ids, id)
+  }
+  return ids, rows.Err()
+}
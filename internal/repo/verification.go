@@ -0,0 +1,218 @@
+package repo
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+
+	"github.com/Viet-CodingStars/kyupi-kyupi-backend/internal/models"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrVerificationNotFound   = errors.New("verification not found")
+	ErrVerificationInProgress = errors.New("a verification selfie is already waiting for review")
+)
+
+const verificationColumns = `v.id, v.user_id, v.pose, v.selfie_url, v.status, v.reason, v.reviewed_by,
+	v.created_at, v.submitted_at, v.reviewed_at, v.avatar_url`
+
+// VerificationRepo handles database operations for selfie verifications
+type VerificationRepo struct {
+	db *sql.DB
+}
+
+// NewVerificationRepo creates a new VerificationRepo
+func NewVerificationRepo(db *sql.DB) *VerificationRepo {
+	return &VerificationRepo{db: db}
+}
+
+// Start opens a verification asking for a selfie in the given pose. A request that is still
+// awaiting its selfie is replaced, so asking again gives a new pose; one waiting for review is
+// reported as ErrVerificationInProgress.
+func (r *VerificationRepo) Start(userID uuid.UUID, pose string) (*models.PhotoVerification, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`DELETE FROM photo_verifications WHERE user_id = $1 AND status = $2`,
+		userID, models.VerificationAwaitingSelfie)
+	if err != nil {
+		return nil, err
+	}
+	v, err := scanVerification(tx.QueryRow(`
+		INSERT INTO photo_verifications AS v (user_id, pose, status)
+		SELECT id, $2, $3 FROM users WHERE id = $1
+		RETURNING `+verificationColumns,
+		userID, pose, models.VerificationAwaitingSelfie,
+	))
+	if err == sql.ErrNoRows {
+		return nil, ErrUserNotFound
+	}
+	if err != nil {
+		if strings.Contains(err.Error(), "idx_photo_verifications_open") {
+			return nil, ErrVerificationInProgress
+		}
+		return nil, err
+	}
+	return v, tx.Commit()
+}
+
+// Latest returns the user's most recent verification
+func (r *VerificationRepo) Latest(userID uuid.UUID) (*models.PhotoVerification, error) {
+	v, err := scanVerification(r.db.QueryRow(`
+		SELECT `+verificationColumns+` FROM photo_verifications v
+		WHERE v.user_id = $1 ORDER BY v.created_at DESC LIMIT 1
+	`, userID))
+	if err == sql.ErrNoRows {
+		return nil, ErrVerificationNotFound
+	}
+	return v, err
+}
+
+// Submit attaches the selfie to the user's verification awaiting one and queues it for review,
+// recording the user's current avatar as the photo it is compared with. Returns
+// ErrVerificationNotFound when no verification is awaiting a selfie.
+func (r *VerificationRepo) Submit(userID uuid.UUID, selfieURL string) (*models.PhotoVerification, error) {
+	v, err := scanVerification(r.db.QueryRow(`
+		UPDATE photo_verifications v SET selfie_url = $2, status = $3, submitted_at = NOW(),
+			avatar_url = (SELECT avatar_url FROM users WHERE id = v.user_id)
+		WHERE v.user_id = $1 AND v.status = $4
+		RETURNING `+verificationColumns,
+		userID, selfieURL, models.VerificationPending, models.VerificationAwaitingSelfie,
+	))
+	if err == sql.ErrNoRows {
+		return nil, ErrVerificationNotFound
+	}
+	return v, err
+}
+
+// ListPending returns the review queue, longest waiting first, each with the avatar to compare
+// the selfie with.
+func (r *VerificationRepo) ListPending(limit int) ([]*models.PhotoVerification, error) {
+	rows, err := r.db.Query(`
+		SELECT `+verificationColumns+`
+		FROM photo_verifications v
+		JOIN users u ON u.id = v.user_id
+		WHERE v.status = $1 AND u.deletion_scheduled_at IS NULL
+		ORDER BY v.submitted_at, v.id
+		LIMIT $2
+	`, models.VerificationPending, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	queue := make([]*models.PhotoVerification, 0)
+	for rows.Next() {
+		v, err := scanVerification(rows)
+		if err != nil {
+			return nil, err
+		}
+		queue = append(queue, v)
+	}
+	return queue, rows.Err()
+}
+
+// Review records the decision on a pending verification; reviewerID is nil when an automated
+// check decided. Approving marks the user as verified, but only while their avatar is still the
+// one recorded with the selfie; otherwise the verification is rejected with
+// models.VerificationReasonAvatarChanged. Returns ErrVerificationNotFound when the verification
+// is not waiting for review.
+func (r *VerificationRepo) Review(id uuid.UUID, reviewerID *uuid.UUID, status, reason string) (*models.PhotoVerification, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	// Locking the user row holds back an avatar change until the decision is committed; the
+	// change then clears verified_at again.
+	var avatarChanged bool
+	err = tx.QueryRow(`
+		SELECT u.avatar_url IS DISTINCT FROM v.avatar_url
+		FROM photo_verifications v JOIN users u ON u.id = v.user_id
+		WHERE v.id = $1 AND v.status = $2
+		FOR UPDATE
+	`, id, models.VerificationPending).Scan(&avatarChanged)
+	if err == sql.ErrNoRows {
+		return nil, ErrVerificationNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if status == models.VerificationApproved && avatarChanged {
+		status, reason = models.VerificationRejected, models.VerificationReasonAvatarChanged
+	}
+
+	v, err := scanVerification(tx.QueryRow(`
+		UPDATE photo_verifications v SET status = $3, reason = NULLIF($4, ''), reviewed_by = $5, reviewed_at = NOW()
+		WHERE v.id = $1 AND v.status = $2
+		RETURNING `+verificationColumns,
+		id, models.VerificationPending, status, reason, reviewerID,
+	))
+	if err == sql.ErrNoRows {
+		return nil, ErrVerificationNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if status == models.VerificationApproved {
+		_, err := tx.Exec(`UPDATE users SET verified_at = NOW() WHERE id = $1 AND avatar_url = $2`, v.UserID, v.AvatarURL)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return v, tx.Commit()
+}
+
+// ListByUser returns every verification the user requested, oldest first
+func (r *VerificationRepo) ListByUser(userID uuid.UUID) ([]*models.PhotoVerification, error) {
+	rows, err := r.db.Query(`
+		SELECT `+verificationColumns+` FROM photo_verifications v
+		WHERE v.user_id = $1 ORDER BY v.created_at
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	verifications := make([]*models.PhotoVerification, 0)
+	for rows.Next() {
+		v, err := scanVerification(rows)
+		if err != nil {
+			return nil, err
+		}
+		verifications = append(verifications, v)
+	}
+	return verifications, rows.Err()
+}
+
+func scanVerification(row rowScanner) (*models.PhotoVerification, error) {
+	v := &models.PhotoVerification{}
+	var selfieURL, reason, avatarURL sql.NullString
+	var reviewedBy uuid.NullUUID
+	var submittedAt, reviewedAt sql.NullTime
+	err := row.Scan(
+		&v.ID, &v.UserID, &v.Pose, &selfieURL, &v.Status, &reason, &reviewedBy,
+		&v.CreatedAt, &submittedAt, &reviewedAt, &avatarURL,
+	)
+	if err != nil {
+		return nil, err
+	}
+	v.SelfieURL = selfieURL.String
+	v.Reason = reason.String
+	v.AvatarURL = avatarURL.String
+	if reviewedBy.Valid {
+		v.ReviewedBy = &reviewedBy.UUID
+	}
+	if submittedAt.Valid {
+		v.SubmittedAt = &submittedAt.Time
+	}
+	if reviewedAt.Valid {
+		v.ReviewedAt = &reviewedAt.Time
+	}
+	return v, nil
+}
@@ -2,14 +2,17 @@ package routes
 
 import (
 	"database/sql"
+	"log"
 	"net/http"
-	"time"
 
+	"github.com/Viet-CodingStars/kyupi-kyupi-backend/internal/auth"
 	"github.com/Viet-CodingStars/kyupi-kyupi-backend/internal/config"
 	"github.com/Viet-CodingStars/kyupi-kyupi-backend/internal/handlers"
 	"github.com/Viet-CodingStars/kyupi-kyupi-backend/internal/middleware"
+	"github.com/Viet-CodingStars/kyupi-kyupi-backend/internal/oidc"
 	"github.com/Viet-CodingStars/kyupi-kyupi-backend/internal/repo"
 	"github.com/Viet-CodingStars/kyupi-kyupi-backend/internal/storage"
+	"github.com/Viet-CodingStars/kyupi-kyupi-backend/internal/verification"
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 	swaggerFiles "github.com/swaggo/files"
@@ -17,8 +20,12 @@ import (
 	"go.mongodb.org/mongo-driver/mongo"
 )
 
+// SelfieURLPrefix is the admin-only route serving verification selfies; selfie storage returns
+// URLs under it.
+const SelfieURLPrefix = "/api/admin/verifications/selfies"
+
 // NewRouter creates the Gin engine for the application.
-func NewRouter(db *sql.DB, cfg *config.Config, avatarStorage storage.AvatarStorage, mongoClient *mongo.Client) http.Handler {
+func NewRouter(db *sql.DB, cfg *config.Config, keys *auth.KeySet, avatarStorage storage.AvatarStorage, selfieStorage storage.SelfieStorage, mongoClient *mongo.Client, recommender handlers.Recommender, picker handlers.PickSelector, verifier verification.Verifier) http.Handler {
 	if cfg.IsProduction() {
 		gin.SetMode(gin.ReleaseMode)
 	}
@@ -26,14 +33,26 @@ func NewRouter(db *sql.DB, cfg *config.Config, avatarStorage storage.AvatarStora
 	router := gin.New()
 	router.Use(gin.Logger(), gin.Recovery())
 
-	router.Use(cors.New(cors.Config{
-		AllowOrigins:     []string{"http://localhost:5174"},
-		AllowMethods:     []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
-		AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "Authorization"},
+	// Gin trusts X-Forwarded-For from any peer by default, which lets clients pick the IP
+	// recorded for sessions and signups. Only configured proxies are believed.
+	if err := router.SetTrustedProxies(cfg.TrustedProxies); err != nil {
+		log.Printf("invalid TRUSTED_PROXIES, trusting no proxy: %v", err)
+		router.SetTrustedProxies(nil)
+	}
+
+	corsConfig := cors.Config{
+		AllowMethods:     cfg.CORSAllowMethods,
+		AllowHeaders:     cfg.CORSAllowHeaders,
 		ExposeHeaders:    []string{"Content-Length"},
-		AllowCredentials: true,
-		MaxAge:           12 * time.Hour,
-	}))
+		AllowCredentials: cfg.CORSAllowCredentials,
+		MaxAge:           cfg.CORSMaxAge,
+	}
+	if cfg.CORSAllowsAnyOrigin() {
+		corsConfig.AllowAllOrigins = true
+	} else {
+		corsConfig.AllowOriginFunc = cfg.AllowsOrigin
+	}
+	router.Use(cors.New(corsConfig))
 
 	if cfg.AvatarStorageDir != "" && cfg.AvatarURLPrefix != "" {
 		router.Static(cfg.AvatarURLPrefix, cfg.AvatarStorageDir)
@@ -42,35 +61,103 @@ func NewRouter(db *sql.DB, cfg *config.Config, avatarStorage storage.AvatarStora
 	router.GET("/", handlers.RootHandler)
 	router.GET("/health", handlers.HealthHandler)
 	router.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
+	router.GET("/.well-known/jwks.json", handlers.NewJWKSHandler(keys).GetJWKS)
 
-	userHandler := handlers.NewUserHandler(db, cfg.JWTSecret, avatarStorage)
-	likeHandler := handlers.NewLikeHandler(db)
-	passHandler := handlers.NewPassHandler(db)
+	// Chat messages live in MongoDB; new matches are seeded with like comments
+	mongoDB := mongoClient.Database(cfg.MongoDatabase)
+	messageRepo := repo.NewMessageRepo(mongoDB)
+
+	userHandler := handlers.NewUserHandler(db, keys, avatarStorage, messageRepo, recommender, cfg.AccountDeletionGracePeriod)
+
+	oidcProviders := make(map[string]handlers.OIDCProvider, len(cfg.OIDCProviders))
+	for _, p := range cfg.OIDCProviders {
+		oidcProviders[p.Name] = oidc.NewProvider(oidc.ProviderConfig{
+			Name:         p.Name,
+			Issuer:       p.Issuer,
+			ClientID:     p.ClientID,
+			ClientSecret: p.ClientSecret,
+			RedirectURL:  p.RedirectURL,
+			Scopes:       p.Scopes,
+		}, nil)
+	}
+	oidcHandler := handlers.NewOIDCHandler(db, keys, oidcProviders)
+	mfaHandler := handlers.NewMFAHandler(db, keys)
+	likeHandler := handlers.NewLikeHandler(db, messageRepo, cfg.SuperLikeDailyQuota)
+	passHandler := handlers.NewPassHandler(db, cfg.RewindWindow, cfg.RewindDailyLimit)
+	pickHandler := handlers.NewPickHandler(db, picker, messageRepo, cfg.DailyPicksCount, cfg.PickLikeDailyQuota)
 	matchHandler := handlers.NewMatchHandler(db)
 	
 	// MongoDB handlers
-	mongoDB := mongoClient.Database(cfg.MongoDatabase)
-	messageRepo := repo.NewMessageRepo(mongoDB)
 	matchRepo := repo.NewMatchRepo(db)
 	chatHandler := handlers.NewChatHandler(messageRepo, matchRepo)
 	
-	authMw := middleware.AuthMiddleware(cfg.JWTSecret)
+	sessionHandler := handlers.NewSessionHandler(db)
+	exportHandler := handlers.NewExportHandler(db)
+	preferenceHandler := handlers.NewPreferenceHandler(db)
+	interestHandler := handlers.NewInterestHandler(db, cfg.MaxInterests)
+	promptHandler := handlers.NewPromptHandler(db)
+	questionHandler := handlers.NewQuestionHandler(db)
+	verificationHandler := handlers.NewVerificationHandler(db, selfieStorage, verifier)
+	moderationHandler := handlers.NewModerationHandler(db)
+	authMw := middleware.AuthMiddleware(keys, repo.NewSessionRepo(db))
 
 	api := router.Group("/api")
 	{
 		api.POST("/users", userHandler.SignUp)
 		api.POST("/users/sign_in", userHandler.SignIn)
-		api.GET("/users", userHandler.GetUsers)
-		api.GET("/users/:user_id/detail", userHandler.GetUserDetail)
+		api.POST("/users/sign_in/mfa", mfaHandler.SignIn)
+
+		api.GET("/auth/oidc/:provider/authorize", oidcHandler.Authorize)
+		api.POST("/auth/oidc/:provider/callback", oidcHandler.Callback)
+		api.POST("/auth/oidc/register", oidcHandler.Register)
+
+		api.GET("/exports/download/:token", exportHandler.DownloadExport)
+
+		api.GET("/interests", interestHandler.GetCatalog)
+		api.GET("/prompts", promptHandler.GetCatalog)
+		api.GET("/questions", questionHandler.GetQuestions)
 
 		users := api.Group("/users")
 		users.Use(authMw)
 		{
+			users.GET("", userHandler.GetUsers)
+			users.GET("/:user_id/detail", userHandler.GetUserDetail)
 			users.DELETE("/sign_out", userHandler.SignOut)
 			users.GET("/profile", userHandler.GetProfile)
 			users.PATCH("/profile", userHandler.UpdateProfile)
 			users.PUT("/profile", userHandler.UpdateProfile)
+			users.DELETE("/profile", userHandler.DeleteAccount)
+			users.POST("/profile/restore", userHandler.RestoreAccount)
+			users.GET("/reauthenticate/:provider/authorize", oidcHandler.AuthorizeReauthentication)
+			users.POST("/profile/pause", userHandler.PauseAccount)
+			users.POST("/profile/resume", userHandler.ResumeAccount)
 			users.POST("/profile/avatar", userHandler.UploadAvatar)
+			users.GET("/preferences", preferenceHandler.GetPreferences)
+			users.PUT("/preferences", preferenceHandler.UpdatePreferences)
+			users.GET("/interests", interestHandler.GetInterests)
+			users.PUT("/interests", interestHandler.UpdateInterests)
+			users.GET("/prompts", promptHandler.GetAnswers)
+			users.PUT("/prompts", promptHandler.UpdateAnswers)
+			users.GET("/questions", questionHandler.GetAnswers)
+			users.PUT("/questions/:question_id", questionHandler.AnswerQuestion)
+			users.DELETE("/questions/:question_id", questionHandler.DeleteAnswer)
+			users.GET("/verification", verificationHandler.GetVerification)
+			users.POST("/verification", verificationHandler.StartVerification)
+			users.POST("/verification/selfie", verificationHandler.SubmitSelfie)
+
+			users.GET("/mfa", mfaHandler.GetStatus)
+			users.POST("/mfa/totp", mfaHandler.EnrollTOTP)
+			users.POST("/mfa/totp/verify", mfaHandler.ConfirmTOTP)
+			users.DELETE("/mfa/totp", mfaHandler.DisableTOTP)
+			users.POST("/mfa/recovery_codes", mfaHandler.RegenerateRecoveryCodes)
+
+			users.GET("/sessions", sessionHandler.GetSessions)
+			users.DELETE("/sessions", sessionHandler.RevokeOtherSessions)
+			users.DELETE("/sessions/:session_id", sessionHandler.RevokeSession)
+
+			users.POST("/exports", exportHandler.RequestExport)
+			users.GET("/exports", exportHandler.GetExports)
+			users.GET("/exports/:export_id", exportHandler.GetExport)
 		}
 
 		// Protected API routes
@@ -78,13 +165,35 @@ func NewRouter(db *sql.DB, cfg *config.Config, avatarStorage storage.AvatarStora
 		protected.Use(authMw)
 		{
 			protected.POST("/likes", likeHandler.CreateLike)
+			protected.GET("/likes/received", likeHandler.GetReceivedLikes)
+			protected.GET("/likes/super_likes", likeHandler.GetSuperLikeQuota)
 			protected.POST("/passes", passHandler.CreatePass)
+			protected.POST("/passes/rewind", passHandler.RewindPass)
+			protected.GET("/picks", pickHandler.GetPicks)
+			protected.POST("/picks/:user_id/like", pickHandler.LikePick)
 			protected.GET("/matches", matchHandler.GetMatches)
 			
 			// Chat endpoints
 			protected.POST("/messages", chatHandler.SendMessage)
 			protected.GET("/matches/:match_id/messages", chatHandler.GetMessages)
 		}
+
+		// Admin routes; the role is checked against the database on every request
+		admin := api.Group("/admin")
+		admin.Use(authMw, middleware.RequireAdmin(repo.NewUserRepo(db)))
+		{
+			admin.GET("/interests", interestHandler.ListCatalog)
+			admin.POST("/interests", interestHandler.CreateInterest)
+			admin.PUT("/interests/:slug", interestHandler.UpdateInterest)
+			admin.DELETE("/interests/:slug", interestHandler.DeleteInterest)
+			admin.GET("/verifications", verificationHandler.ListVerificationQueue)
+			admin.GET("/verifications/selfies/:user_id/:filename", verificationHandler.GetSelfie)
+			admin.POST("/verifications/:verification_id/approve", verificationHandler.ApproveVerification)
+			admin.POST("/verifications/:verification_id/reject", verificationHandler.RejectVerification)
+			admin.GET("/flags", moderationHandler.ListFlags)
+			admin.POST("/flags/:flag_id/dismiss", moderationHandler.DismissFlag)
+			admin.POST("/flags/:flag_id/confirm", moderationHandler.ConfirmFlag)
+		}
 	}
 
 	return router
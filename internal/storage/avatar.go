@@ -15,6 +15,10 @@ import (
 // AvatarStorage defines persistence behaviour for user avatar files.
 type AvatarStorage interface {
 	Save(userID uuid.UUID, data io.Reader, originalFilename string) (string, error)
+	// DeleteAll removes every file stored for the user. It succeeds if there are none.
+	DeleteAll(userID uuid.UUID) error
+	// ForEach calls fn with every file stored for the user. It succeeds if there are none.
+	ForEach(userID uuid.UUID, fn func(filename string, data io.Reader) error) error
 }
 
 // LocalAvatarStorage stores avatar images on the local filesystem and exposes them via a configurable URL prefix.
@@ -72,6 +76,52 @@ func (s *LocalAvatarStorage) Save(userID uuid.UUID, data io.Reader, originalFile
 	return publicPath, nil
 }
 
+// DeleteAll removes the user's avatar directory.
+func (s *LocalAvatarStorage) DeleteAll(userID uuid.UUID) error {
+	if userID == uuid.Nil {
+		return fmt.Errorf("userID is required")
+	}
+	if err := os.RemoveAll(filepath.Join(s.baseDir, userID.String())); err != nil {
+		return fmt.Errorf("remove user avatar dir: %w", err)
+	}
+	return nil
+}
+
+// ForEach walks the user's avatar directory in filename order.
+func (s *LocalAvatarStorage) ForEach(userID uuid.UUID, fn func(filename string, data io.Reader) error) error {
+	if userID == uuid.Nil {
+		return fmt.Errorf("userID is required")
+	}
+
+	userDir := filepath.Join(s.baseDir, userID.String())
+	entries, err := os.ReadDir(userDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("read user avatar dir: %w", err)
+	}
+
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() {
+			continue
+		}
+		if err := s.visit(filepath.Join(userDir, entry.Name()), entry.Name(), fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *LocalAvatarStorage) visit(fullPath, filename string, fn func(string, io.Reader) error) error {
+	file, err := os.Open(fullPath)
+	if err != nil {
+		return fmt.Errorf("open avatar file: %w", err)
+	}
+	defer file.Close()
+	return fn(filename, file)
+}
+
 func trimTrailingSlash(prefix string) string {
 	if prefix == "/" {
 		return prefix
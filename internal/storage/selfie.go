@@ -0,0 +1,65 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/google/uuid"
+)
+
+// ErrFileNotFound is returned by Open when the user has no file with that name.
+var ErrFileNotFound = errors.New("file not found")
+
+// SelfieStorage keeps photo verification selfies. Selfies are biometric data, so unlike
+// avatars they are never served statically; they are read back through Open by a handler
+// that checks who is asking.
+type SelfieStorage interface {
+	AvatarStorage
+	// Open returns one of the user's files by the name in the path Save returned.
+	Open(userID uuid.UUID, filename string) (io.ReadCloser, error)
+}
+
+// LocalSelfieStorage stores selfies on the local filesystem in a directory of their own, which
+// must not be publicly served. Save returns urlPrefix/<user id>/<file name>, where urlPrefix is
+// the authenticated route serving them.
+type LocalSelfieStorage struct {
+	*LocalAvatarStorage
+}
+
+// NewLocalSelfieStorage constructs a LocalSelfieStorage ensuring the target directory exists
+// and is only readable by the service.
+func NewLocalSelfieStorage(baseDir, urlPrefix string) (*LocalSelfieStorage, error) {
+	if baseDir == "" {
+		return nil, fmt.Errorf("baseDir is required")
+	}
+	if err := os.MkdirAll(baseDir, 0o700); err != nil {
+		return nil, fmt.Errorf("create selfie storage dir: %w", err)
+	}
+	files, err := NewLocalAvatarStorage(baseDir, urlPrefix)
+	if err != nil {
+		return nil, err
+	}
+	return &LocalSelfieStorage{LocalAvatarStorage: files}, nil
+}
+
+// Open returns the user's selfie with the given file name.
+func (s *LocalSelfieStorage) Open(userID uuid.UUID, filename string) (io.ReadCloser, error) {
+	if userID == uuid.Nil {
+		return nil, fmt.Errorf("userID is required")
+	}
+	if filename == "" || filename != filepath.Base(filename) || filename == "." || filename == ".." {
+		return nil, ErrFileNotFound
+	}
+
+	file, err := os.Open(filepath.Join(s.baseDir, userID.String(), filename))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, ErrFileNotFound
+		}
+		return nil, fmt.Errorf("open selfie file: %w", err)
+	}
+	return file, nil
+}
@@ -0,0 +1,36 @@
+// Package verification decides whether a verification selfie matches the user's photos.
+package verification
+
+import (
+	"context"
+
+	"github.com/Viet-CodingStars/kyupi-kyupi-backend/internal/models"
+)
+
+// Result is a Verifier's decision on a submitted selfie.
+type Result struct {
+	// Status is models.VerificationApproved or models.VerificationRejected, or
+	// models.VerificationPending to leave the selfie for a reviewer.
+	Status string
+	Reason string // why the selfie was rejected
+}
+
+// Verifier compares a submitted selfie with the requested pose and the user's avatar.
+// Implementations decide how (a reviewer, a face-matching service, ...).
+type Verifier interface {
+	Verify(ctx context.Context, v *models.PhotoVerification, avatarURL string) (Result, error)
+}
+
+// ManualReview leaves every selfie in the review queue for an admin to decide. It is the
+// default until an automated checker is configured.
+type ManualReview struct{}
+
+// NewManualReview creates a ManualReview
+func NewManualReview() *ManualReview {
+	return &ManualReview{}
+}
+
+// Verify never decides, so the selfie stays pending.
+func (m *ManualReview) Verify(ctx context.Context, v *models.PhotoVerification, avatarURL string) (Result, error) {
+	return Result{Status: models.VerificationPending}, nil
+}
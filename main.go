@@ -10,17 +10,33 @@ import (
 	"path/filepath"
 	"syscall"
 	"time"
+	_ "time/tzdata" // the runtime image ships without zoneinfo; needed to validate user timezones
 
 	docs "github.com/Viet-CodingStars/kyupi-kyupi-backend/docs"
+	"github.com/Viet-CodingStars/kyupi-kyupi-backend/internal/auth"
 	"github.com/Viet-CodingStars/kyupi-kyupi-backend/internal/config"
 	"github.com/Viet-CodingStars/kyupi-kyupi-backend/internal/db"
+	"github.com/Viet-CodingStars/kyupi-kyupi-backend/internal/discovery"
+	"github.com/Viet-CodingStars/kyupi-kyupi-backend/internal/jobs"
+	"github.com/Viet-CodingStars/kyupi-kyupi-backend/internal/notify"
+	"github.com/Viet-CodingStars/kyupi-kyupi-backend/internal/repo"
 	"github.com/Viet-CodingStars/kyupi-kyupi-backend/internal/routes"
 	"github.com/Viet-CodingStars/kyupi-kyupi-backend/internal/storage"
+	"github.com/Viet-CodingStars/kyupi-kyupi-backend/internal/verification"
 )
 
 func main() {
 	cfg := config.LoadFromEnv()
 
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "config":
+			os.Exit(runConfigCommand(cfg, os.Args[2:], os.Stdout, os.Stderr))
+		default:
+			log.Fatalf("unknown command %q", os.Args[1])
+		}
+	}
+
 	if err := os.MkdirAll(cfg.LogDir, 0o755); err != nil {
 		log.Fatalf("failed to create log directory: %v", err)
 	}
@@ -34,15 +50,48 @@ func main() {
 
 	log.SetOutput(io.MultiWriter(os.Stdout, logFile))
 
+	if problems := cfg.Validate(); len(problems) > 0 {
+		for _, problem := range problems {
+			log.Printf("config problem: %v", problem)
+		}
+		if cfg.IsProduction() {
+			log.Fatalf("refusing to start in production with %d configuration problem(s); run `config check` for details", len(problems))
+		}
+	}
+
 	docs.SwaggerInfo.BasePath = "/"
 	docs.SwaggerInfo.Title = "Kyupi Kyupi Backend API"
 	docs.SwaggerInfo.Description = "API documentation for the Kyupi Kyupi backend service."
 	docs.SwaggerInfo.Version = "1.0"
 
+	keys, err := auth.LoadKeySet(auth.KeySource{
+		Secret:           cfg.JWTSecret,
+		SigningKeyPEM:    cfg.JWTSigningKey,
+		SigningKeyFile:   cfg.JWTSigningKeyFile,
+		SigningKeyID:     cfg.JWTSigningKeyID,
+		VerificationKeys: cfg.JWTVerificationKeys,
+		VerificationJWKS: cfg.JWTVerificationJWKS,
+	})
+	if err != nil {
+		log.Fatalf("failed to load JWT keys: %v", err)
+	}
+	if kid := keys.SigningKeyID(); kid != "" {
+		log.Printf("signing tokens with key %s", kid)
+	}
+
+	discoveryWeights, err := discovery.ParseWeights(cfg.DiscoveryWeights)
+	if err != nil {
+		log.Fatalf("invalid DISCOVERY_WEIGHTS: %v", err)
+	}
+
 	avatarStorage, err := storage.NewLocalAvatarStorage(cfg.AvatarStorageDir, cfg.AvatarURLPrefix)
 	if err != nil {
 		log.Fatalf("failed to initialize avatar storage: %v", err)
 	}
+	selfieStorage, err := storage.NewLocalSelfieStorage(cfg.VerificationStorageDir, routes.SelfieURLPrefix)
+	if err != nil {
+		log.Fatalf("failed to initialize verification selfie storage: %v", err)
+	}
 
 	pg, err := db.Connect(cfg)
 	if err != nil {
@@ -62,10 +111,45 @@ func main() {
 		}
 	}()
 
+	ranker := discovery.NewEngine(repo.NewDiscoveryRepo(pg), discoveryWeights,
+		float64(cfg.DiscoveryMinCompleteness)/100, cfg.DiscoveryCacheTTL, cfg.DiscoveryCacheSize)
+
+	jobsCtx, stopJobs := context.WithCancel(context.Background())
+	defer stopJobs()
+	if cfg.AccountPurgeInterval > 0 {
+		purger := jobs.NewAccountPurger(pg, mongoClient.Database(cfg.MongoDatabase), avatarStorage, selfieStorage, cfg.ExportStorageDir)
+		go jobs.Every(jobsCtx, cfg.AccountPurgeInterval, "account-purge", purger.Run)
+	}
+	if cfg.DataExportInterval > 0 {
+		exporter := jobs.NewDataExporter(pg, mongoClient.Database(cfg.MongoDatabase), avatarStorage, selfieStorage,
+			notify.NewLogNotifier(), cfg.ExportStorageDir, cfg.DataExportRetention)
+		go jobs.Every(jobsCtx, cfg.DataExportInterval, "data-export", exporter.Run)
+	}
+	if cfg.PauseResumeInterval > 0 {
+		resumer := jobs.NewPauseResumer(pg, mongoClient.Database(cfg.MongoDatabase))
+		go jobs.Every(jobsCtx, cfg.PauseResumeInterval, "pause-resume", resumer.Run)
+	}
+	if cfg.PassExpiryInterval > 0 && cfg.PassExpiry > 0 {
+		expirer := jobs.NewPassExpirer(pg, cfg.PassExpiry)
+		go jobs.Every(jobsCtx, cfg.PassExpiryInterval, "pass-expiry", expirer.Run)
+	}
+	if cfg.DiscoveryRefreshInterval > 0 {
+		refresher := jobs.NewCandidateRefresher(pg, ranker, cfg.DiscoveryCacheTTL)
+		go jobs.Every(jobsCtx, cfg.DiscoveryRefreshInterval, "discovery-refresh", refresher.Run)
+	}
+	if cfg.DesirabilityInterval > 0 {
+		scorer := jobs.NewDesirabilityScorer(pg)
+		go jobs.Every(jobsCtx, cfg.DesirabilityInterval, "desirability", scorer.Run)
+	}
+	if cfg.ModerationScanInterval > 0 {
+		screener := jobs.NewAccountScreener(pg, avatarStorage, cfg.ModerationAutoRestrict)
+		go jobs.Every(jobsCtx, cfg.ModerationScanInterval, "account-screening", screener.Run)
+	}
+
 	addr := cfg.Addr()
 	srv := &http.Server{
 		Addr:    addr,
-		Handler: routes.NewRouter(pg, cfg, avatarStorage, mongoClient),
+		Handler: routes.NewRouter(pg, cfg, keys, avatarStorage, selfieStorage, mongoClient, ranker, ranker, verification.NewManualReview()),
 	}
 
 	log.Printf("starting server (env=%s) on %s", cfg.Env, addr)
@@ -82,6 +166,7 @@ func main() {
 	signal.Notify(quit, os.Interrupt, syscall.SIGTERM)
 	<-quit
 	log.Println("shutting down server...")
+	stopJobs()
 
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()